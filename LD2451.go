@@ -2,231 +2,785 @@ package LD2451
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/tarm/serial"
+	"github.com/Battlekeeper/LD2451/v2/hlk"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/transport"
 )
 
 type Config struct {
-	SerialPort       string
-	BaudRate         int
-	TargetBufferSize int //Size of the channel buffer to store targets in
-}
+	SerialPort       string // Serial port to open, required by Open
+	BaudRate         int    // Baud rate configured on the module, one of the Baud constants (default 115200)
+	AnyBaudRate      bool   // Accept a BaudRate other than the Baud constants, e.g. for a serial bridge that converts rates
+	TargetBufferSize int    // Size of the channel buffer to store targets in (default 64)
 
-type Target struct {
-	Angle     int       // Angle of the target relative to the perpendicular direction of the antenna
-	Distance  int       // Distance in meters to the target
-	Direction Direction // Direction of movement relative to the antenna
-	Speed     int       // Speed in KM/H
-	SNR       int       // Signal to Noise Ratio
-}
+	SensorID string // Set as Sensor on every Target, Frame and event, to tell the sources of a pipeline fed by several sensors apart
 
-const (
-	DirectionAway   Direction = 0
-	DirectionToward Direction = 1
-)
+	SpeedSmoothing  SmoothingMode // Smoothing applied to Speed before targets are delivered
+	SmoothingWindow int           // Number of frames averaged when using SmoothingMovingAverage
+	SmoothingFactor float64       // Weight of the newest sample (0-1] when using SmoothingExponential
 
-type Direction int
+	Heartbeats bool // Deliver a Heartbeat for every frame that reports no targets
 
-func (d Direction) String() string {
-	switch d {
-	case DirectionAway:
-		return "Away"
-	case DirectionToward:
-		return "Toward"
-	default:
-		return "Unknown"
-	}
+	ClearTickInterval time.Duration // Deliver a ClearTick this often while no target is delivered, zero disables them
+
+	ErrorBufferSize int // Size of the channel buffers to store errors and diagnostics in, errors beyond it are dropped and counted (default 8)
+
+	MaxTargetAge time.Duration // Buffered targets older than this are discarded by ReadTarget, zero keeps all
+
+	OpenRetryTimeout time.Duration // Keep retrying to open the port for this long before Open fails, zero fails immediately
+	OpenRetryBackoff time.Duration // Initial delay between open attempts, doubled after every failure up to 5s (default 250ms)
+
+	DetectionParameters *DetectionParameters // Desired detection parameters, verified and applied by Open when set
+	AlarmParameters     *AlarmParameters     // Desired alarm trigger count and SNR threshold, verified and applied by Open when set; the trigger speed and hold time come with DetectionParameters
+
+	DetectProtocol bool              // Query the protocol and firmware version at Open and decode frames in the layout they use, see DetectProtocol
+	FrameVariant   *protocol.Variant // Layout frames are decoded with, e.g. for firmware reporting sub-meter distances, taking precedence over DetectProtocol (default protocol.VariantV1)
+
+	RecoverConfigMode time.Duration // At Open, wait this long for a data frame and end the config mode a previous process may have left the module in when none arrives, zero disables
+
+	WaitForFirstFrame time.Duration // Open fails unless a data frame arrives, or the module answers a firmware version query, within this long, catching a wrong port or baud rate at startup; zero doesn't wait
+
+	DegradedAfter time.Duration // The sensor is considered degraded when no valid frame arrives for this long (default 3s)
+
+	FrameGapFactor float64       // Publish a FrameGap event when frames are further apart than this multiple of FramePeriod, zero disables
+	FramePeriod    time.Duration // Expected interval between frames, measured from the incoming frames if zero
+
+	Filters []Filter // Targets are only delivered when every filter allows them
+
+	MinFrames       int // Only deliver targets reported in at least this many consecutive frames, suppressing single frame blips; 0 or 1 delivers every target
+	PersistenceGate int // Largest distance change in meters between frames for a target to count as the same one for MinFrames (default 2)
+
+	ReportInterval time.Duration // Deliver the targets of at most one frame per interval, zero delivers every frame
+
+	MaxTargets     int            // Most targets accepted per frame, at most protocol.MaxTargets (default protocol.MaxTargets)
+	TargetOverflow OverflowPolicy // What happens to frames reporting more than MaxTargets targets (default OverflowTruncate)
+
+	UnknownFrames UnknownFramePolicy // What happens to command frames that are no acknowledgement of a known command (default UnknownSkip)
+
+	ParseFailureLimit   int           // After this many frames in a row failed to decode, pause and flush the input before resynchronizing, zero disables
+	ParseFailureBackoff time.Duration // How long reading pauses after ParseFailureLimit failures (default 100ms)
+
+	ParseMode ParseMode // How strictly malformed frames are treated, e.g. ParseStrict for safety related uses failing loudly (default ParseDefault)
+
+	TargetValidation ValidationPolicy // What happens to decoded targets outside TargetLimits (default ValidateReject)
+	TargetLimits     TargetLimits     // Plausible values of a target, checked before Mounting is applied
+
+	AngleSign   AngleSign // Side of the antenna perpendicular positive angles are on (default AnglePositiveRight, as reported by the module)
+	InvertAngle bool      // Negate angles on top of AngleSign, e.g. for a sensor mounted upside down
+
+	Mounting *Mounting // Correct distance and angle of targets for the installation, before any filter sees them
+
+	CosineCorrection bool // Correct Speed for the angle between the line of sight and the road, which makes off-axis targets read low, keeping the reported speed in Target.RadialSpeed. Uses Mounting when set and the reported angle otherwise
+
+	SnapshotWindow time.Duration // How long delivered targets are returned by CurrentTargets (default 1s)
+	SnapshotLimit  int           // Most targets held for CurrentTargets, the oldest evicted first when more arrive within SnapshotWindow (default 1024)
+
+	PeakWindow  time.Duration // Length of the windows Peaks reports the peak concurrency for (default 1m)
+	PeakWindows int           // Number of windows Peaks keeps, the oldest evicted first (default 60)
+
+	SinkFlushInterval time.Duration // How often sinks added with AddSink are flushed (default 1s)
+
+	SummaryInterval time.Duration // Deliver a Summary of the delivered targets this often, zero disables summaries
+	SummaryOnly     bool          // Only count targets in summaries instead of delivering them, so nobody needs to read them
+
+	SpeedHistogramBucket int // Width in KM/H of the speed ranges of SpeedStats.Histogram (default 10)
+
+	Tap io.Writer // Receives a copy of every byte read from and written to the port, in order, e.g. to capture traffic while debugging firmware quirks. Unused with NewSource
+
+	DecodeLog io.Writer // Receives a line of text for every frame read from or written to the port, naming its kind, length, command word, status and targets, to debug the protocol without the datasheet at hand
+
+	Logger Logger // Receives diagnostics such as read and parse errors, resyncs and state changes, nil logs nothing
+
+	Clock Clock // Source of time for timestamps, timeouts and windows, nil uses SystemClock
+
+	HardwareReset *HardwareReset // How the module's reset is wired to the adapter's modem lines, enabling ResetHardware
+
+	Reconnect bool // Keep reopening the serial port after it failed, e.g. because the USB adapter was unplugged, instead of stopping the reader. Use a /dev/serial/by-id path to find the same adapter under a new name
+
+	ReopenAfterBaudChange bool // Make SetBaudRate restart the module and reopen the serial port at the new rate, so reading carries on instead of the port going quiet; needs a port opened by Open
 }
 
+// The wire level types are defined by the protocol package.
+type (
+	Target     = protocol.Target
+	Frame      = protocol.Frame
+	Direction  = protocol.Direction
+	ParseError = protocol.ParseError
+)
+
+const (
+	DirectionAway   = protocol.DirectionAway
+	DirectionToward = protocol.DirectionToward
+)
+
 type LD2451 struct {
 	config  Config
-	targets chan Target
-	errors  chan error
-	port    *serial.Port
+	targets chan delivery
+	errors  chan error    //transport errors, see diagnostics for recoverable ones
+	done    chan struct{} //closed when the read goroutine stops
+	fatal   error         //error that stopped the read goroutine, set before done is closed
+	beats   chan Heartbeat
+	alarms  chan AlarmEvent
+	alarm   bool
+	port    io.WriteCloser                         //guarded by writeMu once the read goroutine runs
+	frames  Source                                 //only used by the read goroutine
+	reopen  func(baud int) (transport.Port, error) //opens the port again at baud, nil when it was not opened by name
+	closed  chan struct{}                          //closed by Close
+	closeMu sync.Once
+
+	targetScratch []Target   //targets slice reused for every frame by the read goroutine
+	portName      string     //describes port in errors
+	writeMu       sync.Mutex //guards writes to port
+
+	statsMu    sync.Mutex
+	stats      Stats
+	opened     time.Time
+	lastFrame  time.Time
+	lastTarget time.Time
+	state      SensorState
+	stateSince time.Time
+	peaks      []Peak //peak concurrency per Config.PeakWindow, oldest first
+
+	latencyTotal time.Duration //sum of the latencies counted in stats.LatencySamples
+	peaksEvicted uint64        //windows evicted for exceeding Config.PeakWindows
+
+	stateChanges chan StateChange
+	diagnostics  chan error
+	connections  chan ConnectionEvent
+	summaries    chan Summary
+	clearTicks   chan ClearTick
+	summary      *summarizer //nil without Config.SummaryInterval
+
+	smoother    *speedSmoother
+	persistence *persistence //nil unless Config.MinFrames is above 1
+
+	filterMu sync.Mutex
+	sector   *angleSector
+
+	lastReport time.Time //when the targets of a frame were last delivered
+
+	frameExpected time.Time     //when the last frame arrived while frames kept coming, zero after a pause; guarded by statsMu
+	framePeriod   time.Duration //measured interval between frames, guarded by statsMu
+	periodSamples int
+
+	pollMu  sync.Mutex
+	pollers []chan Frame
+	mode    ReportMode
+
+	subsMu    sync.Mutex
+	subs      map[chan Target]struct{}
+	frameSubs map[chan Frame]struct{}
+
+	snapshotMu      sync.Mutex
+	snapshot        []Target //targets delivered within the snapshot window, oldest first
+	snapshotEvicted uint64   //targets evicted for exceeding Config.SnapshotLimit
+
+	eventsMu      sync.Mutex //acquired after statsMu when both are held
+	eventSubs     map[chan Event]struct{}
+	droppedEvents uint64
+
+	queueMu      sync.Mutex
+	queue        []commandRequest //configuration sessions waiting to be executed in order
+	queued       chan struct{}    //signals runCommands that queue is not empty
+	queueStopped bool             //set once runCommands stopped, nothing runs after that
+	commander    *hlk.Commander   //exchanges commands, fed the acknowledgements read by the read goroutine
+	asleep       bool             //module is held in config mode by Sleep, only used on the command queue
+	configInfo   []byte           //reply to the last enable config command, only used on the command queue
+	noSerial     bool             //the firmware didn't answer CmdReadSerial, only used on the command queue
+
+	firstFrame    chan struct{} //closed when the first data frame arrived
+	commandFrames chan struct{} //signaled for every command frame read
+
+	decode *decodeLog //nil without Config.DecodeLog
+
+	baud     atomic.Int64 //rate the port is opened at, Config.BaudRate until SetBaudRate reopened it
+	switched chan Source  //set by SetBaudRate before it closes the port, receives the reader of the reopened port or nil; guarded by writeMu
+
+	aligned       bool //whether the previous packet was read without error, only used by the read goroutine
+	parseFailures int  //frames in a row that failed to decode, only used by the read goroutine
+
+	pull   bool   //read by Puller.Next instead of a goroutine
+	pulled *Frame //frame handled by the last step in pull mode
+	idle   bool   //the last step read nothing within the port's read timeout
+
+	protocolMu sync.Mutex
+	protocol   ProtocolInfo
+
+	pauseMu    sync.Mutex  //held while Pause and Resume put the module to sleep or wake it
+	pauseSlept bool        //whether Pause put the module to sleep
+	paused     atomic.Bool //read for every frame, so it doesn't wait for pauseMu
+
+	flushMu      sync.Mutex
+	flushedAt    time.Time //when Flush was last called
+	flushPending bool      //set by Flush until the read goroutine dropped its buffer
 }
 
-var (
-	frameheader = []byte{0xf4, 0xf3, 0xf2, 0xf1}
+const (
+	defaultErrorBufferSize      = 8
+	defaultSubscriberBufferSize = 64
+	defaultOpenRetryBackoff     = 250 * time.Millisecond
+	maxOpenRetryBackoff         = 5 * time.Second
 )
 
-func Open(config Config) (*LD2451, error) {
-	serialConfig := &serial.Config{
-		Name:        config.SerialPort,
-		Baud:        config.BaudRate,
-		ReadTimeout: time.Second * 2,
-		Parity:      serial.ParityNone,
+// Open opens the serial port named in config and starts reading from it.
+func Open(config Config, options ...Option) (*LD2451, error) {
+	for _, option := range options {
+		option(&config)
+	}
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, wrapError("open", config.SerialPort, err)
+	}
+	if err := config.validatePort(); err != nil {
+		return nil, wrapError("open", config.SerialPort, err)
 	}
+	port, err := openPort(config)
+	if err != nil {
+		return nil, wrapError("open", config.SerialPort, err)
+	}
+	tap := newTap(config)
+	port = tap.port(port)
+	reopen := func(baud int) (transport.Port, error) {
+		port, err := transport.OpenSerial(transport.SerialConfig{Name: config.SerialPort, Baud: baud})
+		if err != nil {
+			return nil, err
+		}
+		return tap.port(port), nil
+	}
+	return start(protocol.NewReader(port), port, config, reopen)
+}
 
-	port, err := serial.OpenPort(serialConfig)
+// New starts reading from an already opened port, e.g. a TCP connection to a
+// serial bridge. Config.SerialPort, Config.BaudRate and the open retry
+// settings are not used, and neither is Config.Reconnect since New cannot
+// reopen port. The port is closed when New fails.
+func New(port transport.Port, config Config, options ...Option) (*LD2451, error) {
+	for _, option := range options {
+		option(&config)
+	}
+	port = newTap(config).port(port)
+	return start(protocol.NewReader(port), port, config, nil)
+}
+
+// start reads packets from frames and writes commands to port.
+func start(frames Source, port io.WriteCloser, config Config, reopen func(baud int) (transport.Port, error)) (*LD2451, error) {
+	ld2451, err := newSensor(frames, port, config, reopen)
 	if err != nil {
 		return nil, err
 	}
+	config = ld2451.config
 
-	ld2451 := &LD2451{
-		config:  config,
-		targets: make(chan Target, config.TargetBufferSize),
-		errors:  make(chan error),
-		port:    port,
+	ld2451.reportConnection(Connected, nil, 0)
+	go ld2451.read()
+	go ld2451.runCommands()
+	go ld2451.watchState()
+	if ld2451.summary != nil {
+		go ld2451.summarize()
+	}
+	if config.ClearTickInterval > 0 {
+		go ld2451.tickClear()
+	}
+
+	if config.RecoverConfigMode > 0 {
+		if err := ld2451.recoverConfigMode(); err != nil {
+			ld2451.Close()
+			return nil, err
+		}
+	}
+	if config.WaitForFirstFrame > 0 {
+		if err := ld2451.waitForFirstFrame(); err != nil {
+			ld2451.Close()
+			return nil, err
+		}
+	}
+	if config.DetectProtocol {
+		if _, err := ld2451.DetectProtocol(); err != nil {
+			ld2451.Close()
+			return nil, err
+		}
+	}
+	if config.DetectionParameters != nil {
+		_, err := ld2451.EnsureDetectionParameters(*config.DetectionParameters)
+		if err != nil {
+			ld2451.Close()
+			return nil, err
+		}
+	}
+	if config.AlarmParameters != nil {
+		_, err := ld2451.EnsureAlarmParameters(*config.AlarmParameters)
+		if err != nil {
+			ld2451.Close()
+			return nil, err
+		}
 	}
 
-	ld2451.syn()
+	return ld2451, nil
+}
 
-	go ld2451.read()
+// newSensor sets up the sensor without starting any goroutine.
+func newSensor(frames Source, port io.WriteCloser, config Config, reopen func(baud int) (transport.Port, error)) (*LD2451, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		port.Close()
+		return nil, wrapError("open", portName(config, port), err)
+	}
+
+	now := config.Clock.Now()
+	ld2451 := &LD2451{
+		config:     config,
+		targets:    make(chan delivery, config.TargetBufferSize),
+		errors:     make(chan error, errorBufferSize(config)),
+		done:       make(chan struct{}),
+		closed:     make(chan struct{}),
+		beats:      make(chan Heartbeat, 1),
+		alarms:     make(chan AlarmEvent, alarmBufferSize),
+		port:       port,
+		portName:   portName(config, port),
+		frames:     frames,
+		reopen:     reopen,
+		opened:     now,
+		state:      StateConnecting,
+		stateSince: now,
+
+		stateChanges: make(chan StateChange, stateBufferSize),
+		diagnostics:  make(chan error, errorBufferSize(config)),
+		connections:  make(chan ConnectionEvent, stateBufferSize),
+		summaries:    make(chan Summary, summaryBufferSize),
+		clearTicks:   make(chan ClearTick, 1),
+
+		smoother:    newSpeedSmoother(config),
+		persistence: newPersistence(config),
+		protocol:    ProtocolInfo{Variant: protocol.VariantV1},
 
+		queued: make(chan struct{}, 1),
+
+		firstFrame:    make(chan struct{}),
+		commandFrames: make(chan struct{}, 1),
+
+		decode: newDecodeLog(config),
+	}
+	ld2451.commander = hlk.NewCommander(ld2451.write, config.Clock, ld2451.done, func() error { return ld2451.fatal })
+	ld2451.baud.Store(int64(config.BaudRate))
+	if config.FrameVariant != nil {
+		ld2451.protocol.Variant = *config.FrameVariant
+	}
+	if err := ld2451.releaseReset(); err != nil {
+		port.Close()
+		return nil, wrapError("open", ld2451.portName, err)
+	}
+	if config.SummaryInterval > 0 {
+		ld2451.summary = &summarizer{bucket: config.SpeedHistogramBucket}
+	}
 	return ld2451, nil
 }
 
-func (ld2451 *LD2451) Close() {
-	ld2451.port.Close()
+// throttle reports whether the targets of a frame received at the given time
+// must be held back to honor Config.ReportInterval.
+func (ld2451 *LD2451) throttle(received time.Time) bool {
+	if ld2451.config.ReportInterval <= 0 {
+		return false
+	}
+	if !ld2451.lastReport.IsZero() && received.Sub(ld2451.lastReport) < ld2451.config.ReportInterval {
+		ld2451.recordThrottledFrame()
+		return true
+	}
+	ld2451.lastReport = received
+	return false
 }
 
-func (ld2451 *LD2451) read() {
+// openPort opens the serial port, retrying with exponential backoff for up to
+// Config.OpenRetryTimeout since USB serial devices often appear some time
+// after boot.
+func openPort(config Config) (transport.Port, error) {
+	backoff := config.OpenRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultOpenRetryBackoff
+	}
+	deadline := config.Clock.Now().Add(config.OpenRetryTimeout)
 	for {
-		buf := make([]byte, 1)
-		_, err := ld2451.port.Read(buf)
-		if err != nil {
-			ld2451.errors <- err
-			return
+		port, err := transport.OpenSerial(transport.SerialConfig{
+			Name: config.SerialPort,
+			Baud: config.BaudRate,
+		})
+		if err == nil {
+			return port, nil
 		}
-
-		if buf[0] != frameheader[0] {
-			continue
+		remaining := deadline.Sub(config.Clock.Now())
+		if remaining <= 0 {
+			return nil, err
 		}
+		config.Logger.Debug("opening port failed, retrying", "port", config.SerialPort, "error", err, "retry", min(backoff, remaining))
+		sleep(config.Clock, min(backoff, remaining), nil)
+		backoff = min(backoff*2, maxOpenRetryBackoff)
+	}
+}
 
-		//check if the next 3 bytes are the frame header
-		buf = make([]byte, 3)
-		_, err = ld2451.port.Read(buf)
+func (ld2451 *LD2451) Close() {
+	ld2451.closeMu.Do(func() { close(ld2451.closed) })
+	ld2451.setState(StateClosed)
+	ld2451.writeMu.Lock()
+	defer ld2451.writeMu.Unlock()
+	ld2451.port.Close()
+}
+
+// reconnect reopens the port after a read error and reports whether reading
+// can continue. It keeps retrying until the device is back or Close is called.
+func (ld2451 *LD2451) reconnect() bool {
+	if ld2451.reopen == nil || !ld2451.config.Reconnect {
+		return false
+	}
+	ld2451.writeMu.Lock()
+	ld2451.port.Close()
+	ld2451.writeMu.Unlock()
+
+	backoff := ld2451.config.OpenRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultOpenRetryBackoff
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !sleep(ld2451.config.Clock, backoff, ld2451.closed) {
+			return false
+		}
+		ld2451.reportConnection(Reconnecting, err, attempt)
+		var port transport.Port
+		port, err = ld2451.reopen(int(ld2451.baud.Load()))
 		if err != nil {
-			ld2451.errors <- err
-			return
+			ld2451.config.Logger.Debug("reopening port failed", "error", err, "retry", backoff)
+			backoff = min(backoff*2, maxOpenRetryBackoff)
+			continue
 		}
 
-		if bytes.Equal(buf, frameheader[1:]) {
-			//get length of the frame (next 2 bytes)
-			buf = make([]byte, 2)
-			_, err := ld2451.port.Read(buf)
-			if err != nil {
-				ld2451.errors <- err
-				return
-			}
-			frameLength := int(buf[1])<<8 | int(buf[0])
-			if frameLength == 0 {
-				//restart loop if there is no more data
-				//read the next 4 bytes, this is the frame footer []byte{0xf8, 0xf7, 0xf6, 0xf5}
-				buf = make([]byte, 4)
-				_, err = ld2451.port.Read(buf)
-				if err != nil {
-					ld2451.errors <- err
-					return
-				}
-				continue
-			}
-			//read the rest of the frame
-			buf = make([]byte, frameLength)
-			_, err = ld2451.port.Read(buf)
-			if err != nil {
-				ld2451.errors <- err
-				return
-			}
-			//get the number of targets in the frame, this is the next byte after the frame length
-			numTargets := int(buf[0])
-			//move to the next byte AND skip alarm state
-			buf = buf[2:]
-
-			//loop over and parse each target
-			for i := 0; i < numTargets; i++ {
-				target := Target{}
-				//get the target data
-				target.Angle = int(buf[1]) - 0x80
-				target.Distance = int(buf[2])
-				target.Direction = Direction(buf[3])
-				target.Speed = int(buf[4])
-				target.SNR = int(buf[5])
-
-				//send the target to the channel
-				ld2451.targets <- target
-				//move to the next target
-				buf = buf[6:]
-			}
-			//flush the rest of the frame
-			buf = make([]byte, 4)
-			_, err = ld2451.port.Read(buf)
-			if err != nil {
-				ld2451.errors <- err
-				return
-			}
+		ld2451.writeMu.Lock()
+		select {
+		case <-ld2451.closed:
+			//Close ran while the port was being opened
+			ld2451.writeMu.Unlock()
+			port.Close()
+			return false
+		default:
 		}
+		ld2451.port = port
+		ld2451.writeMu.Unlock()
+		if err := ld2451.releaseReset(); err != nil {
+			ld2451.config.Logger.Warn("releasing the hardware reset failed", "error", err)
+		}
+
+		ld2451.useFrames(protocol.NewReader(port))
+		ld2451.recordReconnect()
+		ld2451.reportConnection(Connected, nil, attempt)
+		ld2451.config.Logger.Info("port reopened")
+		return true
 	}
 }
 
-func (ld2451 *LD2451) ReadTarget() (Target, error) {
-	select {
-	case target := <-ld2451.targets:
-		return target, nil
-	case err := <-ld2451.errors:
-		return Target{}, err
+// useFrames continues reading from frames of a reopened port, forgetting the
+// targets seen before.
+func (ld2451 *LD2451) useFrames(frames Source) {
+	ld2451.frames = frames
+	ld2451.smoother.trim(0)
+	if ld2451.persistence != nil {
+		ld2451.persistence.reset()
+	}
+}
+
+func (ld2451 *LD2451) read() {
+	for ld2451.step() {
 	}
 }
 
-func (ld2451 *LD2451) sendCommand(command []byte) {
-	//send bytes FD FC FB FA 04 00 FF 00 01 00 04 03 02 01
-	ld2451.port.Write([]byte{0xfd, 0xfc, 0xfb, 0xfa, 0x04, 0x00, 0xff, 0x00, 0x01, 0x00, 0x04, 0x03, 0x02, 0x01})
-	//read the response
-	buf := make([]byte, 1)
-	_, err := ld2451.port.Read(buf)
+// step reads and handles a single packet and reports whether reading can
+// continue. It runs on the read goroutine, or on the caller of Puller.Next.
+func (ld2451 *LD2451) step() bool {
+	packet, err := ld2451.frames.Next()
+	ld2451.recordPacket(packet)
+	ld2451.idle = errors.Is(err, transport.ErrReadTimeout)
+	if ld2451.idle {
+		//the module is silent, e.g. in config mode, the port is fine
+		return true
+	}
+	ld2451.logPacket(packet, err)
+	if err == nil && ld2451.aligned && (packet.Skipped > 0 || packet.Oversized > 0) {
+		//bytes skipped before the first frame are only the tail of a frame sent before opening
+		ld2451.reportStrict(&ResyncError{Skipped: packet.Skipped, Oversized: packet.Oversized})
+	}
+	ld2451.aligned = err == nil
+	if err != nil && ld2451.awaitSwitch() {
+		return true
+	}
 	if err != nil {
-		ld2451.errors <- err
-		return
+		ld2451.recordReadError()
+		if ld2451.State() != StateClosed {
+			ld2451.reportConnection(Disconnected, err, 0)
+		}
+		if ld2451.reopen != nil && ld2451.config.Reconnect && ld2451.State() != StateClosed {
+			ld2451.config.Logger.Warn("port failed, reconnecting", "error", err)
+			ld2451.reportError(err)
+			if ld2451.reconnect() {
+				return true
+			}
+		}
+		if ld2451.State() != StateClosed {
+			ld2451.config.Logger.Error("port failed, reader stopped", "error", err)
+		}
+		err = ld2451.wrap("read", err)
+		ld2451.fatal = err
+		close(ld2451.done)
+		ld2451.closeSubscribers()
+		ld2451.closeEventSubscribers()
+		ld2451.reportError(err)
+		return false
 	}
-	if buf[0] != 0xfd {
-		ld2451.errors <- fmt.Errorf("failed to send command to the LD2451")
-		return
+
+	if ld2451.takeFlush() {
+		if reader, ok := ld2451.frames.(interface{ Discard() int }); ok {
+			reader.Discard()
+		}
 	}
 
-	buf = make([]byte, 17)
-	_, err = ld2451.port.Read(buf)
+	if packet.Kind == protocol.KindCommand {
+		ld2451.sawCommandFrame()
+		ld2451.deliverAck(packet.Payload)
+		return true
+	}
+
+	if len(packet.Payload) == 0 {
+		//restart loop if there is no more data
+		ld2451.parseFailures = 0
+		ld2451.recordFrame()
+		ld2451.smoother.trim(0)
+		if ld2451.persistence != nil {
+			ld2451.persistence.endFrame()
+		}
+		ld2451.recordConcurrency(0, ld2451.now())
+		ld2451.heartbeat()
+		ld2451.updateAlarm(false)
+		empty := Frame{Time: ld2451.now(), Sensor: ld2451.config.SensorID}
+		if ld2451.pull {
+			ld2451.pulled = &empty
+		}
+		ld2451.notifyPollers(empty)
+		if ld2451.frameSubscribed() {
+			ld2451.deliverFrame(empty)
+		}
+		return true
+	}
+
+	received := ld2451.now()
+	start := received.Add(-ld2451.wireTime(len(packet.Payload)))
+	if ld2451.flushedBefore(start) {
+		return true
+	}
+	frame, err := ld2451.variant().ParseFrame(packet.Payload, ld2451.targetScratch[:0])
+	if err != nil && ld2451.config.ParseMode == ParseLenient {
+		if salvaged, lost, salvageErr := ld2451.variant().SalvageFrame(packet.Payload, ld2451.targetScratch[:0]); salvageErr == nil {
+			ld2451.config.Logger.Debug("salvaged malformed frame", "error", err, "lost", lost)
+			ld2451.recordSalvagedFrame()
+			frame, err = salvaged, nil
+		}
+	}
 	if err != nil {
-		ld2451.errors <- err
-		return
+		//the frame was delimited correctly, so the stream is still aligned
+		ld2451.recordParseError()
+		ld2451.config.Logger.Warn("dropping undecodable frame", "error", err)
+		ld2451.publish(ParseErrorEvent{Err: err, Time: received, Sensor: ld2451.config.SensorID})
+		ld2451.reportDiagnostic(err)
+		ld2451.reportStrict(err)
+		ld2451.parseFailed()
+		return true
+	}
+	ld2451.parseFailures = 0
+	ld2451.targetScratch = frame.Targets[:0]
+	ld2451.recordConcurrency(len(frame.Targets), received)
+	if len(frame.Targets) > ld2451.config.MaxTargets {
+		if ld2451.config.TargetOverflow == OverflowError || ld2451.config.ParseMode == ParseStrict {
+			err := &ParseError{
+				Reason:  fmt.Sprintf("%d targets exceed the maximum of %d", len(frame.Targets), ld2451.config.MaxTargets),
+				Payload: bytes.Clone(packet.Payload),
+			}
+			ld2451.recordParseError()
+			ld2451.config.Logger.Warn("dropping frame with too many targets", "error", err)
+			ld2451.publish(ParseErrorEvent{Err: err, Time: received, Sensor: ld2451.config.SensorID})
+			ld2451.reportDiagnostic(err)
+			ld2451.reportStrict(err)
+			return true
+		}
+		ld2451.config.Logger.Warn("truncating frame with too many targets", "targets", len(frame.Targets), "max", ld2451.config.MaxTargets)
+		ld2451.recordTruncatedFrame()
+		frame.Targets = frame.Targets[:ld2451.config.MaxTargets]
 	}
-	status := buf[7:]
-	status = status[:len(status)-8]
-	endFrame := buf[len(buf)-4:]
+	ld2451.recordFrame()
+	frame.Time = received
+	ld2451.updateAlarm(frame.Alarm)
+	paused := ld2451.Paused()
+	if paused {
+		ld2451.recordPausedFrame()
+	}
+	throttled := paused || ld2451.throttle(received)
 
-	if !bytes.Equal(endFrame, []byte{0x04, 0x03, 0x02, 0x01}) || !bytes.Equal(status, []byte{00, 00}) {
-		ld2451.errors <- fmt.Errorf("failed to send command to the LD2451")
-		return
+	//only collect the delivered targets when somebody polls or subscribed to frames
+	polled := ld2451.polling() || ld2451.pull
+	framed := !throttled && ld2451.frameSubscribed()
+	delivered := Frame{Alarm: frame.Alarm, Time: received, Sensor: ld2451.config.SensorID}
+	for i, target := range frame.Targets {
+		target.Time = received
+		target.Sensor = ld2451.config.SensorID
+		var valid bool
+		if target, valid = ld2451.validate(i, target, packet.Payload, received); !valid {
+			continue
+		}
+		target.Angle = ld2451.orientAngle(target.Angle)
+		if ld2451.config.CosineCorrection {
+			target = ld2451.correctCosine(target)
+		}
+		if ld2451.config.Mounting != nil {
+			target = ld2451.config.Mounting.Correct(target)
+		}
+		//keep smoothing every frame, even the ones that are not delivered
+		speed := ld2451.smoother.smooth(i, target)
+		target.Speed = int(math.Round(speed))
+		if target.Fine {
+			target.FineSpeed = speed
+		}
+		persistent := ld2451.persistence == nil || ld2451.persistence.observe(target) >= ld2451.config.MinFrames
+		if throttled {
+			continue
+		}
+		if !persistent {
+			ld2451.recordFilteredTarget()
+			continue
+		}
+		if !ld2451.allow(target) {
+			ld2451.recordFilteredTarget()
+			continue
+		}
+
+		if ld2451.summary != nil {
+			ld2451.summary.target(target)
+		}
+		if !ld2451.config.SummaryOnly && !ld2451.pull {
+			ld2451.deliver(target, start)
+		}
+		ld2451.remember(target)
+		ld2451.recordTarget()
+		if polled || framed {
+			delivered.Targets = append(delivered.Targets, target)
+		}
+	}
+	ld2451.smoother.trim(len(frame.Targets))
+	if ld2451.persistence != nil {
+		ld2451.persistence.endFrame()
 	}
+	if ld2451.pull {
+		ld2451.pulled = &delivered
+	}
+	if polled {
+		ld2451.notifyPollers(delivered)
+	}
+	if framed {
+		ld2451.deliverFrame(delivered)
+	}
+	return true
+}
 
-	//send command
+func (ld2451 *LD2451) ReadTarget() (Target, error) {
+	for {
+		d, err := ld2451.nextTarget()
+		if err != nil {
+			return Target{}, err
+		}
+		if !ld2451.discarded(d) {
+			ld2451.recordLatency(d.start)
+			return d.target, nil
+		}
+	}
+}
 
-	ld2451.port.Write([]byte{0xfd, 0xfc, 0xfb, 0xfa, 0x02, 0x00, 0xfe, 0x00, 0x04, 0x03, 0x02, 0x01})
+// ReadTargets returns up to max targets that are currently buffered without
+// waiting for more, or everything buffered if max is not positive. Stale and
+// flushed targets are discarded like by ReadTarget. Errors are not reported, use
+// ReadTarget or Health to observe them.
+func (ld2451 *LD2451) ReadTargets(max int) []Target {
+	var targets []Target
+	for max <= 0 || len(targets) < max {
+		select {
+		case d := <-ld2451.targets:
+			if ld2451.discarded(d) {
+				continue
+			}
+			ld2451.recordLatency(d.start)
+			targets = append(targets, d.target)
+		default:
+			return targets
+		}
+	}
+	return targets
+}
 
-	buf = make([]byte, 1)
-	_, err = ld2451.port.Read(buf)
-	if err != nil {
-		ld2451.errors <- err
-		return
+func (ld2451 *LD2451) nextTarget() (delivery, error) {
+	//buffered targets come first, a source running dry reports its error right behind them
+	select {
+	case d := <-ld2451.targets:
+		return d, nil
+	default:
 	}
-	if buf[0] != 0xfd {
-		ld2451.errors <- fmt.Errorf("failed to send command to the LD2451")
-		return
+	select {
+	case d := <-ld2451.targets:
+		return d, nil
+	case err := <-ld2451.errors:
+		return delivery{}, err
+	case <-ld2451.done:
+		//hand out what was read before the reader stopped
+		select {
+		case d := <-ld2451.targets:
+			return d, nil
+		default:
+			return delivery{}, ld2451.fatal
+		}
 	}
+}
 
-	buf = make([]byte, 13)
-	_, err = ld2451.port.Read(buf)
-	if err != nil {
-		ld2451.errors <- err
-		return
+// discarded reports whether d must not be handed out because it is stale or
+// was read before Flush, and counts it.
+func (ld2451 *LD2451) discarded(d delivery) bool {
+	switch {
+	case ld2451.stale(d.target):
+		ld2451.recordStaleTarget()
+		return true
+	case ld2451.flushedBefore(d.start):
+		ld2451.recordFlushedTargets(1)
+		return true
 	}
-	status = buf[7:]
-	status = status[:len(status)-4]
-	endFrame = buf[len(buf)-4:]
+	return false
+}
+
+// stale reports whether target has been buffered for longer than Config.MaxTargetAge.
+func (ld2451 *LD2451) stale(target Target) bool {
+	return ld2451.config.MaxTargetAge > 0 && ld2451.since(target.Time) > ld2451.config.MaxTargetAge
+}
+
+// reportError delivers err without ever blocking the caller. Errors that don't
+// fit in the buffer are dropped and counted in Stats.DroppedErrors.
+func (ld2451 *LD2451) reportError(err error) {
+	select {
+	case ld2451.errors <- err:
+	default:
+		ld2451.recordDroppedError()
+		ld2451.config.Logger.Debug("error channel full, dropping error", "error", err)
+	}
+}
 
-	if !bytes.Equal(endFrame, []byte{0x04, 0x03, 0x02, 0x01}) || !bytes.Equal(status, []byte{00, 00}) {
-		ld2451.errors <- fmt.Errorf("failed to send command to the LD2451")
-		return
+func errorBufferSize(config Config) int {
+	if config.ErrorBufferSize > 0 {
+		return config.ErrorBufferSize
 	}
+	return defaultErrorBufferSize
 }
@@ -0,0 +1,50 @@
+package LD2451
+
+import "time"
+
+const alarmBufferSize = 8
+
+type AlarmSource int
+
+const (
+	AlarmSourceFrame AlarmSource = 0 // Alarm state reported inside the data frames
+	AlarmSourcePin   AlarmSource = 1 // Alarm output pin of the module, read by an external helper
+)
+
+func (s AlarmSource) String() string {
+	switch s {
+	case AlarmSourceFrame:
+		return "Frame"
+	case AlarmSourcePin:
+		return "Pin"
+	default:
+		return "Unknown"
+	}
+}
+
+type AlarmEvent struct {
+	Active bool        `json:"active"`           // Whether the alarm is raised
+	Source AlarmSource `json:"source"`           // Where the alarm state was observed
+	Time   time.Time   `json:"time"`             // When the change was observed
+	Sensor string      `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+// Alarms returns the channel alarm state changes reported in the data frames
+// are delivered on. Changes are dropped rather than stalling the reader when
+// nobody keeps up with the channel.
+func (ld2451 *LD2451) Alarms() <-chan AlarmEvent {
+	return ld2451.alarms
+}
+
+func (ld2451 *LD2451) updateAlarm(active bool) {
+	if active == ld2451.alarm {
+		return
+	}
+	ld2451.alarm = active
+	event := AlarmEvent{Active: active, Source: AlarmSourceFrame, Time: ld2451.now(), Sensor: ld2451.config.SensorID}
+	ld2451.publish(event)
+	select {
+	case ld2451.alarms <- event:
+	default:
+	}
+}
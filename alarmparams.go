@@ -0,0 +1,84 @@
+package LD2451
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// AlarmParameters describes when the module raises its alarm. The trigger
+// speed and hold time are the same settings as DetectionParameters.MinSpeed
+// and DetectionParameters.NoTargetDelay, repeated here so the alarm behavior
+// can be inspected in one place.
+type AlarmParameters struct {
+	TriggerCount int           `json:"trigger_count"` // Consecutive detections needed before the alarm is raised (1-10)
+	SNRThreshold int           `json:"snr_threshold"` // Minimum SNR for a detection to count toward the alarm, higher is less sensitive
+	TriggerSpeed int           `json:"trigger_speed"` // Minimum speed in KM/H of a target raising the alarm
+	HoldTime     time.Duration `json:"hold_time"`     // How long the alarm stays raised after the last target disappeared
+}
+
+// AlarmParameters reads the alarm related configuration from the module.
+func (ld2451 *LD2451) AlarmParameters() (AlarmParameters, error) {
+	var params AlarmParameters
+	err := ld2451.configure("AlarmParameters", func() error {
+		var err error
+		params, err = ld2451.readAlarmParameters()
+		return err
+	})
+	return params, err
+}
+
+func (ld2451 *LD2451) readAlarmParameters() (AlarmParameters, error) {
+	data, err := ld2451.command(protocol.CmdReadSensitivity, nil)
+	if err != nil {
+		return AlarmParameters{}, err
+	}
+	detection, err := ld2451.readDetectionParameters()
+	if err != nil {
+		return AlarmParameters{}, err
+	}
+	return alarmParameters(data, detection)
+}
+
+// alarmParameters decodes a sensitivity reply, completed by the detection
+// parameters it shares settings with.
+func alarmParameters(data []byte, detection DetectionParameters) (AlarmParameters, error) {
+	if len(data) < 2 {
+		return AlarmParameters{}, fmt.Errorf("sensitivity parameters reply of %d bytes is too short", len(data))
+	}
+	return AlarmParameters{
+		TriggerCount: int(data[0]),
+		SNRThreshold: int(data[1]),
+		TriggerSpeed: detection.MinSpeed,
+		HoldTime:     detection.NoTargetDelay,
+	}, nil
+}
+
+// EnsureAlarmParameters reads the trigger count and SNR threshold back from
+// the module and writes the ones of desired if they differ, e.g. after the
+// module was reset to factory settings. The trigger speed and hold time are
+// left to EnsureDetectionParameters. It reports whether the parameters had
+// to be changed.
+func (ld2451 *LD2451) EnsureAlarmParameters(desired AlarmParameters) (bool, error) {
+	if err := desired.validate(); err != nil {
+		return false, ld2451.wrap("EnsureAlarmParameters", err)
+	}
+	changed := false
+	err := ld2451.configure("EnsureAlarmParameters", func() error {
+		data, err := ld2451.command(protocol.CmdReadSensitivity, nil)
+		if err != nil {
+			return err
+		}
+		current, err := alarmParameters(data, DetectionParameters{})
+		if err != nil {
+			return err
+		}
+		if current.TriggerCount == desired.TriggerCount && current.SNRThreshold == desired.SNRThreshold {
+			return nil
+		}
+		changed = true
+		return ld2451.writeSensitivity(desired)()
+	})
+	return changed, err
+}
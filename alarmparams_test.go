@@ -0,0 +1,82 @@
+package LD2451_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+// TestOpenRestoresParameters opens a module reset to factory settings with
+// desired detection and alarm parameters, which must be written once and
+// left alone on the next Open.
+func TestOpenRestoresParameters(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	//the module keeps what it was given, starting from the factory settings
+	var mu sync.Mutex
+	stored := map[uint16][]byte{
+		protocol.CmdReadDetection:   {100, 2, 0, 1},
+		protocol.CmdReadSensitivity: {1, 4, 0, 0},
+	}
+	keep := func(read uint16) sensortest.Handler {
+		return func(command sensortest.Command) (uint16, []byte, bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			stored[read] = bytes.Clone(command.Value)
+			return 0, nil, true
+		}
+	}
+	reply := func(command sensortest.Command) (uint16, []byte, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return 0, bytes.Clone(stored[command.Word]), true
+	}
+	sensor.Handle(protocol.CmdReadDetection, reply)
+	sensor.Handle(protocol.CmdReadSensitivity, reply)
+	sensor.Handle(protocol.CmdSetDetection, keep(protocol.CmdReadDetection))
+	sensor.Handle(protocol.CmdSetSensitivity, keep(protocol.CmdReadSensitivity))
+
+	config := sensor.Config()
+	config.DetectionParameters = &LD2451.DetectionParameters{MaxDistance: 60, Direction: LD2451.DetectToward, MinSpeed: 10, NoTargetDelay: 2 * time.Second}
+	config.AlarmParameters = &LD2451.AlarmParameters{TriggerCount: 3, SNRThreshold: 8}
+	writes := func() (n int) {
+		for _, command := range sensor.Commands() {
+			if command.Word == protocol.CmdSetDetection || command.Word == protocol.CmdSetSensitivity {
+				n++
+			}
+		}
+		return n
+	}
+
+	radar, err := LD2451.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	radar.Close()
+	mu.Lock()
+	detection, sensitivity := stored[protocol.CmdReadDetection], stored[protocol.CmdReadSensitivity]
+	mu.Unlock()
+	if !bytes.Equal(detection, []byte{60, 1, 10, 2}) || !bytes.Equal(sensitivity, []byte{3, 8, 0, 0}) {
+		t.Fatalf("module holds detection % x and sensitivity % x", detection, sensitivity)
+	}
+	if n := writes(); n != 2 {
+		t.Fatalf("wrote the parameters %d times, expected twice", n)
+	}
+
+	radar, err = LD2451.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	radar.Close()
+	if n := writes(); n != 2 {
+		t.Errorf("rewrote parameters the module already had, %d writes", n)
+	}
+}
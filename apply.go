@@ -0,0 +1,106 @@
+package LD2451
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// ApplyError reports which parameter ApplyConfig failed to write.
+type ApplyError struct {
+	Parameter   string // "detection" or "alarm"
+	Err         error
+	RollbackErr error // Why restoring the parameters written before failed, nil when they were restored
+}
+
+func (e *ApplyError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("write %s parameters: %v, rolling back failed: %v", e.Parameter, e.Err, e.RollbackErr)
+	}
+	return fmt.Sprintf("write %s parameters: %v", e.Parameter, e.Err)
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+func (p AlarmParameters) validate() error {
+	if p.TriggerCount < 1 || p.TriggerCount > 10 {
+		return fmt.Errorf("trigger count %d is outside 1-10", p.TriggerCount)
+	}
+	if p.SNRThreshold < 0 || p.SNRThreshold > 255 {
+		return fmt.Errorf("SNR threshold %d is outside 0-255", p.SNRThreshold)
+	}
+	return nil
+}
+
+// ApplyConfig writes the detection and alarm parameters of desired in a
+// single configuration session. Only parameters that differ from what the
+// module reports are written and every write must be acknowledged. When a
+// write fails the parameters written before it are restored and an
+// *ApplyError names the failing one. The read only fields of desired are
+// ignored, and the trigger speed and hold time of the alarm must be zero or
+// equal to the detection parameters they share their setting with.
+func (ld2451 *LD2451) ApplyConfig(desired DeviceConfig) error {
+	if err := desired.Detection.validate(); err != nil {
+		return ld2451.wrap("ApplyConfig", err)
+	}
+	if err := desired.Alarm.validate(); err != nil {
+		return ld2451.wrap("ApplyConfig", err)
+	}
+	alarm := desired.Alarm
+	if (alarm.TriggerSpeed != 0 && alarm.TriggerSpeed != desired.Detection.MinSpeed) ||
+		(alarm.HoldTime != 0 && alarm.HoldTime != desired.Detection.NoTargetDelay) {
+		return ld2451.wrap("ApplyConfig", fmt.Errorf("alarm trigger speed and hold time differ from the detection parameters"))
+	}
+
+	return ld2451.configure("ApplyConfig", func() error {
+		current, err := ld2451.readConfiguration()
+		if err != nil {
+			return err
+		}
+		desired.Detection.NoTargetDelay = desired.Detection.NoTargetDelay.Truncate(time.Second)
+
+		var undo []func() error
+		step := func(parameter string, changed bool, write, restore func() error) error {
+			if !changed {
+				return nil
+			}
+			if err := write(); err != nil {
+				applyErr := &ApplyError{Parameter: parameter, Err: err}
+				//restore in reverse order so the module ends up as it was
+				for i := len(undo) - 1; i >= 0; i-- {
+					if err := undo[i](); err != nil && applyErr.RollbackErr == nil {
+						applyErr.RollbackErr = err
+					}
+				}
+				return applyErr
+			}
+			undo = append(undo, restore)
+			return nil
+		}
+
+		err = step("detection", current.Detection != desired.Detection,
+			ld2451.writeDetectionParameters(desired.Detection),
+			ld2451.writeDetectionParameters(current.Detection))
+		if err != nil {
+			return err
+		}
+		return step("alarm",
+			current.Alarm.TriggerCount != alarm.TriggerCount || current.Alarm.SNRThreshold != alarm.SNRThreshold,
+			ld2451.writeSensitivity(alarm),
+			ld2451.writeSensitivity(current.Alarm))
+	})
+}
+
+func (ld2451 *LD2451) writeSensitivity(params AlarmParameters) func() error {
+	return func() error {
+		_, err := ld2451.command(protocol.CmdSetSensitivity, []byte{
+			byte(params.TriggerCount),
+			byte(params.SNRThreshold),
+			0, 0,
+		})
+		return err
+	}
+}
@@ -0,0 +1,72 @@
+package LD2451
+
+// Result is the outcome of an asynchronous read from the module.
+//
+// The Async variants of the configuration commands queue the command like
+// their blocking counterparts and return at once, so e.g. UI code can keep
+// rendering targets during the round trips to the module. The returned
+// channel receives exactly one result. Commands run in the order they were
+// issued, whether blocking or not.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// SetDetectionParametersAsync is the non-blocking SetDetectionParameters.
+func (ld2451 *LD2451) SetDetectionParametersAsync(params DetectionParameters) <-chan error {
+	result := make(chan error, 1)
+	if err := params.validate(); err != nil {
+		result <- ld2451.wrap("SetDetectionParameters", err)
+		return result
+	}
+	errs := ld2451.submit(func() error {
+		return ld2451.session(ld2451.writeDetectionParameters(params))
+	})
+	go func() {
+		result <- ld2451.wrap("SetDetectionParameters", <-errs)
+	}()
+	return result
+}
+
+// DetectionParametersAsync is the non-blocking DetectionParameters.
+func (ld2451 *LD2451) DetectionParametersAsync() <-chan Result[DetectionParameters] {
+	return readAsync(ld2451, "DetectionParameters", ld2451.readDetectionParameters)
+}
+
+// AlarmParametersAsync is the non-blocking AlarmParameters.
+func (ld2451 *LD2451) AlarmParametersAsync() <-chan Result[AlarmParameters] {
+	return readAsync(ld2451, "AlarmParameters", ld2451.readAlarmParameters)
+}
+
+// FirmwareVersionAsync is the non-blocking FirmwareVersion.
+func (ld2451 *LD2451) FirmwareVersionAsync() <-chan Result[FirmwareVersion] {
+	return readAsync(ld2451, "FirmwareVersion", ld2451.readFirmwareVersion)
+}
+
+// SerialNumberAsync is the non-blocking SerialNumber.
+func (ld2451 *LD2451) SerialNumberAsync() <-chan Result[string] {
+	return readAsync(ld2451, "SerialNumber", ld2451.readSerialNumber)
+}
+
+// GetConfigurationAsync is the non-blocking GetConfiguration.
+func (ld2451 *LD2451) GetConfigurationAsync() <-chan Result[DeviceConfig] {
+	return readAsync(ld2451, "GetConfiguration", ld2451.readConfiguration)
+}
+
+// readAsync runs read in a configuration session on the command queue.
+func readAsync[T any](ld2451 *LD2451, op string, read func() (T, error)) <-chan Result[T] {
+	var value T
+	errs := ld2451.submit(func() error {
+		return ld2451.session(func() error {
+			var err error
+			value, err = read()
+			return err
+		})
+	})
+	results := make(chan Result[T], 1)
+	go func() {
+		err := <-errs
+		results <- Result[T]{Value: value, Err: ld2451.wrap(op, err)}
+	}()
+	return results
+}
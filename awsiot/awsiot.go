@@ -0,0 +1,210 @@
+// Package awsiot publishes LD2451 targets to AWS IoT Core and keeps the thing's
+// device shadow in sync with the sensor: the detection parameters and health
+// are reported, and desired detection parameters set on the shadow are applied
+// to the module.
+//
+// The MQTT connection is provided by the application through the Client
+// interface, typically an adapter around github.com/eclipse/paho.mqtt.golang
+// configured with the tls.Config returned by TLSConfig.
+package awsiot
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	// Port is the AWS IoT Core MQTT port for mutual TLS authentication.
+	Port = 8883
+
+	DefaultTargetTopic    = "ld2451/{thing}/targets"
+	DefaultShadowInterval = time.Minute
+)
+
+// Client is the subset of an MQTT client the publisher needs. Handlers may be
+// called from the client's own goroutines.
+type Client interface {
+	Publish(topic string, qos byte, payload []byte) error
+	Subscribe(topic string, qos byte, handler func(topic string, payload []byte)) error
+}
+
+type Config struct {
+	ThingName      string
+	TargetTopic    string        // Topic targets are published to, {thing} is replaced by ThingName (default DefaultTargetTopic)
+	ShadowInterval time.Duration // How often the reported shadow state is refreshed (default DefaultShadowInterval)
+
+	Clock LD2451.Clock // Source of time for ShadowInterval, nil uses LD2451.SystemClock
+}
+
+// TLSConfig loads the device certificate and key issued by AWS IoT and the
+// Amazon root CA for connecting to the account's IoT endpoint.
+func TLSConfig(certFile, keyFile, rootCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := os.ReadFile(rootCAFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates found in " + rootCAFile)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      roots,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// Publisher implements LD2451.Sink. Shadow deltas received meanwhile are
+// applied and the reported state is refreshed when it is flushed, so with
+// RunSink and AddSink that happens every LD2451.Config.SinkFlushInterval.
+type Publisher struct {
+	client   Client
+	sensor   *LD2451.LD2451
+	config   Config
+	deltas   chan []byte
+	reported time.Time //when the shadow state was last reported
+}
+
+func New(client Client, sensor *LD2451.LD2451, config Config) *Publisher {
+	if config.TargetTopic == "" {
+		config.TargetTopic = DefaultTargetTopic
+	}
+	config.TargetTopic = strings.ReplaceAll(config.TargetTopic, "{thing}", config.ThingName)
+	if config.ShadowInterval <= 0 {
+		config.ShadowInterval = DefaultShadowInterval
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+	return &Publisher{
+		client: client,
+		sensor: sensor,
+		config: config,
+		deltas: make(chan []byte, 1),
+	}
+}
+
+type reportedState struct {
+	Detection *LD2451.DetectionParameters `json:"detection,omitempty"`
+	Health    LD2451.Health               `json:"health"`
+	LastError string                      `json:"last_error"`
+}
+
+type shadowUpdate struct {
+	State struct {
+		Reported reportedState `json:"reported"`
+	} `json:"state"`
+}
+
+type shadowDelta struct {
+	State struct {
+		Detection json.RawMessage `json:"detection"`
+	} `json:"state"`
+}
+
+// Run subscribes to the shadow deltas, reports the shadow state and runs the
+// publisher with the sensor's RunSink.
+func (p *Publisher) Run(ctx context.Context) error {
+	err := p.client.Subscribe(p.shadowTopic("update/delta"), 1, func(topic string, payload []byte) {
+		//applying parameters takes several round trips, keep it off the client's goroutine
+		select {
+		case p.deltas <- payload:
+		default:
+			//a newer delta replaces the pending one, the shadow always carries the full desired state
+			select {
+			case <-p.deltas:
+			default:
+			}
+			p.deltas <- payload
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := p.report(nil); err != nil {
+		return err
+	}
+	return p.sensor.RunSink(ctx, p)
+}
+
+// Write publishes target to Config.TargetTopic.
+func (p *Publisher) Write(target LD2451.Target) error {
+	payload, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	return p.client.Publish(p.config.TargetTopic, 0, payload)
+}
+
+// Flush applies a pending shadow delta, or refreshes the reported shadow state
+// once Config.ShadowInterval passed since it was last reported.
+func (p *Publisher) Flush() error {
+	select {
+	case delta := <-p.deltas:
+		return p.report(p.apply(delta))
+	default:
+	}
+	if p.config.Clock.Now().Sub(p.reported) >= p.config.ShadowInterval {
+		return p.report(nil)
+	}
+	return nil
+}
+
+func (p *Publisher) Close() error { return nil }
+
+// apply merges the desired detection parameters from a shadow delta into the
+// module's current parameters and writes them.
+func (p *Publisher) apply(payload []byte) error {
+	var delta shadowDelta
+	if err := json.Unmarshal(payload, &delta); err != nil {
+		return err
+	}
+	if len(delta.State.Detection) == 0 {
+		return nil
+	}
+	params, err := p.sensor.DetectionParameters()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(delta.State.Detection, &params); err != nil {
+		return err
+	}
+	return p.sensor.SetDetectionParameters(params)
+}
+
+// report publishes the current detection parameters and health as the
+// reported shadow state, together with the outcome of the last reconfiguration.
+func (p *Publisher) report(applyErr error) error {
+	var update shadowUpdate
+	update.State.Reported.Health = p.sensor.Health()
+	if params, err := p.sensor.DetectionParameters(); err == nil {
+		update.State.Reported.Detection = &params
+	} else if applyErr == nil {
+		applyErr = err
+	}
+	if applyErr != nil {
+		update.State.Reported.LastError = applyErr.Error()
+	}
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	p.reported = p.config.Clock.Now()
+	return p.client.Publish(p.shadowTopic("update"), 1, payload)
+}
+
+func (p *Publisher) shadowTopic(suffix string) string {
+	return "$aws/things/" + p.config.ThingName + "/shadow/" + suffix
+}
@@ -0,0 +1,186 @@
+package awsiot_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/awsiot"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+type message struct {
+	topic   string
+	qos     byte
+	payload []byte
+}
+
+// client records what was published and the handlers subscribed.
+type client struct {
+	mu        sync.Mutex
+	published chan message
+	handlers  map[string]func(topic string, payload []byte)
+}
+
+func newClient() *client {
+	return &client{published: make(chan message, 64), handlers: map[string]func(string, []byte){}}
+}
+
+func (c *client) Publish(topic string, qos byte, payload []byte) error {
+	c.published <- message{topic, qos, payload}
+	return nil
+}
+
+func (c *client) Subscribe(topic string, qos byte, handler func(topic string, payload []byte)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[topic] = handler
+	return nil
+}
+
+// deliver passes payload to the handler subscribed to topic.
+func (c *client) deliver(t *testing.T, topic string, payload string) {
+	t.Helper()
+	c.mu.Lock()
+	handler := c.handlers[topic]
+	c.mu.Unlock()
+	if handler == nil {
+		t.Fatalf("nothing subscribed to %s", topic)
+	}
+	handler(topic, []byte(payload))
+}
+
+type reported struct {
+	State struct {
+		Reported struct {
+			Detection *LD2451.DetectionParameters `json:"detection"`
+			LastError string                      `json:"last_error"`
+		} `json:"reported"`
+	} `json:"state"`
+}
+
+// next waits for the next message published to topic.
+func (c *client) next(t *testing.T, topic string) message {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case m := <-c.published:
+			if m.topic == topic {
+				return m
+			}
+		case <-timeout:
+			t.Fatalf("nothing published to %s", topic)
+		}
+	}
+}
+
+// report waits for the next reported shadow state of thing.
+func (c *client) report(t *testing.T, thing string) reported {
+	t.Helper()
+	m := c.next(t, "$aws/things/"+thing+"/shadow/update")
+	var state reported
+	if err := json.Unmarshal(m.payload, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestWrite(t *testing.T) {
+	c := newClient()
+	publisher := awsiot.New(c, nil, awsiot.Config{ThingName: "north"})
+	target := LD2451.Target{Distance: 20, Direction: LD2451.DirectionToward, Speed: 40, SNR: 9}
+	if err := publisher.Write(target); err != nil {
+		t.Fatal(err)
+	}
+	m := c.next(t, "ld2451/north/targets")
+	var published LD2451.Target
+	if err := json.Unmarshal(m.payload, &published); err != nil {
+		t.Fatal(err)
+	}
+	if published != target || m.qos != 0 {
+		t.Errorf("published %+v at QoS %d", published, m.qos)
+	}
+
+	publisher = awsiot.New(c, nil, awsiot.Config{ThingName: "south", TargetTopic: "radar/{thing}"})
+	publisher.Write(target)
+	c.next(t, "radar/south")
+}
+
+func TestShadow(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	//the module keeps the parameters it was given
+	var mu sync.Mutex
+	params := []byte{60, 2, 10, 2}
+	sensor.Handle(protocol.CmdReadDetection, func(sensortest.Command) (uint16, []byte, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return 0, bytes.Clone(params), true
+	})
+	sensor.Handle(protocol.CmdSetDetection, func(command sensortest.Command) (uint16, []byte, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		params = bytes.Clone(command.Value)
+		return 0, nil, true
+	})
+	config := sensor.Config()
+	config.SinkFlushInterval = 10 * time.Millisecond
+	radar, err := LD2451.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer radar.Close()
+
+	c := newClient()
+	clock := sensortest.NewClock(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	publisher := awsiot.New(c, radar, awsiot.Config{ThingName: "north", ShadowInterval: time.Minute, Clock: clock})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- publisher.Run(ctx) }()
+
+	state := c.report(t, "north")
+	if state.State.Reported.Detection == nil || state.State.Reported.Detection.MaxDistance != 60 || state.State.Reported.LastError != "" {
+		t.Fatalf("reported %+v at start", state.State.Reported)
+	}
+
+	//the desired distance is merged into the module's parameters
+	c.deliver(t, "$aws/things/north/shadow/update/delta", `{"state":{"detection":{"max_distance":100}}}`)
+	state = c.report(t, "north")
+	expected := LD2451.DetectionParameters{MaxDistance: 100, Direction: LD2451.DetectBoth, MinSpeed: 10, NoTargetDelay: 2 * time.Second}
+	if state.State.Reported.Detection == nil || *state.State.Reported.Detection != expected || state.State.Reported.LastError != "" {
+		t.Fatalf("reported %+v after the delta", state.State.Reported)
+	}
+
+	c.deliver(t, "$aws/things/north/shadow/update/delta", `{"state":{"detection":{"max_distance":5}}}`)
+	state = c.report(t, "north")
+	if state.State.Reported.LastError == "" || state.State.Reported.Detection.MaxDistance != 100 {
+		t.Fatalf("reported %+v after a delta out of range", state.State.Reported)
+	}
+
+	//without deltas the state is only refreshed every ShadowInterval
+	select {
+	case m := <-c.published:
+		t.Fatalf("published %s to %s before the interval", m.payload, m.topic)
+	case <-time.After(100 * time.Millisecond):
+	}
+	clock.Advance(time.Minute)
+	c.report(t, "north")
+
+	sensor.SendTargets(false, LD2451.Target{Distance: 20, Direction: LD2451.DirectionToward, Speed: 40, SNR: 9})
+	c.next(t, "ld2451/north/targets")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package LD2451
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// Baud rates the module's UART can be configured for.
+const (
+	Baud9600   = 9600
+	Baud19200  = 19200
+	Baud38400  = 38400
+	Baud57600  = 57600
+	Baud115200 = 115200
+	Baud230400 = 230400
+	Baud256000 = 256000
+	Baud460800 = 460800
+)
+
+// baudRates lists the supported rates in the order of the values the
+// SetBaudRate command uses for them, starting at 1.
+var baudRates = []int{Baud9600, Baud19200, Baud38400, Baud57600, Baud115200, Baud230400, Baud256000, Baud460800}
+
+// BaudRates returns the baud rates supported by the module, slowest first.
+func BaudRates() []int {
+	return append([]int(nil), baudRates...)
+}
+
+// baudRateValue returns the value selecting rate in the SetBaudRate command.
+func baudRateValue(rate int) (uint16, bool) {
+	for i, r := range baudRates {
+		if r == rate {
+			return uint16(i + 1), true
+		}
+	}
+	return 0, false
+}
+
+// SetBaudRate configures the module's UART for rate, one of the Baud
+// constants. The module keeps talking at the current rate until it is
+// restarted, afterwards the port has to be opened with the new rate. With
+// Config.ReopenAfterBaudChange both happen right away and reading carries on
+// at the new rate once the module booted.
+func (ld2451 *LD2451) SetBaudRate(rate int) error {
+	value, ok := baudRateValue(rate)
+	if !ok {
+		return ld2451.wrap("SetBaudRate", fmt.Errorf("baud rate %d is not supported by the LD2451", rate))
+	}
+	set := func() error {
+		_, err := ld2451.command(protocol.CmdSetBaudRate, binary.LittleEndian.AppendUint16(nil, value))
+		return err
+	}
+	if !ld2451.config.ReopenAfterBaudChange {
+		return ld2451.configure("SetBaudRate", set)
+	}
+	if ld2451.reopen == nil {
+		return ld2451.wrap("SetBaudRate", errors.New("reopening the port after a baud change needs a port opened by Open"))
+	}
+	return ld2451.enqueue("SetBaudRate", func() error {
+		if err := ld2451.session(set); err != nil {
+			return err
+		}
+		if err := ld2451.restart(); err != nil {
+			return err
+		}
+		if err := ld2451.switchBaudRate(rate); err != nil {
+			return err
+		}
+		sleep(ld2451.config.Clock, defaultResetBootDelay, ld2451.closed)
+		return nil
+	})
+}
+
+// Restart reboots the module, e.g. to apply a baud rate set by SetBaudRate.
+// Queued commands wait a second for the module to start, and a sleeping
+// module reports again afterwards.
+func (ld2451 *LD2451) Restart() error {
+	return ld2451.enqueue("Restart", func() error {
+		if err := ld2451.restart(); err != nil {
+			return err
+		}
+		sleep(ld2451.config.Clock, defaultResetBootDelay, ld2451.closed)
+		return nil
+	})
+}
+
+// restart sends CmdRestart in config mode. The module reboots instead of
+// leaving config mode, so that is only tried when it refused to restart.
+func (ld2451 *LD2451) restart() error {
+	defer ld2451.beginConfiguring()()
+
+	if !ld2451.asleep {
+		info, err := ld2451.command(protocol.CmdEnableConfig, []byte{0x01, 0x00})
+		if err != nil {
+			return err
+		}
+		ld2451.configInfo = info
+	}
+	if _, err := ld2451.command(protocol.CmdRestart, nil); err != nil {
+		if !ld2451.asleep {
+			ld2451.command(protocol.CmdEndConfig, nil)
+		}
+		return err
+	}
+	ld2451.config.Logger.Info("module restarted")
+	//the module comes back reporting, whatever mode it was left in
+	if ld2451.asleep {
+		ld2451.asleep = false
+		ld2451.setState(StateReporting)
+	}
+	return nil
+}
+
+// switchBaudRate closes the port and opens it again at rate, handing the
+// new port to the read goroutine, which sees the old one fail meanwhile.
+func (ld2451 *LD2451) switchBaudRate(rate int) error {
+	switched := make(chan Source, 1)
+	ld2451.writeMu.Lock()
+	select {
+	case <-ld2451.closed:
+		ld2451.writeMu.Unlock()
+		return errors.New("sensor is closed")
+	default:
+	}
+	ld2451.switched = switched
+	ld2451.port.Close()
+	port, err := ld2451.reopen(rate)
+	if err != nil {
+		ld2451.writeMu.Unlock()
+		switched <- nil
+		return fmt.Errorf("reopen %s at %d baud: %w", ld2451.portName, rate, err)
+	}
+	ld2451.port = port
+	ld2451.baud.Store(int64(rate))
+	ld2451.writeMu.Unlock()
+
+	if err := ld2451.releaseReset(); err != nil {
+		ld2451.config.Logger.Warn("releasing the hardware reset failed", "error", err)
+	}
+	switched <- protocol.NewReader(port)
+	ld2451.config.Logger.Info("port reopened at new baud rate", "baud", rate)
+	return nil
+}
+
+// awaitSwitch reports whether the read error just seen came from
+// SetBaudRate closing the port, and continues with the reopened port if so.
+func (ld2451 *LD2451) awaitSwitch() bool {
+	ld2451.writeMu.Lock()
+	switched := ld2451.switched
+	ld2451.switched = nil
+	ld2451.writeMu.Unlock()
+	if switched == nil {
+		return false
+	}
+	select {
+	case frames := <-switched:
+		if frames == nil {
+			return false
+		}
+		ld2451.useFrames(frames)
+		return true
+	case <-ld2451.closed:
+		return false
+	}
+}
@@ -0,0 +1,300 @@
+// Package capture stores raw traffic of an LD2451 in a compact, self
+// describing file format. A capture starts with a header describing the
+// sensor, followed by one record per packet holding its time, direction and
+// payload, so long recordings take a fraction of a hex dump and keep their
+// timing.
+//
+// The layout is the magic "LDCAP", a version byte, the length of the JSON
+// encoded Metadata as uvarint followed by the metadata itself, then records of
+//
+//	uvarint flags      bit 0 set for command frames, bit 1 for packets sent to the module
+//	varint  delta      microseconds since the previous record, or since Metadata.Start
+//	uvarint length     of the payload
+//	payload            the frame without header, length and footer
+//
+// Recording a live sensor goes through a Recorder:
+//
+//	w, err := capture.NewWriter(file, capture.Metadata{Port: "/dev/ttyUSB0"})
+//	...
+//	sensor, err := LD2451.NewSource(capture.NewRecorder(port, w, config.Clock), config)
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+const (
+	magic   = "LDCAP"
+	version = 1
+
+	flagCommand = 1 << 0
+	flagSent    = 1 << 1
+
+	maxMetadataLength = 1 << 20
+)
+
+var ErrFormat = errors.New("capture: not a capture file")
+
+// Metadata describes the recorded sensor.
+type Metadata struct {
+	Sensor      string            `json:"sensor"` // Module type (default "LD2451")
+	Firmware    string            `json:"firmware,omitempty"`
+	Port        string            `json:"port,omitempty"`
+	BaudRate    int               `json:"baud_rate,omitempty"`
+	Start       time.Time         `json:"start"`             // Time the record times count from (default the time NewWriter was called)
+	Variant     *protocol.Variant `json:"variant,omitempty"` // Layout of the recorded data frames, see LD2451.ProtocolInfo; nil for protocol.VariantV1
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// Record is a packet read from or sent to the module.
+type Record struct {
+	Time    time.Time
+	Kind    protocol.Kind
+	Sent    bool // The packet was sent to the module rather than received from it
+	Payload []byte
+}
+
+// Writer appends records to a capture. It is safe for concurrent use.
+type Writer struct {
+	mu   sync.Mutex
+	w    *bufio.Writer
+	last time.Time
+	buf  []byte
+}
+
+// NewWriter writes the header of a capture described by metadata to w.
+func NewWriter(w io.Writer, metadata Metadata) (*Writer, error) {
+	if metadata.Sensor == "" {
+		metadata.Sensor = "LD2451"
+	}
+	if metadata.Start.IsZero() {
+		metadata.Start = time.Now()
+	}
+	header, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	writer := &Writer{w: bufio.NewWriter(w), last: metadata.Start}
+	writer.buf = append(writer.buf, magic...)
+	writer.buf = append(writer.buf, version)
+	writer.buf = binary.AppendUvarint(writer.buf, uint64(len(header)))
+	writer.buf = append(writer.buf, header...)
+	if _, err := writer.w.Write(writer.buf); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write appends record. Records are buffered until Flush.
+func (w *Writer) Write(record Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var flags uint64
+	if record.Kind == protocol.KindCommand {
+		flags |= flagCommand
+	}
+	if record.Sent {
+		flags |= flagSent
+	}
+	//times are kept relative to the stored, not the exact, previous time so
+	//rounding never adds up
+	delta := record.Time.Sub(w.last).Microseconds()
+	w.last = w.last.Add(time.Duration(delta) * time.Microsecond)
+	w.buf = binary.AppendUvarint(w.buf[:0], flags)
+	w.buf = binary.AppendVarint(w.buf, delta)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(record.Payload)))
+	w.buf = append(w.buf, record.Payload...)
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// Flush writes buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Flush()
+}
+
+// Reader reads the records of a capture.
+type Reader struct {
+	r        *bufio.Reader
+	metadata Metadata
+	last     time.Time
+}
+
+// NewReader reads the header of a capture from r.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+	head := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(reader.r, head); err != nil || string(head[:len(magic)]) != magic {
+		return nil, ErrFormat
+	}
+	if head[len(magic)] != version {
+		return nil, fmt.Errorf("capture: unsupported version %d", head[len(magic)])
+	}
+	length, err := binary.ReadUvarint(reader.r)
+	if err != nil || length > maxMetadataLength {
+		return nil, ErrFormat
+	}
+	header := make([]byte, length)
+	if _, err := io.ReadFull(reader.r, header); err != nil {
+		return nil, ErrFormat
+	}
+	if err := json.Unmarshal(header, &reader.metadata); err != nil {
+		return nil, fmt.Errorf("capture: metadata: %w", err)
+	}
+	reader.last = reader.metadata.Start
+	return reader, nil
+}
+
+func (r *Reader) Metadata() Metadata {
+	return r.metadata
+}
+
+// Next returns the next record, or io.EOF after the last one. A capture cut
+// short while writing ends with io.ErrUnexpectedEOF.
+func (r *Reader) Next() (Record, error) {
+	flags, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return Record{}, err
+	}
+	delta, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return Record{}, unexpected(err)
+	}
+	length, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return Record{}, unexpected(err)
+	}
+	if length > protocol.MaxPayloadLength {
+		return Record{}, fmt.Errorf("capture: record of %d bytes is too long", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		return Record{}, unexpected(err)
+	}
+	r.last = r.last.Add(time.Duration(delta) * time.Microsecond)
+	record := Record{Time: r.last, Kind: protocol.KindData, Sent: flags&flagSent != 0, Payload: payload}
+	if flags&flagCommand != 0 {
+		record.Kind = protocol.KindCommand
+	}
+	return record, nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Frames returns the received data frames decoded in the layout of
+// Metadata.Variant, with their recorded time, for replay.New or
+// LD2451.FrameSource. It returns io.EOF after the last one.
+func (r *Reader) Frames() func() (LD2451.Frame, error) {
+	variant := protocol.VariantV1
+	if r.metadata.Variant != nil {
+		variant = *r.metadata.Variant
+	}
+	return func() (LD2451.Frame, error) {
+		for {
+			record, err := r.Next()
+			if err != nil {
+				return LD2451.Frame{}, err
+			}
+			if record.Sent || record.Kind != protocol.KindData {
+				continue
+			}
+			frame, err := variant.ParseFrame(record.Payload, nil)
+			frame.Time = record.Time
+			return frame, err
+		}
+	}
+}
+
+// Recorder reads packets from a port for LD2451.NewSource and records them,
+// together with the commands sent to the module. Failing to write the capture
+// doesn't disturb the sensor, recording stops and the error is kept for Err.
+type Recorder struct {
+	port   io.ReadWriteCloser
+	frames *protocol.Reader
+	w      *Writer
+	clock  LD2451.Clock
+
+	mu  sync.Mutex
+	err error //first error writing the capture
+}
+
+// NewRecorder records the packets of port to w, timed by clock, which should
+// be the one of the sensor; nil uses LD2451.SystemClock.
+func NewRecorder(port io.ReadWriteCloser, w *Writer, clock LD2451.Clock) *Recorder {
+	if clock == nil {
+		clock = LD2451.SystemClock
+	}
+	return &Recorder{port: port, frames: protocol.NewReader(port), w: w, clock: clock}
+}
+
+func (r *Recorder) Next() (protocol.Packet, error) {
+	packet, err := r.frames.Next()
+	if err != nil {
+		return packet, err
+	}
+	r.record(Record{Time: r.clock.Now(), Kind: packet.Kind, Payload: packet.Payload})
+	return packet, nil
+}
+
+// Write forwards a command frame to the module and records its payload.
+func (r *Recorder) Write(data []byte) (int, error) {
+	n, err := r.port.Write(data)
+	if err != nil {
+		return n, err
+	}
+	//commands are written as whole frames, take them apart like received ones
+	frames := protocol.NewReader(bytes.NewReader(data))
+	for {
+		packet, err := frames.Next()
+		if err != nil {
+			break
+		}
+		r.record(Record{Time: r.clock.Now(), Kind: packet.Kind, Sent: true, Payload: packet.Payload})
+	}
+	return n, nil
+}
+
+// record writes record unless writing failed before.
+func (r *Recorder) record(record Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.w.Write(record)
+}
+
+// Err returns the error that stopped the recording, nil while it is going.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close closes the port and flushes the capture.
+func (r *Recorder) Close() error {
+	err := r.port.Close()
+	if flushErr := r.w.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
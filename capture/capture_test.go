@@ -0,0 +1,262 @@
+package capture_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/capture"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+var start = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func TestRoundTrip(t *testing.T) {
+	frame := protocol.EncodeFramePayload([]protocol.Target{{Angle: 5, Distance: 20, Direction: protocol.DirectionToward, Speed: 30, SNR: 40}}, 0)
+	records := []capture.Record{
+		{Time: start.Add(1500 * time.Microsecond), Kind: protocol.KindData, Payload: frame},
+		{Time: start.Add(time.Second), Kind: protocol.KindCommand, Sent: true, Payload: []byte{0xff, 0x00, 0x01, 0x00}},
+		{Time: start.Add(time.Second + 100*time.Millisecond), Kind: protocol.KindCommand, Payload: []byte{0xff, 0x01, 0x00, 0x00}},
+		//a record out of order is kept as it is
+		{Time: start.Add(time.Second), Kind: protocol.KindData, Payload: nil},
+	}
+	metadata := capture.Metadata{Firmware: "V1.02", Port: "/dev/ttyUSB0", BaudRate: 115200, Start: start, Labels: map[string]string{"site": "north"}}
+
+	var file bytes.Buffer
+	w, err := capture.NewWriter(&file, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := capture.NewReader(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := r.Metadata()
+	if read.Sensor != "LD2451" || read.Firmware != metadata.Firmware || !read.Start.Equal(start) || read.Labels["site"] != "north" || read.Variant != nil {
+		t.Errorf("got metadata %+v", read)
+	}
+	for i, expected := range records {
+		record, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !record.Time.Equal(expected.Time) || record.Kind != expected.Kind || record.Sent != expected.Sent || !bytes.Equal(record.Payload, expected.Payload) {
+			t.Errorf("record %d is %+v, expected %+v", i, record, expected)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("got %v after the last record, expected io.EOF", err)
+	}
+
+	//cut short while writing
+	r, err = capture.NewReader(bytes.NewReader(file.Bytes()[:file.Len()-3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = r.Next()
+	}
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v for a truncated capture, expected io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestNewReaderRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"hex dump", []byte("f4 f3 f2 f1 00 00 f8 f7 f6 f5")},
+		{"header cut short", []byte("LDCAP\x01\x10{}")},
+		{"unknown version", []byte("LDCAP\x07\x02{}")},
+		{"broken metadata", []byte("LDCAP\x01\x02{x")},
+	}
+	for _, test := range tests {
+		if _, err := capture.NewReader(bytes.NewReader(test.data)); err == nil {
+			t.Errorf("%s: read as a capture", test.name)
+		}
+	}
+	if _, err := capture.NewReader(bytes.NewReader([]byte("hello world"))); !errors.Is(err, capture.ErrFormat) {
+		t.Errorf("got %v, expected ErrFormat", err)
+	}
+}
+
+func TestFramesUseTheVariant(t *testing.T) {
+	//two targets in 8 byte records, as newer firmware reports them
+	payload := []byte{2, 1, 0, 0x85, 20, 1, 30, 40, 0xaa, 0xbb, 0, 0x7b, 25, 0, 50, 60, 0xcc, 0xdd}
+	expected := []LD2451.Target{
+		{Angle: 5, Distance: 20, Direction: LD2451.DirectionToward, Speed: 30, SNR: 40},
+		{Angle: -5, Distance: 25, Direction: LD2451.DirectionAway, Speed: 50, SNR: 60},
+	}
+	tests := []struct {
+		name    string
+		variant *protocol.Variant
+		ok      bool
+	}{
+		{"recorded as v1", nil, false},
+		{"recorded as extended", &protocol.VariantExtended, true},
+		{"recorded with the record size", &protocol.Variant{Name: "custom", RecordSize: 8}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var file bytes.Buffer
+			w, err := capture.NewWriter(&file, capture.Metadata{Start: start, Variant: test.variant})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(capture.Record{Time: start, Kind: protocol.KindCommand, Sent: true, Payload: []byte{0xff, 0x00}})
+			w.Write(capture.Record{Time: start.Add(time.Second), Kind: protocol.KindData, Payload: payload})
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := capture.NewReader(&file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			frames := r.Frames()
+			frame, err := frames()
+			if !test.ok {
+				var parseErr *protocol.ParseError
+				if !errors.As(err, &parseErr) {
+					t.Errorf("got %v, expected a ParseError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(frame.Targets, expected) || !frame.Alarm || !frame.Time.Equal(start.Add(time.Second)) {
+				t.Errorf("got %+v", frame)
+			}
+			if _, err := frames(); err != io.EOF {
+				t.Errorf("got %v after the last frame, expected io.EOF", err)
+			}
+		})
+	}
+}
+
+// port is a module sending data and taking the commands written to it.
+type port struct {
+	io.Reader
+	written bytes.Buffer
+	closed  bool
+}
+
+func (p *port) Write(data []byte) (int, error) { return p.written.Write(data) }
+func (p *port) Close() error                   { p.closed = true; return nil }
+
+func TestRecorder(t *testing.T) {
+	frame := protocol.EncodeFrame([]protocol.Target{{Distance: 20, Direction: protocol.DirectionToward, Speed: 30, SNR: 40}}, 0)
+	ack := protocol.EncodeAck(protocol.CmdEnableConfig, 0, []byte{0x01, 0x00})
+	module := &port{Reader: bytes.NewReader(slices.Concat(frame, ack))}
+	clock := sensortest.NewClock(start)
+
+	var file bytes.Buffer
+	w, err := capture.NewWriter(&file, capture.Metadata{Start: clock.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := capture.NewRecorder(module, w, clock)
+	clock.Advance(10 * time.Millisecond)
+	if _, err := recorder.Next(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(10 * time.Millisecond)
+	command := protocol.EncodeCommand(protocol.CmdEnableConfig, []byte{0x01, 0x00})
+	if _, err := recorder.Write(command); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(10 * time.Millisecond)
+	if _, err := recorder.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.Next(); err != io.EOF {
+		t.Fatalf("got %v at the end of the port, expected io.EOF", err)
+	}
+	if err := recorder.Close(); err != nil || !module.closed || recorder.Err() != nil {
+		t.Fatalf("closed the port %t, got %v and recording error %v", module.closed, err, recorder.Err())
+	}
+	if !bytes.Equal(module.written.Bytes(), command) {
+		t.Errorf("module got % x, expected % x", module.written.Bytes(), command)
+	}
+
+	r, err := capture.NewReader(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []capture.Record{
+		{Time: start.Add(10 * time.Millisecond), Kind: protocol.KindData, Payload: frame[6 : len(frame)-4]},
+		{Time: start.Add(20 * time.Millisecond), Kind: protocol.KindCommand, Sent: true, Payload: command[6 : len(command)-4]},
+		{Time: start.Add(30 * time.Millisecond), Kind: protocol.KindCommand, Payload: ack[6 : len(ack)-4]},
+	}
+	for i, expected := range expected {
+		record, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !record.Time.Equal(expected.Time) || record.Kind != expected.Kind || record.Sent != expected.Sent || !bytes.Equal(record.Payload, expected.Payload) {
+			t.Errorf("record %d is %+v, expected %+v", i, record, expected)
+		}
+	}
+}
+
+// failingWriter takes the capture header, then fails.
+type failingWriter struct {
+	header bool
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (w *failingWriter) Write(data []byte) (int, error) {
+	if w.header {
+		return 0, errDiskFull
+	}
+	w.header = true
+	return len(data), nil
+}
+
+func TestRecorderWriteErrorKeepsReading(t *testing.T) {
+	frame := protocol.EncodeFrame([]protocol.Target{{Distance: 20, Direction: protocol.DirectionToward, Speed: 30, SNR: 40}}, 0)
+	const frames = 1000 //far more than the capture buffers
+	module := &port{Reader: bytes.NewReader(bytes.Repeat(frame, frames))}
+
+	w, err := capture.NewWriter(&failingWriter{}, capture.Metadata{Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	recorder := capture.NewRecorder(module, w, sensortest.NewClock(start))
+	for i := range frames {
+		packet, err := recorder.Next()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if packet.Kind != protocol.KindData {
+			t.Fatalf("frame %d read as %+v", i, packet)
+		}
+	}
+	if err := recorder.Err(); !errors.Is(err, errDiskFull) {
+		t.Errorf("got recording error %v, expected %v", err, errDiskFull)
+	}
+	if _, err := recorder.Next(); err != io.EOF {
+		t.Errorf("got %v at the end of the port, expected io.EOF", err)
+	}
+}
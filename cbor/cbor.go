@@ -0,0 +1,211 @@
+// Package cbor encodes LD2451 targets, frames and tracks as CBOR (RFC 8949)
+// for links where every byte counts, such as LoRa or MQTT-SN. Messages are
+// maps keyed by small integers rather than names, fields holding their zero
+// value are left out, and times keep their nanoseconds as extended times (tag
+// 1001 of RFC 9581) unless they fall on a whole second, which takes an epoch
+// time (tag 1). Decoded times are in UTC. A target typically takes about 30
+// bytes, under a third of its JSON:
+//
+//	target: {1: angle, 2: distance, 3: direction, 4: speed, 5: snr, 6: time,
+//	         7: sensor, 8: fine_distance, 9: fine_speed, 10: radial_speed,
+//	         11: fine}
+//	frame:  {1: [target, ...], 2: alarm, 3: time, 4: sensor}
+//
+// Direction is 0 for away and 1 for toward. Like the protobuf package the
+// format is written by hand, so no CBOR library is needed on the Go side.
+package cbor
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/tracking"
+)
+
+// MarshalTarget encodes target as a CBOR map.
+func MarshalTarget(target LD2451.Target) []byte {
+	return appendTarget(nil, target)
+}
+
+// UnmarshalTarget decodes a map written by MarshalTarget. Unknown keys are
+// skipped.
+func UnmarshalTarget(data []byte) (LD2451.Target, error) {
+	d := &decoder{data: data}
+	return d.target()
+}
+
+// MarshalFrame encodes frame as a CBOR map.
+func MarshalFrame(frame LD2451.Frame) []byte {
+	e := &encoder{}
+	e.begin()
+	if len(frame.Targets) > 0 {
+		e.key(1)
+		e.buf = appendHead(e.buf, majorArray, uint64(len(frame.Targets)))
+		for _, target := range frame.Targets {
+			e.buf = appendTarget(e.buf, target)
+		}
+	}
+	e.bool(2, frame.Alarm)
+	e.time(3, frame.Time)
+	e.text(4, frame.Sensor)
+	e.end()
+	return e.buf
+}
+
+// UnmarshalFrame decodes a map written by MarshalFrame.
+func UnmarshalFrame(data []byte) (LD2451.Frame, error) {
+	frame := LD2451.Frame{}
+	d := &decoder{data: data}
+	fields, err := d.mapLen()
+	if err != nil {
+		return frame, err
+	}
+	for range fields {
+		key, err := d.key()
+		if err != nil {
+			return frame, err
+		}
+		switch key {
+		case 1:
+			major, _, n, err := d.head()
+			if err != nil {
+				return frame, err
+			}
+			if major != majorArray || n > uint64(len(d.data)) {
+				return frame, fmt.Errorf("cbor: targets of a frame are no array")
+			}
+			frame.Targets = make([]LD2451.Target, 0, n)
+			for range n {
+				target, err := d.target()
+				if err != nil {
+					return frame, err
+				}
+				frame.Targets = append(frame.Targets, target)
+			}
+		case 2:
+			frame.Alarm, err = d.bool()
+		case 3:
+			frame.Time, err = d.time()
+		case 4:
+			frame.Sensor, err = d.text()
+		default:
+			err = d.skip(0)
+		}
+		if err != nil {
+			return frame, err
+		}
+	}
+	return frame, nil
+}
+
+// MarshalTrack encodes track as a CBOR map with the keys
+//
+//	{1: id, 2: direction, 3: start, 4: end, 5: detections, 6: distance,
+//	 7: entry_distance, 8: angle, 9: speed, 10: max_speed, 11: mean_speed,
+//	 12: max_snr, 13: mean_snr, 14: class, 15: lane, 16: band, 17: sensor}
+//
+// leaving out the arrival and acceleration estimates.
+func MarshalTrack(track tracking.Track) []byte {
+	e := &encoder{}
+	e.begin()
+	e.int(1, int64(track.ID))
+	e.int(2, int64(track.Direction))
+	e.time(3, track.Start)
+	e.time(4, track.End)
+	e.int(5, int64(track.Detections))
+	e.int(6, int64(track.Distance))
+	e.int(7, int64(track.EntryDistance))
+	e.int(8, int64(track.Angle))
+	e.int(9, int64(track.Speed))
+	e.int(10, int64(track.MaxSpeed))
+	e.float(11, track.MeanSpeed)
+	e.int(12, int64(track.MaxSNR))
+	e.float(13, track.MeanSNR)
+	e.int(14, int64(track.Class))
+	e.int(15, int64(track.Lane))
+	e.text(16, track.Band)
+	e.text(17, track.Sensor)
+	e.end()
+	return e.buf
+}
+
+func appendTarget(buf []byte, target LD2451.Target) []byte {
+	e := &encoder{buf: buf}
+	e.begin()
+	e.int(1, int64(target.Angle))
+	e.int(2, int64(target.Distance))
+	e.int(3, int64(target.Direction))
+	e.int(4, int64(target.Speed))
+	e.int(5, int64(target.SNR))
+	e.time(6, target.Time)
+	e.text(7, target.Sensor)
+	e.float(8, target.FineDistance)
+	e.float(9, target.FineSpeed)
+	e.float(10, target.RadialSpeed)
+	e.bool(11, target.Fine)
+	e.end()
+	return e.buf
+}
+
+func (d *decoder) target() (LD2451.Target, error) {
+	target := LD2451.Target{}
+	fields, err := d.mapLen()
+	if err != nil {
+		return target, err
+	}
+	for range fields {
+		key, err := d.key()
+		if err != nil {
+			return target, err
+		}
+		var v int64
+		switch key {
+		case 1, 2, 3, 4, 5:
+			v, err = d.int()
+		case 6:
+			target.Time, err = d.time()
+		case 7:
+			target.Sensor, err = d.text()
+		case 8:
+			target.FineDistance, err = d.float()
+		case 9:
+			target.FineSpeed, err = d.float()
+		case 10:
+			target.RadialSpeed, err = d.float()
+		case 11:
+			target.Fine, err = d.bool()
+		default:
+			err = d.skip(0)
+		}
+		if err != nil {
+			return target, err
+		}
+		switch key {
+		case 1:
+			target.Angle = int(v)
+		case 2:
+			target.Distance = int(v)
+		case 3:
+			target.Direction = LD2451.Direction(v)
+		case 4:
+			target.Speed = int(v)
+		case 5:
+			target.SNR = int(v)
+		}
+	}
+	return target, nil
+}
+
+// Codec encodes targets like MarshalTarget for an LD2451.EncodedSink.
+var Codec LD2451.Codec = LD2451.CodecFunc(func(buf []byte, target LD2451.Target) ([]byte, error) {
+	return appendTarget(buf, target), nil
+})
+
+// NewSink writes targets to w as a CBOR sequence (RFC 8742), one map after
+// the other without any framing, e.g. into a file or a serial link. For
+// transports taking one message per target, such as MQTT-SN, wrap
+// MarshalTarget in an LD2451.SinkFunc instead.
+func NewSink(w io.Writer) *LD2451.EncodedSink {
+	return LD2451.NewEncodedSink(w, Codec)
+}
@@ -0,0 +1,212 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// The vectors are taken from RFC 8949 Appendix A.
+func TestIntVectors(t *testing.T) {
+	tests := []struct {
+		v   int64
+		hex string
+	}{
+		{0, "00"},
+		{1, "01"},
+		{10, "0a"},
+		{23, "17"},
+		{24, "1818"},
+		{25, "1819"},
+		{100, "1864"},
+		{1000, "1903e8"},
+		{1000000, "1a000f4240"},
+		{1000000000000, "1b000000e8d4a51000"},
+		{-1, "20"},
+		{-10, "29"},
+		{-100, "3863"},
+		{-1000, "3903e7"},
+	}
+	for _, test := range tests {
+		want := mustHex(t, test.hex)
+		if got := appendInt(nil, test.v); !bytes.Equal(got, want) {
+			t.Errorf("appendInt(%d) = %x, want %s", test.v, got, test.hex)
+		}
+		d := &decoder{data: want}
+		if got, err := d.int(); err != nil || got != test.v {
+			t.Errorf("decoding %s = %d, %v, want %d", test.hex, got, err, test.v)
+		}
+	}
+}
+
+func TestFloatVectors(t *testing.T) {
+	encoded := []struct {
+		v   float64
+		hex string
+	}{
+		{100000.0, "fa47c35000"},
+		{3.4028234663852886e+38, "fa7f7fffff"},
+		{1.1, "fb3ff199999999999a"},
+		{1.0e+300, "fb7e37e43c8800759c"},
+		{-4.1, "fbc010666666666666"},
+	}
+	for _, test := range encoded {
+		if got := appendFloat(nil, test.v); !bytes.Equal(got, mustHex(t, test.hex)) {
+			t.Errorf("appendFloat(%v) = %x, want %s", test.v, got, test.hex)
+		}
+	}
+
+	//half precision is never written but has to be read
+	decoded := append(encoded, []struct {
+		v   float64
+		hex string
+	}{
+		{0.0, "f90000"},
+		{-0.0, "f98000"},
+		{1.0, "f93c00"},
+		{1.5, "f93e00"},
+		{65504.0, "f97bff"},
+		{5.960464477539063e-08, "f90001"},
+		{0.00006103515625, "f90400"},
+		{-4.0, "f9c400"},
+		{math.Inf(1), "f97c00"},
+		{math.Inf(-1), "f9fc00"},
+	}...)
+	for _, test := range decoded {
+		d := &decoder{data: mustHex(t, test.hex)}
+		if got, err := d.float(); err != nil || got != test.v {
+			t.Errorf("decoding %s = %v, %v, want %v", test.hex, got, err, test.v)
+		}
+	}
+	d := &decoder{data: mustHex(t, "f97e00")}
+	if got, err := d.float(); err != nil || !math.IsNaN(got) {
+		t.Errorf("decoding f97e00 = %v, %v, want NaN", got, err)
+	}
+}
+
+func TestSimpleAndTextVectors(t *testing.T) {
+	for hexBool, want := range map[string]bool{"f4": false, "f5": true} {
+		d := &decoder{data: mustHex(t, hexBool)}
+		if got, err := d.bool(); err != nil || got != want {
+			t.Errorf("decoding %s = %v, %v, want %v", hexBool, got, err, want)
+		}
+	}
+	for hexText, want := range map[string]string{"60": "", "6161": "a", "6449455446": "IETF", "62225c": "\"\\", "62c3bc": "ü"} {
+		d := &decoder{data: mustHex(t, hexText)}
+		if got, err := d.text(); err != nil || got != want {
+			t.Errorf("decoding %s = %q, %v, want %q", hexText, got, err, want)
+		}
+	}
+}
+
+func TestEpochTimeVectors(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want time.Time
+	}{
+		{"c11a514b67b0", time.Unix(1363896240, 0)},
+		{"c1fb41d452d9ec200000", time.Unix(1363896240, 500000000)},
+	}
+	for _, test := range tests {
+		d := &decoder{data: mustHex(t, test.hex)}
+		got, err := d.time()
+		if err != nil || !got.Equal(test.want) {
+			t.Errorf("decoding %s = %s, %v, want %s", test.hex, got, err, test.want)
+		}
+		if got.Location() != time.UTC {
+			t.Errorf("decoding %s returned a time in %s, want UTC", test.hex, got.Location())
+		}
+	}
+
+	e := &encoder{}
+	e.time(0, time.Unix(1363896240, 0))
+	if want := mustHex(t, "00c11a514b67b0"); !bytes.Equal(e.buf, want) {
+		t.Errorf("whole second encoded as %x, want %x", e.buf, want)
+	}
+}
+
+func TestExtendedTime(t *testing.T) {
+	e := &encoder{}
+	e.time(0, time.Unix(1363896240, 123456789))
+	//key 0, tag 1001, {1: 1363896240, -9: 123456789}
+	want := mustHex(t, "00d903e9a2011a514b67b0281a075bcd15")
+	if !bytes.Equal(e.buf, want) {
+		t.Fatalf("encoded as %x, want %x", e.buf, want)
+	}
+
+	d := &decoder{data: mustHex(t, "d903e9a2011a514b67b02219012c")} //{1: 1363896240, -3: 300}
+	got, err := d.time()
+	if err != nil || !got.Equal(time.Unix(1363896240, 300000000)) {
+		t.Fatalf("decoding milliseconds = %s, %v", got, err)
+	}
+}
+
+func TestTargetRoundTrip(t *testing.T) {
+	now := time.Now()
+	targets := []LD2451.Target{
+		{},
+		{Angle: -20, Distance: 45, Direction: LD2451.DirectionToward, Speed: 48, SNR: 180, Time: now, Sensor: "north"},
+		{Distance: 3, Time: time.Unix(1700000000, 0), Fine: true, FineDistance: 3.25, FineSpeed: -7.5, RadialSpeed: 1.1},
+		{Distance: 2, Fine: true, FineDistance: 2.5},
+		{Time: time.Date(2024, 2, 29, 23, 59, 59, 999999999, time.UTC)},
+	}
+	for _, target := range targets {
+		got, err := UnmarshalTarget(MarshalTarget(target))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Time.Equal(target.Time) {
+			t.Fatalf("time came back as %s, want %s, off by %s", got.Time, target.Time, got.Time.Sub(target.Time))
+		}
+		if !target.Time.IsZero() && got.Time.Location() != time.UTC {
+			t.Fatalf("time came back in %s, want UTC", got.Time.Location())
+		}
+		got.Time = target.Time
+		if got != target {
+			t.Fatalf("got %+v, want %+v", got, target)
+		}
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	frame := LD2451.Frame{
+		Targets: []LD2451.Target{{Distance: 10, Speed: 30}, {Angle: 5, FineSpeed: 12.5}},
+		Alarm:   true,
+		Time:    time.Now(),
+		Sensor:  "north",
+	}
+	got, err := UnmarshalFrame(MarshalFrame(frame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Targets) != 2 || got.Targets[0] != frame.Targets[0] || got.Targets[1] != frame.Targets[1] {
+		t.Fatalf("got targets %+v, want %+v", got.Targets, frame.Targets)
+	}
+	if !got.Alarm || !got.Time.Equal(frame.Time) || got.Sensor != frame.Sensor {
+		t.Fatalf("got %+v, want %+v", got, frame)
+	}
+}
+
+func TestUnmarshalSkipsUnknownKeys(t *testing.T) {
+	//{2: 12, 99: [1, {"a": "b"}], "x": 1}
+	target, err := UnmarshalTarget(mustHex(t, "a3020c18638201a161616162617801"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Distance != 12 {
+		t.Fatalf("got distance %d, want 12", target.Distance)
+	}
+}
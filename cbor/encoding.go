@@ -0,0 +1,390 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Major types.
+const (
+	majorUint   = 0
+	majorNegint = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+const (
+	tagEpoch    = 1
+	tagExtended = 1001 //extended time of RFC 9581, a map of the seconds and their fraction
+	simpleFalse = 20
+	simpleTrue  = 21
+)
+
+// Keys of an extended time.
+const (
+	timeSeconds = 1
+	timeMillis  = -3
+	timeMicros  = -6
+	timeNanos   = -9
+)
+
+// maxDepth limits the nesting of skipped items, so crafted input can't
+// exhaust the stack.
+const maxDepth = 16
+
+var errTruncated = errors.New("cbor: truncated item")
+
+// encoder appends the fields of a map keyed by small integers, leaving out
+// fields holding their zero value like the protobuf package does.
+type encoder struct {
+	buf   []byte
+	count int
+	start int //offset of the map header, rewritten by end
+}
+
+func (e *encoder) begin() {
+	e.start = len(e.buf)
+	//a placeholder for up to 23 fields, the most any type has
+	e.buf = append(e.buf, majorMap<<5)
+	e.count = 0
+}
+
+func (e *encoder) end() {
+	e.buf[e.start] = majorMap<<5 | byte(e.count)
+}
+
+func (e *encoder) key(key int) {
+	e.count++
+	e.buf = appendHead(e.buf, majorUint, uint64(key))
+}
+
+func (e *encoder) int(key int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.key(key)
+	e.buf = appendInt(e.buf, v)
+}
+
+func (e *encoder) bool(key int, v bool) {
+	if !v {
+		return
+	}
+	e.key(key)
+	e.buf = append(e.buf, majorSimple<<5|simpleTrue)
+}
+
+func (e *encoder) float(key int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.key(key)
+	e.buf = appendFloat(e.buf, v)
+}
+
+func (e *encoder) text(key int, s string) {
+	if s == "" {
+		return
+	}
+	e.key(key)
+	e.buf = appendHead(e.buf, majorText, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// time appends t as an epoch-based date/time when it falls on a whole second
+// and as an extended time with its nanoseconds otherwise, which a float can't
+// hold for current dates.
+func (e *encoder) time(key int, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	e.key(key)
+	if t.Nanosecond() == 0 {
+		e.buf = appendHead(e.buf, majorTag, tagEpoch)
+		e.buf = appendInt(e.buf, t.Unix())
+		return
+	}
+	e.buf = appendHead(e.buf, majorTag, tagExtended)
+	e.buf = appendHead(e.buf, majorMap, 2)
+	e.buf = appendInt(e.buf, timeSeconds)
+	e.buf = appendInt(e.buf, t.Unix())
+	e.buf = appendInt(e.buf, timeNanos)
+	e.buf = appendInt(e.buf, int64(t.Nanosecond()))
+}
+
+func appendHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major<<5|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major<<5|27), n)
+	}
+}
+
+func appendInt(buf []byte, v int64) []byte {
+	if v < 0 {
+		return appendHead(buf, majorNegint, uint64(-1-v))
+	}
+	return appendHead(buf, majorUint, uint64(v))
+}
+
+// appendFloat uses single precision when it holds v exactly.
+func appendFloat(buf []byte, v float64) []byte {
+	if float64(float32(v)) == v {
+		return binary.BigEndian.AppendUint32(append(buf, majorSimple<<5|26), math.Float32bits(float32(v)))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, majorSimple<<5|27), math.Float64bits(v))
+}
+
+// decoder reads the items of a buffer.
+type decoder struct {
+	data []byte
+}
+
+// head reads the initial byte of an item and its argument. For floats the
+// argument holds the raw bits.
+func (d *decoder) head() (major byte, info byte, arg uint64, err error) {
+	if len(d.data) == 0 {
+		return 0, 0, 0, errTruncated
+	}
+	major, info = d.data[0]>>5, d.data[0]&0x1f
+	d.data = d.data[1:]
+	var size int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		size = 1 << (info - 24)
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+	}
+	if len(d.data) < size {
+		return 0, 0, 0, errTruncated
+	}
+	for _, b := range d.data[:size] {
+		arg = arg<<8 | uint64(b)
+	}
+	d.data = d.data[size:]
+	return major, info, arg, nil
+}
+
+// mapLen reads the header of a map.
+func (d *decoder) mapLen() (int, error) {
+	major, _, n, err := d.head()
+	if err != nil {
+		return 0, err
+	}
+	if major != majorMap {
+		return 0, fmt.Errorf("cbor: expected a map, got major type %d", major)
+	}
+	if n > uint64(len(d.data)) {
+		return 0, errTruncated
+	}
+	return int(n), nil
+}
+
+// key reads a map key, or -1 for a key that is no unsigned integer, whose
+// value should be skipped.
+func (d *decoder) key() (int, error) {
+	if len(d.data) > 0 && d.data[0]>>5 != majorUint {
+		return -1, d.skip(0)
+	}
+	_, _, n, err := d.head()
+	if err != nil || n > math.MaxInt32 {
+		return -1, err
+	}
+	return int(n), nil
+}
+
+func (d *decoder) int() (int64, error) {
+	major, _, n, err := d.head()
+	switch {
+	case err != nil:
+		return 0, err
+	case major == majorUint && n <= math.MaxInt64:
+		return int64(n), nil
+	case major == majorNegint && n <= math.MaxInt64:
+		return -1 - int64(n), nil
+	}
+	return 0, fmt.Errorf("cbor: expected an integer, got major type %d", major)
+}
+
+// float reads a float of any precision, or an integer.
+func (d *decoder) float() (float64, error) {
+	if len(d.data) > 0 && d.data[0]>>5 != majorSimple {
+		v, err := d.int()
+		return float64(v), err
+	}
+	_, info, bits, err := d.head()
+	switch {
+	case err != nil:
+		return 0, err
+	case info == 25:
+		return halfFloat(uint16(bits)), nil
+	case info == 26:
+		return float64(math.Float32frombits(uint32(bits))), nil
+	case info == 27:
+		return math.Float64frombits(bits), nil
+	}
+	return 0, fmt.Errorf("cbor: expected a float, got simple value %d", bits)
+}
+
+func (d *decoder) bool() (bool, error) {
+	major, _, n, err := d.head()
+	switch {
+	case err != nil:
+		return false, err
+	case major == majorSimple && n == simpleTrue:
+		return true, nil
+	case major == majorSimple && n == simpleFalse:
+		return false, nil
+	}
+	return false, fmt.Errorf("cbor: expected a bool, got major type %d", major)
+}
+
+func (d *decoder) text() (string, error) {
+	major, _, n, err := d.head()
+	switch {
+	case err != nil:
+		return "", err
+	case major != majorText:
+		return "", fmt.Errorf("cbor: expected a text string, got major type %d", major)
+	case n > uint64(len(d.data)):
+		return "", errTruncated
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s, nil
+}
+
+// time reads an epoch-based date/time or an extended time, untagged numbers
+// are accepted as well. Times are returned in UTC.
+func (d *decoder) time() (time.Time, error) {
+	if len(d.data) > 0 && d.data[0]>>5 == majorTag {
+		_, _, tag, err := d.head()
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch tag {
+		case tagEpoch:
+		case tagExtended:
+			return d.extendedTime()
+		default:
+			return time.Time{}, fmt.Errorf("cbor: expected an epoch time, got tag %d", tag)
+		}
+	}
+	if len(d.data) > 0 && d.data[0]>>5 != majorSimple {
+		seconds, err := d.int()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	seconds, err := d.float()
+	if err != nil {
+		return time.Time{}, err
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(math.Round(frac*1e9))).UTC(), nil
+}
+
+// extendedTime reads the map of an extended time following its tag. Only the
+// seconds and one of their decimal fractions are understood.
+func (d *decoder) extendedTime() (time.Time, error) {
+	fields, err := d.mapLen()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var seconds, nanos int64
+	for range fields {
+		key, err := d.int()
+		if err != nil {
+			return time.Time{}, err
+		}
+		v, err := d.int()
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch key {
+		case timeSeconds:
+			seconds = v
+		case timeMillis:
+			nanos = v * 1e6
+		case timeMicros:
+			nanos = v * 1e3
+		case timeNanos:
+			nanos = v
+		default:
+			return time.Time{}, fmt.Errorf("cbor: unsupported extended time key %d", key)
+		}
+	}
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+// skip steps over an item, including everything nested in it.
+func (d *decoder) skip(depth int) error {
+	if depth > maxDepth {
+		return errors.New("cbor: items nested too deeply")
+	}
+	major, _, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case majorBytes, majorText:
+		if n > uint64(len(d.data)) {
+			return errTruncated
+		}
+		d.data = d.data[n:]
+	case majorArray, majorMap:
+		if n > uint64(len(d.data)) {
+			return errTruncated
+		}
+		items := int(n)
+		if major == majorMap {
+			items *= 2
+		}
+		for range items {
+			if err := d.skip(depth + 1); err != nil {
+				return err
+			}
+		}
+	case majorTag:
+		return d.skip(depth + 1)
+	}
+	return nil
+}
+
+// halfFloat converts an IEEE 754 half precision float.
+func halfFloat(bits uint16) float64 {
+	exp, mant := int(bits>>10)&0x1f, float64(bits&0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		v = math.Inf(1)
+		if mant != 0 {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if bits&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
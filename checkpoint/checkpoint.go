@@ -0,0 +1,138 @@
+// Package checkpoint periodically saves the state of a tracking.Stream or
+// report.Report to disk and restores it at startup, so a service restart in
+// the middle of the day doesn't lose the running counts and the vehicles
+// passing:
+//
+//	c := checkpoint.New(r, checkpoint.Config{Path: "/var/lib/ld2451/report.json"})
+//	if err := c.Load(); err != nil {
+//		log.Print(err)
+//	}
+//	go c.Run(ctx)
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const DefaultInterval = time.Minute
+
+// State is implemented by *tracking.Stream and *report.Report. MarshalJSON
+// must be safe to call while the state is in use, which a bare
+// *tracking.Tracker is not, so trackers are saved through their Stream.
+type State interface {
+	json.Marshaler
+	json.Unmarshaler
+}
+
+type Config struct {
+	Path     string        // File the state is saved to, replaced atomically
+	Interval time.Duration // How often the state is saved (default DefaultInterval)
+	MaxAge   time.Duration // Saved state older than this is not restored, e.g. tracks after a long outage; 0 restores any
+
+	Clock LD2451.Clock // Source of time for saving and MaxAge, the one of the sensor; nil uses LD2451.SystemClock
+}
+
+type Checkpoint struct {
+	state  State
+	config Config
+}
+
+// file is the content of Config.Path.
+type file struct {
+	Saved time.Time       `json:"saved"`
+	State json.RawMessage `json:"state"`
+}
+
+func New(state State, config Config) *Checkpoint {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+	return &Checkpoint{state: state, config: config}
+}
+
+// Load restores the state saved to Config.Path. A missing file, as on the first
+// start, and state older than Config.MaxAge leave the state as it is and
+// return nil.
+func (c *Checkpoint) Load() error {
+	data, err := os.ReadFile(c.config.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved file
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("checkpoint %s: %w", c.config.Path, err)
+	}
+	if c.config.MaxAge > 0 && c.config.Clock.Now().Sub(saved.Saved) > c.config.MaxAge {
+		return nil
+	}
+	if err := c.state.UnmarshalJSON(saved.State); err != nil {
+		return fmt.Errorf("checkpoint %s: %w", c.config.Path, err)
+	}
+	return nil
+}
+
+// Run saves the state every Config.Interval until ctx is done or saving
+// fails, and a last time before returning because ctx is done.
+func (c *Checkpoint) Run(ctx context.Context) error {
+	ticker := c.config.Clock.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := c.Save(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-ticker.C():
+			if err := c.Save(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Save writes the current state to Config.Path. The file is replaced
+// atomically so a crash while saving leaves the previous checkpoint.
+func (c *Checkpoint) Save() error {
+	state, err := c.state.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(file{Saved: c.config.Clock.Now(), State: state})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.config.Path), "."+filepath.Base(c.config.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	//the data must be on disk before the rename makes it the checkpoint
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.config.Path)
+}
@@ -0,0 +1,146 @@
+package checkpoint_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/checkpoint"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+	"github.com/Battlekeeper/LD2451/v2/tracking"
+)
+
+// counter is a State counting how often it was saved.
+type counter struct {
+	mu    sync.Mutex
+	Value int
+	saves int
+}
+
+func (c *counter) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saves++
+	return json.Marshal(struct {
+		Value int `json:"value"`
+	}{c.Value})
+}
+
+func (c *counter) UnmarshalJSON(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var v struct {
+		Value int `json:"value"`
+	}
+	err := json.Unmarshal(data, &v)
+	c.Value = v.Value
+	return err
+}
+
+func (c *counter) saved() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saves
+}
+
+func TestSaveAndLoadStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tracks.json")
+	tracker, err := tracking.NewTracker(tracking.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	tracker.Update(LD2451.Frame{Targets: []LD2451.Target{{Distance: 40, Direction: LD2451.DirectionToward, Speed: 50}}, Time: now})
+	if err := checkpoint.New(tracking.NewStream(nil, tracker), checkpoint.Config{Path: path}).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := tracking.NewTracker(tracking.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkpoint.New(tracking.NewStream(nil, restored), checkpoint.Config{Path: path}).Load(); err != nil {
+		t.Fatal(err)
+	}
+	active := restored.Active()
+	if len(active) != 1 || active[0].Distance != 40 || !active[0].Start.Equal(now) {
+		t.Errorf("restored %+v", active)
+	}
+}
+
+func TestLoadWithoutCheckpoint(t *testing.T) {
+	state := &counter{Value: 7}
+	c := checkpoint.New(state, checkpoint.Config{Path: filepath.Join(t.TempDir(), "missing.json")})
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if state.Value != 7 {
+		t.Errorf("state changed to %d", state.Value)
+	}
+}
+
+func TestLoadSkipsOldState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	clock := sensortest.NewClock(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	config := checkpoint.Config{Path: path, MaxAge: time.Hour, Clock: clock}
+	if err := checkpoint.New(&counter{Value: 3}, config).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(30 * time.Minute)
+	fresh := &counter{}
+	if err := checkpoint.New(fresh, config).Load(); err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Value != 3 {
+		t.Errorf("restored %d, expected 3", fresh.Value)
+	}
+
+	clock.Advance(time.Hour)
+	stale := &counter{}
+	if err := checkpoint.New(stale, config).Load(); err != nil {
+		t.Fatal(err)
+	}
+	if stale.Value != 0 {
+		t.Errorf("restored %d from a checkpoint older than MaxAge", stale.Value)
+	}
+}
+
+func TestRunSavesOnTheClock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	clock := sensortest.NewClock(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	state := &counter{Value: 1}
+	c := checkpoint.New(state, checkpoint.Config{Path: path, Interval: time.Minute, Clock: clock})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	//the ticker may be created after the first Advance, so keep advancing
+	deadline := time.Now().Add(2 * time.Second)
+	for state.saved() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("not saved after an interval")
+		}
+		clock.Advance(time.Minute)
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	saves := state.saved()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v", err)
+	}
+	if state.saved() != saves+1 {
+		t.Errorf("saved %d times when stopping, expected once", state.saved()-saves)
+	}
+}
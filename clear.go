@@ -0,0 +1,64 @@
+package LD2451
+
+import "time"
+
+// ClearTick is delivered every Config.ClearTickInterval while no target is
+// delivered, so state machines such as "road clear for 30s, close the gate"
+// can be driven from the event stream alone.
+type ClearTick struct {
+	Since  time.Time     `json:"since"` // When the field of view became clear, as far as the sensor knows
+	Clear  time.Duration `json:"clear"` // How long the field of view has been clear
+	Time   time.Time     `json:"time"`
+	Sensor string        `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+func (e ClearTick) EventTime() time.Time { return e.Time }
+
+// ClearTicks returns the channel ClearTick events are delivered on when
+// Config.ClearTickInterval is set. Ticks are dropped rather than stalling the
+// sensor when nobody keeps up with the channel.
+func (ld2451 *LD2451) ClearTicks() <-chan ClearTick {
+	return ld2451.clearTicks
+}
+
+func (ld2451 *LD2451) tickClear() {
+	interval := ld2451.config.ClearTickInterval
+	ticker := ld2451.config.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ld2451.done:
+			return
+		case now := <-ticker.C():
+			tick, ok := ld2451.clearTick(now)
+			if !ok {
+				continue
+			}
+			ld2451.publish(tick)
+			select {
+			case ld2451.clearTicks <- tick:
+			default:
+			}
+		}
+	}
+}
+
+// clearTick reports how long no target was delivered. Only a sensor that is
+// reporting can tell the field of view is clear, so the clear time restarts
+// whenever reporting resumes.
+func (ld2451 *LD2451) clearTick(now time.Time) (ClearTick, bool) {
+	ld2451.statsMu.Lock()
+	defer ld2451.statsMu.Unlock()
+	if ld2451.state != StateReporting {
+		return ClearTick{}, false
+	}
+	since := ld2451.lastTarget
+	if ld2451.stateSince.After(since) {
+		since = ld2451.stateSince
+	}
+	clear := now.Sub(since)
+	if clear < ld2451.config.ClearTickInterval {
+		return ClearTick{}, false
+	}
+	return ClearTick{Since: since, Clear: clear, Time: now, Sensor: ld2451.config.SensorID}, true
+}
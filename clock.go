@@ -0,0 +1,40 @@
+package LD2451
+
+import (
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/hlk"
+)
+
+// Clock is the source of time for timestamps, timeouts, windows and the state
+// watchdog, replaceable through Config.Clock so tests can control time, e.g.
+// with sensortest.Clock. It is defined by the hlk package, so command
+// timeouts of every module run on the same clock.
+type (
+	Clock  = hlk.Clock
+	Timer  = hlk.Timer
+	Ticker = hlk.Ticker
+)
+
+// SystemClock is the Clock used when Config.Clock is nil.
+var SystemClock = hlk.SystemClock
+
+// sleep waits for d on clock, or until cancel is closed and reports whether d passed.
+func sleep(clock Clock, d time.Duration, cancel <-chan struct{}) bool {
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+func (ld2451 *LD2451) now() time.Time {
+	return ld2451.config.Clock.Now()
+}
+
+func (ld2451 *LD2451) since(t time.Time) time.Duration {
+	return ld2451.config.Clock.Now().Sub(t)
+}
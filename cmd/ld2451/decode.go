@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/capture"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// decode prints an annotated breakdown of every frame found in its input.
+// Arguments naming a file are read as a capture or as raw bytes, other
+// arguments as hex. Without arguments stdin is read the same way as a file,
+// with hex text accepted as well.
+func decode(config LD2451.Config, args []string) error {
+	flags := flag.NewFlagSet("decode", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ld2451 decode [hex | file ...]")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return decodeInput("stdin", data, true)
+	}
+	for _, arg := range flags.Args() {
+		data, err := os.ReadFile(arg)
+		if errors.Is(err, os.ErrNotExist) {
+			if err := decodeInput("argument", []byte(arg), true); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := decodeInput(arg, data, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeInput(name string, data []byte, allowHex bool) error {
+	if r, err := capture.NewReader(bytes.NewReader(data)); err == nil {
+		return decodeCapture(name, r)
+	}
+	if allowHex {
+		if raw, err := hex.DecodeString(strings.Join(strings.Fields(string(data)), "")); err == nil {
+			data = raw
+		}
+	}
+	reader := protocol.NewReader(bytes.NewReader(data))
+	for n := 1; ; n++ {
+		packet, err := reader.Next()
+		if packet.Skipped > 0 {
+			fmt.Printf("skipped %d bytes not belonging to a frame\n", packet.Skipped)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Printf("frame %d\n", n)
+		describe(packet.Kind, packet.Payload, false)
+	}
+}
+
+func decodeCapture(name string, r *capture.Reader) error {
+	metadata := r.Metadata()
+	fmt.Printf("capture of %s", metadata.Sensor)
+	if metadata.Port != "" {
+		fmt.Printf(" on %s", metadata.Port)
+	}
+	if metadata.Firmware != "" {
+		fmt.Printf(", firmware %s", metadata.Firmware)
+	}
+	fmt.Printf(", started %s\n", metadata.Start.Format(time.RFC3339))
+	for n := 1; ; n++ {
+		record, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		direction := "received"
+		if record.Sent {
+			direction = "sent"
+		}
+		fmt.Printf("frame %d, %s at +%s\n", n, direction, record.Time.Sub(metadata.Start))
+		describe(record.Kind, record.Payload, record.Sent)
+	}
+}
+
+// describe prints the fields of a frame with the bytes they were decoded from.
+func describe(kind protocol.Kind, payload []byte, sent bool) {
+	framing := protocol.LD2451Framing
+	if kind == protocol.KindCommand {
+		framing = protocol.CommandFraming
+	}
+	field("header", framing.Header, kindName(kind))
+	field("length", binary.LittleEndian.AppendUint16(nil, uint16(len(payload))), fmt.Sprintf("%d bytes", len(payload)))
+	if kind == protocol.KindCommand {
+		describeCommand(payload, sent)
+	} else {
+		describeData(payload)
+	}
+	field("footer", framing.Footer, "")
+}
+
+func kindName(kind protocol.Kind) string {
+	if kind == protocol.KindCommand {
+		return "command"
+	}
+	return "data"
+}
+
+func describeData(payload []byte) {
+	if len(payload) == 0 {
+		fmt.Println("  (no targets)")
+		return
+	}
+	frame, err := protocol.ParseFrame(payload, nil)
+	if err != nil {
+		field("payload", payload, err.Error())
+		return
+	}
+	field("count", payload[:1], fmt.Sprintf("%d targets", len(frame.Targets)))
+	alarm := "off"
+	if frame.Alarm {
+		alarm = "on"
+	}
+	field("alarm", payload[1:2], alarm)
+	for i, target := range frame.Targets {
+		record := payload[2+i*6 : 2+(i+1)*6]
+		field(fmt.Sprintf("target %d", i+1), record, target.String())
+	}
+}
+
+func describeCommand(payload []byte, sent bool) {
+	if len(payload) < 2 {
+		field("payload", payload, "too short for a command word")
+		return
+	}
+	word := binary.LittleEndian.Uint16(payload)
+	if sent || word&protocol.AckFlag == 0 {
+		field("command", payload[:2], protocol.CommandName(word))
+		if len(payload) > 2 {
+			field("value", payload[2:], "")
+		}
+		return
+	}
+	ack, err := protocol.ParseAck(payload)
+	if err != nil {
+		field("payload", payload, err.Error())
+		return
+	}
+	field("ack", payload[:2], protocol.CommandName(ack.Word))
+	status := "success"
+	if ack.Status != 0 {
+		status = "failed"
+	}
+	field("status", payload[2:4], status)
+	if len(ack.Data) > 0 {
+		field("data", ack.Data, "")
+	}
+}
+
+func field(name string, data []byte, meaning string) {
+	line := fmt.Sprintf("  %-9s %-24s %s", name, hexBytes(data), meaning)
+	fmt.Println(strings.TrimRight(line, " "))
+}
+
+func hexBytes(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// doctor walks through the usual reasons for getting no data: a missing or
+// inaccessible port, a wrong baud rate, broken wiring and a module that was
+// switched to query mode. Every check prints a finding, failed ones with a
+// hint on what to do about it.
+func doctor(config LD2451.Config, args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	listen := flags.Duration("listen", 1500*time.Millisecond, "how long to listen for frames at each baud rate")
+	flags.Parse(args)
+
+	d := &diagnosis{}
+	if !d.checkPort(config.SerialPort) {
+		return d.result()
+	}
+
+	//the configured rate first, the others only when it shows no frames
+	rates := append([]int{config.BaudRate}, slices.DeleteFunc(LD2451.BaudRates(), func(rate int) bool {
+		return rate == config.BaudRate
+	})...)
+	found, garbled := 0, 0
+	for _, rate := range rates {
+		probe, err := listenAt(config.SerialPort, rate, *listen)
+		if err != nil && rate == config.BaudRate {
+			d.fail(fmt.Sprintf("reading at %d baud: %v", rate, err), "")
+			return d.result()
+		}
+		if err != nil {
+			//not every platform's serial driver supports the fastest rates
+			continue
+		}
+		if probe.frames > 0 {
+			found = rate
+			d.reportProbe(rate, probe)
+			break
+		}
+		if probe.bytes > 0 && garbled == 0 {
+			garbled = rate
+		}
+	}
+
+	if found == 0 {
+		//a module in query mode stays silent but still answers commands
+		for _, rate := range rates {
+			if version, err := firmwareAt(config, rate); err == nil {
+				d.fail(fmt.Sprintf("firmware %s answers commands at %d baud but sends no frames", version, rate),
+					"the module may be in query mode, switch it back with SetReportMode")
+				return d.result()
+			}
+		}
+	}
+	switch {
+	case found == 0 && garbled != 0:
+		d.fail(fmt.Sprintf("bytes arrive but never form a frame at any baud rate, e.g. at %d baud", garbled),
+			"check that the adapter uses 3.3 V logic levels and that ground is connected between the module and the adapter")
+		return d.result()
+	case found == 0:
+		d.fail(fmt.Sprintf("no data at any baud rate within %s each", *listen),
+			"check the 5 V supply, and that the module's TX goes to the adapter's RX; swapping TX and RX is the most common mistake")
+		return d.result()
+	case found != config.BaudRate:
+		d.fail(fmt.Sprintf("the module reports at %d baud, not the configured %d", found, config.BaudRate),
+			fmt.Sprintf("run with -baud %d, or set the module back with SetBaudRate", found))
+	}
+
+	version, err := firmwareAt(config, found)
+	switch {
+	case errors.Is(err, LD2451.ErrCommandTimeout):
+		d.fail("the module does not answer commands",
+			"frames arrive, so the module's RX is probably not connected to the adapter's TX")
+		return d.result()
+	case err != nil:
+		d.fail(fmt.Sprintf("querying the firmware version: %v", err), "")
+		return d.result()
+	}
+	d.ok(fmt.Sprintf("firmware %s", version))
+	return d.result()
+}
+
+type diagnosis struct {
+	failed bool
+}
+
+func (d *diagnosis) ok(finding string) {
+	fmt.Printf("ok    %s\n", finding)
+}
+
+func (d *diagnosis) warn(finding, hint string) {
+	fmt.Printf("warn  %s\n", finding)
+	if hint != "" {
+		fmt.Printf("      hint: %s\n", hint)
+	}
+}
+
+func (d *diagnosis) fail(finding, hint string) {
+	d.failed = true
+	fmt.Printf("FAIL  %s\n", finding)
+	if hint != "" {
+		fmt.Printf("      hint: %s\n", hint)
+	}
+}
+
+func (d *diagnosis) result() error {
+	if d.failed {
+		return errors.New("doctor found problems")
+	}
+	return nil
+}
+
+// checkPort reports whether the port exists and can be opened.
+func (d *diagnosis) checkPort(path string) bool {
+	if runtime.GOOS != "windows" {
+		if _, err := os.Stat(path); err != nil {
+			d.fail(fmt.Sprintf("%s does not exist", path), knownPorts())
+			return false
+		}
+	}
+	port, err := transport.OpenSerial(transport.SerialConfig{Name: path, Baud: LD2451.Baud115200})
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		d.fail(fmt.Sprintf("no permission to open %s", path), permissionHint())
+		return false
+	case errors.Is(err, syscall.EBUSY):
+		d.fail(fmt.Sprintf("%s is busy", path), "close other programs using the port, e.g. a serial terminal or ModemManager")
+		return false
+	case err != nil:
+		d.fail(fmt.Sprintf("opening %s: %v", path, err), "")
+		return false
+	}
+	port.Close()
+	d.ok(fmt.Sprintf("%s can be opened", path))
+	if users := portUsers(path); len(users) > 0 {
+		d.warn(fmt.Sprintf("%s is also open in %s", path, strings.Join(users, ", ")),
+			"two programs reading the same port each get part of the bytes, stop the others")
+	}
+	return true
+}
+
+func knownPorts() string {
+	found, err := transport.ListPorts()
+	if err != nil || len(found) == 0 {
+		return "no serial ports found, check the USB cable and that the adapter's driver is loaded"
+	}
+	paths := make([]string, 0, len(found))
+	for _, port := range found {
+		paths = append(paths, port.Path)
+	}
+	return "ports present: " + strings.Join(paths, ", ") + ", pass one with -port"
+}
+
+func permissionHint() string {
+	switch runtime.GOOS {
+	case "linux":
+		return "add your user to the group owning the port, usually dialout or uucp on Arch, e.g. 'sudo usermod -aG dialout $USER', then log in again"
+	case "darwin":
+		return "use the /dev/cu.* device rather than /dev/tty.*"
+	default:
+		return "run as a user allowed to access serial ports"
+	}
+}
+
+// portUsers lists the other processes holding path open, found through
+// /proc, so it only finds them on Linux.
+func portUsers(path string) []string {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil
+	}
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	self := fmt.Sprint(os.Getpid())
+	var users []string
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || link != target {
+			continue
+		}
+		pid := strings.Split(fd, "/")[2]
+		if pid == self {
+			continue
+		}
+		name, _ := os.ReadFile("/proc/" + pid + "/comm")
+		user := fmt.Sprintf("%s (pid %s)", strings.TrimSpace(string(name)), pid)
+		if !slices.Contains(users, user) {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+type probe struct {
+	bytes   int
+	frames  int
+	targets int
+	skipped int
+	errors  int
+}
+
+// listenAt reads from path at rate for d and counts what arrives.
+func listenAt(path string, rate int, d time.Duration) (probe, error) {
+	port, err := transport.OpenSerial(transport.SerialConfig{Name: path, Baud: rate, ReadTimeout: 100 * time.Millisecond})
+	if err != nil {
+		return probe{}, err
+	}
+	defer port.Close()
+
+	var raw []byte
+	buf := make([]byte, 4096)
+	for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+		n, err := port.Read(buf)
+		raw = append(raw, buf[:n]...)
+		//timeouts surface as empty reads or EOF depending on the platform
+		if err != nil && err != io.EOF && !errors.Is(err, transport.ErrReadTimeout) {
+			return probe{}, err
+		}
+	}
+
+	result := probe{bytes: len(raw)}
+	reader := protocol.NewReader(bytes.NewReader(raw))
+	for {
+		packet, err := reader.Next()
+		if err != nil {
+			return result, nil
+		}
+		result.skipped += packet.Skipped
+		if packet.Kind != protocol.KindData {
+			continue
+		}
+		result.frames++
+		frame, err := protocol.ParseFrame(packet.Payload, nil)
+		if err != nil {
+			result.errors++
+			continue
+		}
+		result.targets += len(frame.Targets)
+	}
+}
+
+func (d *diagnosis) reportProbe(rate int, p probe) {
+	d.ok(fmt.Sprintf("%d frames at %d baud, %d targets", p.frames, rate, p.targets))
+	if p.skipped > 0 {
+		d.warn(fmt.Sprintf("%d of %d bytes were outside of frames", p.skipped, p.bytes),
+			"a few bytes at the start are normal, many point to a loose connection or electrical noise on long wires")
+	}
+	if p.errors > 0 {
+		d.warn(fmt.Sprintf("%d frames could not be decoded", p.errors), "run 'ld2451 decode' on a capture to see what the module sends")
+	}
+}
+
+// firmwareAt queries the firmware version with the port opened at rate.
+func firmwareAt(config LD2451.Config, rate int) (LD2451.FirmwareVersion, error) {
+	config.BaudRate = rate
+	config.Reconnect = false
+	config.OpenRetryTimeout = 0
+	sensor, err := LD2451.Open(config)
+	if err != nil {
+		return LD2451.FirmwareVersion{}, err
+	}
+	defer sensor.Close()
+	return sensor.FirmwareVersion()
+}
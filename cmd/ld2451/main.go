@@ -0,0 +1,71 @@
+// Command ld2451 talks to an LD2451 radar from the command line.
+//
+//	ld2451 [-config ld2451.yaml] [-port /dev/ttyUSB0] [-baud 115200] [flags] <command>
+//
+// Commands:
+//
+//	decode   annotated breakdown of frames given as hex, capture files or stdin
+//	doctor   checks the port, baud rate, wiring and firmware when no data arrives
+//	monitor  live view of targets, rolling stats and connection status
+//	ports    list the serial ports present, with USB details
+//	replay   decode a recording through the filters, smoothing and persistence
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+var commands = map[string]func(config LD2451.Config, args []string) error{
+	"decode":  decode,
+	"doctor":  doctor,
+	"monitor": monitor,
+	"ports":   ports,
+	"replay":  replayFile,
+}
+
+func main() {
+	config := LD2451.Config{SerialPort: "/dev/ttyUSB0", BaudRate: LD2451.Baud115200, TargetBufferSize: 64}
+	configPath := flag.String("config", "", "YAML, TOML or JSON file to read the configuration from, other flags override it when given")
+	config.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ld2451 [flags] <command>")
+		fmt.Fprintln(os.Stderr, "\ncommands:\n  decode\tannotated breakdown of frames given as hex, capture files or stdin\n  doctor\tchecks the port, baud rate, wiring and firmware when no data arrives\n  monitor\tlive view of targets, rolling stats and connection status\n  ports\tlist the serial ports present, with USB details\n  replay\tdecode a recording through the filters, smoothing and persistence")
+		fmt.Fprintln(os.Stderr, "\nflags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *configPath != "" {
+		loaded, err := LD2451.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ld2451:", err)
+			os.Exit(1)
+		}
+		if loaded.SerialPort == "" {
+			loaded.SerialPort = config.SerialPort
+		}
+		//replay the flags given on the command line onto the loaded config
+		overrides := flag.NewFlagSet("", flag.ContinueOnError)
+		loaded.RegisterFlags(overrides)
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name != "config" {
+				overrides.Set(f.Name, f.Value.String())
+			}
+		})
+		config = loaded
+	}
+
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := command(config, flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "ld2451:", err)
+		os.Exit(1)
+	}
+}
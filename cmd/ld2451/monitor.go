@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+)
+
+// monitor redraws a live view of the sensor until interrupted. It only uses
+// ANSI escape sequences, so it works over plain SSH sessions without any
+// terminal setup.
+func monitor(config LD2451.Config, args []string) error {
+	flags := flag.NewFlagSet("monitor", flag.ExitOnError)
+	refresh := flags.Duration("refresh", 250*time.Millisecond, "how often the screen is redrawn")
+	window := flags.Duration("window", time.Minute, "time span of the rolling stats")
+	linger := flags.Duration("linger", time.Second, "how long a target stays in the table after it was reported")
+	decodeLog := flags.String("decode-log", "", "file receiving a line of text for every frame exchanged with the sensor")
+	flags.Parse(args)
+
+	if *decodeLog != "" {
+		file, err := os.Create(*decodeLog)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		config.DecodeLog = file
+	}
+
+	sensor, err := LD2451.Open(config)
+	if err != nil {
+		return err
+	}
+	defer sensor.Close()
+
+	type reading struct {
+		target LD2451.Target
+		err    error
+	}
+	readings := make(chan reading, config.TargetBufferSize)
+	go func() {
+		for {
+			target, err := sensor.ReadTarget()
+			readings <- reading{target, err}
+			if state := sensor.State(); err != nil && (state == LD2451.StateDisconnected || state == LD2451.StateClosed) {
+				return
+			}
+		}
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	fmt.Print(hideCursor)
+	defer fmt.Print(showCursor)
+
+	var (
+		recent  []LD2451.Target
+		lastErr error
+		ticker  = time.NewTicker(*refresh)
+	)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case r := <-readings:
+			if r.err != nil {
+				lastErr = r.err
+				continue
+			}
+			recent = append(recent, r.target)
+		case err := <-sensor.Diagnostics():
+			lastErr = err
+		case now := <-ticker.C:
+			i := 0
+			for i < len(recent) && now.Sub(recent[i].Time) > *window {
+				i++
+			}
+			recent = recent[i:]
+			os.Stdout.Write(render(sensor, config.SerialPort, recent, lastErr, now, *window, *linger))
+		}
+	}
+}
+
+// render draws one screen from the targets seen within window.
+func render(sensor *LD2451.LD2451, port string, recent []LD2451.Target, lastErr error, now time.Time, window, linger time.Duration) []byte {
+	var b bytes.Buffer
+	b.WriteString(clearScreen)
+
+	health := sensor.Health()
+	fmt.Fprintf(&b, "LD2451 %s  state %s", port, health.State)
+	if health.LastFrame.IsZero() {
+		b.WriteString("  no frame yet\n")
+	} else {
+		fmt.Fprintf(&b, "  last frame %s ago\n", health.SinceLastFrame.Round(time.Millisecond))
+	}
+	stats := sensor.Stats()
+	fmt.Fprintf(&b, "frames %d  targets %d  resyncs %d  parse errors %d  read errors %d  dropped %d\n",
+		stats.Frames, stats.Targets, stats.Resyncs, stats.ParseErrors, stats.ReadErrors, stats.DroppedTargets)
+	if lastErr != nil {
+		fmt.Fprintf(&b, "last error: %v\n", lastErr)
+	}
+
+	var current []LD2451.Target
+	total, fastest, away, toward := 0, 0, 0, 0
+	for _, target := range recent {
+		if now.Sub(target.Time) <= linger {
+			current = append(current, target)
+		}
+		total += target.Speed
+		fastest = max(fastest, target.Speed)
+		if target.Direction == LD2451.DirectionToward {
+			toward++
+		} else {
+			away++
+		}
+	}
+	fmt.Fprintf(&b, "\nlast %s: %d detections (%d away, %d toward)", window, len(recent), away, toward)
+	if len(recent) > 0 {
+		fmt.Fprintf(&b, ", average %d km/h, fastest %d km/h", total/len(recent), fastest)
+	}
+	b.WriteString("\n\n")
+	LD2451.WriteTable(&b, current)
+	return b.Bytes()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// ports lists the serial ports present, preferring the stable path where
+// there is one since it keeps working when the adapter re-enumerates.
+func ports(config LD2451.Config, args []string) error {
+	found, err := transport.ListPorts()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PORT\tVID:PID\tDESCRIPTION\tSERIAL")
+	for _, port := range found {
+		path := port.Path
+		if port.StablePath != "" {
+			path = port.StablePath
+		}
+		id := ""
+		if port.USB {
+			id = fmt.Sprintf("%04x:%04x", port.VID, port.PID)
+		}
+		description := port.Description
+		if port.Manufacturer != "" {
+			description = port.Manufacturer + " " + description
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", path, id, description, port.SerialNumber)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/capture"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/replay"
+)
+
+// replayFile decodes a recording as fast as possible and prints the targets
+// passing the configured filters, smoothing and persistence, so recorded
+// traffic can be analyzed again with other thresholds. Flags may follow the
+// file name:
+//
+//	ld2451 replay capture.bin -min-speed 20 -direction toward -format csv
+//
+// Captures and JSON frames keep their recorded times, raw byte dumps are
+// timed as they are decoded.
+func replayFile(config LD2451.Config, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or csv")
+	config.RegisterFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ld2451 replay <capture | frames.jsonl | dump> [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	var files []string
+	for flags.NArg() > 0 {
+		files = append(files, flags.Arg(0))
+		flags.Parse(flags.Args()[1:])
+	}
+	if len(files) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	var write func(target LD2451.Target) error
+	flush := out.Flush
+	switch *format {
+	case "text":
+		write = func(target LD2451.Target) error {
+			_, err := fmt.Fprintf(out, "%s  %s\n", target.Time.Format(time.RFC3339Nano), target)
+			return err
+		}
+	case "json":
+		encoder := json.NewEncoder(out)
+		write = func(target LD2451.Target) error { return encoder.Encode(target) }
+	case "csv":
+		w := csv.NewWriter(out)
+		flush = func() error {
+			w.Flush()
+			if err := w.Error(); err != nil {
+				return err
+			}
+			return out.Flush()
+		}
+		w.Write([]string{"time", "sensor", "angle", "distance", "direction", "speed", "snr", "radial_speed"})
+		write = func(target LD2451.Target) error {
+			return w.Write([]string{
+				target.Time.Format(time.RFC3339Nano),
+				target.Sensor,
+				strconv.Itoa(target.Angle),
+				strconv.Itoa(target.Distance),
+				target.Direction.String(),
+				strconv.Itoa(target.Speed),
+				strconv.Itoa(target.SNR),
+				strconv.FormatFloat(target.RadialSpeed, 'f', -1, 64),
+			})
+		}
+	default:
+		return fmt.Errorf("unknown format %q, use text, json or csv", *format)
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	clock := &recordedClock{}
+	var source LD2451.Source
+	if r, err := capture.NewReader(bytes.NewReader(data)); err == nil {
+		source = replay.New(clock.frames(r.Frames()), replay.Config{Speed: replay.AsFastAsPossible})
+	} else if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		source = replay.New(clock.frames(replay.JSONFrames(bytes.NewReader(data))), replay.Config{Speed: replay.AsFastAsPossible})
+	} else {
+		source = protocol.NewReader(bytes.NewReader(data))
+	}
+	config.Clock = clock
+
+	sensor, err := LD2451.NewSource(source, config)
+	if err != nil {
+		return err
+	}
+	defer sensor.Close()
+	for {
+		target, err := sensor.ReadTarget()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", files[0], err)
+		}
+		if err := write(target); err != nil {
+			return err
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	stats := sensor.Stats()
+	fmt.Fprintf(os.Stderr, "%d frames, %d targets delivered, %d filtered, %d parse errors\n",
+		stats.Frames, stats.Targets, stats.FilteredTargets, stats.ParseErrors)
+	return nil
+}
+
+// recordedClock tells the time a replayed frame was recorded at while it is
+// decoded, so targets keep their recorded times and windows such as
+// -max-rate or -report-interval see the recorded pace. Frames without a time
+// and raw dumps fall back to the system clock.
+type recordedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// frames wraps next to set the clock to the time of every frame it returns.
+func (c *recordedClock) frames(next func() (LD2451.Frame, error)) func() (LD2451.Frame, error) {
+	return func() (LD2451.Frame, error) {
+		frame, err := next()
+		c.mu.Lock()
+		c.now = frame.Time
+		c.mu.Unlock()
+		return frame, err
+	}
+}
+
+func (c *recordedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.now.IsZero() {
+		return time.Now()
+	}
+	return c.now
+}
+
+func (c *recordedClock) NewTimer(d time.Duration) LD2451.Timer {
+	return LD2451.SystemClock.NewTimer(d)
+}
+
+func (c *recordedClock) NewTicker(d time.Duration) LD2451.Ticker {
+	return LD2451.SystemClock.NewTicker(d)
+}
@@ -0,0 +1,81 @@
+package LD2451
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+)
+
+// Codec serializes targets for an EncodedSink, e.g. JSONCodec, cbor.Codec,
+// msgpack.Codec or protobuf.Codec.
+type Codec interface {
+	AppendTarget(buf []byte, target Target) ([]byte, error) // Appends the encoded target to buf
+}
+
+// CodecFunc adapts a function to a Codec.
+type CodecFunc func(buf []byte, target Target) ([]byte, error)
+
+func (f CodecFunc) AppendTarget(buf []byte, target Target) ([]byte, error) { return f(buf, target) }
+
+// JSONCodec encodes every target as a line of JSON.
+var JSONCodec Codec = CodecFunc(func(buf []byte, target Target) ([]byte, error) {
+	encoded, err := json.Marshal(target)
+	if err != nil {
+		return buf, err
+	}
+	return append(append(buf, encoded...), '\n'), nil
+})
+
+// LengthPrefixed precedes every message of codec with its length as a 4 byte
+// big endian integer, the framing of the ipc package, for encodings whose
+// messages can't simply follow each other, such as protobuf.
+func LengthPrefixed(codec Codec) Codec {
+	return CodecFunc(func(buf []byte, target Target) ([]byte, error) {
+		start := len(buf)
+		buf, err := codec.AppendTarget(append(buf, 0, 0, 0, 0), target)
+		if err != nil {
+			return buf[:start], err
+		}
+		binary.BigEndian.PutUint32(buf[start:], uint32(len(buf)-start-4))
+		return buf, nil
+	})
+}
+
+// EncodedSink writes the targets serialized by a Codec to an io.Writer, one
+// message after the other, so a compact encoding needs no sink of its own.
+type EncodedSink struct {
+	w       io.Writer
+	buf     *bufio.Writer
+	codec   Codec
+	scratch []byte
+}
+
+func NewEncodedSink(w io.Writer, codec Codec) *EncodedSink {
+	return &EncodedSink{w: w, buf: bufio.NewWriter(w), codec: codec}
+}
+
+func (s *EncodedSink) Write(target Target) error {
+	encoded, err := s.codec.AppendTarget(s.scratch[:0], target)
+	if err != nil {
+		return err
+	}
+	s.scratch = encoded
+	_, err = s.buf.Write(encoded)
+	return err
+}
+
+func (s *EncodedSink) Flush() error {
+	return s.buf.Flush()
+}
+
+// Close flushes the sink and closes the writer when it is an io.Closer.
+func (s *EncodedSink) Close() error {
+	err := s.buf.Flush()
+	if closer, ok := s.w.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package LD2451
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/Battlekeeper/LD2451/v2/hlk"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// ErrCommandTimeout is returned when the module doesn't acknowledge a command
+// in time.
+var ErrCommandTimeout = hlk.ErrTimeout
+
+// CommandError is returned when the module acknowledges a command with a
+// failure status.
+type CommandError = hlk.CommandError
+
+// commandRequest is a command waiting on the command queue.
+type commandRequest struct {
+	fn     func() error
+	result chan error
+}
+
+// configure runs fn inside a configuration session and returns its result,
+// wrapped with op.
+func (ld2451 *LD2451) configure(op string, fn func() error) error {
+	return ld2451.enqueue(op, func() error {
+		return ld2451.session(fn)
+	})
+}
+
+// enqueue runs fn on the command queue and returns its result wrapped with op. Commands
+// issued from different goroutines execute in the order they were issued and
+// never interleave on the port.
+func (ld2451 *LD2451) enqueue(op string, fn func() error) error {
+	return ld2451.wrap(op, <-ld2451.submit(fn))
+}
+
+// submit queues fn without waiting for it. The returned channel receives the
+// result of fn, or the error that stopped the reader if fn never ran.
+func (ld2451 *LD2451) submit(fn func() error) <-chan error {
+	request := commandRequest{fn: fn, result: make(chan error, 1)}
+	ld2451.queueMu.Lock()
+	if ld2451.queueStopped {
+		ld2451.queueMu.Unlock()
+		request.result <- ld2451.fatal
+		return request.result
+	}
+	ld2451.queue = append(ld2451.queue, request)
+	ld2451.queueMu.Unlock()
+
+	select {
+	case ld2451.queued <- struct{}{}:
+	default:
+	}
+	return request.result
+}
+
+// runCommands executes queued commands one at a time until the reader stops,
+// then fails everything still queued.
+func (ld2451 *LD2451) runCommands() {
+	for {
+		select {
+		case <-ld2451.queued:
+			for {
+				request, ok := ld2451.nextCommand()
+				if !ok {
+					break
+				}
+				request.result <- request.fn()
+			}
+		case <-ld2451.done:
+			ld2451.queueMu.Lock()
+			ld2451.queueStopped = true
+			for _, request := range ld2451.queue {
+				request.result <- ld2451.fatal
+			}
+			ld2451.queue = nil
+			ld2451.queueMu.Unlock()
+			return
+		}
+	}
+}
+
+func (ld2451 *LD2451) nextCommand() (commandRequest, bool) {
+	ld2451.queueMu.Lock()
+	defer ld2451.queueMu.Unlock()
+	if len(ld2451.queue) == 0 {
+		return commandRequest{}, false
+	}
+	request := ld2451.queue[0]
+	ld2451.queue = ld2451.queue[1:]
+	return request, true
+}
+
+// session runs fn in config mode. While the module sleeps it already is in
+// config mode and must stay there afterwards.
+func (ld2451 *LD2451) session(fn func() error) error {
+	defer ld2451.beginConfiguring()()
+
+	if ld2451.asleep {
+		return fn()
+	}
+	return hlk.Session(ld2451.command, func(info []byte) error {
+		ld2451.configInfo = info
+		return fn()
+	})
+}
+
+// command sends a single command frame and returns the data of its
+// acknowledgement following the status word. It must only be called from
+// sessions running on the command queue.
+func (ld2451 *LD2451) command(word uint16, value []byte) ([]byte, error) {
+	ld2451.logCommand(word, value)
+	data, err := ld2451.commander.Exchange(word, value)
+	if err != nil {
+		ld2451.config.Logger.Warn("command failed", "command", protocol.CommandName(word), "error", err)
+	}
+	return data, err
+}
+
+// deliverAck hands an acknowledgement read by the read goroutine to a waiting
+// command. Anything that isn't an acknowledgement of a known command is
+// handled according to Config.UnknownFrames.
+func (ld2451 *LD2451) deliverAck(payload []byte) {
+	ack, err := protocol.ParseAck(payload)
+	if err != nil || !protocol.KnownCommand(ack.Word) {
+		ld2451.unknownFrame(payload)
+		return
+	}
+	ld2451.commander.Deliver(ack)
+}
+
+func (ld2451 *LD2451) unknownFrame(payload []byte) {
+	ld2451.recordUnknownFrame()
+	err := &UnknownFrameError{Payload: bytes.Clone(payload)}
+	if len(payload) >= 2 {
+		err.Word = binary.LittleEndian.Uint16(payload)
+	}
+	switch ld2451.config.UnknownFrames {
+	case UnknownSkip:
+		ld2451.config.Logger.Debug("ignoring unknown command frame", "error", err)
+		return
+	case UnknownError:
+		ld2451.reportError(err)
+	}
+	ld2451.config.Logger.Warn("unknown command frame", "error", err)
+	ld2451.publish(ParseErrorEvent{Err: err, Time: ld2451.now(), Sensor: ld2451.config.SensorID})
+	ld2451.reportDiagnostic(err)
+}
+
+// write sends data to the module. All writes to the port go through here so
+// they can never interleave, whichever goroutine issues them.
+func (ld2451 *LD2451) write(data []byte) error {
+	ld2451.writeMu.Lock()
+	defer ld2451.writeMu.Unlock()
+	_, err := ld2451.port.Write(data)
+	return err
+}
@@ -0,0 +1,122 @@
+package LD2451_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+var firmwareReply = []byte{0x51, 0x24, 0x01, 0x01, 0x16, 0x04, 0x23, 0x22}
+
+// streamTargets keeps sending frames until the test ends.
+func streamTargets(t *testing.T, send func() error) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		<-stopped
+	})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			send()
+			time.Sleep(2 * time.Millisecond)
+		}
+	}()
+}
+
+// TestCommandsWhileReading runs commands from several goroutines while
+// frames stream in and other goroutines read targets and stats, for go test
+// -race to check the command queue against the read goroutine.
+func TestCommandsWhileReading(t *testing.T) {
+	sensor, radar := openSensor(t, nil)
+	sensor.Handle(protocol.CmdReadFirmware, func(protocol.Command) (uint16, []byte, bool) {
+		return 0, firmwareReply, true
+	})
+	targets, stop := radar.Subscribe()
+	defer stop()
+	streamTargets(t, func() error {
+		return sensor.SendTargets(false, LD2451.Target{Distance: 30, Speed: 50, SNR: 20})
+	})
+
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for range targets {
+			radar.Stats()
+			radar.Health()
+			radar.State()
+		}
+	}()
+
+	var commands sync.WaitGroup
+	for range 4 {
+		commands.Add(1)
+		go func() {
+			defer commands.Done()
+			for range 5 {
+				version, err := radar.FirmwareVersion()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if version.Major != 0x0101 {
+					t.Errorf("firmware major 0x%04x, want 0x0101", version.Major)
+				}
+				if err := radar.Sleep(); err != nil {
+					t.Error(err)
+				}
+				if err := radar.Wake(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	commands.Wait()
+
+	stop()
+	readers.Wait()
+	if stats := radar.Stats(); stats.Frames == 0 {
+		t.Fatal("no frames read while the commands ran")
+	}
+}
+
+// TestAsyncCommandsDontInterleave checks that configuration sessions queued
+// without waiting reach the module one after the other.
+func TestAsyncCommandsDontInterleave(t *testing.T) {
+	sensor, radar := openSensor(t, nil)
+	sensor.Handle(protocol.CmdReadFirmware, func(protocol.Command) (uint16, []byte, bool) {
+		return 0, firmwareReply, true
+	})
+	streamTargets(t, func() error { return sensor.SendTargets(false) })
+
+	var results []<-chan LD2451.Result[LD2451.FirmwareVersion]
+	for range 10 {
+		results = append(results, radar.FirmwareVersionAsync())
+	}
+	for _, result := range results {
+		if r := <-result; r.Err != nil {
+			t.Fatal(r.Err)
+		}
+	}
+
+	session := []uint16{protocol.CmdEnableConfig, protocol.CmdReadFirmware, protocol.CmdEndConfig}
+	commands := sensor.Commands()
+	if len(commands) != 10*len(session) {
+		t.Fatalf("%d commands reached the sensor, want %d", len(commands), 10*len(session))
+	}
+	for i, command := range commands {
+		if want := session[i%len(session)]; command.Word != want {
+			t.Fatalf("command %d is %s, want %s", i, protocol.CommandName(command.Word), protocol.CommandName(want))
+		}
+	}
+}
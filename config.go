@@ -0,0 +1,175 @@
+package LD2451
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+type OverflowPolicy int
+
+const (
+	OverflowTruncate OverflowPolicy = 0 // Deliver the first MaxTargets targets, log a warning and count Stats.TruncatedFrames
+	OverflowError    OverflowPolicy = 1 // Drop the frame and report a ParseError like for any other malformed frame
+)
+
+type ParseMode int
+
+const (
+	ParseDefault ParseMode = 0 // Drop frames that don't decode and report a ParseError on Diagnostics for each
+	ParseStrict  ParseMode = 1 // Also return every ParseError and ResyncError from ReadTarget, and drop frames exceeding MaxTargets
+	ParseLenient ParseMode = 2 // Deliver the complete target records of frames whose length doesn't match their target count, counted in Stats.SalvagedFrames
+)
+
+type UnknownFramePolicy int
+
+const (
+	UnknownSkip   UnknownFramePolicy = 0 // Ignore command frames that are no acknowledgement of a known command, only counting them in Stats.UnknownFrames
+	UnknownReport UnknownFramePolicy = 1 // Also report an *UnknownFrameError with the raw bytes on Diagnostics and as a ParseErrorEvent
+	UnknownError  UnknownFramePolicy = 2 // Also return the *UnknownFrameError from ReadTarget
+)
+
+const (
+	defaultBaudRate         = Baud115200
+	defaultTargetBufferSize = 64
+)
+
+// validatePort checks the settings only Open needs for opening the serial
+// port itself, after defaults were applied.
+func (config Config) validatePort() error {
+	if config.SerialPort == "" {
+		return errors.New("no serial port configured")
+	}
+	if _, ok := baudRateValue(config.BaudRate); !ok && !config.AnyBaudRate {
+		return fmt.Errorf("baud rate %d is not supported by the LD2451", config.BaudRate)
+	}
+	return nil
+}
+
+// withDefaults fills in unset settings and rejects invalid ones, so a
+// mistake fails Open instead of e.g. an unbuffered target channel stalling
+// the reader.
+func (config Config) withDefaults() (Config, error) {
+	if config.BaudRate == 0 {
+		config.BaudRate = defaultBaudRate
+	}
+	if config.TargetBufferSize == 0 {
+		config.TargetBufferSize = defaultTargetBufferSize
+	}
+	if config.MaxTargets == 0 {
+		config.MaxTargets = protocol.MaxTargets
+	}
+	if config.Logger == nil {
+		config.Logger = nopLogger{}
+	}
+	if config.TargetLimits.MaxAngle == 0 {
+		config.TargetLimits.MaxAngle = defaultMaxAngle
+	}
+	if config.TargetLimits.MaxDistance == 0 {
+		config.TargetLimits.MaxDistance = defaultMaxDistance
+	}
+	if config.TargetLimits.MaxSpeed == 0 {
+		config.TargetLimits.MaxSpeed = defaultMaxSpeed
+	}
+	if config.SpeedHistogramBucket == 0 {
+		config.SpeedHistogramBucket = defaultHistogramBucket
+	}
+	if config.Clock == nil {
+		config.Clock = SystemClock
+	}
+
+	switch {
+	case config.TargetBufferSize < 0:
+		return config, fmt.Errorf("target buffer size %d is negative", config.TargetBufferSize)
+	case config.ErrorBufferSize < 0:
+		return config, fmt.Errorf("error buffer size %d is negative", config.ErrorBufferSize)
+	case config.SpeedSmoothing < SmoothingNone || config.SpeedSmoothing > SmoothingExponential:
+		return config, fmt.Errorf("unknown speed smoothing mode %d", config.SpeedSmoothing)
+	case config.SmoothingWindow < 0:
+		return config, fmt.Errorf("smoothing window %d is negative", config.SmoothingWindow)
+	case config.SmoothingFactor < 0 || config.SmoothingFactor > 1:
+		return config, fmt.Errorf("smoothing factor %g is outside (0, 1]", config.SmoothingFactor)
+	case config.ClearTickInterval < 0:
+		return config, fmt.Errorf("clear tick interval %s is negative", config.ClearTickInterval)
+	case config.MaxTargetAge < 0:
+		return config, fmt.Errorf("max target age %s is negative", config.MaxTargetAge)
+	case config.OpenRetryTimeout < 0:
+		return config, fmt.Errorf("open retry timeout %s is negative", config.OpenRetryTimeout)
+	case config.OpenRetryBackoff < 0:
+		return config, fmt.Errorf("open retry backoff %s is negative", config.OpenRetryBackoff)
+	case config.FrameGapFactor < 0:
+		return config, fmt.Errorf("frame gap factor %g is negative", config.FrameGapFactor)
+	case config.FramePeriod < 0:
+		return config, fmt.Errorf("frame period %s is negative", config.FramePeriod)
+	case config.RecoverConfigMode < 0:
+		return config, fmt.Errorf("recover config mode %s is negative", config.RecoverConfigMode)
+	case config.WaitForFirstFrame < 0:
+		return config, fmt.Errorf("wait for first frame %s is negative", config.WaitForFirstFrame)
+	case config.DegradedAfter < 0:
+		return config, fmt.Errorf("degraded after %s is negative", config.DegradedAfter)
+	case config.MaxTargets < 0 || config.MaxTargets > protocol.MaxTargets:
+		return config, fmt.Errorf("max targets %d is outside 1-%d", config.MaxTargets, protocol.MaxTargets)
+	case config.TargetOverflow != OverflowTruncate && config.TargetOverflow != OverflowError:
+		return config, fmt.Errorf("unknown target overflow policy %d", config.TargetOverflow)
+	case config.FrameVariant != nil && config.FrameVariant.RecordSize != 0 && config.FrameVariant.RecordSize < 6:
+		return config, fmt.Errorf("frame variant record size %d is shorter than the 6 known bytes", config.FrameVariant.RecordSize)
+	case config.FrameVariant != nil && (config.FrameVariant.DistanceResolution < 0 || config.FrameVariant.SpeedResolution < 0):
+		return config, errors.New("frame variant resolution is negative")
+	case config.UnknownFrames < UnknownSkip || config.UnknownFrames > UnknownError:
+		return config, fmt.Errorf("unknown frame policy %d is invalid", config.UnknownFrames)
+	case config.ParseFailureLimit < 0:
+		return config, fmt.Errorf("parse failure limit %d is negative", config.ParseFailureLimit)
+	case config.ParseFailureBackoff < 0:
+		return config, fmt.Errorf("parse failure backoff %s is negative", config.ParseFailureBackoff)
+	case config.ParseMode < ParseDefault || config.ParseMode > ParseLenient:
+		return config, fmt.Errorf("unknown parse mode %d", config.ParseMode)
+	case config.MinFrames < 0:
+		return config, fmt.Errorf("min frames %d is negative", config.MinFrames)
+	case config.PersistenceGate < 0:
+		return config, fmt.Errorf("persistence gate %d m is negative", config.PersistenceGate)
+	case config.SummaryInterval < 0:
+		return config, fmt.Errorf("summary interval %s is negative", config.SummaryInterval)
+	case config.SpeedHistogramBucket < 0:
+		return config, fmt.Errorf("speed histogram bucket %d is negative", config.SpeedHistogramBucket)
+	case config.SummaryOnly && config.SummaryInterval == 0:
+		return config, errors.New("summary only without a summary interval delivers nothing")
+	case config.SinkFlushInterval < 0:
+		return config, fmt.Errorf("sink flush interval %s is negative", config.SinkFlushInterval)
+	case config.TargetValidation < ValidateReject || config.TargetValidation > ValidateOff:
+		return config, fmt.Errorf("unknown target validation policy %d", config.TargetValidation)
+	case config.AngleSign != AnglePositiveRight && config.AngleSign != AnglePositiveLeft:
+		return config, fmt.Errorf("unknown angle sign %d", config.AngleSign)
+	case config.TargetLimits.MaxAngle < 0 || config.TargetLimits.MaxDistance < 0 || config.TargetLimits.MaxSpeed < 0:
+		return config, fmt.Errorf("target limits %+v are negative", config.TargetLimits)
+	case config.PeakWindow < 0:
+		return config, fmt.Errorf("peak window %s is negative", config.PeakWindow)
+	case config.SnapshotWindow < 0:
+		return config, fmt.Errorf("snapshot window %s is negative", config.SnapshotWindow)
+	case config.SnapshotLimit < 0:
+		return config, fmt.Errorf("snapshot limit %d is negative", config.SnapshotLimit)
+	case config.PeakWindows < 0:
+		return config, fmt.Errorf("peak windows %d is negative", config.PeakWindows)
+	case config.ReportInterval < 0:
+		return config, fmt.Errorf("report interval %s is negative", config.ReportInterval)
+	case slices.Contains(config.Filters, nil):
+		return config, errors.New("nil filter configured")
+	}
+	if config.DetectionParameters != nil {
+		if err := config.DetectionParameters.validate(); err != nil {
+			return config, err
+		}
+	}
+	if config.AlarmParameters != nil {
+		if err := config.AlarmParameters.validate(); err != nil {
+			return config, err
+		}
+	}
+	if config.HardwareReset != nil {
+		if err := config.HardwareReset.validate(); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,401 @@
+package LD2451
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// LoadConfig reads a Config from a YAML (.yaml, .yml), TOML (.toml) or JSON
+// (.json) file, chosen by its extension. Keys are the snake case names of the
+// Config fields, e.g. serial_port or target_buffer_size, durations are
+// strings such as "500ms" and enums are given by name. Filters are described
+// under filters and the desired detection parameters under
+// detection_parameters, so a deployment can be set up entirely from the file:
+//
+//	serial_port: /dev/serial/by-id/usb-1a86_USB_Serial-if00-port0
+//	reconnect: true
+//	speed_smoothing: exponential
+//	filters:
+//	  direction: toward
+//	  min_speed: 10
+//	detection_parameters:
+//	  max_distance: 60
+//	  direction: both
+//	  no_target_delay: 2s
+//
+// frame_variant is the name of a protocol variant, v1 or extended, or a
+// layout of its own such as {record_size: 8, distance_resolution: 0.1}.
+// Unknown keys are rejected so typos don't go unnoticed. Logger and Clock
+// can't be described in a file and are left unset.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	config, err := parseConfig(data, strings.ToLower(filepath.Ext(path)))
+	if err != nil {
+		return Config{}, fmt.Errorf("load config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func parseConfig(data []byte, ext string) (Config, error) {
+	doc, err := parseDocument(data, ext)
+	if err != nil {
+		return Config{}, err
+	}
+	return decodeConfig(doc)
+}
+
+// parseDocument reads a config file into its generic form, the keys of which
+// are the keys of configFile.
+func parseDocument(data []byte, ext string) (map[string]any, error) {
+	var doc map[string]any
+	var err error
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(data, &doc)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("unknown config file format %q", ext)
+	}
+	return doc, err
+}
+
+// decodeConfig turns a document of any format into a Config. It goes through
+// JSON so a single set of keys describes every format.
+func decodeConfig(doc map[string]any) (Config, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return Config{}, err
+	}
+	var file configFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return Config{}, err
+	}
+	return file.config()
+}
+
+// configFile is the file representation of Config.
+type configFile struct {
+	SerialPort       string `json:"serial_port"`
+	BaudRate         int    `json:"baud_rate"`
+	AnyBaudRate      bool   `json:"any_baud_rate"`
+	TargetBufferSize int    `json:"target_buffer_size"`
+	ErrorBufferSize  int    `json:"error_buffer_size"`
+
+	SpeedSmoothing  SmoothingMode `json:"speed_smoothing"`
+	SmoothingWindow int           `json:"smoothing_window"`
+	SmoothingFactor float64       `json:"smoothing_factor"`
+
+	Heartbeats        bool     `json:"heartbeats"`
+	ClearTickInterval duration `json:"clear_tick_interval"`
+
+	MaxTargetAge     duration `json:"max_target_age"`
+	OpenRetryTimeout duration `json:"open_retry_timeout"`
+	OpenRetryBackoff duration `json:"open_retry_backoff"`
+	DegradedAfter    duration `json:"degraded_after"`
+
+	DetectionParameters *detectionFile `json:"detection_parameters"`
+	AlarmParameters     *alarmFile     `json:"alarm_parameters"`
+
+	DetectProtocol bool         `json:"detect_protocol"`
+	FrameVariant   *variantFile `json:"frame_variant"`
+
+	RecoverConfigMode duration `json:"recover_config_mode"`
+	WaitForFirstFrame duration `json:"wait_for_first_frame"`
+
+	FrameGapFactor float64  `json:"frame_gap_factor"`
+	FramePeriod    duration `json:"frame_period"`
+
+	Filters filtersFile `json:"filters"`
+
+	MinFrames       int `json:"min_frames"`
+	PersistenceGate int `json:"persistence_gate"`
+
+	ReportInterval duration `json:"report_interval"`
+
+	MaxTargets     int            `json:"max_targets"`
+	TargetOverflow OverflowPolicy `json:"target_overflow"`
+
+	ParseMode ParseMode `json:"parse_mode"`
+
+	UnknownFrames UnknownFramePolicy `json:"unknown_frames"`
+
+	ParseFailureLimit   int      `json:"parse_failure_limit"`
+	ParseFailureBackoff duration `json:"parse_failure_backoff"`
+
+	SensorID string `json:"sensor_id"`
+
+	TargetValidation ValidationPolicy `json:"target_validation"`
+	TargetLimits     struct {
+		MaxAngle    int `json:"max_angle"`
+		MaxDistance int `json:"max_distance"`
+		MaxSpeed    int `json:"max_speed"`
+	} `json:"target_limits"`
+
+	AngleSign   AngleSign `json:"angle_sign"`
+	InvertAngle bool      `json:"invert_angle"`
+
+	Mounting *struct {
+		Height        float64 `json:"height"`
+		LateralOffset float64 `json:"lateral_offset"`
+		Tilt          float64 `json:"tilt"`
+	} `json:"mounting"`
+
+	SnapshotWindow    duration `json:"snapshot_window"`
+	PeakWindow        duration `json:"peak_window"`
+	SinkFlushInterval duration `json:"sink_flush_interval"`
+	SummaryInterval   duration `json:"summary_interval"`
+	SummaryOnly       bool     `json:"summary_only"`
+
+	SnapshotLimit int `json:"snapshot_limit"`
+	PeakWindows   int `json:"peak_windows"`
+
+	CosineCorrection bool `json:"cosine_correction"`
+
+	SpeedHistogramBucket int `json:"speed_histogram_bucket"`
+
+	HardwareReset *struct {
+		Line      ModemLine `json:"line"`
+		Inverted  bool      `json:"inverted"`
+		Pulse     duration  `json:"pulse"`
+		BootDelay duration  `json:"boot_delay"`
+	} `json:"hardware_reset"`
+
+	Reconnect bool `json:"reconnect"`
+
+	ReopenAfterBaudChange bool `json:"reopen_after_baud_change"`
+}
+
+type detectionFile struct {
+	MaxDistance   int                `json:"max_distance"`
+	Direction     DetectionDirection `json:"direction"`
+	MinSpeed      int                `json:"min_speed"`
+	NoTargetDelay duration           `json:"no_target_delay"`
+}
+
+type alarmFile struct {
+	TriggerCount int `json:"trigger_count"`
+	SNRThreshold int `json:"snr_threshold"`
+}
+
+// filtersFile describes the filters of Config.Filters, each one is only added
+// when set.
+type filtersFile struct {
+	AngleRange *struct {
+		Min int `json:"min"`
+		Max int `json:"max"`
+	} `json:"angle_range"`
+	MinSpeed  int        `json:"min_speed"`
+	Direction *Direction `json:"direction"`
+	MinSNR    int        `json:"min_snr"`
+	SNRBands  []struct {
+		MaxDistance int `json:"max_distance"`
+		MinSNR      int `json:"min_snr"`
+	} `json:"snr_bands"`
+	MaxRate *struct {
+		Rate  float64 `json:"rate"`
+		Burst int     `json:"burst"`
+	} `json:"max_rate"`
+	AdaptiveSNR *struct {
+		Window       duration `json:"window"`
+		Margin       int      `json:"margin"`
+		ClutterSpeed int      `json:"clutter_speed"`
+		ClutterRate  float64  `json:"clutter_rate"`
+	} `json:"adaptive_snr"`
+}
+
+func (file configFile) config() (Config, error) {
+	config := Config{
+		SerialPort:       file.SerialPort,
+		BaudRate:         file.BaudRate,
+		AnyBaudRate:      file.AnyBaudRate,
+		TargetBufferSize: file.TargetBufferSize,
+		ErrorBufferSize:  file.ErrorBufferSize,
+		SpeedSmoothing:   file.SpeedSmoothing,
+		SmoothingWindow:  file.SmoothingWindow,
+		SmoothingFactor:  file.SmoothingFactor,
+		Heartbeats:       file.Heartbeats,
+		MaxTargetAge:     time.Duration(file.MaxTargetAge),
+		OpenRetryTimeout: time.Duration(file.OpenRetryTimeout),
+		OpenRetryBackoff: time.Duration(file.OpenRetryBackoff),
+		DegradedAfter:    time.Duration(file.DegradedAfter),
+		FrameGapFactor:   file.FrameGapFactor,
+		FramePeriod:      time.Duration(file.FramePeriod),
+		MinFrames:        file.MinFrames,
+		PersistenceGate:  file.PersistenceGate,
+		ReportInterval:   time.Duration(file.ReportInterval),
+		MaxTargets:       file.MaxTargets,
+		TargetOverflow:   file.TargetOverflow,
+		TargetValidation: file.TargetValidation,
+		TargetLimits: TargetLimits{
+			MaxAngle:    file.TargetLimits.MaxAngle,
+			MaxDistance: file.TargetLimits.MaxDistance,
+			MaxSpeed:    file.TargetLimits.MaxSpeed,
+		},
+		AngleSign:         file.AngleSign,
+		InvertAngle:       file.InvertAngle,
+		SnapshotWindow:    time.Duration(file.SnapshotWindow),
+		PeakWindow:        time.Duration(file.PeakWindow),
+		SinkFlushInterval: time.Duration(file.SinkFlushInterval),
+		SummaryInterval:   time.Duration(file.SummaryInterval),
+		SummaryOnly:       file.SummaryOnly,
+		ClearTickInterval: time.Duration(file.ClearTickInterval),
+		Reconnect:         file.Reconnect,
+
+		SpeedHistogramBucket: file.SpeedHistogramBucket,
+
+		DetectProtocol: file.DetectProtocol,
+		ParseMode:      file.ParseMode,
+
+		RecoverConfigMode: time.Duration(file.RecoverConfigMode),
+		WaitForFirstFrame: time.Duration(file.WaitForFirstFrame),
+
+		UnknownFrames: file.UnknownFrames,
+
+		ParseFailureLimit:   file.ParseFailureLimit,
+		ParseFailureBackoff: time.Duration(file.ParseFailureBackoff),
+
+		SensorID: file.SensorID,
+
+		SnapshotLimit: file.SnapshotLimit,
+		PeakWindows:   file.PeakWindows,
+
+		CosineCorrection: file.CosineCorrection,
+
+		ReopenAfterBaudChange: file.ReopenAfterBaudChange,
+	}
+	if p := file.DetectionParameters; p != nil {
+		config.DetectionParameters = &DetectionParameters{
+			MaxDistance:   p.MaxDistance,
+			Direction:     p.Direction,
+			MinSpeed:      p.MinSpeed,
+			NoTargetDelay: time.Duration(p.NoTargetDelay),
+		}
+		if err := config.DetectionParameters.validate(); err != nil {
+			return Config{}, fmt.Errorf("detection_parameters: %w", err)
+		}
+	}
+	if p := file.AlarmParameters; p != nil {
+		config.AlarmParameters = &AlarmParameters{TriggerCount: p.TriggerCount, SNRThreshold: p.SNRThreshold}
+		if err := config.AlarmParameters.validate(); err != nil {
+			return Config{}, fmt.Errorf("alarm_parameters: %w", err)
+		}
+	}
+	if v := file.FrameVariant; v != nil {
+		variant := protocol.Variant(*v)
+		config.FrameVariant = &variant
+	}
+	if r := file.HardwareReset; r != nil {
+		config.HardwareReset = &HardwareReset{Line: r.Line, Inverted: r.Inverted, Pulse: time.Duration(r.Pulse), BootDelay: time.Duration(r.BootDelay)}
+	}
+	if m := file.Mounting; m != nil {
+		config.Mounting = &Mounting{Height: m.Height, LateralOffset: m.LateralOffset, Tilt: m.Tilt}
+	}
+
+	filters := file.Filters
+	//the noise filter goes first so it learns from slow targets other filters drop
+	if n := filters.AdaptiveSNR; n != nil {
+		config.Filters = append(config.Filters, AdaptiveSNR(NoiseProfile{
+			Window:       time.Duration(n.Window),
+			Margin:       n.Margin,
+			ClutterSpeed: n.ClutterSpeed,
+			ClutterRate:  n.ClutterRate,
+		}))
+	}
+	if r := filters.AngleRange; r != nil {
+		if r.Min > r.Max {
+			return Config{}, fmt.Errorf("filters: angle range %d to %d is empty", r.Min, r.Max)
+		}
+		config.Filters = append(config.Filters, AngleRange(r.Min, r.Max))
+	}
+	if filters.MinSpeed > 0 {
+		config.Filters = append(config.Filters, MinSpeed(filters.MinSpeed))
+	}
+	if filters.Direction != nil {
+		config.Filters = append(config.Filters, OnlyDirection(*filters.Direction))
+	}
+	if filters.MinSNR > 0 {
+		config.Filters = append(config.Filters, MinSNR(filters.MinSNR))
+	}
+	if len(filters.SNRBands) > 0 {
+		bands := make([]SNRBand, len(filters.SNRBands))
+		for i, band := range filters.SNRBands {
+			bands[i] = SNRBand{MaxDistance: band.MaxDistance, MinSNR: band.MinSNR}
+		}
+		config.Filters = append(config.Filters, DistanceSNR(bands...))
+	}
+	//rate limiting goes last so targets other filters reject don't use up the budget
+	if r := filters.MaxRate; r != nil {
+		if r.Rate <= 0 {
+			return Config{}, fmt.Errorf("filters: max rate %g is not positive", r.Rate)
+		}
+		if r.Burst < 1 {
+			return Config{}, fmt.Errorf("filters: max rate burst %d is below 1", r.Burst)
+		}
+		config.Filters = append(config.Filters, MaxRate(r.Rate, r.Burst))
+	}
+	return config, nil
+}
+
+// duration reads a time.Duration from a string such as "1.5s".
+type duration time.Duration
+
+var errDuration = errors.New("durations must be strings such as \"500ms\" or \"2s\"")
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errDuration
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// variantFile reads a protocol.Variant from its name or from an object of its
+// fields.
+type variantFile protocol.Variant
+
+func (v *variantFile) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		variant, ok := protocol.LookupVariant(name)
+		if !ok {
+			return fmt.Errorf("unknown frame variant %q", name)
+		}
+		*v = variantFile(variant)
+		return nil
+	}
+	var variant protocol.Variant
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&variant); err != nil {
+		return fmt.Errorf("frame variant: %w", err)
+	}
+	if variant.Name == "" {
+		variant.Name = "custom"
+	}
+	*v = variantFile(variant)
+	return nil
+}
@@ -0,0 +1,54 @@
+package LD2451
+
+import (
+	"errors"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// recoverConfigMode ends a configuration session a previous process left
+// open, e.g. because it was killed between its enable and end config
+// commands, which keeps the module from reporting until it is power cycled.
+// It waits Config.RecoverConfigMode for a data frame and sends the end config
+// command when only command frames or nothing arrived instead. A module that
+// doesn't answer is left alone, it may just not be powered yet.
+func (ld2451 *LD2451) recoverConfigMode() error {
+	timer := ld2451.config.Clock.NewTimer(ld2451.config.RecoverConfigMode)
+	defer timer.Stop()
+	var evidence string
+	select {
+	case <-ld2451.firstFrame:
+		return nil
+	case <-ld2451.commandFrames:
+		evidence = "command frames instead of data frames"
+	case <-timer.C():
+		evidence = "no data frames"
+	case <-ld2451.done:
+		return ld2451.fatal
+	}
+
+	ld2451.config.Logger.Warn("module seems to be stuck in config mode, ending it", "evidence", evidence)
+	err := ld2451.enqueue("open", func() error {
+		_, err := ld2451.command(protocol.CmdEndConfig, nil)
+		return err
+	})
+	var commandErr *CommandError
+	switch {
+	case err == nil:
+		ld2451.config.Logger.Info("ended the config mode left open")
+	case errors.Is(err, ErrCommandTimeout), errors.Is(err, ErrReadOnlySource), errors.As(err, &commandErr):
+		//not in config mode after all, or nothing to talk to
+		ld2451.config.Logger.Debug("ending config mode at open failed", "error", err)
+	default:
+		return err
+	}
+	return nil
+}
+
+// sawCommandFrame notes that a command frame arrived, for recoverConfigMode.
+func (ld2451 *LD2451) sawCommandFrame() {
+	select {
+	case ld2451.commandFrames <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,29 @@
+package LD2451_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+func TestRecoverConfigModeOnSilentModule(t *testing.T) {
+	sensor, _ := openSensor(t, func(config *LD2451.Config) { config.RecoverConfigMode = silence })
+	for _, command := range sensor.Commands() {
+		if command.Word == protocol.CmdEndConfig {
+			return
+		}
+	}
+	t.Fatalf("no end config command sent after %s without data", silence)
+}
+
+func TestRecoverConfigModeKeepsReporting(t *testing.T) {
+	sensor, radar := openSensor(t, func(config *LD2451.Config) { config.RecoverConfigMode = time.Second })
+	if err := sensor.SendTargets(false, LD2451.Target{Distance: 5, Speed: 12}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := radar.ReadTarget(); err != nil {
+		t.Fatal(err)
+	}
+}
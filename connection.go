@@ -0,0 +1,51 @@
+package LD2451
+
+import "time"
+
+type ConnectionStatus int
+
+const (
+	Connected    ConnectionStatus = 0 // The port was opened, initially or after reconnecting
+	Disconnected ConnectionStatus = 1 // The port failed, Err holds the cause
+	Reconnecting ConnectionStatus = 2 // The port is about to be reopened, Err holds why the previous attempt failed
+)
+
+func (s ConnectionStatus) String() string {
+	switch s {
+	case Connected:
+		return "Connected"
+	case Disconnected:
+		return "Disconnected"
+	case Reconnecting:
+		return "Reconnecting"
+	default:
+		return "Unknown"
+	}
+}
+
+// ConnectionEvent reports a change of the link to the module. Unlike state
+// changes they are only caused by the port itself, so a flapping link shows up
+// as alternating Connected and Disconnected events.
+type ConnectionEvent struct {
+	Status  ConnectionStatus `json:"status"`
+	Err     error            `json:"error,omitempty"`   // Cause of a disconnect, or of the failed attempt before Reconnecting
+	Attempt int              `json:"attempt,omitempty"` // Reopen attempt since the disconnect, when Reconnecting or Connected after reconnecting
+	Time    time.Time        `json:"time"`
+	Sensor  string           `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+// ConnectionEvents returns the channel connection events are delivered on.
+// Events are dropped rather than stalling the library when nobody keeps up
+// with the channel. Closing the sensor is not reported.
+func (ld2451 *LD2451) ConnectionEvents() <-chan ConnectionEvent {
+	return ld2451.connections
+}
+
+func (ld2451 *LD2451) reportConnection(status ConnectionStatus, err error, attempt int) {
+	event := ConnectionEvent{Status: status, Err: err, Attempt: attempt, Time: ld2451.now(), Sensor: ld2451.config.SensorID}
+	ld2451.publish(event)
+	select {
+	case ld2451.connections <- event:
+	default:
+	}
+}
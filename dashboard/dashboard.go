@@ -0,0 +1,232 @@
+// Package dashboard serves a small web page for verifying the aim of a sensor
+// in the field: live targets on a polar plot, the recent speed history and
+// the detection parameters and health of the module.
+//
+//	dash := dashboard.New(sensor, dashboard.Config{})
+//	go dash.Run(ctx)
+//	mux.Handle("/radar/", http.StripPrefix("/radar", dash))
+//
+// The detection parameters are read when Run starts and again when the page
+// asks for it, as reading them pauses target reports while the module is in
+// config mode. The page and its script are embedded, so no assets need to be
+// deployed.
+package dashboard
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	DefaultHistory        = time.Minute
+	DefaultTargetLifetime = time.Second
+	DefaultMaxTargets     = 256
+	DefaultMaxSamples     = 4096
+)
+
+//go:embed index.html
+var index []byte
+
+type Config struct {
+	History        time.Duration // How far back the speed history reaches (default DefaultHistory)
+	TargetLifetime time.Duration // Targets stay on the polar plot for this long after they were reported (default DefaultTargetLifetime)
+
+	MaxTargets int // Most targets on the polar plot, the oldest evicted first (default DefaultMaxTargets)
+	MaxSamples int // Most samples in the speed history, the oldest evicted first (default DefaultMaxSamples)
+
+	Clock LD2451.Clock // Source of time targets and samples expire on, the one of the sensor; nil uses LD2451.SystemClock
+}
+
+// Sample is a point of the speed history.
+type Sample struct {
+	Time      time.Time        `json:"time"`
+	Speed     int              `json:"speed"`
+	Direction LD2451.Direction `json:"direction"`
+}
+
+// Retention reports the usage of the dashboard's stores.
+type Retention struct {
+	Targets LD2451.StoreUsage `json:"targets"`
+	History LD2451.StoreUsage `json:"history"`
+}
+
+// State is the JSON document polled by the page.
+type State struct {
+	Targets     []LD2451.Target             `json:"targets"`
+	History     []Sample                    `json:"history"`
+	HistorySpan time.Duration               `json:"history_span"`
+	Parameters  *LD2451.DetectionParameters `json:"parameters,omitempty"`
+	Health      LD2451.Health               `json:"health"`
+}
+
+type Dashboard struct {
+	sensor *LD2451.LD2451
+	config Config
+
+	mu         sync.Mutex
+	targets    []LD2451.Target
+	history    []Sample
+	parameters *LD2451.DetectionParameters
+
+	evictedTargets uint64
+	evictedSamples uint64
+}
+
+func New(sensor *LD2451.LD2451, config Config) *Dashboard {
+	if config.History <= 0 {
+		config.History = DefaultHistory
+	}
+	if config.TargetLifetime <= 0 {
+		config.TargetLifetime = DefaultTargetLifetime
+	}
+	if config.MaxTargets <= 0 {
+		config.MaxTargets = DefaultMaxTargets
+	}
+	if config.MaxSamples <= 0 {
+		config.MaxSamples = DefaultMaxSamples
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+	return &Dashboard{sensor: sensor, config: config}
+}
+
+// Run collects targets until ctx is done or the sensor stops. Targets are
+// consumed through Subscribe, so the dashboard can run next to any other
+// reader of the sensor.
+func (d *Dashboard) Run(ctx context.Context) error {
+	targets, unsubscribe := d.sensor.Subscribe()
+	defer unsubscribe()
+
+	//a module that doesn't answer leaves the parameters off the page
+	d.Refresh()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case target, ok := <-targets:
+			if !ok {
+				//the subscription is closed once the sensor stopped reading
+				return d.sensor.Err()
+			}
+			d.add(target)
+		}
+	}
+}
+
+// Refresh reads the detection parameters from the module again, keeping the
+// last known ones when it doesn't answer. Target reports pause meanwhile.
+func (d *Dashboard) Refresh() error {
+	params, err := d.sensor.DetectionParameters()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.parameters = &params
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *Dashboard) add(target LD2451.Target) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.targets = append(d.targets, target)
+	d.history = append(d.history, Sample{Time: target.Time, Speed: target.Speed, Direction: target.Direction})
+	d.expire(target.Time)
+	if excess := len(d.targets) - d.config.MaxTargets; excess > 0 {
+		d.targets = d.targets[excess:]
+		d.evictedTargets += uint64(excess)
+	}
+	if excess := len(d.history) - d.config.MaxSamples; excess > 0 {
+		d.history = d.history[excess:]
+		d.evictedSamples += uint64(excess)
+	}
+}
+
+// expire drops targets and samples that are too old to be shown. d.mu must be held.
+func (d *Dashboard) expire(now time.Time) {
+	i := 0
+	for i < len(d.targets) && now.Sub(d.targets[i].Time) > d.config.TargetLifetime {
+		i++
+	}
+	d.targets = d.targets[i:]
+	i = 0
+	for i < len(d.history) && now.Sub(d.history[i].Time) > d.config.History {
+		i++
+	}
+	d.history = d.history[i:]
+}
+
+// Retention returns the current usage of the dashboard's stores.
+func (d *Dashboard) Retention() Retention {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	target, sample := int(unsafe.Sizeof(LD2451.Target{})), int(unsafe.Sizeof(Sample{}))
+	return Retention{
+		Targets: LD2451.StoreUsage{
+			Entries:    len(d.targets),
+			MaxEntries: d.config.MaxTargets,
+			Bytes:      cap(d.targets) * target,
+			MaxBytes:   d.config.MaxTargets * target,
+			Evicted:    d.evictedTargets,
+		},
+		History: LD2451.StoreUsage{
+			Entries:    len(d.history),
+			MaxEntries: d.config.MaxSamples,
+			Bytes:      cap(d.history) * sample,
+			MaxBytes:   d.config.MaxSamples * sample,
+			Evicted:    d.evictedSamples,
+		},
+	}
+}
+
+// State returns what the page currently shows.
+func (d *Dashboard) State() State {
+	d.mu.Lock()
+	d.expire(d.config.Clock.Now())
+	state := State{
+		Targets:     append([]LD2451.Target{}, d.targets...),
+		History:     append([]Sample{}, d.history...),
+		HistorySpan: d.config.History,
+		Parameters:  d.parameters,
+	}
+	d.mu.Unlock()
+	state.Health = d.sensor.Health()
+	return state
+}
+
+// ServeHTTP serves the page at the root and its state at "state", both
+// relative to where the dashboard is mounted. A POST to "parameters" calls
+// Refresh.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/", "":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(index)
+	case "/state":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(d.State())
+	case "/parameters":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := d.Refresh(); err != nil {
+			http.Error(w, fmt.Sprintf("reading the detection parameters failed: %v", err), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
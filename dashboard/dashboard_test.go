@@ -0,0 +1,101 @@
+package dashboard_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/dashboard"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+// reads counts the detection parameter reads the fake sensor answered.
+func reads(sensor *sensortest.Sensor) int {
+	n := 0
+	for _, command := range sensor.Commands() {
+		if command.Word == protocol.CmdReadDetection {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDashboard(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	sensor.Handle(protocol.CmdReadDetection, func(sensortest.Command) (uint16, []byte, bool) {
+		return 0, []byte{60, 2, 10, 2}, true
+	})
+	radar, err := LD2451.Open(sensor.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer radar.Close()
+
+	dash := dashboard.New(radar, dashboard.Config{})
+	done := make(chan error, 1)
+	go func() { done <- dash.Run(context.Background()) }()
+
+	//the parameters are read once at start and the targets keep coming
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		sensor.SendTargets(false, LD2451.Target{Distance: 20, Speed: 30})
+		state := dash.State()
+		if state.Parameters != nil && len(state.Targets) > 0 {
+			if state.Parameters.MaxDistance != 60 {
+				t.Errorf("got parameters %+v", *state.Parameters)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("dashboard state never filled: %+v", state)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := reads(sensor); n != 1 {
+		t.Errorf("read the parameters %d times, expected once", n)
+	}
+
+	server := httptest.NewServer(dash)
+	defer server.Close()
+	response, err := http.Get(server.URL + "/parameters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET parameters: got status %d", response.StatusCode)
+	}
+	response, err = http.Post(server.URL+"/parameters", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNoContent || reads(sensor) != 2 {
+		t.Errorf("POST parameters: got status %d and %d reads", response.StatusCode, reads(sensor))
+	}
+
+	//unplugging stops Run with the reader's error, without taking the application's targets
+	radar.ReadTargets(0)
+	sensor.SendTargets(false, LD2451.Target{Distance: 21, Speed: 31})
+	time.Sleep(50 * time.Millisecond)
+	sensor.Close()
+	select {
+	case err := <-done:
+		if err == nil || err != radar.Err() {
+			t.Errorf("Run returned %v, the reader stopped with %v", err, radar.Err())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run kept going after the sensor was unplugged")
+	}
+	if target, err := radar.ReadTarget(); err != nil || target.Distance != 21 {
+		t.Errorf("ReadTarget returned %+v, %v", target, err)
+	}
+}
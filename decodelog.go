@@ -0,0 +1,127 @@
+package LD2451
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// decodeLog writes a line of text to Config.DecodeLog for every frame read
+// from or written to the port. Like the tap, a failing writer is dropped
+// instead of taking the sensor down.
+type decodeLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	logger Logger
+	failed bool
+}
+
+func newDecodeLog(config Config) *decodeLog {
+	if config.DecodeLog == nil {
+		return nil
+	}
+	return &decodeLog{w: config.DecodeLog, logger: config.Logger}
+}
+
+func (d *decodeLog) printf(t time.Time, direction, format string, args ...any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.failed {
+		return
+	}
+	line := t.Format("15:04:05.000") + " " + direction + " " + fmt.Sprintf(format, args...) + "\n"
+	if _, err := io.WriteString(d.w, line); err != nil {
+		d.failed = true
+		d.logger.Warn("decode log failed, no longer logging frames", "error", err)
+	}
+}
+
+// logPacket describes a packet returned by the frame source, with the bytes
+// skipped before it.
+func (ld2451 *LD2451) logPacket(packet protocol.Packet, err error) {
+	d := ld2451.decode
+	if d == nil {
+		return
+	}
+	now := ld2451.now()
+	if packet.Skipped > 0 {
+		skipped := fmt.Sprintf("%d bytes", packet.Skipped)
+		if packet.Skipped == 1 {
+			skipped = "1 byte"
+		}
+		if packet.Oversized > 0 {
+			skipped += fmt.Sprintf(", %d headers with an implausible length", packet.Oversized)
+		}
+		d.printf(now, "rx", "skipped %s looking for the next frame", skipped)
+	}
+	if err != nil {
+		d.printf(now, "rx", "read failed: %v", err)
+		return
+	}
+	if packet.Kind == protocol.KindCommand {
+		d.printf(now, "rx", "command frame, %s: %s", payloadLength(len(packet.Payload)), describeAck(packet.Payload))
+		return
+	}
+	if len(packet.Payload) == 0 {
+		d.printf(now, "rx", "data frame, %s: no targets", payloadLength(0))
+		return
+	}
+	frame, err := ld2451.variant().ParseFrame(packet.Payload, nil)
+	if err != nil {
+		d.printf(now, "rx", "data frame, %s: undecodable, %v", payloadLength(len(packet.Payload)), err)
+		return
+	}
+	d.printf(now, "rx", "data frame, %s: %s", payloadLength(len(packet.Payload)), frame)
+}
+
+// logCommand describes a command about to be written to the port, before its
+// acknowledgement can be read.
+func (ld2451 *LD2451) logCommand(word uint16, value []byte) {
+	if d := ld2451.decode; d != nil {
+		d.printf(ld2451.now(), "tx", "command frame, %s: %s", payloadLength(2+len(value)), describeCommand(word, value))
+	}
+}
+
+func payloadLength(n int) string {
+	return fmt.Sprintf("%d byte payload", n)
+}
+
+func describeCommand(word uint16, value []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (0x%04x)", protocol.CommandName(word), word)
+	if len(value) > 0 {
+		fmt.Fprintf(&b, ", value % x", value)
+	}
+	if word == protocol.CmdSetBaudRate && len(value) >= 2 {
+		if index := int(binary.LittleEndian.Uint16(value)); index >= 1 && index <= len(baudRates) {
+			fmt.Fprintf(&b, " (%d baud)", baudRates[index-1])
+		}
+	}
+	return b.String()
+}
+
+func describeAck(payload []byte) string {
+	ack, err := protocol.ParseAck(payload)
+	if err != nil {
+		return "not an acknowledgement, " + err.Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "ack of %s (0x%04x), ", protocol.CommandName(ack.Word), ack.Word)
+	if ack.Status == 0 {
+		b.WriteString("succeeded")
+	} else {
+		fmt.Fprintf(&b, "failed with status %d", ack.Status)
+	}
+	if len(ack.Data) > 0 {
+		fmt.Fprintf(&b, ", data % x", ack.Data)
+	}
+	if ack.Word == protocol.CmdEnableConfig && ack.Status == 0 && len(ack.Data) >= 4 {
+		fmt.Fprintf(&b, " (protocol version %d, buffer size %d)", binary.LittleEndian.Uint16(ack.Data), binary.LittleEndian.Uint16(ack.Data[2:]))
+	}
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package LD2451
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+type DetectionDirection int
+
+const (
+	DetectAway   DetectionDirection = 0 // Only report targets moving away from the antenna
+	DetectToward DetectionDirection = 1 // Only report targets moving toward the antenna
+	DetectBoth   DetectionDirection = 2 // Report targets moving in either direction
+)
+
+func (d DetectionDirection) String() string {
+	switch d {
+	case DetectAway:
+		return "Away"
+	case DetectToward:
+		return "Toward"
+	case DetectBoth:
+		return "Both"
+	default:
+		return "Unknown"
+	}
+}
+
+type DetectionParameters struct {
+	MaxDistance   int                `json:"max_distance"`    // Maximum detection distance in meters (10-255)
+	Direction     DetectionDirection `json:"direction"`       // Movement directions that are reported
+	MinSpeed      int                `json:"min_speed"`       // Minimum speed in KM/H for a target to be reported (0-120)
+	NoTargetDelay time.Duration      `json:"no_target_delay"` // Delay before the module reports that targets disappeared, in whole seconds (0-255s)
+}
+
+func (p DetectionParameters) validate() error {
+	switch {
+	case p.MaxDistance < 10 || p.MaxDistance > 255:
+		return fmt.Errorf("max distance %d m is outside 10-255 m", p.MaxDistance)
+	case p.Direction < DetectAway || p.Direction > DetectBoth:
+		return fmt.Errorf("unknown detection direction %d", p.Direction)
+	case p.MinSpeed < 0 || p.MinSpeed > 120:
+		return fmt.Errorf("min speed %d km/h is outside 0-120 km/h", p.MinSpeed)
+	case p.NoTargetDelay < 0 || p.NoTargetDelay > 255*time.Second:
+		return fmt.Errorf("no target delay %s is outside 0-255s", p.NoTargetDelay)
+	}
+	return nil
+}
+
+// DetectionParameters reads the target detection parameters from the module.
+func (ld2451 *LD2451) DetectionParameters() (DetectionParameters, error) {
+	var params DetectionParameters
+	err := ld2451.configure("DetectionParameters", func() error {
+		var err error
+		params, err = ld2451.readDetectionParameters()
+		return err
+	})
+	return params, err
+}
+
+func (ld2451 *LD2451) readDetectionParameters() (DetectionParameters, error) {
+	data, err := ld2451.command(protocol.CmdReadDetection, nil)
+	if err != nil {
+		return DetectionParameters{}, err
+	}
+	if len(data) < 4 {
+		return DetectionParameters{}, fmt.Errorf("detection parameters reply of %d bytes is too short", len(data))
+	}
+	return DetectionParameters{
+		MaxDistance:   int(data[0]),
+		Direction:     DetectionDirection(data[1]),
+		MinSpeed:      int(data[2]),
+		NoTargetDelay: time.Duration(data[3]) * time.Second,
+	}, nil
+}
+
+// SetDetectionParameters writes the target detection parameters to the module.
+func (ld2451 *LD2451) SetDetectionParameters(params DetectionParameters) error {
+	if err := params.validate(); err != nil {
+		return ld2451.wrap("SetDetectionParameters", err)
+	}
+	return ld2451.configure("SetDetectionParameters", ld2451.writeDetectionParameters(params))
+}
+
+func (ld2451 *LD2451) writeDetectionParameters(params DetectionParameters) func() error {
+	return func() error {
+		_, err := ld2451.command(protocol.CmdSetDetection, []byte{
+			byte(params.MaxDistance),
+			byte(params.Direction),
+			byte(params.MinSpeed),
+			byte(params.NoTargetDelay / time.Second),
+		})
+		return err
+	}
+}
+
+// EnsureDetectionParameters reads the detection parameters back from the
+// module and writes desired if they differ, e.g. after the module was reset
+// to factory settings. It reports whether the parameters had to be changed.
+func (ld2451 *LD2451) EnsureDetectionParameters(desired DetectionParameters) (bool, error) {
+	if err := desired.validate(); err != nil {
+		return false, ld2451.wrap("EnsureDetectionParameters", err)
+	}
+	//the module stores the delay in whole seconds
+	desired.NoTargetDelay = desired.NoTargetDelay.Truncate(time.Second)
+
+	current, err := ld2451.DetectionParameters()
+	if err != nil {
+		return false, err
+	}
+	if current == desired {
+		return false, nil
+	}
+	return true, ld2451.SetDetectionParameters(desired)
+}
@@ -0,0 +1,101 @@
+package LD2451
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// FirmwareVersion identifies the firmware running on the module.
+type FirmwareVersion struct {
+	Type  uint16 `json:"type"`
+	Major uint16 `json:"major"` // Major version in the high byte, minor version in the low byte
+	Build uint32 `json:"build"` // Build date, reads like a decimal number when printed in hex
+}
+
+func (v FirmwareVersion) String() string {
+	return fmt.Sprintf("V%x.%02x.%x", v.Major>>8, v.Major&0xff, v.Build)
+}
+
+// DeviceConfig is everything that can be read from the module at once.
+type DeviceConfig struct {
+	ProtocolVersion uint16              `json:"protocol_version"` // Reported when entering config mode
+	BufferSize      uint16              `json:"buffer_size"`      // Reported when entering config mode
+	Firmware        FirmwareVersion     `json:"firmware"`
+	Detection       DetectionParameters `json:"detection"`
+	Alarm           AlarmParameters     `json:"alarm"`
+
+	SerialNumber string `json:"serial_number,omitempty"` // Empty when the firmware doesn't report one, see SerialNumber
+}
+
+// FirmwareVersion reads the firmware version from the module.
+func (ld2451 *LD2451) FirmwareVersion() (FirmwareVersion, error) {
+	var version FirmwareVersion
+	err := ld2451.configure("FirmwareVersion", func() error {
+		var err error
+		version, err = ld2451.readFirmwareVersion()
+		return err
+	})
+	return version, err
+}
+
+func (ld2451 *LD2451) readFirmwareVersion() (FirmwareVersion, error) {
+	data, err := ld2451.command(protocol.CmdReadFirmware, nil)
+	if err != nil {
+		return FirmwareVersion{}, err
+	}
+	if len(data) < 8 {
+		return FirmwareVersion{}, fmt.Errorf("firmware version reply of %d bytes is too short", len(data))
+	}
+	return FirmwareVersion{
+		Type:  binary.LittleEndian.Uint16(data),
+		Major: binary.LittleEndian.Uint16(data[2:]),
+		Build: binary.LittleEndian.Uint32(data[4:]),
+	}, nil
+}
+
+// GetConfiguration reads the firmware version, detection and alarm
+// parameters in a single configuration session, which is considerably
+// faster than reading them one by one since every session enters and leaves
+// config mode.
+func (ld2451 *LD2451) GetConfiguration() (DeviceConfig, error) {
+	var config DeviceConfig
+	err := ld2451.configure("GetConfiguration", func() error {
+		var err error
+		config, err = ld2451.readConfiguration()
+		return err
+	})
+	return config, err
+}
+
+func (ld2451 *LD2451) readConfiguration() (DeviceConfig, error) {
+	var config DeviceConfig
+	if len(ld2451.configInfo) >= 4 {
+		config.ProtocolVersion = binary.LittleEndian.Uint16(ld2451.configInfo)
+		config.BufferSize = binary.LittleEndian.Uint16(ld2451.configInfo[2:])
+	}
+	var err error
+	config.Firmware, err = ld2451.readFirmwareVersion()
+	if err != nil {
+		return config, err
+	}
+	config.Detection, err = ld2451.readDetectionParameters()
+	if err != nil {
+		return config, err
+	}
+	data, err := ld2451.command(protocol.CmdReadSensitivity, nil)
+	if err != nil {
+		return config, err
+	}
+	config.Alarm, err = alarmParameters(data, config.Detection)
+	if err != nil {
+		return config, err
+	}
+	config.SerialNumber, err = ld2451.readSerialNumber()
+	if errors.Is(err, ErrNoSerialNumber) {
+		err = nil
+	}
+	return config, err
+}
@@ -0,0 +1,54 @@
+package LD2451
+
+import (
+	"fmt"
+)
+
+// ResyncError is delivered on Diagnostics when the reader lost frame
+// alignment and had to search for the next header.
+type ResyncError struct {
+	Skipped   int // Bytes discarded before the next frame
+	Oversized int // Headers rejected for declaring an implausible length
+}
+
+func (e *ResyncError) Error() string {
+	return fmt.Sprintf("lost frame alignment, skipped %d bytes and %d oversized headers", e.Skipped, e.Oversized)
+}
+
+// UnknownFrameError is a correctly delimited command frame that is no
+// acknowledgement of a command the library knows, e.g. from newer firmware or
+// another process talking to the module. See Config.UnknownFrames.
+type UnknownFrameError struct {
+	Word    uint16 // Command word of the frame, zero if it is too short to hold one
+	Payload []byte // Raw bytes between length and footer
+}
+
+func (e *UnknownFrameError) Error() string {
+	return fmt.Sprintf("unknown command frame 0x%04x: % x", e.Word, e.Payload)
+}
+
+// Diagnostics returns the channel recoverable errors are delivered on: a
+// *ParseError for every frame or target that was dropped and a *ResyncError
+// whenever bytes were skipped. The reader carries on after them, so they never
+// reach ReadTarget, which only reports transport errors. Diagnostics that
+// don't fit in the buffer are dropped and counted in Stats.DroppedErrors.
+func (ld2451 *LD2451) Diagnostics() <-chan error {
+	return ld2451.diagnostics
+}
+
+// reportStrict also returns err from ReadTarget with Config.ParseMode
+// ParseStrict.
+func (ld2451 *LD2451) reportStrict(err error) {
+	if ld2451.config.ParseMode == ParseStrict {
+		ld2451.reportError(err)
+	}
+}
+
+func (ld2451 *LD2451) reportDiagnostic(err error) {
+	select {
+	case ld2451.diagnostics <- err:
+	default:
+		ld2451.recordDroppedError()
+		ld2451.config.Logger.Debug("diagnostics channel full, dropping error", "error", err)
+	}
+}
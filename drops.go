@@ -0,0 +1,37 @@
+package LD2451
+
+import "time"
+
+// dropQuietPeriod is how long no target may be dropped before the next drop
+// starts a new burst.
+const dropQuietPeriod = time.Second
+
+// TargetDrops is published when target deliveries start being dropped
+// because ReadTarget or a Subscribe channel is not read fast enough. Further
+// drops are only counted in Stats.DroppedTargets until none was dropped for a
+// second.
+type TargetDrops struct {
+	Reader  bool      `json:"reader"`  // The first drop was a target for ReadTarget rather than for a subscriber
+	Dropped uint64    `json:"dropped"` // Stats.DroppedTargets including the first drop of the burst
+	Time    time.Time `json:"time"`
+	Sensor  string    `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+func (e TargetDrops) EventTime() time.Time { return e.Time }
+
+// recordDroppedTarget counts a dropped delivery and reports the start of a
+// burst of drops.
+func (ld2451 *LD2451) recordDroppedTarget(reader bool) {
+	ld2451.statsMu.Lock()
+	defer ld2451.statsMu.Unlock()
+	now := ld2451.now()
+	ld2451.stats.DroppedTargets++
+	previous := ld2451.stats.LastTargetDrop
+	ld2451.stats.LastTargetDrop = now
+	if !previous.IsZero() && now.Sub(previous) < dropQuietPeriod {
+		return
+	}
+	ld2451.stats.TargetDropBursts++
+	ld2451.config.Logger.Warn("dropping targets, a consumer does not keep up", "reader", reader, "dropped", ld2451.stats.DroppedTargets)
+	ld2451.publish(TargetDrops{Reader: reader, Dropped: ld2451.stats.DroppedTargets, Time: now, Sensor: ld2451.config.SensorID})
+}
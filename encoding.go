@@ -0,0 +1,162 @@
+package LD2451
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func (s AlarmSource) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+func (s *AlarmSource) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "frame":
+		*s = AlarmSourceFrame
+	case "pin":
+		*s = AlarmSourcePin
+	default:
+		return fmt.Errorf("unknown alarm source %q", text)
+	}
+	return nil
+}
+
+func (d DetectionDirection) MarshalText() ([]byte, error) {
+	switch d {
+	case DetectAway, DetectToward, DetectBoth:
+		return []byte(strings.ToLower(d.String())), nil
+	default:
+		return []byte(strconv.Itoa(int(d))), nil
+	}
+}
+
+func (d *DetectionDirection) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "away":
+		*d = DetectAway
+	case "toward":
+		*d = DetectToward
+	case "both":
+		*d = DetectBoth
+	default:
+		v, err := strconv.Atoi(string(text))
+		if err != nil {
+			return fmt.Errorf("unknown detection direction %q", text)
+		}
+		*d = DetectionDirection(v)
+	}
+	return nil
+}
+
+func (s SensorState) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+func (s ConnectionStatus) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+func (m SmoothingMode) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(m.String())), nil
+}
+
+func (m *SmoothingMode) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "none":
+		*m = SmoothingNone
+	case "movingaverage":
+		*m = SmoothingMovingAverage
+	case "exponential":
+		*m = SmoothingExponential
+	default:
+		return fmt.Errorf("unknown speed smoothing mode %q", text)
+	}
+	return nil
+}
+
+func (p *OverflowPolicy) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "truncate":
+		*p = OverflowTruncate
+	case "error":
+		*p = OverflowError
+	default:
+		return fmt.Errorf("unknown target overflow policy %q", text)
+	}
+	return nil
+}
+
+func (m *ParseMode) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "default":
+		*m = ParseDefault
+	case "strict":
+		*m = ParseStrict
+	case "lenient":
+		*m = ParseLenient
+	default:
+		return fmt.Errorf("unknown parse mode %q", text)
+	}
+	return nil
+}
+
+func (l ModemLine) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(l.String())), nil
+}
+
+func (l *ModemLine) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "dtr":
+		*l = LineDTR
+	case "rts":
+		*l = LineRTS
+	default:
+		return fmt.Errorf("unknown modem line %q", text)
+	}
+	return nil
+}
+
+func (p *UnknownFramePolicy) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "skip":
+		*p = UnknownSkip
+	case "report":
+		*p = UnknownReport
+	case "error":
+		*p = UnknownError
+	default:
+		return fmt.Errorf("unknown frame policy %q is invalid", text)
+	}
+	return nil
+}
+
+func (p *ValidationPolicy) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "reject":
+		*p = ValidateReject
+	case "clamp":
+		*p = ValidateClamp
+	case "off":
+		*p = ValidateOff
+	default:
+		return fmt.Errorf("unknown target validation policy %q", text)
+	}
+	return nil
+}
+
+func (s AngleSign) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+func (s *AngleSign) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "positiveright":
+		*s = AnglePositiveRight
+	case "positiveleft":
+		*s = AnglePositiveLeft
+	default:
+		return fmt.Errorf("unknown angle sign %q", text)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package LD2451
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "LD2451_"
+
+// envAliases are short names for the most common settings.
+var envAliases = map[string]string{
+	"port": "serial_port",
+	"baud": "baud_rate",
+}
+
+// FromEnv reads a Config from LD2451_ environment variables, for containers
+// and fleet deployments where flags and files are awkward. Variables are
+// named after the keys LoadConfig accepts, e.g. LD2451_TARGET_BUFFER_SIZE=128
+// or LD2451_MAX_TARGET_AGE=2s, and LD2451_PORT and LD2451_BAUD are short for
+// the serial port and baud rate. Nested keys are separated by a double
+// underscore and values are read as YAML, so lists can be given inline:
+//
+//	LD2451_FILTERS__MIN_SPEED=10
+//	LD2451_FILTERS__SNR_BANDS=[{max_distance: 30, min_snr: 4}]
+//	LD2451_DETECTION_PARAMETERS__DIRECTION=toward
+//	LD2451_FRAME_VARIANT=extended
+//
+// When LD2451_CONFIG names a file it is loaded first and the other variables
+// override its settings. Unknown LD2451_ variables are rejected.
+func FromEnv() (Config, error) {
+	return configFromEnv(os.Environ())
+}
+
+func configFromEnv(environ []string) (Config, error) {
+	doc := map[string]any{}
+	if path := envValue(environ, envPrefix+"CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		if doc, err = parseDocument(data, strings.ToLower(filepath.Ext(path))); err != nil {
+			return Config{}, fmt.Errorf("load config %s: %w", path, err)
+		}
+		if doc == nil {
+			doc = map[string]any{}
+		}
+	}
+
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(name, envPrefix) || name == envPrefix+"CONFIG" {
+			continue
+		}
+		path := strings.Split(strings.ToLower(strings.TrimPrefix(name, envPrefix)), "__")
+		if alias, ok := envAliases[path[0]]; ok && len(path) == 1 {
+			path[0] = alias
+		}
+		if err := setPath(doc, path, envScalar(value)); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	config, err := decodeConfig(doc)
+	if err != nil {
+		return Config{}, fmt.Errorf("config from environment: %w", err)
+	}
+	return config, nil
+}
+
+// setPath stores value under the nested keys of path, creating maps on the
+// way.
+func setPath(doc map[string]any, path []string, value any) error {
+	for _, key := range path[:len(path)-1] {
+		next, ok := doc[key].(map[string]any)
+		if !ok {
+			if _, set := doc[key]; set {
+				return fmt.Errorf("%s is not a section", key)
+			}
+			next = map[string]any{}
+			doc[key] = next
+		}
+		doc = next
+	}
+	doc[path[len(path)-1]] = value
+	return nil
+}
+
+// envScalar interprets value as YAML, so numbers, booleans and inline lists
+// get their types. Anything that isn't valid YAML is taken as a string.
+func envScalar(value string) any {
+	var v any
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil || v == nil {
+		return value
+	}
+	return v
+}
+
+func envValue(environ []string, name string) string {
+	for _, variable := range environ {
+		if n, value, _ := strings.Cut(variable, "="); n == name {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package LD2451
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// OpError adds the failed operation and the port it happened on to errors
+// returned by the library, e.g.
+//
+//	ld2451: SetDetectionParameters on /dev/ttyUSB0: read ack for set detection parameters: timeout
+//
+// Use errors.Is and errors.As to inspect the underlying error.
+type OpError struct {
+	Op   string // Operation that failed, e.g. "open", "read" or the name of the method called
+	Port string // Port the sensor is connected through, empty if unknown
+	Err  error
+}
+
+func (e *OpError) Error() string {
+	if e.Port == "" {
+		return fmt.Sprintf("ld2451: %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("ld2451: %s on %s: %v", e.Op, e.Port, e.Err)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// wrap adds op and the port to err. Errors that already carry an operation,
+// such as the read error that stopped the reader, are returned unchanged.
+func (ld2451 *LD2451) wrap(op string, err error) error {
+	return wrapError(op, ld2451.portName, err)
+}
+
+func wrapError(op, port string, err error) error {
+	var opErr *OpError
+	if err == nil || errors.As(err, &opErr) {
+		return err
+	}
+	return &OpError{Op: op, Port: port, Err: err}
+}
+
+// portName describes port for error messages.
+func portName(config Config, port any) string {
+	if config.SerialPort != "" {
+		return config.SerialPort
+	}
+	switch p := port.(type) {
+	case interface{ Name() string }:
+		return p.Name()
+	case interface{ RemoteAddr() net.Addr }:
+		return p.RemoteAddr().String()
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package LD2451
+
+import "time"
+
+// Event is anything that happens to the sensor: a TargetEvent, AlarmEvent,
+// StateChange, ConnectionEvent, ParseErrorEvent, FrameGap, TargetDrops,
+// Summary, ClearTick or Heartbeat. Switch on the concrete type to handle them.
+type Event interface {
+	EventTime() time.Time
+}
+
+// TargetEvent is a delivered target.
+type TargetEvent struct {
+	Target
+}
+
+// ParseErrorEvent reports a frame that was delimited correctly but could not
+// be decoded.
+type ParseErrorEvent struct {
+	Err    error     `json:"error"`
+	Time   time.Time `json:"time"`
+	Sensor string    `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+func (e TargetEvent) EventTime() time.Time     { return e.Time }
+func (e AlarmEvent) EventTime() time.Time      { return e.Time }
+func (e StateChange) EventTime() time.Time     { return e.Time }
+func (e ConnectionEvent) EventTime() time.Time { return e.Time }
+func (e ParseErrorEvent) EventTime() time.Time { return e.Time }
+func (e Heartbeat) EventTime() time.Time       { return e.Time }
+
+// Events returns a channel receiving every event from now on, in the order
+// they happened, and a function that ends the subscription and closes the
+// channel. The channel is also closed when the reader stops. Events that
+// don't fit in a full channel are dropped and counted in Stats.DroppedEvents,
+// so a slow consumer never stalls the reader. Heartbeats are only published
+// with Config.Heartbeats.
+func (ld2451 *LD2451) Events() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize(ld2451.config))
+
+	ld2451.eventsMu.Lock()
+	select {
+	case <-ld2451.done:
+		//the reader already stopped, nothing will ever be published
+		close(ch)
+		ld2451.eventsMu.Unlock()
+		return ch, func() {}
+	default:
+	}
+	if ld2451.eventSubs == nil {
+		ld2451.eventSubs = make(map[chan Event]struct{})
+	}
+	ld2451.eventSubs[ch] = struct{}{}
+	ld2451.eventsMu.Unlock()
+
+	return ch, func() {
+		ld2451.eventsMu.Lock()
+		defer ld2451.eventsMu.Unlock()
+		if _, ok := ld2451.eventSubs[ch]; ok {
+			delete(ld2451.eventSubs, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish hands event to all event subscribers without blocking. It may be
+// called with statsMu held, so drops are counted under eventsMu instead.
+func (ld2451 *LD2451) publish(event Event) {
+	ld2451.eventsMu.Lock()
+	defer ld2451.eventsMu.Unlock()
+	for ch := range ld2451.eventSubs {
+		select {
+		case ch <- event:
+		default:
+			ld2451.droppedEvents++
+		}
+	}
+}
+
+func (ld2451 *LD2451) closeEventSubscribers() {
+	ld2451.eventsMu.Lock()
+	defer ld2451.eventsMu.Unlock()
+	for ch := range ld2451.eventSubs {
+		close(ch)
+	}
+	ld2451.eventSubs = nil
+}
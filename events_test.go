@@ -0,0 +1,27 @@
+package LD2451_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestEventsOnlyDoesNotStall(t *testing.T) {
+	sensor, radar := openSensor(t, nil)
+	events, stop := radar.Events()
+	defer stop()
+	go sendFrames(t, sensor)
+
+	timeout := time.After(5 * time.Second)
+	for received := 0; received < frameCount; {
+		select {
+		case event := <-events:
+			if _, ok := event.(LD2451.TargetEvent); ok {
+				received++
+			}
+		case <-timeout:
+			t.Fatalf("received %d of %d target events, the reader stalled", received, frameCount)
+		}
+	}
+}
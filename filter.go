@@ -0,0 +1,130 @@
+package LD2451
+
+import (
+	"fmt"
+	"time"
+)
+
+// Filter decides whether a target is delivered to consumers.
+type Filter interface {
+	Allow(target Target) bool
+}
+
+type FilterFunc func(target Target) bool
+
+func (f FilterFunc) Allow(target Target) bool {
+	return f(target)
+}
+
+// AngleRange only allows targets within the sector from min to max degrees.
+func AngleRange(min, max int) Filter {
+	return FilterFunc(func(target Target) bool {
+		return target.Angle >= min && target.Angle <= max
+	})
+}
+
+// MinSpeed only allows targets moving at least speed KM/H.
+func MinSpeed(speed int) Filter {
+	return FilterFunc(func(target Target) bool {
+		return target.Speed >= speed
+	})
+}
+
+// OnlyDirection only allows targets moving in direction.
+func OnlyDirection(direction Direction) Filter {
+	return FilterFunc(func(target Target) bool {
+		return target.Direction == direction
+	})
+}
+
+// MinSNR only allows targets with an SNR of at least snr.
+func MinSNR(snr int) Filter {
+	return FilterFunc(func(target Target) bool {
+		return target.SNR >= snr
+	})
+}
+
+// MaxRate allows rate targets per second on average and bursts of up to burst
+// targets, dropping the rest, e.g. to keep a LoRa or cellular uplink from
+// being saturated by the full report rate. Time is taken from the targets, so
+// replays are limited like live data. The filter keeps state, use a separate
+// one per sensor and place it after the filters that drop targets anyway.
+func MaxRate(rate float64, burst int) Filter {
+	tokens := float64(burst)
+	var last time.Time
+	return FilterFunc(func(target Target) bool {
+		if !last.IsZero() {
+			tokens = min(tokens+target.Time.Sub(last).Seconds()*rate, float64(burst))
+		}
+		last = target.Time
+		if tokens < 1 {
+			return false
+		}
+		tokens--
+		return true
+	})
+}
+
+// SNRBand is the SNR a target within MaxDistance needs to be delivered.
+type SNRBand struct {
+	MaxDistance int // Farthest distance in meters the band covers, starting where the previous band ends
+	MinSNR      int
+}
+
+// DistanceSNR only allows targets reaching the SNR of the band they are in,
+// e.g. a high threshold for the first few meters to suppress clutter from
+// fences and trees without losing sensitivity at long range. The module has
+// no per distance sensitivity of its own, only the single threshold of
+// AlarmParameters. Bands must be sorted by MaxDistance, targets beyond the
+// last band are always allowed.
+func DistanceSNR(bands ...SNRBand) Filter {
+	return FilterFunc(func(target Target) bool {
+		for _, band := range bands {
+			if target.Distance <= band.MaxDistance {
+				return target.SNR >= band.MinSNR
+			}
+		}
+		return true
+	})
+}
+
+type angleSector struct {
+	min, max int
+}
+
+// SetAngleRange restricts delivered targets to the sector from min to max
+// degrees, e.g. to a single approach lane. The module itself cannot restrict
+// its reporting angle, so targets outside the sector are dropped by the
+// library and counted in Stats.FilteredTargets.
+func (ld2451 *LD2451) SetAngleRange(min, max int) error {
+	if min > max {
+		return fmt.Errorf("angle range %d° to %d° is empty", min, max)
+	}
+	ld2451.filterMu.Lock()
+	ld2451.sector = &angleSector{min: min, max: max}
+	ld2451.filterMu.Unlock()
+	return nil
+}
+
+// ClearAngleRange delivers targets at any angle again.
+func (ld2451 *LD2451) ClearAngleRange() {
+	ld2451.filterMu.Lock()
+	ld2451.sector = nil
+	ld2451.filterMu.Unlock()
+}
+
+// allow applies the angle sector and Config.Filters to target.
+func (ld2451 *LD2451) allow(target Target) bool {
+	ld2451.filterMu.Lock()
+	sector := ld2451.sector
+	ld2451.filterMu.Unlock()
+	if sector != nil && (target.Angle < sector.min || target.Angle > sector.max) {
+		return false
+	}
+	for _, filter := range ld2451.config.Filters {
+		if !filter.Allow(target) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package LD2451
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoData is returned by Open when Config.WaitForFirstFrame passed without
+// a data frame or an answer from the module.
+var ErrNoData = errors.New("no data from the module")
+
+// WithWaitForFirstFrame makes Open wait up to timeout for the module to show
+// it is there, see Config.WaitForFirstFrame.
+func WithWaitForFirstFrame(timeout time.Duration) Option {
+	return func(config *Config) {
+		config.WaitForFirstFrame = timeout
+	}
+}
+
+// waitForFirstFrame waits Config.WaitForFirstFrame for the first data frame.
+// The module only reports while it is out of config mode and may be quiet
+// for other reasons, so when half the time passed without a frame its
+// firmware version is queried as well and an answer counts as evidence too.
+func (ld2451 *LD2451) waitForFirstFrame() error {
+	timeout := ld2451.config.WaitForFirstFrame
+	timer := ld2451.config.Clock.NewTimer(timeout)
+	defer timer.Stop()
+	half := ld2451.config.Clock.NewTimer(timeout / 2)
+	defer half.Stop()
+
+	var query <-chan Result[FirmwareVersion]
+	var commandFrames bool
+	for {
+		select {
+		case <-ld2451.firstFrame:
+			return nil
+		case <-half.C():
+			query = ld2451.FirmwareVersionAsync()
+		case result := <-query:
+			if result.Err == nil {
+				ld2451.config.Logger.Info("module answered but sent no data frame yet", "firmware", result.Value)
+				return nil
+			}
+			//a read-only source or a module stuck in a bad state, keep waiting for frames
+			ld2451.config.Logger.Debug("firmware version query while waiting for the first frame failed", "error", result.Err)
+			query = nil
+		case <-ld2451.commandFrames:
+			commandFrames = true
+		case <-timer.C():
+			err := fmt.Errorf("%w within %s, check the port, baud rate and wiring", ErrNoData, timeout)
+			if commandFrames {
+				err = fmt.Errorf("%w within %s, only command frames arrived, the module may be stuck in config mode, see Config.RecoverConfigMode", ErrNoData, timeout)
+			}
+			return ld2451.wrap("open", err)
+		case <-ld2451.done:
+			return ld2451.fatal
+		}
+	}
+}
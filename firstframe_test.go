@@ -0,0 +1,97 @@
+package LD2451_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+func TestWaitForFirstFrameOnSilentPort(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	sensor.Handle(protocol.CmdReadFirmware, func(sensortest.Command) (uint16, []byte, bool) {
+		return 0, nil, false
+	})
+
+	radar, err := LD2451.Open(sensor.Config(), LD2451.WithWaitForFirstFrame(silence))
+	if err == nil {
+		radar.Close()
+		t.Fatal("Open succeeded on a silent port")
+	}
+	if !errors.Is(err, LD2451.ErrNoData) {
+		t.Fatalf("Open failed with %v, want ErrNoData", err)
+	}
+	probed := false
+	for _, command := range sensor.Commands() {
+		probed = probed || command.Word == protocol.CmdReadFirmware
+	}
+	if !probed {
+		t.Fatal("the firmware version was not queried while waiting")
+	}
+}
+
+func TestWaitForFirstFrameAcceptsAnsweringModule(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	sensor.Handle(protocol.CmdReadFirmware, func(sensortest.Command) (uint16, []byte, bool) {
+		return 0, []byte{0x51, 0x24, 0x01, 0x01, 0x16, 0x04, 0x23, 0x22}, true
+	})
+
+	//the module answers but sends no data frames
+	radar, err := LD2451.Open(sensor.Config(), LD2451.WithWaitForFirstFrame(silence))
+	if err != nil {
+		t.Fatal(err)
+	}
+	radar.Close()
+}
+
+func TestPullerWaitForFirstFrameOnSilentPort(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+
+	puller, err := LD2451.OpenPuller(sensor.Config(), LD2451.WithWaitForFirstFrame(time.Second))
+	if err == nil {
+		puller.Close()
+		t.Fatal("OpenPuller succeeded on a silent port")
+	}
+	if !errors.Is(err, LD2451.ErrNoData) {
+		t.Fatalf("OpenPuller failed with %v, want ErrNoData", err)
+	}
+}
+
+func TestPullerWaitForFirstFrameKeepsTheFrame(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	if err := sensor.SendTargets(false, LD2451.Target{Distance: 12, Speed: 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	puller, err := LD2451.OpenPuller(sensor.Config(), LD2451.WithWaitForFirstFrame(silence))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer puller.Close()
+	frame, err := puller.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frame.Targets) != 1 || frame.Targets[0].Distance != 12 {
+		t.Fatalf("first frame has targets %+v, want one at 12 m", frame.Targets)
+	}
+}
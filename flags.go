@@ -0,0 +1,159 @@
+package LD2451
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// RegisterFlags defines flags on flags for the most common settings, writing
+// into config when they are parsed, so CLIs built on the package share the
+// same names. The current values of config are the defaults. Filter flags
+// append to Config.Filters in the order they are given:
+//
+//	-port /dev/ttyUSB0 -baud 115200 -reconnect
+//	-min-speed 10 -direction toward -angle-range -30:30 -max-rate 5:10
+func (config *Config) RegisterFlags(flags *flag.FlagSet) {
+	flags.StringVar(&config.SerialPort, "port", config.SerialPort, "serial port the sensor is connected to")
+	flags.IntVar(&config.BaudRate, "baud", config.BaudRate, "baud rate configured on the sensor")
+	flags.StringVar(&config.SensorID, "sensor-id", config.SensorID, "ID attached to every target, frame and event of the sensor")
+	flags.BoolVar(&config.Reconnect, "reconnect", config.Reconnect, "keep reopening the serial port after it failed")
+	flags.BoolVar(&config.ReopenAfterBaudChange, "reopen-after-baud-change", config.ReopenAfterBaudChange, "restart the sensor and reopen the port at the new rate after setting the baud rate")
+	flags.BoolVar(&config.DetectProtocol, "detect-protocol", config.DetectProtocol, "query the firmware at startup and decode frames in the layout it uses")
+	flags.Var(&variantValue{config: config}, "frame-variant", "frame layout: v1, extended or record_size[:distance_resolution[:speed_resolution]]")
+	flags.DurationVar(&config.RecoverConfigMode, "recover-config-mode", config.RecoverConfigMode, "end a config mode left open when no frame arrives within this long at startup, 0 disables")
+	flags.DurationVar(&config.WaitForFirstFrame, "wait-first-frame", config.WaitForFirstFrame, "fail at startup unless the sensor sends data or answers within this long, 0 doesn't wait")
+	flags.IntVar(&config.TargetBufferSize, "buffer", config.TargetBufferSize, "number of targets buffered for slow readers")
+	flags.DurationVar(&config.MaxTargetAge, "max-target-age", config.MaxTargetAge, "discard buffered targets older than this, 0 keeps all")
+	flags.DurationVar(&config.OpenRetryTimeout, "open-retry", config.OpenRetryTimeout, "keep retrying to open the port for this long")
+	flags.TextVar(&config.SpeedSmoothing, "smoothing", config.SpeedSmoothing, "speed smoothing: none, movingaverage or exponential")
+	flags.IntVar(&config.SmoothingWindow, "smoothing-window", config.SmoothingWindow, "frames averaged by movingaverage smoothing")
+	flags.Float64Var(&config.SmoothingFactor, "smoothing-factor", config.SmoothingFactor, "weight of the newest speed for exponential smoothing")
+	flags.IntVar(&config.MinFrames, "min-frames", config.MinFrames, "only deliver targets seen in this many consecutive frames")
+	flags.DurationVar(&config.ReportInterval, "report-interval", config.ReportInterval, "deliver the targets of at most one frame per interval")
+	flags.BoolVar(&config.InvertAngle, "invert-angle", config.InvertAngle, "negate angles, e.g. for a sensor mounted upside down")
+	flags.BoolVar(&config.CosineCorrection, "cosine-correction", config.CosineCorrection, "correct speeds for the angle between the line of sight and the road")
+	flags.DurationVar(&config.SinkFlushInterval, "sink-flush", config.SinkFlushInterval, "how often sinks are flushed")
+	flags.DurationVar(&config.SummaryInterval, "summary-interval", config.SummaryInterval, "deliver a summary this often, 0 disables summaries")
+
+	config.filterFlag(flags, "adaptive-snr", "learn the noise floor per distance and require this SNR margin above it where clutter is, give it first", func(value string) (Filter, error) {
+		margin, err := strconv.Atoi(value)
+		return AdaptiveSNR(NoiseProfile{Margin: margin}), err
+	})
+	config.filterFlag(flags, "min-speed", "only deliver targets moving at least this many km/h", func(value string) (Filter, error) {
+		speed, err := strconv.Atoi(value)
+		return MinSpeed(speed), err
+	})
+	config.filterFlag(flags, "min-snr", "only deliver targets with at least this SNR", func(value string) (Filter, error) {
+		snr, err := strconv.Atoi(value)
+		return MinSNR(snr), err
+	})
+	config.filterFlag(flags, "direction", "only deliver targets moving away or toward", func(value string) (Filter, error) {
+		var direction Direction
+		err := direction.UnmarshalText([]byte(value))
+		return OnlyDirection(direction), err
+	})
+	config.filterFlag(flags, "angle-range", "only deliver targets within min:max degrees", func(value string) (Filter, error) {
+		lo, hi, err := flagPair(value)
+		if err == nil && lo > hi {
+			err = fmt.Errorf("angle range %d to %d is empty", lo, hi)
+		}
+		return AngleRange(lo, hi), err
+	})
+	config.filterFlag(flags, "max-rate", "deliver at most rate[:burst] targets per second, give it last", func(value string) (Filter, error) {
+		rateText, burstText, hasBurst := strings.Cut(value, ":")
+		rate, err := strconv.ParseFloat(rateText, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("rate %q is not a positive number", rateText)
+		}
+		burst := max(int(rate), 1)
+		if hasBurst {
+			if burst, err = strconv.Atoi(burstText); err != nil || burst < 1 {
+				return nil, fmt.Errorf("burst %q is not a positive number", burstText)
+			}
+		}
+		return MaxRate(rate, burst), nil
+	})
+}
+
+// filterFlag defines a flag appending the filter parse returns to
+// Config.Filters every time it is set.
+func (config *Config) filterFlag(flags *flag.FlagSet, name string, usage string, parse func(value string) (Filter, error)) {
+	flags.Var(&filterValue{config: config, parse: parse}, name, usage)
+}
+
+type filterValue struct {
+	config *Config
+	parse  func(value string) (Filter, error)
+	value  string //last value set, so flags can be replayed onto another Config
+}
+
+func (v *filterValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *filterValue) Set(value string) error {
+	filter, err := v.parse(value)
+	if err != nil {
+		return err
+	}
+	v.config.Filters = append(v.config.Filters, filter)
+	v.value = value
+	return nil
+}
+
+// variantValue sets Config.FrameVariant from the name of a variant or the
+// fields of a layout of its own.
+type variantValue struct {
+	config *Config
+}
+
+func (v *variantValue) String() string {
+	if v == nil || v.config == nil || v.config.FrameVariant == nil {
+		return ""
+	}
+	return v.config.FrameVariant.Name
+}
+
+func (v *variantValue) Set(value string) error {
+	if variant, ok := protocol.LookupVariant(value); ok {
+		v.config.FrameVariant = &variant
+		return nil
+	}
+	fields := strings.Split(value, ":")
+	if len(fields) > 3 {
+		return fmt.Errorf("%q is not of the form record_size[:distance_resolution[:speed_resolution]]", value)
+	}
+	variant := protocol.Variant{Name: "custom"}
+	var err error
+	if variant.RecordSize, err = strconv.Atoi(fields[0]); err != nil {
+		return fmt.Errorf("%q is neither a variant name nor a record size", fields[0])
+	}
+	resolutions := []*float64{&variant.DistanceResolution, &variant.SpeedResolution}
+	for i, field := range fields[1:] {
+		if *resolutions[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return fmt.Errorf("resolution %q is not a number", field)
+		}
+	}
+	v.config.FrameVariant = &variant
+	return nil
+}
+
+func flagPair(value string) (int, int, error) {
+	a, b, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not of the form min:max", value)
+	}
+	lo, err := strconv.Atoi(a)
+	if err != nil {
+		return 0, 0, err
+	}
+	hi, err := strconv.Atoi(b)
+	return lo, hi, err
+}
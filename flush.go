@@ -0,0 +1,59 @@
+package LD2451
+
+import (
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// Flush discards the bytes received but not decoded yet and the targets
+// buffered for ReadTarget, so nothing that arrived before the call is
+// delivered afterwards, e.g. before commands or after a reconnect. A frame
+// that was still arriving is discarded as well. Targets already handed to subscribers, events or sinks are not taken back.
+func (ld2451 *LD2451) Flush() error {
+	ld2451.flushMu.Lock()
+	ld2451.flushedAt = ld2451.now()
+	ld2451.flushPending = true
+	ld2451.flushMu.Unlock()
+
+	ld2451.writeMu.Lock()
+	var err error
+	if flusher, ok := ld2451.port.(transport.Flusher); ok {
+		err = flusher.Flush()
+	}
+	ld2451.writeMu.Unlock()
+
+	dropped := ld2451.drainTargets()
+	ld2451.recordFlushedTargets(dropped)
+	ld2451.config.Logger.Debug("flushed", "targets", dropped)
+	return ld2451.wrap("Flush", err)
+}
+
+// drainTargets empties the target buffer and returns how many were in it.
+func (ld2451 *LD2451) drainTargets() int {
+	for n := 0; ; n++ {
+		select {
+		case <-ld2451.targets:
+		default:
+			return n
+		}
+	}
+}
+
+// takeFlush reports whether Flush was called since the last call, so the
+// read goroutine drops what its reader buffered.
+func (ld2451 *LD2451) takeFlush() bool {
+	ld2451.flushMu.Lock()
+	defer ld2451.flushMu.Unlock()
+	pending := ld2451.flushPending
+	ld2451.flushPending = false
+	return pending
+}
+
+// flushedBefore reports whether Flush was called after a frame started
+// arriving at start.
+func (ld2451 *LD2451) flushedBefore(start time.Time) bool {
+	ld2451.flushMu.Lock()
+	defer ld2451.flushMu.Unlock()
+	return start.Before(ld2451.flushedAt)
+}
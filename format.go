@@ -0,0 +1,17 @@
+package LD2451
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteTable writes targets to w as an aligned table with one row per target.
+func WriteTable(w io.Writer, targets []Target) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "DISTANCE\tSPEED\tDIRECTION\tANGLE\tSNR\t")
+	for _, target := range targets {
+		fmt.Fprintf(tw, "%d m\t%d km/h\t%s\t%d°\t%d\t\n", target.Distance, target.Speed, target.Direction, target.Angle, target.SNR)
+	}
+	return tw.Flush()
+}
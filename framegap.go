@@ -0,0 +1,51 @@
+package LD2451
+
+import "time"
+
+// framePeriodSamples is the number of intervals averaged into the measured
+// frame period before gaps are reported.
+const framePeriodSamples = 10
+
+// FrameGap reports frames arriving further apart than Config.FrameGapFactor
+// times the frame period, e.g. because of RF interference or bytes lost on
+// the serial link.
+type FrameGap struct {
+	Duration time.Duration `json:"duration"`         // Time between the frames around the gap
+	Expected time.Duration `json:"expected"`         // Frame period the gap was compared to
+	Time     time.Time     `json:"time"`             // When the frame ending the gap arrived
+	Sensor   string        `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+func (e FrameGap) EventTime() time.Time { return e.Time }
+
+// checkFrameGap measures the interval to the previous frame and publishes a
+// FrameGap when it is too long. The caller must hold statsMu.
+func (ld2451 *LD2451) checkFrameGap(now time.Time) {
+	previous := ld2451.frameExpected
+	ld2451.frameExpected = now
+	if ld2451.config.FrameGapFactor <= 0 || previous.IsZero() {
+		return
+	}
+	interval := now.Sub(previous)
+
+	period := ld2451.config.FramePeriod
+	if period <= 0 {
+		if ld2451.periodSamples < framePeriodSamples {
+			//average the first intervals evenly, then follow slow drift
+			ld2451.periodSamples++
+			ld2451.framePeriod += (interval - ld2451.framePeriod) / time.Duration(ld2451.periodSamples)
+			return
+		}
+		period = ld2451.framePeriod
+	}
+
+	if float64(interval) <= ld2451.config.FrameGapFactor*float64(period) {
+		if ld2451.config.FramePeriod <= 0 {
+			ld2451.framePeriod += (interval - ld2451.framePeriod) / framePeriodSamples
+		}
+		return
+	}
+	ld2451.stats.FrameGaps++
+	ld2451.config.Logger.Warn("frame gap", "duration", interval, "expected", period)
+	ld2451.publish(FrameGap{Duration: interval, Expected: period, Time: now, Sensor: ld2451.config.SensorID})
+}
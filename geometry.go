@@ -0,0 +1,102 @@
+package LD2451
+
+import "math"
+
+// AngleSign selects which side of the antenna perpendicular positive angles
+// are on, seen from behind the sensor looking out.
+type AngleSign int
+
+const (
+	AnglePositiveRight AngleSign = 0 // The module's own convention
+	AnglePositiveLeft  AngleSign = 1
+)
+
+func (s AngleSign) String() string {
+	switch s {
+	case AnglePositiveRight:
+		return "PositiveRight"
+	case AnglePositiveLeft:
+		return "PositiveLeft"
+	default:
+		return "Unknown"
+	}
+}
+
+// orientAngle applies Config.AngleSign and Config.InvertAngle to an angle
+// reported by the module.
+func (ld2451 *LD2451) orientAngle(angle int) int {
+	if (ld2451.config.AngleSign == AnglePositiveLeft) != ld2451.config.InvertAngle {
+		return -angle
+	}
+	return angle
+}
+
+// Mounting describes where the sensor is installed relative to the road it
+// watches. The module reports the slant range from the antenna, which on a
+// pole differs noticeably from the distance along the road for nearby targets.
+type Mounting struct {
+	Height        float64 // Height of the antenna above the road in meters
+	LateralOffset float64 // Distance in meters across the road to the watched lane, negative on the side of negative angles, zero to locate targets by their angle instead
+	Tilt          float64 // Degrees the antenna perpendicular is turned away from the road direction, positive towards positive angles, unused with LateralOffset
+}
+
+// Correct converts the distance of target into the distance along the road
+// and its angle into the angle relative to the road direction. Speed is left
+// as reported, see Config.CosineCorrection.
+func (m Mounting) Correct(target Target) Target {
+	along, heading := m.locate(target)
+	target.Distance = int(math.Round(along))
+	if target.Fine {
+		target.FineDistance = along
+	}
+	target.Angle = int(math.Round(heading))
+	return target
+}
+
+// CosineFactor returns the cosine of the angle between the line of sight to
+// target and the road, the share of the speed along the road the radar
+// measures. target must be as reported, before Correct.
+func (m Mounting) CosineFactor(target Target) float64 {
+	slant := target.PreciseDistance()
+	if slant <= 0 {
+		return 1
+	}
+	along, _ := m.locate(target)
+	return along / slant
+}
+
+// locate returns the distance of target along the road and its angle
+// relative to the road direction.
+func (m Mounting) locate(target Target) (along, heading float64) {
+	slant := target.PreciseDistance()
+	ground := math.Sqrt(max(slant*slant-m.Height*m.Height, 0))
+	if m.LateralOffset != 0 {
+		//the lane is known, which is more accurate than the coarse angle
+		along = math.Sqrt(max(ground*ground-m.LateralOffset*m.LateralOffset, 0))
+		return along, math.Atan2(m.LateralOffset, along) * 180 / math.Pi
+	}
+	heading = float64(target.Angle) + m.Tilt
+	return ground * math.Cos(heading*math.Pi/180), heading
+}
+
+// maxCosineAngle limits the cosine correction. Further off the road the
+// factor approaches zero, so the coarse angle of the module would inflate
+// speeds without bound; targets beyond it are at most doubled.
+const maxCosineAngle = 60
+
+// correctCosine turns the radial speed of target into the speed along the
+// road, see Config.CosineCorrection.
+func (ld2451 *LD2451) correctCosine(target Target) Target {
+	factor := math.Cos(float64(target.Angle) * math.Pi / 180)
+	if ld2451.config.Mounting != nil {
+		factor = ld2451.config.Mounting.CosineFactor(target)
+	}
+	factor = max(factor, math.Cos(maxCosineAngle*math.Pi/180))
+
+	target.RadialSpeed = target.PreciseSpeed()
+	target.Speed = int(math.Round(target.RadialSpeed / factor))
+	if target.Fine {
+		target.FineSpeed = target.RadialSpeed / factor
+	}
+	return target
+}
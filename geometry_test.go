@@ -0,0 +1,89 @@
+package LD2451_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestMountingCorrect(t *testing.T) {
+	tests := []struct {
+		name     string
+		mounting LD2451.Mounting
+		target   LD2451.Target
+		distance int
+		angle    int
+		factor   float64
+	}{
+		{"on the road", LD2451.Mounting{}, LD2451.Target{Distance: 20, Angle: 0}, 20, 0, 1},
+		{"off axis", LD2451.Mounting{}, LD2451.Target{Distance: 20, Angle: 60}, 10, 60, 0.5},
+		{"on a pole", LD2451.Mounting{Height: 6}, LD2451.Target{Distance: 10}, 8, 0, 0.8},
+		{"below the antenna", LD2451.Mounting{Height: 6}, LD2451.Target{Distance: 5}, 0, 0, 0},
+		{"tilted", LD2451.Mounting{Tilt: 10}, LD2451.Target{Distance: 20, Angle: -10}, 20, 0, 1},
+		{"tilted the other way", LD2451.Mounting{Tilt: -30}, LD2451.Target{Distance: 20, Angle: -30}, 10, -60, 0.5},
+		//the lane replaces the angle, which the tilt doesn't apply to then
+		{"known lane", LD2451.Mounting{Height: 6, LateralOffset: 3, Tilt: 20}, LD2451.Target{Distance: 10, Angle: 40}, 7, 22, math.Sqrt(55) / 10},
+		{"lane on the negative side", LD2451.Mounting{LateralOffset: -6}, LD2451.Target{Distance: 10}, 8, -37, 0.8},
+		{"nearer than the lane", LD2451.Mounting{LateralOffset: 6}, LD2451.Target{Distance: 5}, 0, 90, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			corrected := test.mounting.Correct(test.target)
+			if corrected.Distance != test.distance || corrected.Angle != test.angle {
+				t.Errorf("corrected to %d m at %d°, expected %d m at %d°", corrected.Distance, corrected.Angle, test.distance, test.angle)
+			}
+			if corrected.Speed != test.target.Speed {
+				t.Errorf("speed changed to %d", corrected.Speed)
+			}
+			if factor := test.mounting.CosineFactor(test.target); math.Abs(factor-test.factor) > 1e-9 {
+				t.Errorf("cosine factor %g, expected %g", factor, test.factor)
+			}
+		})
+	}
+}
+
+func TestMountingCorrectFine(t *testing.T) {
+	target := LD2451.Target{Distance: 10, Fine: true, FineDistance: 10.4, FineSpeed: 30.5}
+	corrected := LD2451.Mounting{Height: 6}.Correct(target)
+	along := math.Sqrt(10.4*10.4 - 36)
+	if math.Abs(corrected.FineDistance-along) > 1e-9 || corrected.Distance != 8 {
+		t.Errorf("corrected to %d m, fine %g m; expected fine %g m", corrected.Distance, corrected.FineDistance, along)
+	}
+}
+
+func TestCosineCorrection(t *testing.T) {
+	tests := []struct {
+		name     string
+		mounting *LD2451.Mounting
+		target   LD2451.Target
+		speed    int
+		distance int
+	}{
+		{"on axis", nil, LD2451.Target{Distance: 20, Angle: 0, Speed: 40}, 40, 20},
+		{"off axis", nil, LD2451.Target{Distance: 20, Angle: 60, Speed: 40}, 80, 20},
+		{"beyond the limit", nil, LD2451.Target{Distance: 20, Angle: 80, Speed: 40}, 80, 20},
+		{"negative angle", nil, LD2451.Target{Distance: 20, Angle: -60, Speed: 25}, 50, 20},
+		{"by the mounting", &LD2451.Mounting{Height: 6}, LD2451.Target{Distance: 10, Speed: 40}, 50, 8},
+		{"below the antenna", &LD2451.Mounting{Height: 6}, LD2451.Target{Distance: 5, Speed: 40}, 80, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.target.Direction, test.target.SNR = LD2451.DirectionToward, 10
+			targets := readThrough(t, LD2451.Config{CosineCorrection: true, Mounting: test.mounting}, []LD2451.Target{test.target})
+			if len(targets) != 1 {
+				t.Fatalf("got %d targets, expected 1", len(targets))
+			}
+			target := targets[0]
+			if target.Speed != test.speed || target.RadialSpeed != float64(test.target.Speed) || target.Distance != test.distance {
+				t.Errorf("got %d KM/H, radial %g KM/H at %d m; expected %d KM/H, radial %d KM/H at %d m",
+					target.Speed, target.RadialSpeed, target.Distance, test.speed, test.target.Speed, test.distance)
+			}
+		})
+	}
+
+	targets := readThrough(t, LD2451.Config{}, []LD2451.Target{{Distance: 20, Angle: 60, Direction: LD2451.DirectionToward, Speed: 40, SNR: 10}})
+	if len(targets) != 1 || targets[0].Speed != 40 || targets[0].RadialSpeed != 0 {
+		t.Errorf("corrected without CosineCorrection: %+v", targets)
+	}
+}
@@ -1,8 +1,14 @@
-module github.com/Battlekeeper/LD2451
+module github.com/Battlekeeper/LD2451/v2
 
 go 1.23.1
 
 require (
-	github.com/tarm/serial v0.0.0-20180830185346-98f6abe2eb07 // indirect
-	golang.org/x/sys v0.28.0 // indirect
+	github.com/tarm/serial v0.0.0-20180830185346-98f6abe2eb07
+	periph.io/x/conn/v3 v3.7.2
+)
+
+require (
+	github.com/BurntSushi/toml v1.4.0
+	golang.org/x/sys v0.28.0
+	gopkg.in/yaml.v3 v3.0.1
 )
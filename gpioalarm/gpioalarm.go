@@ -0,0 +1,131 @@
+// Package gpioalarm connects the LD2451 alarm to GPIO pins using periph.io: it
+// can follow the module's hardware alarm output and drive a relay from alarm
+// events, for installations on boards such as the Raspberry Pi.
+package gpioalarm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"periph.io/x/conn/v3/gpio"
+)
+
+// edgeTimeout bounds how long the pin watcher blocks before checking its context.
+const edgeTimeout = 100 * time.Millisecond
+
+type PinConfig struct {
+	SensorID string       // Set as AlarmEvent.Sensor, the Config.SensorID of the sensor the pin is wired to
+	Clock    LD2451.Clock // Source of the event times, the one of the sensor; nil uses LD2451.SystemClock
+}
+
+// WatchPin follows the alarm output of the module wired to pin and delivers an
+// event for the initial level and every change after it. The channel is
+// closed when ctx is done.
+func WatchPin(ctx context.Context, pin gpio.PinIn, config PinConfig) (<-chan LD2451.AlarmEvent, error) {
+	if err := pin.In(gpio.PullDown, gpio.BothEdges); err != nil {
+		return nil, err
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+
+	events := make(chan LD2451.AlarmEvent, 1)
+	go func() {
+		defer close(events)
+		level := pin.Read()
+		if !send(ctx, events, level, config) {
+			return
+		}
+		for ctx.Err() == nil {
+			if !pin.WaitForEdge(edgeTimeout) {
+				continue
+			}
+			//edges can bounce, only report actual level changes
+			if current := pin.Read(); current != level {
+				level = current
+				if !send(ctx, events, level, config) {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Merge combines the alarm events reported in the data frames of sensor with
+// the events read from its alarm pin into a single stream. The channel is
+// closed when ctx is done.
+func Merge(ctx context.Context, sensor *LD2451.LD2451, pin gpio.PinIn, config PinConfig) (<-chan LD2451.AlarmEvent, error) {
+	pinEvents, err := WatchPin(ctx, pin, config)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(chan LD2451.AlarmEvent, 1)
+	var wg sync.WaitGroup
+	forward := func(events <-chan LD2451.AlarmEvent) {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				select {
+				case merged <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+	wg.Add(2)
+	go forward(sensor.Alarms())
+	go forward(pinEvents)
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+	return merged, nil
+}
+
+// DriveRelay sets pin high while the alarm is active and low otherwise, until
+// events is closed or ctx is done. The pin is left low when DriveRelay returns.
+func DriveRelay(ctx context.Context, events <-chan LD2451.AlarmEvent, pin gpio.PinOut) error {
+	if err := pin.Out(gpio.Low); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			pin.Out(gpio.Low)
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return pin.Out(gpio.Low)
+			}
+			if err := pin.Out(gpio.Level(event.Active)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func send(ctx context.Context, events chan<- LD2451.AlarmEvent, level gpio.Level, config PinConfig) bool {
+	event := LD2451.AlarmEvent{
+		Active: bool(level),
+		Source: LD2451.AlarmSourcePin,
+		Time:   config.Clock.Now(),
+		Sensor: config.SensorID,
+	}
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
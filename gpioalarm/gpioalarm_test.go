@@ -0,0 +1,88 @@
+package gpioalarm_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/gpioalarm"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+	"periph.io/x/conn/v3/gpio"
+)
+
+// pin is an input whose level is changed by set, like the alarm output of a
+// module.
+type pin struct {
+	mu    sync.Mutex
+	level gpio.Level
+	edges chan struct{}
+}
+
+func (p *pin) String() string                          { return "ALARM" }
+func (p *pin) Halt() error                             { return nil }
+func (p *pin) Name() string                            { return "ALARM" }
+func (p *pin) Number() int                             { return 17 }
+func (p *pin) Function() string                        { return "In/PullDown" }
+func (p *pin) In(pull gpio.Pull, edge gpio.Edge) error { return nil }
+func (p *pin) Pull() gpio.Pull                         { return gpio.PullDown }
+func (p *pin) DefaultPull() gpio.Pull                  { return gpio.PullDown }
+
+func (p *pin) Read() gpio.Level {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.level
+}
+
+func (p *pin) WaitForEdge(timeout time.Duration) bool {
+	select {
+	case <-p.edges:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (p *pin) set(level gpio.Level) {
+	p.mu.Lock()
+	p.level = level
+	p.mu.Unlock()
+	p.edges <- struct{}{}
+}
+
+func TestWatchPin(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	clock := sensortest.NewClock(start)
+	alarm := &pin{edges: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := gpioalarm.WatchPin(ctx, alarm, gpioalarm.PinConfig{SensorID: "north", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(active bool, at time.Time) {
+		t.Helper()
+		select {
+		case event := <-events:
+			if event.Active != active || event.Source != LD2451.AlarmSourcePin || event.Sensor != "north" || !event.Time.Equal(at) {
+				t.Fatalf("got %+v, expected active %t at %s", event, active, at)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event")
+		}
+	}
+	expect(false, start)
+	clock.Advance(time.Second)
+	alarm.set(gpio.High)
+	expect(true, start.Add(time.Second))
+	//a bouncing edge without a change of level is no event
+	alarm.set(gpio.High)
+	clock.Advance(time.Second)
+	alarm.set(gpio.Low)
+	expect(false, start.Add(2*time.Second))
+
+	cancel()
+	for range events {
+	}
+}
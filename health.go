@@ -0,0 +1,40 @@
+package LD2451
+
+import "time"
+
+type Health struct {
+	State           SensorState   `json:"state"`
+	SinceLastFrame  time.Duration `json:"since_last_frame"`  // Time since the last valid frame, or since Open if none arrived yet
+	SinceLastTarget time.Duration `json:"since_last_target"` // Time since the last target, or since Open if none was seen yet
+	LastFrame       time.Time     `json:"last_frame"`        // Zero if no valid frame was received yet
+	LastTarget      time.Time     `json:"last_target"`       // Zero if no target was received yet
+	ReadErrors      uint64        `json:"read_errors"`
+	ParseErrors     uint64        `json:"parse_errors"`
+	Resyncs         uint64        `json:"resyncs"`
+}
+
+// Health reports how recently the sensor produced data together with the
+// connection state and error counters, for supervisors deciding whether the
+// sensor needs to be restarted.
+func (ld2451 *LD2451) Health() Health {
+	ld2451.statsMu.Lock()
+	defer ld2451.statsMu.Unlock()
+
+	now := ld2451.now()
+	since := func(t time.Time) time.Duration {
+		if t.IsZero() {
+			return now.Sub(ld2451.opened)
+		}
+		return now.Sub(t)
+	}
+	return Health{
+		State:           ld2451.state,
+		SinceLastFrame:  since(ld2451.lastFrame),
+		SinceLastTarget: since(ld2451.lastTarget),
+		LastFrame:       ld2451.lastFrame,
+		LastTarget:      ld2451.lastTarget,
+		ReadErrors:      ld2451.stats.ReadErrors,
+		ParseErrors:     ld2451.stats.ParseErrors,
+		Resyncs:         ld2451.stats.Resyncs,
+	}
+}
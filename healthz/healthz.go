@@ -0,0 +1,114 @@
+// Package healthz serves liveness and readiness endpoints for a sensor, for
+// the probes of Kubernetes, a systemd ExecStartPost check or a load balancer:
+//
+//	checks := healthz.New(sensor, healthz.Config{})
+//	mux.Handle("/healthz", checks.Liveness())
+//	mux.Handle("/readyz", checks.Readiness())
+//
+// Both answer 200 when the check passes and 503 otherwise, with the sensor's
+// health as a JSON body either way.
+package healthz
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	DefaultMaxFrameAge = 3 * time.Second
+	DefaultStaleAfter  = 30 * time.Second
+)
+
+// HealthReporter is implemented by *LD2451.LD2451.
+type HealthReporter interface {
+	Health() LD2451.Health
+}
+
+type Config struct {
+	MaxFrameAge time.Duration // Readiness fails when the last valid frame is older (default DefaultMaxFrameAge)
+	StaleAfter  time.Duration // Liveness fails when the last valid frame is older, so the service gets restarted (default DefaultStaleAfter)
+}
+
+// Status is the JSON body of both endpoints.
+type Status struct {
+	OK        bool          `json:"ok"`
+	Reason    string        `json:"reason,omitempty"` // Why the check failed
+	Connected bool          `json:"connected"`        // The serial port is open and being read
+	Health    LD2451.Health `json:"health"`
+}
+
+type Checks struct {
+	sensor HealthReporter
+	config Config
+}
+
+func New(sensor HealthReporter, config Config) *Checks {
+	if config.MaxFrameAge <= 0 {
+		config.MaxFrameAge = DefaultMaxFrameAge
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = DefaultStaleAfter
+	}
+	return &Checks{sensor: sensor, config: config}
+}
+
+// Liveness fails only when restarting the service could help: the sensor was
+// closed, or no valid frame arrived for Config.StaleAfter. A port waiting to
+// come back with Config.Reconnect and a module in standby stay alive.
+func (c *Checks) Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := c.sensor.Health()
+		status := status(health)
+		switch {
+		case health.State == LD2451.StateClosed:
+			status.Reason = "the sensor was closed"
+		case health.State != LD2451.StateStandby && health.SinceLastFrame > c.config.StaleAfter:
+			status.Reason = fmt.Sprintf("no valid frame for %s", health.SinceLastFrame.Round(time.Millisecond))
+		}
+		respond(w, status)
+	})
+}
+
+// Readiness passes while targets are being reported: the port is connected,
+// no configuration session is running and the last valid frame is younger
+// than Config.MaxFrameAge.
+func (c *Checks) Readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := c.sensor.Health()
+		status := status(health)
+		switch {
+		case !status.Connected:
+			status.Reason = "the serial port is not connected"
+		case health.State != LD2451.StateReporting:
+			status.Reason = fmt.Sprintf("the sensor is %s", health.State)
+		case health.SinceLastFrame > c.config.MaxFrameAge:
+			status.Reason = fmt.Sprintf("no valid frame for %s", health.SinceLastFrame.Round(time.Millisecond))
+		}
+		respond(w, status)
+	})
+}
+
+// Register adds both handlers to mux at /healthz and /readyz.
+func (c *Checks) Register(mux *http.ServeMux) {
+	mux.Handle("/healthz", c.Liveness())
+	mux.Handle("/readyz", c.Readiness())
+}
+
+func status(health LD2451.Health) Status {
+	connected := health.State != LD2451.StateDisconnected && health.State != LD2451.StateClosed
+	return Status{Connected: connected, Health: health}
+}
+
+func respond(w http.ResponseWriter, status Status) {
+	status.OK = status.Reason == ""
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !status.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
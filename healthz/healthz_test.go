@@ -0,0 +1,84 @@
+package healthz_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/healthz"
+)
+
+// sensor reports a fixed health.
+type sensor LD2451.Health
+
+func (s sensor) Health() LD2451.Health { return LD2451.Health(s) }
+
+// status is the body of a check, with the state as the text it is encoded as.
+type status struct {
+	OK        bool   `json:"ok"`
+	Reason    string `json:"reason"`
+	Connected bool   `json:"connected"`
+	Health    struct {
+		State string `json:"state"`
+	} `json:"health"`
+}
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		health    LD2451.Health
+		live      bool
+		ready     bool
+		connected bool
+	}{
+		{"reporting", LD2451.Health{State: LD2451.StateReporting, SinceLastFrame: 100 * time.Millisecond}, true, true, true},
+		{"connecting", LD2451.Health{State: LD2451.StateConnecting}, true, false, true},
+		{"configuring", LD2451.Health{State: LD2451.StateConfiguring, SinceLastFrame: time.Second}, true, false, true},
+		{"frames late", LD2451.Health{State: LD2451.StateReporting, SinceLastFrame: 5 * time.Second}, true, false, true},
+		{"degraded", LD2451.Health{State: LD2451.StateDegraded, SinceLastFrame: 10 * time.Second}, true, false, true},
+		{"stale", LD2451.Health{State: LD2451.StateDegraded, SinceLastFrame: time.Minute}, false, false, true},
+		{"waiting to reconnect", LD2451.Health{State: LD2451.StateDisconnected, SinceLastFrame: 10 * time.Second}, true, false, false},
+		{"in standby", LD2451.Health{State: LD2451.StateStandby, SinceLastFrame: time.Hour}, true, false, true},
+		{"closed", LD2451.Health{State: LD2451.StateClosed}, false, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			healthz.New(sensor(test.health), healthz.Config{}).Register(mux)
+			for path, ok := range map[string]bool{"/healthz": test.live, "/readyz": test.ready} {
+				response := httptest.NewRecorder()
+				mux.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+				var status status
+				if err := json.Unmarshal(response.Body.Bytes(), &status); err != nil {
+					t.Fatal(err)
+				}
+				expectedCode := http.StatusOK
+				if !ok {
+					expectedCode = http.StatusServiceUnavailable
+				}
+				if response.Code != expectedCode || status.OK != ok || (status.Reason == "") != ok {
+					t.Errorf("%s answered %d with %+v", path, response.Code, status)
+				}
+				if status.Connected != test.connected || status.Health.State != strings.ToLower(test.health.State.String()) {
+					t.Errorf("%s reported %+v", path, status)
+				}
+			}
+		})
+	}
+}
+
+func TestConfiguredAges(t *testing.T) {
+	health := LD2451.Health{State: LD2451.StateReporting, SinceLastFrame: 2 * time.Second}
+	checks := healthz.New(sensor(health), healthz.Config{MaxFrameAge: time.Second, StaleAfter: time.Second})
+	for _, handler := range []http.Handler{checks.Liveness(), checks.Readiness()} {
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+		if response.Code != http.StatusServiceUnavailable {
+			t.Errorf("answered %d past the configured age", response.Code)
+		}
+	}
+}
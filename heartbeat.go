@@ -0,0 +1,29 @@
+package LD2451
+
+import "time"
+
+// Heartbeat is delivered for frames that report no targets, showing that the
+// sensor is alive while nothing is in its field of view.
+type Heartbeat struct {
+	Time   time.Time `json:"time"`             // When the empty frame was received
+	Sensor string    `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+// Heartbeats returns the channel heartbeats are delivered on when
+// Config.Heartbeats is set. Heartbeats are dropped rather than stalling the
+// reader when nobody keeps up with the channel.
+func (ld2451 *LD2451) Heartbeats() <-chan Heartbeat {
+	return ld2451.beats
+}
+
+func (ld2451 *LD2451) heartbeat() {
+	if !ld2451.config.Heartbeats {
+		return
+	}
+	beat := Heartbeat{Time: ld2451.now(), Sensor: ld2451.config.SensorID}
+	ld2451.publish(beat)
+	select {
+	case ld2451.beats <- beat:
+	default:
+	}
+}
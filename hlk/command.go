@@ -0,0 +1,108 @@
+package hlk
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+const commandTimeout = time.Second
+
+var ErrTimeout = errors.New("timeout")
+
+// CommandError is returned when a module acknowledges a command with a
+// failure status.
+type CommandError struct {
+	Command uint16
+	Status  uint16
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("module rejected command 0x%04x with status %d", e.Command, e.Status)
+}
+
+// Commander writes commands to a module and waits for their
+// acknowledgements, which whoever reads the frames of the module hands over
+// with Deliver. It doesn't serialize commands itself, its callers run one
+// command at a time.
+type Commander struct {
+	write func([]byte) error
+	clock Clock
+	done  <-chan struct{}
+	err   func() error
+	acks  chan protocol.Ack
+}
+
+// NewCommander writes commands with write and times them out on clock.
+// Waiting stops once done is closed, failing with what err returns then.
+func NewCommander(write func([]byte) error, clock Clock, done <-chan struct{}, err func() error) *Commander {
+	return &Commander{
+		write: write,
+		clock: clock,
+		done:  done,
+		err:   err,
+		acks:  make(chan protocol.Ack, 1),
+	}
+}
+
+// Deliver hands an acknowledgement read from the module to the waiting
+// command. It never blocks, acknowledgements nobody waits for are dropped.
+func (c *Commander) Deliver(ack protocol.Ack) {
+	select {
+	case c.acks <- ack:
+	default:
+	}
+}
+
+// Exchange sends a single command and returns the data of its
+// acknowledgement following the status word.
+func (c *Commander) Exchange(word uint16, value []byte) ([]byte, error) {
+	//drop acknowledgements nobody waited for
+	select {
+	case <-c.acks:
+	default:
+	}
+
+	if err := c.write(protocol.EncodeCommand(word, value)); err != nil {
+		return nil, fmt.Errorf("write %s: %w", protocol.CommandName(word), err)
+	}
+
+	timeout := c.clock.NewTimer(commandTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case ack := <-c.acks:
+			if ack.Word != word {
+				//acknowledgement for something else, keep waiting
+				continue
+			}
+			if ack.Status != 0 {
+				return nil, &CommandError{Command: word, Status: ack.Status}
+			}
+			return ack.Data, nil
+		case <-timeout.C():
+			return nil, fmt.Errorf("read ack for %s: %w", protocol.CommandName(word), ErrTimeout)
+		case <-c.done:
+			return nil, c.err()
+		}
+	}
+}
+
+// Session runs fn in config mode, entered and left with command, and passes
+// it the reply to the enable config command. Config mode is left again even
+// if fn fails.
+func Session(command func(word uint16, value []byte) ([]byte, error), fn func(info []byte) error) error {
+	info, err := command(protocol.CmdEnableConfig, []byte{0x01, 0x00})
+	if err != nil {
+		return err
+	}
+	err = fn(info)
+	//always try to leave config mode, otherwise the module stops reporting targets
+	_, endErr := command(protocol.CmdEndConfig, nil)
+	if err != nil {
+		return err
+	}
+	return endErr
+}
@@ -0,0 +1,144 @@
+// Package hlk is the core shared by the radar modules of the HLK family, such
+// as the LD2410, LD2450 and LD2451. They use the same command framing and
+// configuration sessions and only differ in how their data frames are
+// delimited and what those frames contain, so a module package only needs to
+// supply a protocol.Framing and a parser for its payloads. The LD2451 package
+// reads frames itself and only builds on Commander and Session.
+package hlk
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+var ErrClosed = errors.New("device closed")
+
+// Device reads the data frames of a module and exchanges commands with it
+// over a port of the transport package.
+type Device struct {
+	port      transport.Port
+	payloads  chan []byte
+	commander *Commander
+	clock     Clock
+
+	commandMu sync.Mutex //serializes commands and sessions
+	writeMu   sync.Mutex
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{}
+	err       error //why the read goroutine stopped, valid once done is closed
+}
+
+// New starts reading frames delimited by framing from port. The device owns
+// the port from now on and closes it when closed. Command timeouts are timed
+// by clock, nil uses SystemClock.
+func New(port transport.Port, framing protocol.Framing, clock Clock) *Device {
+	if clock == nil {
+		clock = SystemClock
+	}
+	d := &Device{
+		port:     port,
+		payloads: make(chan []byte, 16),
+		clock:    clock,
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	d.commander = NewCommander(d.write, clock, d.done, func() error { return d.err })
+	go d.read(protocol.NewFramedReader(port, framing))
+	return d
+}
+
+func (d *Device) read(frames *protocol.Reader) {
+	for {
+		packet, err := frames.Next()
+		if errors.Is(err, transport.ErrReadTimeout) {
+			//the module is silent, e.g. in config mode, the port is fine
+			continue
+		}
+		if err != nil {
+			d.err = err
+			close(d.done)
+			return
+		}
+		if packet.Kind == protocol.KindCommand {
+			ack, err := protocol.ParseAck(packet.Payload)
+			if err != nil {
+				continue
+			}
+			d.commander.Deliver(ack)
+			continue
+		}
+		select {
+		case d.payloads <- bytes.Clone(packet.Payload):
+		case <-d.closed:
+			d.err = ErrClosed
+			close(d.done)
+			return
+		}
+	}
+}
+
+// Next returns the payload of the next data frame, for the parser of the
+// module to decode.
+func (d *Device) Next() ([]byte, error) {
+	select {
+	case payload := <-d.payloads:
+		return payload, nil
+	case <-d.done:
+		//frames read before the port failed are still handed out
+		select {
+		case payload := <-d.payloads:
+			return payload, nil
+		default:
+			return nil, d.err
+		}
+	}
+}
+
+// Now returns the time of the device's clock, which module packages time
+// their reports with.
+func (d *Device) Now() time.Time {
+	return d.clock.Now()
+}
+
+// Command sends a single command and returns the data of its acknowledgement
+// following the status word. Most commands are only accepted inside Configure.
+func (d *Device) Command(word uint16, value []byte) ([]byte, error) {
+	d.commandMu.Lock()
+	defer d.commandMu.Unlock()
+	return d.commander.Exchange(word, value)
+}
+
+// Configure runs fn in config mode, leaving it again afterwards even if fn
+// fails. fn issues its commands through exchange, which is passed in because
+// Command must not be called while the session holds the device.
+func (d *Device) Configure(fn func(exchange func(word uint16, value []byte) ([]byte, error)) error) error {
+	d.commandMu.Lock()
+	defer d.commandMu.Unlock()
+	return Session(d.commander.Exchange, func([]byte) error {
+		return fn(d.commander.Exchange)
+	})
+}
+
+func (d *Device) write(data []byte) error {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	_, err := d.port.Write(data)
+	return err
+}
+
+// Close closes the port, which stops the read goroutine.
+func (d *Device) Close() error {
+	err := ErrClosed
+	d.closeOnce.Do(func() {
+		close(d.closed)
+		err = d.port.Close()
+	})
+	return err
+}
@@ -0,0 +1,52 @@
+package hlk_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2/hlk"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// quietPort times out like a serial port on a silent module before every
+// chunk of data it hands out.
+type quietPort struct {
+	chunks  chan []byte
+	timeout bool
+}
+
+func (p *quietPort) Read(b []byte) (int, error) {
+	p.timeout = !p.timeout
+	if p.timeout {
+		return 0, transport.ErrReadTimeout
+	}
+	chunk, ok := <-p.chunks
+	if !ok {
+		return 0, hlk.ErrClosed
+	}
+	return copy(b, chunk), nil
+}
+
+func (p *quietPort) Write(b []byte) (int, error) { return len(b), nil }
+func (p *quietPort) Close() error                { return nil }
+
+func TestDeviceSurvivesReadTimeouts(t *testing.T) {
+	port := &quietPort{chunks: make(chan []byte, 2)}
+	device := hlk.New(port, protocol.LD2451Framing, nil)
+	defer device.Close()
+
+	want := [][]byte{{0x01, 0x02}, {0x03}}
+	for _, payload := range want {
+		port.chunks <- protocol.LD2451Framing.Encode(payload)
+	}
+	for _, payload := range want {
+		got, err := device.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Fatalf("got payload % x, want % x", got, payload)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package ipc streams LD2451 targets to other local processes over a Unix
+// domain socket, so Python analytics or shell scripts can consume the sensor
+// without linking Go code or opening a network port.
+//
+//	server, err := ipc.Listen("/run/ld2451.sock", sensor, ipc.Config{})
+//	go server.Run(ctx)
+//
+// Every message is a 4 byte big endian length followed by that many bytes of
+// a JSON encoded target or, with FormatProtobuf, an ld2451.Target message as
+// described by the protobuf package. Reading a stream takes a few lines in
+// any language:
+//
+//	length = struct.unpack(">I", sock.recv(4))[0]
+//	target = json.loads(sock.recv(length))
+package ipc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protobuf"
+)
+
+type Format int
+
+const (
+	FormatJSON     Format = 0
+	FormatProtobuf Format = 1
+)
+
+const DefaultMode os.FileMode = 0o660
+
+type Config struct {
+	Format Format      // Encoding of the messages (default FormatJSON)
+	Mode   os.FileMode // Permissions of the socket file (default DefaultMode)
+}
+
+type Server struct {
+	sensor   *LD2451.LD2451
+	config   Config
+	path     string
+	listener net.Listener
+
+	mu      sync.Mutex
+	clients map[net.Conn]struct{}
+}
+
+// Listen creates the socket at path, replacing a socket left behind by a
+// previous run. Clients are only served once Run is called.
+func Listen(path string, sensor *LD2451.LD2451, config Config) (*Server, error) {
+	if config.Format != FormatJSON && config.Format != FormatProtobuf {
+		return nil, fmt.Errorf("ipc: unknown format %d", config.Format)
+	}
+	if config.Mode == 0 {
+		config.Mode = DefaultMode
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, config.Mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &Server{
+		sensor:   sensor,
+		config:   config,
+		path:     path,
+		listener: listener,
+		clients:  make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// Run accepts clients until ctx is done, then disconnects them and removes
+// the socket. Every client receives the targets read from the moment it
+// connected through its own subscription, so a slow client only loses its
+// own targets.
+func (s *Server) Run(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() { s.listener.Close() })
+	defer stop()
+	defer os.Remove(s.path)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer s.disconnect()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		s.mu.Lock()
+		s.clients[conn] = struct{}{}
+		s.mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serve(conn)
+		}()
+	}
+}
+
+// serve streams targets to conn until it fails or the sensor stops. Clients
+// are not expected to send anything, so reading only serves to notice that
+// they hung up.
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	targets, unsubscribe := s.sensor.Subscribe()
+	defer unsubscribe()
+
+	hangup := make(chan struct{})
+	go func() {
+		var b [64]byte
+		for {
+			if _, err := conn.Read(b[:]); err != nil {
+				close(hangup)
+				return
+			}
+		}
+	}()
+
+	var buf []byte
+	for {
+		select {
+		case <-hangup:
+			return
+		case target, ok := <-targets:
+			if !ok {
+				return
+			}
+			var err error
+			buf, err = s.encode(buf[:0], target)
+			if err != nil {
+				return
+			}
+			if _, err := conn.Write(buf); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// encode appends the length prefixed message for target to buf.
+func (s *Server) encode(buf []byte, target LD2451.Target) ([]byte, error) {
+	var message []byte
+	if s.config.Format == FormatProtobuf {
+		message = protobuf.MarshalTarget(target)
+	} else {
+		var err error
+		if message, err = json.Marshal(target); err != nil {
+			return buf, err
+		}
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(message)))
+	return append(buf, message...), nil
+}
+
+func (s *Server) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.clients {
+		conn.Close()
+	}
+}
+
+// Close stops accepting clients without waiting for Run to return.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
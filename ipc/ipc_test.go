@@ -0,0 +1,152 @@
+package ipc_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/ipc"
+	"github.com/Battlekeeper/LD2451/v2/protobuf"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+var sent = LD2451.Target{Angle: 5, Distance: 20, Direction: LD2451.DirectionToward, Speed: 40, SNR: 9}
+
+func TestStream(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	radar, err := LD2451.Open(sensor.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer radar.Close()
+	//clients only get the targets read after they connected, so keep sending
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(2 * time.Millisecond):
+				sensor.SendTargets(false, sent)
+			}
+		}
+	}()
+
+	tests := []struct {
+		name   string
+		format ipc.Format
+		decode func([]byte) (LD2451.Target, error)
+	}{
+		{"json", ipc.FormatJSON, func(data []byte) (LD2451.Target, error) {
+			var target LD2451.Target
+			err := json.Unmarshal(data, &target)
+			return target, err
+		}},
+		{"protobuf", ipc.FormatProtobuf, protobuf.UnmarshalTarget},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ld2451.sock")
+			server, err := ipc.Listen(path, radar, ipc.Config{Format: test.format, Mode: 0o600})
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o600 {
+				t.Fatalf("socket mode %v, expected the configured one", info.Mode())
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			stopped := make(chan error, 1)
+			go func() { stopped <- server.Run(ctx) }()
+
+			conn, err := net.Dial("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			for range 3 {
+				var length [4]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					t.Fatal(err)
+				}
+				message := make([]byte, binary.BigEndian.Uint32(length[:]))
+				if _, err := io.ReadFull(conn, message); err != nil {
+					t.Fatal(err)
+				}
+				target, err := test.decode(message)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if target.Distance != sent.Distance || target.Speed != sent.Speed || target.Angle != sent.Angle || target.Direction != sent.Direction {
+					t.Fatalf("received %+v, expected %+v", target, sent)
+				}
+			}
+
+			//stopping disconnects the client and removes the socket
+			cancel()
+			if err := <-stopped; !errors.Is(err, context.Canceled) {
+				t.Errorf("Run returned %v", err)
+			}
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, err := io.ReadAll(conn); err != nil {
+				t.Errorf("client not disconnected: %v", err)
+			}
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("socket left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ld2451.sock")
+	if _, err := ipc.Listen(path, nil, ipc.Config{Format: 7}); err == nil {
+		t.Error("listened with an unknown format")
+	}
+
+	//a socket left behind by a crashed run is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	server, err := ipc.Listen(path, nil, ipc.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != ipc.DefaultMode {
+		t.Errorf("socket mode %v, expected DefaultMode", info.Mode())
+	}
+	server.Close()
+
+	//anything else is left alone
+	other := filepath.Join(t.TempDir(), "ld2451.sock")
+	if err := os.WriteFile(other, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ipc.Listen(other, nil, ipc.Config{}); err == nil {
+		t.Error("replaced a regular file")
+	}
+}
@@ -0,0 +1,144 @@
+// Package kafkasink batches LD2451 targets into a Kafka topic. The Kafka client
+// is plugged in through the Producer interface, so applications choose their
+// own client library and the library itself does not depend on one.
+package kafkasink
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	DefaultBatchSize      = 100
+	DefaultTimeout        = 5 * time.Second
+	DefaultPendingBatches = 10
+)
+
+type Message struct {
+	Key   []byte
+	Value []byte
+}
+
+// Producer writes a batch of messages to topic. Adapting a client is usually
+// a few lines, e.g. for github.com/segmentio/kafka-go a *kafka.Writer whose
+// WriteMessages is called with one kafka.Message per Message.
+type Producer interface {
+	Produce(ctx context.Context, topic string, messages []Message) error
+}
+
+type Config struct {
+	Topic     string
+	SensorID  string        // Message key, so all targets of one sensor land in the same partition (default the Sensor of each target)
+	BatchSize int           // Number of targets that triggers a flush (default 100)
+	Timeout   time.Duration // Bounds every batch produced by Write and Flush (default DefaultTimeout)
+
+	MaxPending int // Targets kept for the next flush while producing fails, the oldest are dropped beyond it (default DefaultPendingBatches batches)
+}
+
+// Sink implements LD2451.Sink. Targets wait in a batch for at most
+// LD2451.Config.SinkFlushInterval when the sink is run with RunSink or AddSink.
+type Sink struct {
+	producer Producer
+	config   Config
+
+	mu      sync.Mutex
+	pending []Message
+	dropped uint64
+}
+
+func New(producer Producer, config Config) *Sink {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.MaxPending < config.BatchSize {
+		config.MaxPending = DefaultPendingBatches * config.BatchSize
+	}
+	return &Sink{
+		producer: producer,
+		config:   config,
+		pending:  make([]Message, 0, config.BatchSize),
+	}
+}
+
+// Write adds target to the current batch like WriteContext, giving up on a
+// full batch after Config.Timeout.
+func (s *Sink) Write(target LD2451.Target) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	return s.WriteContext(ctx, target)
+}
+
+// WriteContext adds target to the current batch and flushes the batch once it
+// is full.
+func (s *Sink) WriteContext(ctx context.Context, target LD2451.Target) error {
+	value, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+
+	key := s.config.SensorID
+	if key == "" {
+		key = target.Sensor
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, Message{Key: []byte(key), Value: value})
+	if over := len(s.pending) - s.config.MaxPending; over > 0 {
+		//the broker is unreachable for long, keep the newest targets
+		s.pending = slices.Delete(s.pending, 0, over)
+		s.dropped += uint64(over)
+	}
+	full := len(s.pending) >= s.config.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.FlushContext(ctx)
+	}
+	return nil
+}
+
+// Dropped returns the number of targets dropped because producing failed for
+// longer than Config.MaxPending targets.
+func (s *Sink) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Flush produces the current batch like FlushContext, giving up after
+// Config.Timeout.
+func (s *Sink) Flush() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	return s.FlushContext(ctx)
+}
+
+// FlushContext produces the current batch. On failure the batch is kept and
+// retried by the next flush, up to Config.MaxPending targets.
+func (s *Sink) FlushContext(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	if err := s.producer.Produce(ctx, s.config.Topic, s.pending); err != nil {
+		return err
+	}
+	s.pending = make([]Message, 0, s.config.BatchSize)
+	return nil
+}
+
+func (s *Sink) Close() error { return nil }
+
+// Run is shorthand for sensor.RunSink(ctx, s).
+func (s *Sink) Run(ctx context.Context, sensor *LD2451.LD2451) error {
+	return sensor.RunSink(ctx, s)
+}
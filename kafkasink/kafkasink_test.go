@@ -0,0 +1,121 @@
+package kafkasink_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/kafkasink"
+)
+
+var errBrokerDown = errors.New("broker down")
+
+// producer records the batches produced, failing while down is set.
+type producer struct {
+	mu      sync.Mutex
+	down    bool
+	topics  []string
+	batches [][]kafkasink.Message
+}
+
+func (p *producer) Produce(ctx context.Context, topic string, messages []kafkasink.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down {
+		return errBrokerDown
+	}
+	p.topics = append(p.topics, topic)
+	p.batches = append(p.batches, append([]kafkasink.Message(nil), messages...))
+	return nil
+}
+
+// speeds decodes the speeds of the targets in messages.
+func speeds(t *testing.T, messages []kafkasink.Message) []int {
+	t.Helper()
+	speeds := make([]int, len(messages))
+	for i, message := range messages {
+		var target LD2451.Target
+		if err := json.Unmarshal(message.Value, &target); err != nil {
+			t.Fatal(err)
+		}
+		speeds[i] = target.Speed
+	}
+	return speeds
+}
+
+func TestBatches(t *testing.T) {
+	p := &producer{}
+	sink := kafkasink.New(p, kafkasink.Config{Topic: "speeds", BatchSize: 2})
+	for i := range 5 {
+		if err := sink.Write(LD2451.Target{Speed: i, Sensor: "north"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(p.batches) != 2 {
+		t.Fatalf("produced %d batches before the flush, expected 2", len(p.batches))
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Flush(); err != nil || len(p.batches) != 3 {
+		t.Fatalf("empty flush produced a batch or failed: %v", err)
+	}
+	for i, expected := range [][]int{{0, 1}, {2, 3}, {4}} {
+		if got := speeds(t, p.batches[i]); !slices.Equal(got, expected) {
+			t.Errorf("batch %d holds speeds %v, expected %v", i, got, expected)
+		}
+		if p.topics[i] != "speeds" || string(p.batches[i][0].Key) != "north" {
+			t.Errorf("batch %d went to %q with key %q", i, p.topics[i], p.batches[i][0].Key)
+		}
+	}
+
+	keyed := kafkasink.New(p, kafkasink.Config{Topic: "speeds", SensorID: "south"})
+	keyed.Write(LD2451.Target{Sensor: "north"})
+	keyed.Flush()
+	if key := string(p.batches[3][0].Key); key != "south" {
+		t.Errorf("got key %q, expected the configured sensor ID", key)
+	}
+}
+
+func TestFailingProducerKeepsTheNewest(t *testing.T) {
+	p := &producer{down: true}
+	sink := kafkasink.New(p, kafkasink.Config{BatchSize: 2, MaxPending: 5})
+	for i := range 10 {
+		err := sink.Write(LD2451.Target{Speed: i})
+		//every write filling a batch tries to produce it
+		if i > 0 && !errors.Is(err, errBrokerDown) {
+			t.Fatalf("write %d: got %v, expected %v", i, err, errBrokerDown)
+		}
+	}
+	if dropped := sink.Dropped(); dropped != 5 {
+		t.Errorf("dropped %d targets, expected 5", dropped)
+	}
+
+	p.mu.Lock()
+	p.down = false
+	p.mu.Unlock()
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.batches) != 1 {
+		t.Fatalf("produced %d batches, expected 1", len(p.batches))
+	}
+	if got := speeds(t, p.batches[0]); !slices.Equal(got, []int{5, 6, 7, 8, 9}) {
+		t.Errorf("produced speeds %v, expected the five newest", got)
+	}
+}
+
+func TestMaxPendingDefault(t *testing.T) {
+	p := &producer{down: true}
+	sink := kafkasink.New(p, kafkasink.Config{BatchSize: 3})
+	for i := range kafkasink.DefaultPendingBatches*3 + 4 {
+		sink.Write(LD2451.Target{Speed: i})
+	}
+	if dropped := sink.Dropped(); dropped != 4 {
+		t.Errorf("dropped %d targets, expected 4", dropped)
+	}
+}
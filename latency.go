@@ -0,0 +1,36 @@
+package LD2451
+
+import "time"
+
+// frameOverhead is the number of bytes framing every payload: header, length
+// and footer.
+const frameOverhead = 10
+
+// delivery is a target waiting for ReadTarget.
+type delivery struct {
+	target Target
+	start  time.Time //estimated arrival of the first byte of the target's frame
+}
+
+// wireTime estimates how long a frame with a payload of n bytes took to
+// arrive at the current baud rate with 8N1 framing, so latency is measured
+// from its first byte although the frame is only seen once complete.
+func (ld2451 *LD2451) wireTime(n int) time.Duration {
+	baud := ld2451.baud.Load()
+	if baud <= 0 {
+		return 0
+	}
+	bits := (n + frameOverhead) * 10
+	return time.Duration(bits) * time.Second / time.Duration(baud)
+}
+
+// recordLatency accounts for a target handed to a consumer by ReadTarget,
+// ReadTargets or Run whose frame started arriving at start.
+func (ld2451 *LD2451) recordLatency(start time.Time) {
+	latency := ld2451.since(start)
+	ld2451.statsMu.Lock()
+	defer ld2451.statsMu.Unlock()
+	ld2451.stats.LatencySamples++
+	ld2451.stats.LatencyMax = max(ld2451.stats.LatencyMax, latency)
+	ld2451.latencyTotal += latency
+}
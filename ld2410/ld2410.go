@@ -0,0 +1,137 @@
+// Package ld2410 supports the HLK-LD2410 human presence sensor, which reports
+// whether moving or static targets are present rather than individual
+// targets. It shares the transports and command handling of the LD2451
+// through the hlk package.
+package ld2410
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/hlk"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// DefaultBaudRate is the baud rate the module ships with.
+const DefaultBaudRate = 256000
+
+// Framing delimits the data frames of the module, which are the same as the
+// ones of the LD2451.
+var Framing = protocol.LD2451Framing
+
+const (
+	dataEngineering = 0x01 //report followed by the energy of every gate
+	dataBasic       = 0x02
+	reportHead      = 0xaa
+	reportTail      = 0x55
+	basicSize       = 13 //type, head, state, 7 bytes of report, tail, check
+)
+
+// State tells which kinds of targets are present.
+type State int
+
+const (
+	StateNone   State = 0
+	StateMoving State = 1
+	StateStatic State = 2
+	StateBoth   State = 3
+)
+
+func (s State) String() string {
+	switch s {
+	case StateNone:
+		return "None"
+	case StateMoving:
+		return "Moving"
+	case StateStatic:
+		return "Static"
+	case StateBoth:
+		return "Both"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+type Report struct {
+	State          State     `json:"state"`           // Kinds of targets present
+	MovingDistance int       `json:"moving_distance"` // Distance in cm to the moving target
+	MovingEnergy   int       `json:"moving_energy"`   // Energy of the moving target, 0-100
+	StaticDistance int       `json:"static_distance"` // Distance in cm to the static target
+	StaticEnergy   int       `json:"static_energy"`   // Energy of the static target, 0-100
+	Distance       int       `json:"distance"`        // Detection distance in cm
+	Engineering    bool      `json:"engineering"`     // The frame was sent in engineering mode, whose gate energies are not decoded
+	Time           time.Time `json:"time"`            // When the frame was received
+}
+
+// Parse decodes the payload of a data frame. Frames sent in engineering mode
+// are accepted, only their common part is decoded.
+func Parse(payload []byte) (Report, error) {
+	if len(payload) < basicSize {
+		return Report{}, fmt.Errorf("malformed frame from the LD2410: payload of %d bytes is shorter than %d bytes", len(payload), basicSize)
+	}
+	kind := payload[0]
+	if kind != dataBasic && kind != dataEngineering {
+		return Report{}, fmt.Errorf("malformed frame from the LD2410: unknown data type 0x%02x", kind)
+	}
+	if payload[1] != reportHead || payload[len(payload)-2] != reportTail {
+		return Report{}, fmt.Errorf("malformed frame from the LD2410: missing report head or tail")
+	}
+	return Report{
+		State:          State(payload[2]),
+		MovingDistance: int(binary.LittleEndian.Uint16(payload[3:])),
+		MovingEnergy:   int(payload[5]),
+		StaticDistance: int(binary.LittleEndian.Uint16(payload[6:])),
+		StaticEnergy:   int(payload[8]),
+		Distance:       int(binary.LittleEndian.Uint16(payload[9:])),
+		Engineering:    kind == dataEngineering,
+	}, nil
+}
+
+// Encode builds the basic mode data frame the module sends for report, the
+// inverse of Parse.
+func Encode(report Report) []byte {
+	payload := []byte{dataBasic, reportHead, byte(report.State)}
+	payload = binary.LittleEndian.AppendUint16(payload, uint16(report.MovingDistance))
+	payload = append(payload, byte(report.MovingEnergy))
+	payload = binary.LittleEndian.AppendUint16(payload, uint16(report.StaticDistance))
+	payload = append(payload, byte(report.StaticEnergy))
+	payload = binary.LittleEndian.AppendUint16(payload, uint16(report.Distance))
+	payload = append(payload, reportTail, 0x00)
+	return Framing.Encode(payload)
+}
+
+type Sensor struct {
+	*hlk.Device
+}
+
+// Open opens the serial port the module is connected to at DefaultBaudRate.
+func Open(name string) (*Sensor, error) {
+	port, err := transport.OpenSerial(transport.SerialConfig{Name: name, Baud: DefaultBaudRate})
+	if err != nil {
+		return nil, err
+	}
+	return New(port, nil), nil
+}
+
+// New reads the module over an already opened port, which is closed with the
+// sensor. Reports are timed by clock, nil uses hlk.SystemClock.
+func New(port transport.Port, clock hlk.Clock) *Sensor {
+	return &Sensor{Device: hlk.New(port, Framing, clock)}
+}
+
+// ReadReport blocks until the next frame and returns what it reports.
+// Malformed frames are returned as errors without stopping the sensor.
+func (s *Sensor) ReadReport() (Report, error) {
+	payload, err := s.Next()
+	if err != nil {
+		return Report{}, err
+	}
+	report, err := Parse(payload)
+	if err != nil {
+		return Report{}, err
+	}
+	report.Time = s.Now()
+	return report, nil
+}
@@ -0,0 +1,81 @@
+package ld2410_test
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/ld2410"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+// port hands out the frames it was created with, then io.EOF.
+type port struct {
+	io.Reader
+}
+
+func (p port) Write(b []byte) (int, error) { return len(b), nil }
+func (p port) Close() error                { return nil }
+
+func TestReadReport(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	reports := []ld2410.Report{
+		{State: ld2410.StateMoving, MovingDistance: 120, MovingEnergy: 80, Distance: 120},
+		{State: ld2410.StateBoth, MovingDistance: 300, MovingEnergy: 40, StaticDistance: 250, StaticEnergy: 100, Distance: 250},
+		{},
+	}
+	var data []byte
+	for _, report := range reports {
+		data = append(data, ld2410.Encode(report)...)
+	}
+	//engineering frames carry the gate energies between the report and its tail
+	engineering := []byte{0x01, 0xaa, 0x02, 0x00, 0x00, 0x00, 0x96, 0x00, 0x64, 0x96, 0x00, 0x08, 0x08, 0x09, 0x09, 0x55, 0x00}
+	data = append(data, ld2410.Framing.Encode(engineering)...)
+
+	sensor := ld2410.New(port{bytes.NewReader(data)}, sensortest.NewClock(start))
+	defer sensor.Close()
+	for i, expected := range reports {
+		report, err := sensor.ReadReport()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected.Time = start
+		if report != expected {
+			t.Errorf("report %d is %+v, expected %+v", i, report, expected)
+		}
+	}
+	report, err := sensor.ReadReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Engineering || report.State != ld2410.StateStatic || report.StaticDistance != 150 || report.StaticEnergy != 100 || report.Distance != 150 {
+		t.Errorf("engineering frame read as %+v", report)
+	}
+	if _, err := sensor.ReadReport(); err != io.EOF {
+		t.Errorf("got %v at the end of the port, expected io.EOF", err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	valid := ld2410.Encode(ld2410.Report{State: ld2410.StateMoving})
+	payload := valid[6 : len(valid)-4]
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{"short", payload[:10]},
+		{"unknown data type", append([]byte{0x07}, payload[1:]...)},
+		{"no head", slices.Concat(payload[:1], []byte{0x00}, payload[2:])},
+		{"no tail", slices.Concat(payload[:len(payload)-2], []byte{0x00, 0x00})},
+	}
+	for _, test := range tests {
+		if _, err := ld2410.Parse(test.payload); err == nil {
+			t.Errorf("%s: parsed % x", test.name, test.payload)
+		}
+	}
+	if report, err := ld2410.Parse(payload); err != nil || report.State != ld2410.StateMoving {
+		t.Errorf("parsed %+v, %v", report, err)
+	}
+}
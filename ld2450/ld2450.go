@@ -0,0 +1,133 @@
+// Package ld2450 supports the HLK-LD2450, which tracks up to three targets in
+// two dimensions. It shares the transports and command handling of the
+// LD2451 through the hlk package.
+package ld2450
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/hlk"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// DefaultBaudRate is the baud rate the module ships with.
+const DefaultBaudRate = 256000
+
+const (
+	maxTargets       = 3
+	targetRecordSize = 8
+)
+
+// Framing delimits the data frames of the module, which have a fixed size and
+// no length field.
+var Framing = protocol.Framing{
+	Header: []byte{0xaa, 0xff, 0x03, 0x00},
+	Footer: []byte{0x55, 0xcc},
+	Length: maxTargets * targetRecordSize,
+}
+
+type Target struct {
+	X          int       `json:"x"`          // Horizontal position in mm, positive to the right of the antenna
+	Y          int       `json:"y"`          // Distance in mm along the perpendicular of the antenna
+	Speed      int       `json:"speed"`      // Speed in cm/s, positive when moving away
+	Resolution int       `json:"resolution"` // Distance resolution in mm
+	Time       time.Time `json:"time"`       // When the frame containing the target was received
+}
+
+// Parse decodes the payload of a data frame into the targets it reports.
+// Unused target slots are all zero and skipped.
+func Parse(payload []byte) ([]Target, error) {
+	if len(payload) != Framing.Length {
+		return nil, fmt.Errorf("malformed frame from the LD2450: payload of %d bytes, want %d", len(payload), Framing.Length)
+	}
+	var targets []Target
+	for i := 0; i < maxTargets; i++ {
+		record := payload[i*targetRecordSize : (i+1)*targetRecordSize]
+		if allZero(record) {
+			continue
+		}
+		targets = append(targets, Target{
+			X:          signMagnitude(binary.LittleEndian.Uint16(record[0:])),
+			Y:          signMagnitude(binary.LittleEndian.Uint16(record[2:])),
+			Speed:      signMagnitude(binary.LittleEndian.Uint16(record[4:])),
+			Resolution: int(binary.LittleEndian.Uint16(record[6:])),
+		})
+	}
+	return targets, nil
+}
+
+// Encode builds the data frame the module sends when reporting targets, the
+// inverse of Parse. Targets beyond the third are ignored.
+func Encode(targets []Target) []byte {
+	payload := make([]byte, Framing.Length)
+	for i, target := range targets[:min(len(targets), maxTargets)] {
+		record := payload[i*targetRecordSize:]
+		binary.LittleEndian.PutUint16(record[0:], toSignMagnitude(target.X))
+		binary.LittleEndian.PutUint16(record[2:], toSignMagnitude(target.Y))
+		binary.LittleEndian.PutUint16(record[4:], toSignMagnitude(target.Speed))
+		binary.LittleEndian.PutUint16(record[6:], uint16(target.Resolution))
+	}
+	return Framing.Encode(payload)
+}
+
+// signMagnitude decodes values carrying their sign in the top bit, which is set for positive values
+func signMagnitude(v uint16) int {
+	if v&0x8000 != 0 {
+		return int(v & 0x7fff)
+	}
+	return -int(v)
+}
+
+func toSignMagnitude(v int) uint16 {
+	if v >= 0 {
+		return uint16(v) | 0x8000
+	}
+	return uint16(-v) & 0x7fff
+}
+
+func allZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type Sensor struct {
+	*hlk.Device
+}
+
+// Open opens the serial port the module is connected to at DefaultBaudRate.
+func Open(name string) (*Sensor, error) {
+	port, err := transport.OpenSerial(transport.SerialConfig{Name: name, Baud: DefaultBaudRate})
+	if err != nil {
+		return nil, err
+	}
+	return New(port, nil), nil
+}
+
+// New reads the module over an already opened port, which is closed with the
+// sensor. Reports are timed by clock, nil uses hlk.SystemClock.
+func New(port transport.Port, clock hlk.Clock) *Sensor {
+	return &Sensor{Device: hlk.New(port, Framing, clock)}
+}
+
+// ReadTargets blocks until the next frame and returns the targets it reports,
+// which is nil while nothing is in the field of view. Malformed frames are
+// returned as errors without stopping the sensor.
+func (s *Sensor) ReadTargets() ([]Target, error) {
+	payload, err := s.Next()
+	if err != nil {
+		return nil, err
+	}
+	received := s.Now()
+	targets, err := Parse(payload)
+	for i := range targets {
+		targets[i].Time = received
+	}
+	return targets, err
+}
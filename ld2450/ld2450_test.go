@@ -0,0 +1,78 @@
+package ld2450_test
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/ld2450"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+// port hands out the frames it was created with, then io.EOF.
+type port struct {
+	io.Reader
+}
+
+func (p port) Write(b []byte) (int, error) { return len(b), nil }
+func (p port) Close() error                { return nil }
+
+func TestParse(t *testing.T) {
+	//the example frame of the datasheet: one target 782 mm left, 1713 mm away, approaching at 16 cm/s
+	frame := []byte{
+		0xaa, 0xff, 0x03, 0x00,
+		0x0e, 0x03, 0xb1, 0x86, 0x10, 0x00, 0x40, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x55, 0xcc,
+	}
+	targets, err := ld2450.Parse(frame[4 : len(frame)-2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ld2450.Target{{X: -782, Y: 1713, Speed: -16, Resolution: 320}}
+	if !slices.Equal(targets, expected) {
+		t.Errorf("parsed %+v, expected %+v", targets, expected)
+	}
+	if encoded := ld2450.Encode(expected); !bytes.Equal(encoded, frame) {
+		t.Errorf("encoded % x, expected % x", encoded, frame)
+	}
+
+	if _, err := ld2450.Parse(frame[4:20]); err == nil {
+		t.Error("parsed a payload cut short")
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	frames := [][]ld2450.Target{
+		{{X: 100, Y: 2000, Speed: 30, Resolution: 360}, {X: -1500, Y: 500, Speed: -20, Resolution: 360}},
+		nil,
+		//a fourth target doesn't fit in the frame
+		{{X: 1, Y: 1}, {X: 2, Y: 2}, {X: 3, Y: 3}, {X: 4, Y: 4}},
+	}
+	var data []byte
+	for _, targets := range frames {
+		data = append(data, ld2450.Encode(targets)...)
+	}
+	sensor := ld2450.New(port{bytes.NewReader(data)}, sensortest.NewClock(start))
+	defer sensor.Close()
+	for i, sent := range frames {
+		targets, err := sensor.ReadTargets()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := slices.Clone(sent[:min(len(sent), 3)])
+		for j := range expected {
+			expected[j].Time = start
+		}
+		if !slices.Equal(targets, expected) {
+			t.Errorf("frame %d read as %+v, expected %+v", i, targets, expected)
+		}
+	}
+	if _, err := sensor.ReadTargets(); err != io.EOF {
+		t.Errorf("got %v at the end of the port, expected io.EOF", err)
+	}
+}
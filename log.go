@@ -0,0 +1,89 @@
+package LD2451
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Logger receives the library's internal diagnostics, with context as
+// alternating keys and values. Some messages are logged while internal locks
+// are held, so a Logger must not call back into the sensor.
+//
+// *slog.Logger implements it as is, PrintfLogger adapts logrus style loggers
+// and LoggerFunc anything else, e.g. zerolog:
+//
+//	LD2451.LoggerFunc(func(level slog.Level, msg string, keyvals ...any) {
+//		zl.WithLevel(zerologLevel(level)).Fields(keyvals).Msg(msg)
+//	})
+type Logger interface {
+	Debug(msg string, keyvals ...any)
+	Info(msg string, keyvals ...any)
+	Warn(msg string, keyvals ...any)
+	Error(msg string, keyvals ...any)
+}
+
+// LoggerFunc adapts a single function to Logger.
+type LoggerFunc func(level slog.Level, msg string, keyvals ...any)
+
+func (f LoggerFunc) Debug(msg string, keyvals ...any) { f(slog.LevelDebug, msg, keyvals...) }
+func (f LoggerFunc) Info(msg string, keyvals ...any)  { f(slog.LevelInfo, msg, keyvals...) }
+func (f LoggerFunc) Warn(msg string, keyvals ...any)  { f(slog.LevelWarn, msg, keyvals...) }
+func (f LoggerFunc) Error(msg string, keyvals ...any) { f(slog.LevelError, msg, keyvals...) }
+
+// Printf is implemented by logrus loggers and entries, zap's SugaredLogger
+// and most other leveled printf style loggers.
+type Printf interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// PrintfLogger adapts a printf style logger, appending the context to the
+// message as key=value pairs.
+func PrintfLogger(logger Printf) Logger {
+	return LoggerFunc(func(level slog.Level, msg string, keyvals ...any) {
+		line := formatKeyvals(msg, keyvals)
+		switch {
+		case level >= slog.LevelError:
+			logger.Errorf("%s", line)
+		case level >= slog.LevelWarn:
+			logger.Warnf("%s", line)
+		case level >= slog.LevelInfo:
+			logger.Infof("%s", line)
+		default:
+			logger.Debugf("%s", line)
+		}
+	})
+}
+
+func formatKeyvals(msg string, keyvals []any) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&b, " %v=%v", keyvals[i], keyvals[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", keyvals[i])
+		}
+	}
+	return b.String()
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// Option adjusts the Config passed to Open or New.
+type Option func(*Config)
+
+// WithLogger routes the library's diagnostics to logger. Without it nothing is logged.
+func WithLogger(logger Logger) Option {
+	return func(config *Config) {
+		config.Logger = logger
+	}
+}
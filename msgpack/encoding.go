@@ -0,0 +1,443 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+const extTimestamp = -1
+
+// maxDepth limits the nesting of skipped values, so crafted input can't
+// exhaust the stack.
+const maxDepth = 16
+
+var errTruncated = errors.New("msgpack: truncated value")
+
+// encoder appends the fields of a map, leaving out fields holding their zero
+// value like the protobuf package does.
+type encoder struct {
+	buf   []byte
+	count int
+	start int //offset of the map header, rewritten by end
+}
+
+func (e *encoder) begin() {
+	e.start = len(e.buf)
+	e.buf = append(e.buf, 0x80)
+	e.count = 0
+}
+
+func (e *encoder) end() {
+	if e.count < 16 {
+		e.buf[e.start] = 0x80 | byte(e.count)
+		return
+	}
+	e.buf[e.start] = 0xde
+	e.buf = slices.Insert(e.buf, e.start+1, byte(e.count>>8), byte(e.count))
+}
+
+func (e *encoder) key(key string) {
+	e.count++
+	e.buf = appendString(e.buf, key)
+}
+
+func (e *encoder) int(key string, v int64) {
+	if v == 0 {
+		return
+	}
+	e.key(key)
+	e.buf = appendInt(e.buf, v)
+}
+
+func (e *encoder) bool(key string, v bool) {
+	if !v {
+		return
+	}
+	e.key(key)
+	e.buf = append(e.buf, 0xc3)
+}
+
+func (e *encoder) float(key string, v float64) {
+	if v == 0 {
+		return
+	}
+	e.key(key)
+	if float64(float32(v)) == v {
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xca), math.Float32bits(float32(v)))
+		return
+	}
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcb), math.Float64bits(v))
+}
+
+func (e *encoder) string(key string, s string) {
+	if s == "" {
+		return
+	}
+	e.key(key)
+	e.buf = appendString(e.buf, s)
+}
+
+// time appends t in the timestamp extension, in its 32 or 64 bit form.
+func (e *encoder) time(key string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	e.key(key)
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	switch {
+	case sec < 0 || sec >= 1<<34:
+		e.buf = append(e.buf, 0xc7, 12, byte(extTimestamp&0xff))
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(nsec))
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(sec))
+	case nsec == 0 && sec < 1<<32:
+		e.buf = append(e.buf, 0xd6, byte(extTimestamp&0xff))
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(sec))
+	default:
+		e.buf = append(e.buf, 0xd7, byte(extTimestamp&0xff))
+		e.buf = binary.BigEndian.AppendUint64(e.buf, nsec<<34|uint64(sec))
+	}
+}
+
+func appendString(buf []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+// appendArray appends the header of an array of n values.
+func appendArray(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, 0xdd), uint32(n))
+	}
+}
+
+// appendInt appends v in its shortest form.
+func appendInt(buf []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v < 128:
+		return append(buf, byte(v))
+	case v < 0 && v >= -32:
+		return append(buf, byte(v))
+	case v >= 0 && v <= math.MaxUint8:
+		return append(buf, 0xcc, byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xcd), uint16(v))
+	case v >= 0 && v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xce), uint32(v))
+	case v >= 0:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcf), uint64(v))
+	case v >= math.MinInt8:
+		return append(buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(v))
+	}
+}
+
+// decoder reads the values of a buffer.
+type decoder struct {
+	data []byte
+}
+
+// take returns the next n bytes.
+func (d *decoder) take(n int) ([]byte, error) {
+	if n < 0 || len(d.data) < n {
+		return nil, errTruncated
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *decoder) byte() (byte, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// uint reads a big endian unsigned integer of size bytes.
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.take(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// length reads the size following a str, array or map type byte, or returns
+// ok false when b is of another type. sized holds the type bytes with a 1, 2
+// and 4 byte length, -1 where a type has no such form.
+func (d *decoder) length(b byte, fix, fixMask byte, sized ...int) (int, bool, error) {
+	if b&^fixMask == fix {
+		return int(b & fixMask), true, nil
+	}
+	for i, t := range sized {
+		if int(b) == t {
+			n, err := d.uint(1 << i)
+			if n > uint64(len(d.data)) {
+				return 0, true, errTruncated
+			}
+			return int(n), true, err
+		}
+	}
+	return 0, false, nil
+}
+
+func (d *decoder) mapLen() (int, error) {
+	b, err := d.byte()
+	if err != nil {
+		return 0, err
+	}
+	n, ok, err := d.length(b, 0x80, 0x0f, -1, 0xde, 0xdf)
+	if !ok {
+		return 0, fmt.Errorf("msgpack: expected a map, got 0x%02x", b)
+	}
+	return n, err
+}
+
+func (d *decoder) arrayLen() (int, error) {
+	b, err := d.byte()
+	if err != nil {
+		return 0, err
+	}
+	n, ok, err := d.length(b, 0x90, 0x0f, -1, 0xdc, 0xdd)
+	if !ok {
+		return 0, fmt.Errorf("msgpack: expected an array, got 0x%02x", b)
+	}
+	return n, err
+}
+
+func (d *decoder) string() (string, error) {
+	b, err := d.byte()
+	if err != nil {
+		return "", err
+	}
+	n, ok, err := d.length(b, 0xa0, 0x1f, 0xd9, 0xda, 0xdb)
+	if !ok {
+		return "", fmt.Errorf("msgpack: expected a string, got 0x%02x", b)
+	}
+	if err != nil {
+		return "", err
+	}
+	s, err := d.take(n)
+	return string(s), err
+}
+
+// key reads a map key, skipping keys that are no string and returning an
+// empty key for them, whose value is skipped like any other unknown one.
+func (d *decoder) key() (string, error) {
+	if len(d.data) > 0 && (d.data[0]&0xe0 == 0xa0 || (d.data[0] >= 0xd9 && d.data[0] <= 0xdb)) {
+		return d.string()
+	}
+	return "", d.skip(0)
+}
+
+func (d *decoder) int() (int64, error) {
+	b, err := d.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case b < 0x80:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b >= 0xcc && b <= 0xcf:
+		v, err := d.uint(1 << (b - 0xcc))
+		if v > math.MaxInt64 {
+			return 0, errors.New("msgpack: integer overflows int64")
+		}
+		return int64(v), err
+	case b >= 0xd0 && b <= 0xd3:
+		size := 1 << (b - 0xd0)
+		v, err := d.uint(size)
+		//sign extend from the integer's width
+		shift := 64 - 8*size
+		return int64(v<<shift) >> shift, err
+	}
+	return 0, fmt.Errorf("msgpack: expected an integer, got 0x%02x", b)
+}
+
+// float reads a float of either precision, or an integer.
+func (d *decoder) float() (float64, error) {
+	if len(d.data) == 0 || (d.data[0] != 0xca && d.data[0] != 0xcb) {
+		v, err := d.int()
+		return float64(v), err
+	}
+	b, _ := d.byte()
+	if b == 0xca {
+		v, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	}
+	v, err := d.uint(8)
+	return math.Float64frombits(v), err
+}
+
+func (d *decoder) bool() (bool, error) {
+	b, err := d.byte()
+	switch {
+	case err != nil:
+		return false, err
+	case b == 0xc3:
+		return true, nil
+	case b == 0xc2:
+		return false, nil
+	}
+	return false, fmt.Errorf("msgpack: expected a bool, got 0x%02x", b)
+}
+
+// time reads the timestamp extension in any of its forms, returning the time
+// in UTC.
+func (d *decoder) time() (time.Time, error) {
+	b, err := d.byte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var size int
+	switch b {
+	case 0xd6:
+		size = 4
+	case 0xd7:
+		size = 8
+	case 0xc7:
+		n, err := d.byte()
+		if err != nil {
+			return time.Time{}, err
+		}
+		size = int(n)
+	default:
+		return time.Time{}, fmt.Errorf("msgpack: expected a timestamp, got 0x%02x", b)
+	}
+	ext, err := d.byte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if int8(ext) != extTimestamp {
+		return time.Time{}, fmt.Errorf("msgpack: expected a timestamp, got extension %d", int8(ext))
+	}
+	data, err := d.take(size)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch size {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)).UTC(), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data))).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("msgpack: timestamp of %d bytes", size)
+}
+
+// skip steps over a value, including everything nested in it.
+func (d *decoder) skip(depth int) error {
+	if depth > maxDepth {
+		return errors.New("msgpack: values nested too deeply")
+	}
+	b, err := d.byte()
+	if err != nil {
+		return err
+	}
+	//sized values: the size of their length field and a fixed part
+	skipSized := func(lengthSize, extra int) error {
+		n, err := d.uint(lengthSize)
+		if err != nil {
+			return err
+		}
+		if n > uint64(len(d.data)) {
+			return errTruncated
+		}
+		_, err = d.take(int(n) + extra)
+		return err
+	}
+	skipItems := func(n int) error {
+		if n > len(d.data) {
+			return errTruncated
+		}
+		for range n {
+			if err := d.skip(depth + 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch {
+	case b < 0x80 || b >= 0xe0 || b == 0xc0 || b == 0xc2 || b == 0xc3:
+		return nil
+	case b&0xf0 == 0x80:
+		return skipItems(2 * int(b&0x0f))
+	case b&0xf0 == 0x90:
+		return skipItems(int(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		_, err := d.take(int(b & 0x1f))
+		return err
+	case b == 0xc4 || b == 0xd9:
+		return skipSized(1, 0)
+	case b == 0xc5 || b == 0xda:
+		return skipSized(2, 0)
+	case b == 0xc6 || b == 0xdb:
+		return skipSized(4, 0)
+	case b == 0xc7:
+		return skipSized(1, 1)
+	case b == 0xc8:
+		return skipSized(2, 1)
+	case b == 0xc9:
+		return skipSized(4, 1)
+	case b == 0xca:
+		_, err := d.take(4)
+		return err
+	case b == 0xcb:
+		_, err := d.take(8)
+		return err
+	case b >= 0xcc && b <= 0xcf:
+		_, err := d.take(1 << (b - 0xcc))
+		return err
+	case b >= 0xd0 && b <= 0xd3:
+		_, err := d.take(1 << (b - 0xd0))
+		return err
+	case b >= 0xd4 && b <= 0xd8:
+		_, err := d.take(1 + 1<<(b-0xd4))
+		return err
+	case b == 0xdc || b == 0xdd:
+		n, err := d.uint(2 << (b - 0xdc))
+		if err != nil {
+			return err
+		}
+		return skipItems(int(min(n, uint64(len(d.data)+1))))
+	case b == 0xde || b == 0xdf:
+		n, err := d.uint(2 << (b - 0xde))
+		if err != nil {
+			return err
+		}
+		return skipItems(2 * int(min(n, uint64(len(d.data)+1))))
+	}
+	return fmt.Errorf("msgpack: unknown type 0x%02x", b)
+}
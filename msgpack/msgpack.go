@@ -0,0 +1,194 @@
+// Package msgpack encodes LD2451 targets, frames and tracks as MessagePack,
+// read by libraries in nearly every language. Messages are maps keyed by the
+// names of the JSON encoding, fields holding their zero value are left out
+// and times use the timestamp extension, so a target takes about half the
+// bytes of its JSON:
+//
+//	target = msgpack.unpackb(data, timestamp=3)
+//
+// Decoded times are in UTC.
+package msgpack
+
+import (
+	"io"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/tracking"
+)
+
+// Codec encodes targets like MarshalTarget for an LD2451.EncodedSink.
+var Codec LD2451.Codec = LD2451.CodecFunc(func(buf []byte, target LD2451.Target) ([]byte, error) {
+	return appendTarget(buf, target), nil
+})
+
+// NewSink writes targets to w as MessagePack maps following each other, which
+// a streaming unpacker reads one by one.
+func NewSink(w io.Writer) *LD2451.EncodedSink {
+	return LD2451.NewEncodedSink(w, Codec)
+}
+
+// MarshalTarget encodes target as a MessagePack map.
+func MarshalTarget(target LD2451.Target) []byte {
+	return appendTarget(nil, target)
+}
+
+// UnmarshalTarget decodes a map written by MarshalTarget. Unknown keys are
+// skipped.
+func UnmarshalTarget(data []byte) (LD2451.Target, error) {
+	d := &decoder{data: data}
+	return d.target()
+}
+
+// MarshalFrame encodes frame as a MessagePack map.
+func MarshalFrame(frame LD2451.Frame) []byte {
+	e := &encoder{}
+	e.begin()
+	if len(frame.Targets) > 0 {
+		e.key("targets")
+		e.buf = appendArray(e.buf, len(frame.Targets))
+		for _, target := range frame.Targets {
+			e.buf = appendTarget(e.buf, target)
+		}
+	}
+	e.bool("alarm", frame.Alarm)
+	e.time("time", frame.Time)
+	e.string("sensor", frame.Sensor)
+	e.end()
+	return e.buf
+}
+
+// UnmarshalFrame decodes a map written by MarshalFrame.
+func UnmarshalFrame(data []byte) (LD2451.Frame, error) {
+	frame := LD2451.Frame{}
+	d := &decoder{data: data}
+	fields, err := d.mapLen()
+	if err != nil {
+		return frame, err
+	}
+	for range fields {
+		key, err := d.key()
+		if err != nil {
+			return frame, err
+		}
+		switch key {
+		case "targets":
+			n, err := d.arrayLen()
+			if err != nil {
+				return frame, err
+			}
+			frame.Targets = make([]LD2451.Target, 0, n)
+			for range n {
+				target, err := d.target()
+				if err != nil {
+					return frame, err
+				}
+				frame.Targets = append(frame.Targets, target)
+			}
+		case "alarm":
+			frame.Alarm, err = d.bool()
+		case "time":
+			frame.Time, err = d.time()
+		case "sensor":
+			frame.Sensor, err = d.string()
+		default:
+			err = d.skip(0)
+		}
+		if err != nil {
+			return frame, err
+		}
+	}
+	return frame, nil
+}
+
+// MarshalTrack encodes track as a MessagePack map, leaving out the arrival
+// and acceleration estimates.
+func MarshalTrack(track tracking.Track) []byte {
+	e := &encoder{}
+	e.begin()
+	e.int("id", int64(track.ID))
+	e.int("direction", int64(track.Direction))
+	e.time("start", track.Start)
+	e.time("end", track.End)
+	e.int("detections", int64(track.Detections))
+	e.int("distance", int64(track.Distance))
+	e.int("entry_distance", int64(track.EntryDistance))
+	e.int("angle", int64(track.Angle))
+	e.int("speed", int64(track.Speed))
+	e.int("max_speed", int64(track.MaxSpeed))
+	e.float("mean_speed", track.MeanSpeed)
+	e.int("max_snr", int64(track.MaxSNR))
+	e.float("mean_snr", track.MeanSNR)
+	e.int("class", int64(track.Class))
+	e.int("lane", int64(track.Lane))
+	e.string("band", track.Band)
+	e.string("sensor", track.Sensor)
+	e.end()
+	return e.buf
+}
+
+func appendTarget(buf []byte, target LD2451.Target) []byte {
+	e := &encoder{buf: buf}
+	e.begin()
+	e.int("angle", int64(target.Angle))
+	e.int("distance", int64(target.Distance))
+	e.int("direction", int64(target.Direction))
+	e.int("speed", int64(target.Speed))
+	e.int("snr", int64(target.SNR))
+	e.time("time", target.Time)
+	e.bool("fine", target.Fine)
+	e.float("fine_distance", target.FineDistance)
+	e.float("fine_speed", target.FineSpeed)
+	e.string("sensor", target.Sensor)
+	e.float("radial_speed", target.RadialSpeed)
+	e.end()
+	return e.buf
+}
+
+func (d *decoder) target() (LD2451.Target, error) {
+	target := LD2451.Target{}
+	fields, err := d.mapLen()
+	if err != nil {
+		return target, err
+	}
+	for range fields {
+		key, err := d.key()
+		if err != nil {
+			return target, err
+		}
+		var v int64
+		switch key {
+		case "angle", "distance", "direction", "speed", "snr":
+			v, err = d.int()
+		case "time":
+			target.Time, err = d.time()
+		case "fine":
+			target.Fine, err = d.bool()
+		case "fine_distance":
+			target.FineDistance, err = d.float()
+		case "fine_speed":
+			target.FineSpeed, err = d.float()
+		case "sensor":
+			target.Sensor, err = d.string()
+		case "radial_speed":
+			target.RadialSpeed, err = d.float()
+		default:
+			err = d.skip(0)
+		}
+		if err != nil {
+			return target, err
+		}
+		switch key {
+		case "angle":
+			target.Angle = int(v)
+		case "distance":
+			target.Distance = int(v)
+		case "direction":
+			target.Direction = LD2451.Direction(v)
+		case "speed":
+			target.Speed = int(v)
+		case "snr":
+			target.SNR = int(v)
+		}
+	}
+	return target, nil
+}
@@ -0,0 +1,158 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// The vectors follow the formats of the MessagePack specification.
+func TestIntVectors(t *testing.T) {
+	tests := []struct {
+		v   int64
+		hex string
+	}{
+		{0, "00"},   //positive fixint
+		{127, "7f"}, //positive fixint
+		{-1, "ff"},  //negative fixint
+		{-32, "e0"}, //negative fixint
+		{128, "cc80"},
+		{255, "ccff"},
+		{256, "cd0100"},
+		{65535, "cdffff"},
+		{65536, "ce00010000"},
+		{1<<32 - 1, "ceffffffff"},
+		{1 << 32, "cf0000000100000000"},
+		{1<<63 - 1, "cf7fffffffffffffff"},
+		{-33, "d0df"},
+		{-128, "d080"},
+		{-129, "d1ff7f"},
+		{-32768, "d18000"},
+		{-32769, "d2ffff7fff"},
+		{-1 << 31, "d280000000"},
+		{-1<<31 - 1, "d3ffffffff7fffffff"},
+		{-1 << 63, "d38000000000000000"},
+	}
+	for _, test := range tests {
+		want := mustHex(t, test.hex)
+		if got := appendInt(nil, test.v); !bytes.Equal(got, want) {
+			t.Errorf("appendInt(%d) = %x, want %s", test.v, got, test.hex)
+		}
+		d := &decoder{data: want}
+		if got, err := d.int(); err != nil || got != test.v {
+			t.Errorf("decoding %s = %d, %v, want %d", test.hex, got, err, test.v)
+		}
+	}
+}
+
+func TestStringVectors(t *testing.T) {
+	tests := []struct {
+		s    string
+		head string
+	}{
+		{"", "a0"},                        //fixstr
+		{"a", "a1"},                       //fixstr
+		{strings.Repeat("x", 31), "bf"},   //fixstr
+		{strings.Repeat("x", 32), "d920"}, //str 8
+		{strings.Repeat("x", 255), "d9ff"},
+		{strings.Repeat("x", 256), "da0100"},       //str 16
+		{strings.Repeat("x", 65536), "db00010000"}, //str 32
+	}
+	for _, test := range tests {
+		want := append(mustHex(t, test.head), test.s...)
+		if got := appendString(nil, test.s); !bytes.Equal(got, want) {
+			t.Errorf("appendString of %d bytes starts with %x, want %s", len(test.s), got[:min(len(got), 5)], test.head)
+		}
+		d := &decoder{data: want}
+		if got, err := d.string(); err != nil || got != test.s {
+			t.Errorf("decoding the string of %d bytes failed: %v", len(test.s), err)
+		}
+	}
+}
+
+func TestTimestampVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		hex  string
+	}{
+		{"timestamp 32", time.Unix(1, 0), "d6ff00000001"},
+		{"timestamp 32 max", time.Unix(1<<32-1, 0), "d6ffffffffff"},
+		{"timestamp 64", time.Unix(1, 1), "d7ff0000000400000001"},
+		{"timestamp 64 max", time.Unix(1<<34-1, 999999999), "d7ffee6b27ffffffffff"},
+		{"timestamp 96 negative", time.Unix(-1, 0), "c70cff00000000ffffffffffffffff"},
+		{"timestamp 96 far", time.Unix(1<<34, 5), "c70cff000000050000000400000000"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := &encoder{}
+			e.time("t", test.t)
+			want := append(mustHex(t, "a174"), mustHex(t, test.hex)...)
+			if !bytes.Equal(e.buf, want) {
+				t.Fatalf("encoded as %x, want a174%s", e.buf, test.hex)
+			}
+			d := &decoder{data: mustHex(t, test.hex)}
+			got, err := d.time()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(test.t) || got.Location() != time.UTC {
+				t.Fatalf("decoded as %s, want %s in UTC", got, test.t.UTC())
+			}
+		})
+	}
+}
+
+func TestTargetRoundTrip(t *testing.T) {
+	targets := []LD2451.Target{
+		{},
+		{Angle: -20, Distance: 45, Direction: LD2451.DirectionToward, Speed: 48, SNR: 180, Time: time.Now(), Sensor: "north"},
+		{Distance: 3, Time: time.Unix(1700000000, 0), Fine: true, FineDistance: 3.25, FineSpeed: -7.5, RadialSpeed: 1.1},
+		{Distance: 2, Fine: true, FineDistance: 2.5},
+	}
+	for _, target := range targets {
+		got, err := UnmarshalTarget(MarshalTarget(target))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Time.Equal(target.Time) {
+			t.Fatalf("time came back as %s, want %s", got.Time, target.Time)
+		}
+		got.Time = target.Time
+		if got != target {
+			t.Fatalf("got %+v, want %+v", got, target)
+		}
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	frame := LD2451.Frame{
+		Targets: []LD2451.Target{{Distance: 10, Speed: 30}, {Angle: 5, FineSpeed: 12.5}},
+		Alarm:   true,
+		Time:    time.Now(),
+		Sensor:  "north",
+	}
+	got, err := UnmarshalFrame(MarshalFrame(frame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Targets) != 2 || got.Targets[0] != frame.Targets[0] || got.Targets[1] != frame.Targets[1] {
+		t.Fatalf("got targets %+v, want %+v", got.Targets, frame.Targets)
+	}
+	if !got.Alarm || !got.Time.Equal(frame.Time) || got.Sensor != frame.Sensor {
+		t.Fatalf("got %+v, want %+v", got, frame)
+	}
+}
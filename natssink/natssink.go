@@ -0,0 +1,113 @@
+// Package natssink publishes LD2451 targets and events to NATS subjects. It
+// only depends on a Publisher, which *nats.Conn from github.com/nats-io/nats.go
+// satisfies, so the NATS client stays out of the library's dependencies.
+package natssink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// Publisher is implemented by *nats.Conn.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+const (
+	DefaultTargetSubject    = "ld2451.targets.{{lower .Direction}}"
+	DefaultAlarmSubject     = "ld2451.alarms"
+	DefaultHeartbeatSubject = "ld2451.heartbeats"
+)
+
+// Config holds the subject templates. Templates are executed with the
+// published value (LD2451.Target, LD2451.AlarmEvent or LD2451.Heartbeat) and
+// a lower function, so "radar.{{lower .Direction}}" publishes targets to
+// radar.away and radar.toward.
+type Config struct {
+	TargetSubject    string // Defaults to DefaultTargetSubject
+	AlarmSubject     string // Defaults to DefaultAlarmSubject
+	HeartbeatSubject string // Defaults to DefaultHeartbeatSubject
+}
+
+var funcs = template.FuncMap{
+	"lower": func(v any) string { return strings.ToLower(fmt.Sprint(v)) },
+}
+
+// Sink implements LD2451.Sink, and LD2451.AlarmHandler and
+// LD2451.HeartbeatHandler to publish alarms and heartbeats with RunSink.
+type Sink struct {
+	publisher Publisher
+	target    *template.Template
+	alarm     *template.Template
+	heartbeat *template.Template
+}
+
+func New(publisher Publisher, config Config) (*Sink, error) {
+	sink := &Sink{publisher: publisher}
+	subjects := []struct {
+		dst  **template.Template
+		text string
+		def  string
+	}{
+		{&sink.target, config.TargetSubject, DefaultTargetSubject},
+		{&sink.alarm, config.AlarmSubject, DefaultAlarmSubject},
+		{&sink.heartbeat, config.HeartbeatSubject, DefaultHeartbeatSubject},
+	}
+	for _, subject := range subjects {
+		text := subject.text
+		if text == "" {
+			text = subject.def
+		}
+		tmpl, err := template.New("subject").Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		*subject.dst = tmpl
+	}
+	return sink, nil
+}
+
+func (s *Sink) PublishTarget(target LD2451.Target) error {
+	return s.publish(s.target, target)
+}
+
+func (s *Sink) PublishAlarm(event LD2451.AlarmEvent) error {
+	return s.publish(s.alarm, event)
+}
+
+func (s *Sink) PublishHeartbeat(heartbeat LD2451.Heartbeat) error {
+	return s.publish(s.heartbeat, heartbeat)
+}
+
+func (s *Sink) Write(target LD2451.Target) error          { return s.PublishTarget(target) }
+func (s *Sink) HandleAlarm(event LD2451.AlarmEvent) error { return s.PublishAlarm(event) }
+func (s *Sink) HandleHeartbeat(heartbeat LD2451.Heartbeat) error {
+	return s.PublishHeartbeat(heartbeat)
+}
+
+// Flush does nothing, every value is published right away.
+func (s *Sink) Flush() error { return nil }
+
+func (s *Sink) Close() error { return nil }
+
+// Run is shorthand for sensor.RunSink(ctx, s).
+func (s *Sink) Run(ctx context.Context, sensor *LD2451.LD2451) error {
+	return sensor.RunSink(ctx, s)
+}
+
+func (s *Sink) publish(subject *template.Template, value any) error {
+	var name strings.Builder
+	if err := subject.Execute(&name, value); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.publisher.Publish(name.String(), data)
+}
@@ -0,0 +1,117 @@
+package natssink_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/natssink"
+)
+
+type message struct {
+	subject string
+	data    []byte
+}
+
+// publisher records what was published, failing with err when set.
+type publisher struct {
+	messages []message
+	err      error
+}
+
+func (p *publisher) Publish(subject string, data []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, message{subject, data})
+	return nil
+}
+
+func TestSubjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  natssink.Config
+		publish func(*natssink.Sink) error
+		subject string
+	}{
+		{"target away", natssink.Config{}, func(s *natssink.Sink) error {
+			return s.Write(LD2451.Target{Direction: LD2451.DirectionAway, Speed: 40})
+		}, "ld2451.targets.away"},
+		{"target toward", natssink.Config{}, func(s *natssink.Sink) error {
+			return s.Write(LD2451.Target{Direction: LD2451.DirectionToward, Speed: 40})
+		}, "ld2451.targets.toward"},
+		{"alarm", natssink.Config{}, func(s *natssink.Sink) error {
+			return s.HandleAlarm(LD2451.AlarmEvent{Active: true})
+		}, "ld2451.alarms"},
+		{"heartbeat", natssink.Config{}, func(s *natssink.Sink) error {
+			return s.HandleHeartbeat(LD2451.Heartbeat{})
+		}, "ld2451.heartbeats"},
+		{"per sensor", natssink.Config{TargetSubject: "radar.{{.Sensor}}.{{lower .Direction}}"}, func(s *natssink.Sink) error {
+			return s.Write(LD2451.Target{Direction: LD2451.DirectionToward, Sensor: "north"})
+		}, "radar.north.toward"},
+		{"custom alarm subject", natssink.Config{AlarmSubject: "radar.{{.Sensor}}.alarm"}, func(s *natssink.Sink) error {
+			return s.PublishAlarm(LD2451.AlarmEvent{Sensor: "south"})
+		}, "radar.south.alarm"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &publisher{}
+			sink, err := natssink.New(p, test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.publish(sink); err != nil {
+				t.Fatal(err)
+			}
+			if len(p.messages) != 1 || p.messages[0].subject != test.subject {
+				t.Fatalf("published %+v, expected one message to %s", p.messages, test.subject)
+			}
+			if !json.Valid(p.messages[0].data) {
+				t.Errorf("published %q", p.messages[0].data)
+			}
+		})
+	}
+}
+
+func TestTargetPayload(t *testing.T) {
+	p := &publisher{}
+	sink, err := natssink.New(p, natssink.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := LD2451.Target{Angle: -5, Distance: 20, Direction: LD2451.DirectionToward, Speed: 40, SNR: 9, Sensor: "north"}
+	if err := sink.PublishTarget(target); err != nil {
+		t.Fatal(err)
+	}
+	var published LD2451.Target
+	if err := json.Unmarshal(p.messages[0].data, &published); err != nil {
+		t.Fatal(err)
+	}
+	if published != target {
+		t.Errorf("published %+v, expected %+v", published, target)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := natssink.New(&publisher{}, natssink.Config{TargetSubject: "radar.{{.Sensor"}); err == nil {
+		t.Error("accepted a broken template")
+	}
+
+	sink, err := natssink.New(&publisher{}, natssink.Config{TargetSubject: "radar.{{.Lane}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(LD2451.Target{}); err == nil {
+		t.Error("published to a subject naming a missing field")
+	}
+
+	errDisconnected := errors.New("disconnected")
+	sink, err = natssink.New(&publisher{err: errDisconnected}, natssink.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(LD2451.Target{}); !errors.Is(err, errDisconnected) {
+		t.Errorf("got %v, expected the publisher's error", err)
+	}
+}
@@ -0,0 +1,145 @@
+package LD2451
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultNoiseWindow  = 10 * time.Minute
+	DefaultNoiseMargin  = 3
+	DefaultClutterSpeed = 5
+	DefaultClutterRate  = 1
+)
+
+// NoiseProfile configures AdaptiveSNR. Rates are in detections per second,
+// so they depend on the frame rate of the module.
+type NoiseProfile struct {
+	Window       time.Duration // Time constant of the learned profile, clutter showing up or going away is picked up within about a window (default DefaultNoiseWindow)
+	Margin       int           // SNR a target needs above the noise floor learned at its distance (default DefaultNoiseMargin)
+	ClutterSpeed int           // KM/H up to which a detection counts as clutter, such as swaying trees or a fence shaking in the wind (default DefaultClutterSpeed)
+	ClutterRate  float64       // Slow detections per second from which a distance counts as cluttered (default DefaultClutterRate)
+}
+
+// NoiseCell is what AdaptiveSNR learned about a distance.
+type NoiseCell struct {
+	Distance  int     `json:"distance"`
+	Floor     float64 `json:"floor"` // Mean SNR of the recent slow detections
+	Rate      float64 `json:"rate"`  // Slow detections per second, averaged over the window
+	Cluttered bool    `json:"cluttered"`
+}
+
+// NoiseFilter is the Filter returned by AdaptiveSNR. It is safe for
+// concurrent use, so Profile can be called while targets are filtered.
+type NoiseFilter struct {
+	config NoiseProfile
+
+	mu     sync.Mutex
+	cells  map[int]*noiseCell
+	latest time.Time //time of the latest target
+}
+
+// noiseCell holds the slow detections at a distance, decayed exponentially
+// with NoiseProfile.Window.
+type noiseCell struct {
+	count float64
+	sum   float64 //SNR of the counted detections
+	last  time.Time
+}
+
+// AdaptiveSNR learns the noise floor per distance from the slow detections
+// the module keeps reporting there, and suppresses targets at a cluttered
+// distance unless their SNR exceeds the floor by NoiseProfile.Margin. Unlike
+// MinSNR or DistanceSNR it needs no tuning for the site, keeps full
+// sensitivity where nothing is in the way and follows clutter that changes
+// with the wind or season. Time is taken from the targets, so replays learn
+// like live data. The filter only learns from the targets it sees, so place
+// it before filters dropping slow targets such as MinSpeed, and use a
+// separate one per sensor.
+func AdaptiveSNR(config NoiseProfile) *NoiseFilter {
+	if config.Window <= 0 {
+		config.Window = DefaultNoiseWindow
+	}
+	if config.Margin <= 0 {
+		config.Margin = DefaultNoiseMargin
+	}
+	if config.ClutterSpeed <= 0 {
+		config.ClutterSpeed = DefaultClutterSpeed
+	}
+	if config.ClutterRate <= 0 {
+		config.ClutterRate = DefaultClutterRate
+	}
+	return &NoiseFilter{config: config, cells: make(map[int]*noiseCell)}
+}
+
+// Allow judges target against what was learned at its distance before it,
+// then learns from it.
+func (f *NoiseFilter) Allow(target Target) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if target.Time.After(f.latest) {
+		f.latest = target.Time
+	}
+	cell := f.cells[target.Distance]
+	if cell == nil {
+		cell = &noiseCell{last: target.Time}
+		f.cells[target.Distance] = cell
+	}
+	cell.decay(target.Time, f.config.Window)
+
+	floor, cluttered := f.floor(*cell)
+	allowed := !cluttered || float64(target.SNR) >= floor+float64(f.config.Margin)
+	//suppressed detections keep teaching the filter, otherwise it would forget the clutter
+	if target.Speed <= f.config.ClutterSpeed {
+		cell.count++
+		cell.sum += float64(target.SNR)
+	}
+	return allowed
+}
+
+// Profile returns the learned noise floor of every distance targets were
+// seen at, nearest first, as of the latest target.
+func (f *NoiseFilter) Profile() []NoiseCell {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	profile := make([]NoiseCell, 0, len(f.cells))
+	for distance, cell := range f.cells {
+		decayed := *cell
+		decayed.decay(f.latest, f.config.Window)
+		floor, cluttered := f.floor(decayed)
+		profile = append(profile, NoiseCell{
+			Distance:  distance,
+			Floor:     floor,
+			Rate:      decayed.count / f.config.Window.Seconds(),
+			Cluttered: cluttered,
+		})
+	}
+	slices.SortFunc(profile, func(a, b NoiseCell) int { return a.Distance - b.Distance })
+	return profile
+}
+
+// Reset forgets everything learned, e.g. after the sensor was moved.
+func (f *NoiseFilter) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.cells)
+}
+
+func (f *NoiseFilter) floor(cell noiseCell) (floor float64, cluttered bool) {
+	if cell.count == 0 {
+		return 0, false
+	}
+	return cell.sum / cell.count, cell.count/f.config.Window.Seconds() >= f.config.ClutterRate
+}
+
+func (c *noiseCell) decay(t time.Time, window time.Duration) {
+	if !t.After(c.last) {
+		return
+	}
+	factor := math.Exp(-t.Sub(c.last).Seconds() / window.Seconds())
+	c.count *= factor
+	c.sum *= factor
+	c.last = t
+}
@@ -0,0 +1,73 @@
+package LD2451_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestAdaptiveSNR(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	//a bush swaying at 20 m, reported ten times a second for three seconds
+	learn := func(filter *LD2451.NoiseFilter) {
+		for i := range 30 {
+			filter.Allow(LD2451.Target{Distance: 20, Speed: 2, SNR: 10, Time: start.Add(time.Duration(i) * 100 * time.Millisecond)})
+		}
+	}
+	learned := start.Add(3 * time.Second)
+	tests := []struct {
+		name    string
+		target  LD2451.Target
+		allowed bool
+	}{
+		{"weak at the clutter", LD2451.Target{Distance: 20, Speed: 50, SNR: 12, Time: learned}, false},
+		{"strong at the clutter", LD2451.Target{Distance: 20, Speed: 50, SNR: 14, Time: learned}, true},
+		{"slow at the clutter", LD2451.Target{Distance: 20, Speed: 3, SNR: 10, Time: learned}, false},
+		{"weak elsewhere", LD2451.Target{Distance: 40, Speed: 50, SNR: 1, Time: learned}, true},
+		{"clutter gone for a while", LD2451.Target{Distance: 20, Speed: 50, SNR: 5, Time: learned.Add(time.Minute)}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := LD2451.AdaptiveSNR(LD2451.NoiseProfile{Window: 10 * time.Second})
+			learn(filter)
+			if allowed := filter.Allow(test.target); allowed != test.allowed {
+				t.Errorf("allowed %t, expected %t", allowed, test.allowed)
+			}
+		})
+	}
+}
+
+func TestAdaptiveSNRProfile(t *testing.T) {
+	filter := LD2451.AdaptiveSNR(LD2451.NoiseProfile{Window: 10 * time.Second})
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	for i := range 30 {
+		at := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		filter.Allow(LD2451.Target{Distance: 20, Speed: 2, SNR: 8 + 4*(i%2), Time: at})
+		//a car passing now and then is no clutter
+		if i%10 == 0 {
+			filter.Allow(LD2451.Target{Distance: 35, Speed: 60, SNR: 30, Time: at})
+		}
+	}
+	filter.Allow(LD2451.Target{Distance: 5, Speed: 1, SNR: 4, Time: start.Add(3 * time.Second)})
+
+	profile := filter.Profile()
+	if len(profile) != 3 || profile[0].Distance != 5 || profile[1].Distance != 20 || profile[2].Distance != 35 {
+		t.Fatalf("got profile %+v, expected distances 5, 20 and 35", profile)
+	}
+	if cell := profile[1]; !cell.Cluttered || math.Abs(cell.Floor-10) > 0.5 || cell.Rate < 1 {
+		t.Errorf("clutter learned as %+v", cell)
+	}
+	if cell := profile[0]; cell.Cluttered || cell.Floor != 4 {
+		t.Errorf("single detection learned as %+v", cell)
+	}
+	if cell := profile[2]; cell.Cluttered || cell.Rate != 0 {
+		t.Errorf("passing cars learned as %+v", cell)
+	}
+
+	filter.Reset()
+	if profile := filter.Profile(); len(profile) != 0 {
+		t.Errorf("Reset left %+v", profile)
+	}
+}
@@ -0,0 +1,301 @@
+// Package notify sends chat messages to Telegram or Slack when a target
+// exceeds a speed limit, the alarm changes or the sensor goes offline. The
+// events are picked like webhook notifications, and messages of each kind
+// are throttled so a busy road doesn't flood the channel.
+//
+//	notifier, err := notify.New(sensor, &notify.Telegram{Token: token, ChatID: "-1001234"}, notify.Config{
+//		SpeedLimit: 50,
+//		Events:     []webhook.EventType{webhook.EventSpeeding, webhook.EventOffline, webhook.EventOnline},
+//	})
+//	...
+//	go notifier.Run(ctx)
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/webhook"
+)
+
+const (
+	DefaultThrottle  = time.Minute
+	DefaultTimeout   = 10 * time.Second
+	DefaultQueueSize = 16
+
+	telegramAPI = "https://api.telegram.org"
+)
+
+// Messenger delivers a text message to a chat.
+type Messenger interface {
+	Send(ctx context.Context, text string) error
+}
+
+// Telegram sends messages through a bot created with @BotFather.
+type Telegram struct {
+	Token   string
+	ChatID  string       // Numeric ID of the chat or @channelname
+	BaseURL string       // Bot API server (default https://api.telegram.org)
+	Client  *http.Client // Defaults to http.DefaultClient
+}
+
+func (t *Telegram) Send(ctx context.Context, text string) error {
+	base := t.BaseURL
+	if base == "" {
+		base = telegramAPI
+	}
+	body, _ := json.Marshal(map[string]string{"chat_id": t.ChatID, "text": text})
+	resp, err := post(ctx, t.Client, base+"/bot"+t.Token+"/sendMessage", body)
+	if err != nil {
+		//the URL holds the token, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("telegram: %w", urlErr.Err)
+		}
+		return err
+	}
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram: %s", result.Description)
+	}
+	return nil
+}
+
+// Slack sends messages to an incoming webhook of a Slack app.
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client // Defaults to http.DefaultClient
+}
+
+func (s *Slack) Send(ctx context.Context, text string) error {
+	body, _ := json.Marshal(map[string]string{"text": text})
+	resp, err := post(ctx, s.Client, s.WebhookURL, body)
+	if err != nil {
+		return err
+	}
+	if answer := strings.TrimSpace(string(resp)); answer != "ok" {
+		return fmt.Errorf("slack: %s", answer)
+	}
+	return nil
+}
+
+// post sends body as JSON and returns the response body of a 2xx answer.
+func post(ctx context.Context, client *http.Client, endpoint string, body []byte) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	//telegram reports its errors in the body of 4xx answers
+	if resp.StatusCode >= 300 && !json.Valid(answer) {
+		return nil, fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	return answer, nil
+}
+
+type Config struct {
+	Events     []webhook.EventType // Events sent as messages, all of them when empty
+	SpeedLimit int                 // Targets faster than this many KM/H are sent as webhook.EventSpeeding, zero disables them
+	Throttle   time.Duration       // Minimum time between two messages of the same event, suppressed ones are counted in the next (default DefaultThrottle)
+	Name       string              // Prefixes every message, e.g. the location of the sensor
+	Timeout    time.Duration       // Limit for sending a single message (default DefaultTimeout)
+	QueueSize  int                 // Messages waiting to be sent before new ones are dropped (default DefaultQueueSize)
+	Logger     LD2451.Logger       // Receives failed sends, discarded when nil
+
+	TowardSpeedLimit int // Speed limit for approaching targets instead of SpeedLimit, zero uses SpeedLimit
+	AwaySpeedLimit   int // Speed limit for receding targets instead of SpeedLimit, zero uses SpeedLimit
+}
+
+type throttle struct {
+	last       time.Time
+	suppressed int
+}
+
+type Notifier struct {
+	sensor    *LD2451.LD2451
+	messenger Messenger
+	config    Config
+	trigger   webhook.Trigger
+	throttles map[webhook.EventType]*throttle
+	queue     chan string
+
+	mu      sync.Mutex
+	dropped uint64
+}
+
+func New(sensor *LD2451.LD2451, messenger Messenger, config Config) (*Notifier, error) {
+	for _, event := range config.Events {
+		if !webhook.ValidEvent(event) {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+	}
+	switch {
+	case config.SpeedLimit < 0:
+		return nil, fmt.Errorf("speed limit %d is negative", config.SpeedLimit)
+	case config.TowardSpeedLimit < 0:
+		return nil, fmt.Errorf("toward speed limit %d is negative", config.TowardSpeedLimit)
+	case config.AwaySpeedLimit < 0:
+		return nil, fmt.Errorf("away speed limit %d is negative", config.AwaySpeedLimit)
+	case config.Throttle < 0:
+		return nil, fmt.Errorf("throttle %s is negative", config.Throttle)
+	}
+	if config.Throttle == 0 {
+		config.Throttle = DefaultThrottle
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	trigger := webhook.Trigger{
+		SpeedLimit:       config.SpeedLimit,
+		SpeedingCooldown: webhook.DefaultSpeedingCooldown,
+		TowardSpeedLimit: config.TowardSpeedLimit,
+		AwaySpeedLimit:   config.AwaySpeedLimit,
+	}
+	return &Notifier{
+		sensor:    sensor,
+		messenger: messenger,
+		config:    config,
+		trigger:   trigger,
+		throttles: make(map[webhook.EventType]*throttle),
+		queue:     make(chan string, config.QueueSize),
+	}, nil
+}
+
+// Dropped returns the number of messages dropped because the queue was full.
+// Throttled messages are not counted.
+func (n *Notifier) Dropped() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.dropped
+}
+
+// Run subscribes to the sensor's events and sends messages until ctx is done
+// or the sensor is closed, in which case queued messages are sent before Run
+// returns.
+func (n *Notifier) Run(ctx context.Context) error {
+	events, cancel := n.sensor.Events()
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for text := range n.queue {
+			sendCtx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+			err := n.messenger.Send(sendCtx, text)
+			cancel()
+			if err != nil && n.config.Logger != nil {
+				n.config.Logger.Warn("notification failed", "err", err)
+			}
+		}
+	}()
+	defer func() {
+		close(n.queue)
+		<-done
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			payload, ok := n.trigger.Match(event)
+			if !ok || (len(n.config.Events) > 0 && !slices.Contains(n.config.Events, payload.Event)) {
+				continue
+			}
+			if text, ok := n.throttle(payload); ok {
+				n.enqueue(text)
+			}
+		}
+	}
+}
+
+// throttle formats payload unless a message of the same event was sent within
+// Config.Throttle.
+func (n *Notifier) throttle(payload webhook.Payload) (string, bool) {
+	t := n.throttles[payload.Event]
+	if t == nil {
+		t = &throttle{}
+		n.throttles[payload.Event] = t
+	}
+	if !t.last.IsZero() && payload.Time.Sub(t.last) < n.config.Throttle {
+		t.suppressed++
+		return "", false
+	}
+	text := Format(payload)
+	if n.config.Name != "" {
+		text = n.config.Name + ": " + text
+	}
+	if t.suppressed > 0 {
+		text += fmt.Sprintf(" (%d more since %s)", t.suppressed, t.last.Format("15:04"))
+	}
+	t.last, t.suppressed = payload.Time, 0
+	return text, true
+}
+
+func (n *Notifier) enqueue(text string) {
+	select {
+	case n.queue <- text:
+	default:
+		n.mu.Lock()
+		n.dropped++
+		n.mu.Unlock()
+	}
+}
+
+// Format renders payload as a one line message.
+func Format(payload webhook.Payload) string {
+	switch payload.Event {
+	case webhook.EventSpeeding:
+		target := payload.Target
+		return fmt.Sprintf("Speeding: %d km/h %s at %d m, %d°", target.Speed, strings.ToLower(target.Direction.String()), target.Distance, target.Angle)
+	case webhook.EventAlarm:
+		if payload.Alarm.Active {
+			return "Alarm raised"
+		}
+		return "Alarm cleared"
+	case webhook.EventOffline:
+		if payload.Error != "" {
+			return "Sensor offline: " + payload.Error
+		}
+		return "Sensor offline"
+	case webhook.EventOnline:
+		return "Sensor back online"
+	default:
+		return string(payload.Event)
+	}
+}
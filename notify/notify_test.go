@@ -0,0 +1,152 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/notify"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+	"github.com/Battlekeeper/LD2451/v2/webhook"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		payload webhook.Payload
+		text    string
+	}{
+		{webhook.Payload{Event: webhook.EventSpeeding, Target: &LD2451.Target{Speed: 72, Direction: LD2451.DirectionToward, Distance: 20, Angle: -5}}, "Speeding: 72 km/h toward at 20 m, -5°"},
+		{webhook.Payload{Event: webhook.EventAlarm, Alarm: &LD2451.AlarmEvent{Active: true}}, "Alarm raised"},
+		{webhook.Payload{Event: webhook.EventAlarm, Alarm: &LD2451.AlarmEvent{}}, "Alarm cleared"},
+		{webhook.Payload{Event: webhook.EventOffline, Error: "port closed"}, "Sensor offline: port closed"},
+		{webhook.Payload{Event: webhook.EventOffline}, "Sensor offline"},
+		{webhook.Payload{Event: webhook.EventOnline}, "Sensor back online"},
+	}
+	for _, test := range tests {
+		if text := notify.Format(test.payload); text != test.text {
+			t.Errorf("formatted %s as %q, expected %q", test.payload.Event, text, test.text)
+		}
+	}
+}
+
+func TestTelegram(t *testing.T) {
+	var path string
+	var body map[string]string
+	answer := `{"ok":true}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(answer))
+	}))
+	defer server.Close()
+
+	telegram := &notify.Telegram{Token: "123:secret", ChatID: "-1001234", BaseURL: server.URL}
+	if err := telegram.Send(context.Background(), "Sensor offline"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/bot123:secret/sendMessage" || body["chat_id"] != "-1001234" || body["text"] != "Sensor offline" {
+		t.Errorf("sent %v to %s", body, path)
+	}
+
+	answer = `{"ok":false,"description":"Forbidden: bot was blocked by the user"}`
+	if err := telegram.Send(context.Background(), "Sensor offline"); err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("got %v, expected the description of the failure", err)
+	}
+
+	server.Close()
+	err := telegram.Send(context.Background(), "Sensor offline")
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("got %v, expected an error without the token", err)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	status, answer := http.StatusOK, "ok"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		io.WriteString(w, answer)
+	}))
+	defer server.Close()
+
+	slack := &notify.Slack{WebhookURL: server.URL}
+	if err := slack.Send(context.Background(), "Alarm raised"); err != nil {
+		t.Fatal(err)
+	}
+	status, answer = http.StatusNotFound, "no_service"
+	if err := slack.Send(context.Background(), "Alarm raised"); err == nil {
+		t.Error("sent to a webhook that was removed")
+	}
+}
+
+// messenger passes the messages it is asked to send on.
+type messenger chan string
+
+func (m messenger) Send(ctx context.Context, text string) error {
+	m <- text
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	radar, err := LD2451.Open(sensor.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer radar.Close()
+
+	messages := make(messenger, 16)
+	notifier, err := notify.New(radar, messages, notify.Config{Name: "north", SpeedLimit: 100, TowardSpeedLimit: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- notifier.Run(ctx) }()
+
+	//receding targets are held to SpeedLimit, approaching ones to TowardSpeedLimit
+	timeout := time.After(2 * time.Second)
+	for received := false; !received; {
+		sensor.SendTargets(false, LD2451.Target{Distance: 30, Direction: LD2451.DirectionAway, Speed: 60, SNR: 9})
+		sensor.SendTargets(false, LD2451.Target{Distance: 20, Direction: LD2451.DirectionToward, Speed: 40, SNR: 9})
+		select {
+		case text := <-messages:
+			if text != "north: Speeding: 40 km/h toward at 20 m, 0°" {
+				t.Fatalf("sent %q", text)
+			}
+			received = true
+		case <-time.After(5 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("no message sent")
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v", err)
+	}
+}
+
+func TestNewRejects(t *testing.T) {
+	for _, config := range []notify.Config{
+		{Events: []webhook.EventType{"flood"}},
+		{SpeedLimit: -1},
+		{TowardSpeedLimit: -1},
+		{AwaySpeedLimit: -1},
+		{Throttle: -time.Second},
+	} {
+		if _, err := notify.New(nil, make(messenger), config); err == nil {
+			t.Errorf("accepted %+v", config)
+		}
+	}
+}
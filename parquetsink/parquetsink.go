@@ -0,0 +1,256 @@
+// Package parquetsink writes LD2451 targets to Parquet files, so months of
+// traffic data can be analyzed in DuckDB or pandas without a database in
+// between:
+//
+//	SELECT date_trunc('hour', time), quantile_cont(speed, 0.85)
+//	FROM 'targets/*.parquet' WHERE direction = 'toward' GROUP BY 1
+//
+// Parquet files can't be appended to, so targets are collected in memory and
+// a new file is written every Config.RotateInterval. Like the protobuf
+// package the format is written by hand: uncompressed, PLAIN encoded columns
+// in a single row group, which every Parquet reader understands.
+package parquetsink
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	DefaultRotateInterval = time.Hour
+	DefaultMaxRows        = 1_000_000
+	DefaultPrefix         = "targets-"
+)
+
+const magic = "PAR1"
+
+// Parquet enum values.
+const (
+	typeInt32     = 1
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	repetitionRequired = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	pageData           = 0
+)
+
+type Config struct {
+	Dir            string        // Directory the files are written to
+	Prefix         string        // Start of the file names, followed by the time of the first target (default DefaultPrefix)
+	RotateInterval time.Duration // A new file is started this long after the first target of the current one (default DefaultRotateInterval)
+	MaxRows        int           // A new file is also started after this many targets (default DefaultMaxRows)
+
+	Clock LD2451.Clock // Source of time RotateInterval is measured with, the one of the sensor; nil uses LD2451.SystemClock
+}
+
+// Sink implements LD2451.Sink. Files are written on Flush once they are due
+// and on Close.
+type Sink struct {
+	config  Config
+	targets []LD2451.Target
+	start   time.Time //time of the first target of the current file
+}
+
+func New(config Config) (*Sink, error) {
+	if info, err := os.Stat(config.Dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", config.Dir)
+	}
+	if config.Prefix == "" {
+		config.Prefix = DefaultPrefix
+	}
+	if config.RotateInterval <= 0 {
+		config.RotateInterval = DefaultRotateInterval
+	}
+	if config.MaxRows <= 0 {
+		config.MaxRows = DefaultMaxRows
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+	return &Sink{config: config}, nil
+}
+
+func (s *Sink) Write(target LD2451.Target) error {
+	if len(s.targets) == 0 {
+		s.start = target.Time
+	}
+	s.targets = append(s.targets, target)
+	if len(s.targets) >= s.config.MaxRows {
+		return s.rotate()
+	}
+	return nil
+}
+
+// Flush writes the current file when it is due.
+func (s *Sink) Flush() error {
+	if len(s.targets) > 0 && s.config.Clock.Now().Sub(s.start) >= s.config.RotateInterval {
+		return s.rotate()
+	}
+	return nil
+}
+
+// Close writes the targets collected so far.
+func (s *Sink) Close() error {
+	return s.rotate()
+}
+
+// rotate writes the collected targets to a file of their own. The file is
+// written under a temporary name and renamed, so readers never see a partial
+// file.
+func (s *Sink) rotate() error {
+	if len(s.targets) == 0 {
+		return nil
+	}
+	path := filepath.Join(s.config.Dir, s.config.Prefix+s.start.UTC().Format("20060102T150405.000Z")+".parquet")
+	tmp, err := os.CreateTemp(s.config.Dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := WriteTargets(tmp, s.targets); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	s.targets = s.targets[:0]
+	return nil
+}
+
+// column is a column of the file with its PLAIN encoded values.
+type column struct {
+	name      string
+	kind      int32
+	converted int32 //-1 for none
+	values    []byte
+}
+
+// WriteTargets writes targets as a complete Parquet file with the columns
+// time (timestamp in microseconds, UTC), angle, distance, direction ("away"
+// or "toward"), speed and snr, followed by sensor when the targets carry a
+// Config.SensorID.
+func WriteTargets(w io.Writer, targets []LD2451.Target) error {
+	columns := []*column{
+		{name: "time", kind: typeInt64, converted: convertedTimestampMicros},
+		{name: "angle", kind: typeInt32, converted: -1},
+		{name: "distance", kind: typeInt32, converted: -1},
+		{name: "direction", kind: typeByteArray, converted: convertedUTF8},
+		{name: "speed", kind: typeInt32, converted: -1},
+		{name: "snr", kind: typeInt32, converted: -1},
+	}
+	var sensor *column
+	if slices.ContainsFunc(targets, func(target LD2451.Target) bool { return target.Sensor != "" }) {
+		sensor = &column{name: "sensor", kind: typeByteArray, converted: convertedUTF8}
+		columns = append(columns, sensor)
+	}
+	for _, target := range targets {
+		columns[0].values = binary.LittleEndian.AppendUint64(columns[0].values, uint64(target.Time.UnixMicro()))
+		columns[1].values = binary.LittleEndian.AppendUint32(columns[1].values, uint32(int32(target.Angle)))
+		columns[2].values = binary.LittleEndian.AppendUint32(columns[2].values, uint32(int32(target.Distance)))
+		direction := strings.ToLower(target.Direction.String())
+		columns[3].values = binary.LittleEndian.AppendUint32(columns[3].values, uint32(len(direction)))
+		columns[3].values = append(columns[3].values, direction...)
+		columns[4].values = binary.LittleEndian.AppendUint32(columns[4].values, uint32(int32(target.Speed)))
+		columns[5].values = binary.LittleEndian.AppendUint32(columns[5].values, uint32(int32(target.SNR)))
+		if sensor != nil {
+			sensor.values = binary.LittleEndian.AppendUint32(sensor.values, uint32(len(target.Sensor)))
+			sensor.values = append(sensor.values, target.Sensor...)
+		}
+	}
+
+	rows := int64(len(targets))
+	buf := []byte(magic)
+	//column chunks hold a single data page each, their metadata goes into the footer
+	meta := &thrift{}
+	meta.begin()
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(columns)+1)
+	meta.begin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.end()
+	for _, c := range columns {
+		meta.begin()
+		meta.i32(1, c.kind)
+		meta.i32(3, repetitionRequired)
+		meta.binary(4, c.name)
+		if c.converted >= 0 {
+			meta.i32(6, c.converted)
+		}
+		meta.end()
+	}
+	meta.i64(3, rows)
+
+	chunks := &thrift{}
+	var total int64
+	for _, c := range columns {
+		offset := int64(len(buf))
+		header := &thrift{}
+		header.begin()
+		header.i32(1, pageData)
+		header.i32(2, int32(len(c.values)))
+		header.i32(3, int32(len(c.values)))
+		header.structField(5)
+		header.i32(1, int32(rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.end()
+		header.end()
+		buf = append(buf, header.buf...)
+		buf = append(buf, c.values...)
+		size := int64(len(header.buf) + len(c.values))
+		total += size
+
+		chunks.begin()
+		chunks.i64(2, offset)
+		chunks.structField(3)
+		chunks.i32(1, c.kind)
+		chunks.list(2, thriftI32, 1)
+		chunks.i32Element(encodingPlain)
+		chunks.list(3, thriftBinary, 1)
+		chunks.binaryElement(c.name)
+		chunks.i32(4, 0) //uncompressed
+		chunks.i64(5, rows)
+		chunks.i64(6, size)
+		chunks.i64(7, size)
+		chunks.i64(9, offset)
+		chunks.end()
+		chunks.end()
+	}
+
+	meta.list(4, thriftStruct, 1)
+	meta.begin()
+	meta.list(1, thriftStruct, len(columns))
+	meta.buf = append(meta.buf, chunks.buf...)
+	meta.i64(2, total)
+	meta.i64(3, rows)
+	meta.end()
+	meta.binary(6, "github.com/Battlekeeper/LD2451 parquetsink")
+	meta.end()
+
+	buf = append(buf, meta.buf...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(meta.buf)))
+	buf = append(buf, magic...)
+	_, err := w.Write(buf)
+	return err
+}
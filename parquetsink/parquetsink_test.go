@@ -0,0 +1,210 @@
+package parquetsink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+// readThrift decodes a Thrift compact protocol struct into a map from field
+// id to value: int64 for integers, []byte for binaries, []any for lists and
+// map[int16]any for structs. It returns the bytes following the struct.
+func readThrift(b []byte) (map[int16]any, []byte, error) {
+	fields := map[int16]any{}
+	var last int16
+	for {
+		if len(b) == 0 {
+			return nil, nil, errors.New("truncated struct")
+		}
+		header := b[0]
+		b = b[1:]
+		if header == 0 {
+			return fields, b, nil
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			v, n := binary.Varint(b)
+			if n <= 0 {
+				return nil, nil, errors.New("truncated field id")
+			}
+			id, b = int16(v), b[n:]
+		}
+		var value any
+		var err error
+		value, b, err = readThriftValue(b, header&0x0f)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields[id] = value
+		last = id
+	}
+}
+
+func readThriftValue(b []byte, kind byte) (any, []byte, error) {
+	switch kind {
+	case thriftI32, thriftI64:
+		v, n := binary.Varint(b)
+		if n <= 0 {
+			return nil, nil, errors.New("truncated integer")
+		}
+		return v, b[n:], nil
+	case thriftBinary:
+		size, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < size {
+			return nil, nil, errors.New("truncated binary")
+		}
+		return b[n : n+int(size)], b[n+int(size):], nil
+	case thriftList:
+		if len(b) == 0 {
+			return nil, nil, errors.New("truncated list")
+		}
+		size, elem := int(b[0]>>4), b[0]&0x0f
+		b = b[1:]
+		if size == 15 {
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, nil, errors.New("truncated list size")
+			}
+			size, b = int(v), b[n:]
+		}
+		list := make([]any, size)
+		for i := range list {
+			var err error
+			if list[i], b, err = readThriftValue(b, elem); err != nil {
+				return nil, nil, err
+			}
+		}
+		return list, b, nil
+	case thriftStruct:
+		return readThrift(b)
+	}
+	return nil, nil, errors.New("unexpected thrift type")
+}
+
+func TestWriteTargetsFileLayout(t *testing.T) {
+	targets := []LD2451.Target{
+		{Angle: -10, Distance: 40, Direction: LD2451.DirectionToward, Speed: 52, SNR: 120, Time: time.UnixMicro(1700000000123456), Sensor: "north"},
+		{Angle: 3, Distance: 12, Direction: LD2451.DirectionAway, Speed: 31, SNR: 90, Time: time.UnixMicro(1700000000223456), Sensor: "north"},
+	}
+	var buf bytes.Buffer
+	if err := WriteTargets(&buf, targets); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	if !bytes.HasPrefix(file, []byte(magic)) || !bytes.HasSuffix(file, []byte(magic)) {
+		t.Fatalf("file doesn't start and end with %q", magic)
+	}
+	footer := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	if footer > len(file)-12 {
+		t.Fatalf("footer length %d exceeds the file of %d bytes", footer, len(file))
+	}
+	meta, rest, err := readThrift(file[len(file)-8-footer : len(file)-8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("%d bytes left after the file metadata", len(rest))
+	}
+	if rows := meta[3].(int64); rows != int64(len(targets)) {
+		t.Fatalf("file metadata counts %d rows, want %d", rows, len(targets))
+	}
+
+	var names []string
+	for _, element := range meta[2].([]any) {
+		names = append(names, string(element.(map[int16]any)[4].([]byte)))
+	}
+	want := []string{"schema", "time", "angle", "distance", "direction", "speed", "snr", "sensor"}
+	if len(names) != len(want) {
+		t.Fatalf("schema has elements %q, want %q", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("schema has elements %q, want %q", names, want)
+		}
+	}
+
+	//read the columns back through the offsets of the footer
+	group := meta[4].([]any)[0].(map[int16]any)
+	columns := map[string][]byte{}
+	for _, chunk := range group[1].([]any) {
+		columnMeta := chunk.(map[int16]any)[3].(map[int16]any)
+		name := string(columnMeta[3].([]any)[0].([]byte))
+		offset := columnMeta[9].(int64)
+		header, values, err := readThrift(file[offset:])
+		if err != nil {
+			t.Fatalf("page header of %s: %v", name, err)
+		}
+		if rows := header[5].(map[int16]any)[1].(int64); rows != int64(len(targets)) {
+			t.Fatalf("page of %s holds %d values, want %d", name, rows, len(targets))
+		}
+		columns[name] = values[:header[3].(int64)]
+	}
+	for i, target := range targets {
+		if got := int64(binary.LittleEndian.Uint64(columns["time"][8*i:])); got != target.Time.UnixMicro() {
+			t.Errorf("row %d has time %d, want %d", i, got, target.Time.UnixMicro())
+		}
+		if got := int32(binary.LittleEndian.Uint32(columns["angle"][4*i:])); int(got) != target.Angle {
+			t.Errorf("row %d has angle %d, want %d", i, got, target.Angle)
+		}
+		if got := int32(binary.LittleEndian.Uint32(columns["distance"][4*i:])); int(got) != target.Distance {
+			t.Errorf("row %d has distance %d, want %d", i, got, target.Distance)
+		}
+	}
+	direction := columns["direction"]
+	for i, want := range []string{"toward", "away"} {
+		n := binary.LittleEndian.Uint32(direction)
+		if got := string(direction[4 : 4+n]); got != want {
+			t.Errorf("row %d has direction %q, want %q", i, got, want)
+		}
+		direction = direction[4+n:]
+	}
+}
+
+func TestFlushRotatesOnTheClock(t *testing.T) {
+	dir := t.TempDir()
+	clock := sensortest.NewClock(time.Unix(1700000000, 0))
+	sink, err := New(Config{Dir: dir, RotateInterval: time.Minute, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(LD2451.Target{Distance: 10, Time: clock.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	files := func() int {
+		t.Helper()
+		matches, err := filepath.Glob(filepath.Join(dir, DefaultPrefix+"*.parquet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(matches)
+	}
+	clock.Advance(time.Minute - time.Second)
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := files(); n != 0 {
+		t.Fatalf("%d files written before RotateInterval passed", n)
+	}
+	clock.Advance(time.Second)
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := files(); n != 1 {
+		t.Fatalf("%d files written once RotateInterval passed, want 1", n)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d entries in the directory, want only the file", len(entries))
+	}
+}
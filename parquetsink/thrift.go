@@ -0,0 +1,82 @@
+package parquetsink
+
+import "encoding/binary"
+
+// Thrift compact protocol types used by the Parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thrift encodes structs in the Thrift compact protocol, which is how Parquet
+// stores its metadata. Fields must be written in increasing id order.
+type thrift struct {
+	buf  []byte
+	last []int16 //id of the last field written, per open struct
+}
+
+func (t *thrift) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thrift) end() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thrift) field(id int16, kind byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|kind)
+	} else {
+		t.buf = append(t.buf, kind)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	*last = id
+}
+
+func (t *thrift) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thrift) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thrift) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+func (t *thrift) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// list writes the header of a list field, its n elements follow.
+func (t *thrift) list(id int16, kind byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|kind)
+	} else {
+		t.buf = append(t.buf, 0xf0|kind)
+		t.buf = binary.AppendUvarint(t.buf, uint64(n))
+	}
+}
+
+// Elements of lists carry no field header.
+
+func (t *thrift) i32Element(v int32) {
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thrift) binaryElement(v string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
@@ -0,0 +1,52 @@
+package LD2451
+
+import (
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+const defaultParseFailureBackoff = 100 * time.Millisecond
+
+// parseFailed counts a frame that failed to decode. After
+// Config.ParseFailureLimit of them in a row, e.g. because of electrical noise
+// or a baud rate mismatch, reading pauses for Config.ParseFailureBackoff and
+// everything received until then is discarded, so the reader resynchronizes
+// on fresh bytes instead of spinning through garbage as fast as it arrives.
+func (ld2451 *LD2451) parseFailed() {
+	ld2451.parseFailures++
+	limit := ld2451.config.ParseFailureLimit
+	if limit == 0 || ld2451.parseFailures < limit {
+		return
+	}
+	ld2451.parseFailures = 0
+	backoff := ld2451.config.ParseFailureBackoff
+	if backoff == 0 {
+		backoff = defaultParseFailureBackoff
+	}
+	ld2451.config.Logger.Warn("frames keep failing to decode, flushing the input", "failures", limit, "pause", backoff)
+	ld2451.recordParseRecovery()
+	if !sleep(ld2451.config.Clock, backoff, ld2451.closed) {
+		return
+	}
+	if err := ld2451.discardInput(); err != nil {
+		ld2451.config.Logger.Debug("flushing the port failed", "error", err)
+	}
+	//the bytes before the next header are the remains of the flushed ones
+	ld2451.aligned = false
+}
+
+// discardInput drops the bytes received but not decoded yet, in the port as
+// well as in the reader. It must only be called by the read goroutine.
+func (ld2451 *LD2451) discardInput() error {
+	ld2451.writeMu.Lock()
+	var err error
+	if flusher, ok := ld2451.port.(transport.Flusher); ok {
+		err = flusher.Flush()
+	}
+	ld2451.writeMu.Unlock()
+	if reader, ok := ld2451.frames.(interface{ Discard() int }); ok {
+		reader.Discard()
+	}
+	return err
+}
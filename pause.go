@@ -0,0 +1,40 @@
+package LD2451
+
+// Pause stops delivering targets without closing the port, e.g. during a
+// maintenance window or a burst of configuration commands. Frames are still
+// read, so statistics, the alarm and the state stay current, but their
+// targets are dropped and the frames counted in Stats.PausedFrames. With
+// sleep the module is also asked to stop reporting, like by Sleep. Resume
+// undoes both.
+func (ld2451 *LD2451) Pause(sleep bool) error {
+	ld2451.pauseMu.Lock()
+	defer ld2451.pauseMu.Unlock()
+	if sleep && !ld2451.pauseSlept {
+		if err := ld2451.Sleep(); err != nil {
+			return err
+		}
+		ld2451.pauseSlept = true
+	}
+	ld2451.paused.Store(true)
+	return nil
+}
+
+// Resume delivers targets again after Pause, and wakes the module when Pause
+// put it to sleep. The sensor stays paused when waking the module fails.
+func (ld2451 *LD2451) Resume() error {
+	ld2451.pauseMu.Lock()
+	defer ld2451.pauseMu.Unlock()
+	if ld2451.pauseSlept {
+		if err := ld2451.Wake(); err != nil {
+			return err
+		}
+		ld2451.pauseSlept = false
+	}
+	ld2451.paused.Store(false)
+	return nil
+}
+
+// Paused reports whether target delivery is paused.
+func (ld2451 *LD2451) Paused() bool {
+	return ld2451.paused.Load()
+}
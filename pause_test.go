@@ -0,0 +1,32 @@
+package LD2451_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestSleepingPauseSurvivesSilence(t *testing.T) {
+	sensor, radar := openSensor(t, nil)
+	if err := radar.Pause(true); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(silence)
+	if state := radar.State(); state != LD2451.StateStandby {
+		t.Fatalf("state after %s paused is %s, want %s", silence, state, LD2451.StateStandby)
+	}
+	if err := radar.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sensor.SendTargets(false, LD2451.Target{Distance: 8, Speed: 25}); err != nil {
+		t.Fatal(err)
+	}
+	target, err := radar.ReadTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Distance != 8 {
+		t.Fatalf("got target at %d m after Resume, want 8 m", target.Distance)
+	}
+}
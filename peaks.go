@@ -0,0 +1,63 @@
+package LD2451
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+const (
+	defaultPeakWindow  = time.Minute
+	defaultPeakWindows = 60
+)
+
+// Peak is the most targets reported in a single frame during a window.
+type Peak struct {
+	Start   time.Time `json:"start"`    // Start of the window, a multiple of Config.PeakWindow
+	Targets int       `json:"targets"`  // Most targets in one frame, before filtering and Config.MaxTargets
+	AtLimit bool      `json:"at_limit"` // A frame reported protocol.MaxTargets targets, so more may have been present
+}
+
+// Peaks returns the peak concurrency of the last Config.PeakWindows windows
+// of Config.PeakWindow that saw frames, oldest first, the last one still
+// running.
+func (ld2451 *LD2451) Peaks() []Peak {
+	ld2451.statsMu.Lock()
+	defer ld2451.statsMu.Unlock()
+	return slices.Clone(ld2451.peaks)
+}
+
+// recordConcurrency accounts for a frame reporting targets targets.
+func (ld2451 *LD2451) recordConcurrency(targets int, received time.Time) {
+	window := ld2451.config.PeakWindow
+	if window <= 0 {
+		window = defaultPeakWindow
+	}
+	start := received.Truncate(window)
+	atLimit := targets >= protocol.MaxTargets
+
+	ld2451.statsMu.Lock()
+	defer ld2451.statsMu.Unlock()
+	ld2451.stats.PeakTargets = max(ld2451.stats.PeakTargets, uint64(targets))
+	if atLimit {
+		ld2451.stats.FramesAtLimit++
+	}
+	if n := len(ld2451.peaks); n == 0 || !ld2451.peaks[n-1].Start.Equal(start) {
+		if n >= ld2451.peakWindows() {
+			ld2451.peaks = slices.Delete(ld2451.peaks, 0, 1)
+			ld2451.peaksEvicted++
+		}
+		ld2451.peaks = append(ld2451.peaks, Peak{Start: start})
+	}
+	peak := &ld2451.peaks[len(ld2451.peaks)-1]
+	peak.Targets = max(peak.Targets, targets)
+	peak.AtLimit = peak.AtLimit || atLimit
+}
+
+func (ld2451 *LD2451) peakWindows() int {
+	if ld2451.config.PeakWindows <= 0 {
+		return defaultPeakWindows
+	}
+	return ld2451.config.PeakWindows
+}
@@ -0,0 +1,62 @@
+package LD2451
+
+import "math"
+
+const defaultHistogramBucket = 10
+
+// SpeedStats describes the speeds of the targets delivered in one direction
+// during a Summary. Every report of a target counts, so an object staying in
+// the field of view for longer weighs more.
+type SpeedStats struct {
+	Count     int   `json:"count"`
+	P50       int   `json:"p50"`       // Median speed in KM/H
+	P85       int   `json:"p85"`       // 85th percentile speed in KM/H, the standard figure of traffic calming studies
+	P95       int   `json:"p95"`       // 95th percentile speed in KM/H
+	Histogram []int `json:"histogram"` // Number of targets per Config.SpeedHistogramBucket wide speed range, starting at 0 KM/H
+}
+
+// speedCounts counts targets per speed in KM/H, which gives exact percentiles
+// since the module only reports whole speeds.
+type speedCounts struct {
+	counts []int
+	total  int
+}
+
+func (c *speedCounts) add(speed int) {
+	speed = max(speed, 0)
+	if speed >= len(c.counts) {
+		c.counts = append(c.counts, make([]int, speed+1-len(c.counts))...)
+	}
+	c.counts[speed]++
+	c.total++
+}
+
+// stats computes the percentiles and the histogram with the given bucket width.
+func (c *speedCounts) stats(bucket int) SpeedStats {
+	stats := SpeedStats{Count: c.total}
+	if c.total == 0 {
+		return stats
+	}
+	stats.P50 = c.percentile(50)
+	stats.P85 = c.percentile(85)
+	stats.P95 = c.percentile(95)
+	stats.Histogram = make([]int, (len(c.counts)+bucket-1)/bucket)
+	for speed, n := range c.counts {
+		stats.Histogram[speed/bucket] += n
+	}
+	return stats
+}
+
+// percentile returns the lowest speed at least p percent of the targets were
+// at or below, the nearest rank method.
+func (c *speedCounts) percentile(p float64) int {
+	rank := max(int(math.Ceil(p/100*float64(c.total))), 1)
+	seen := 0
+	for speed, n := range c.counts {
+		seen += n
+		if seen >= rank {
+			return speed
+		}
+	}
+	return len(c.counts) - 1
+}
@@ -0,0 +1,73 @@
+package LD2451
+
+const defaultPersistenceGate = 2
+
+// persistence counts for how many consecutive frames each target has been
+// reported, matching the targets of a frame to those of the previous frame by
+// direction and distance. Only used by the read goroutine.
+type persistence struct {
+	gate     int
+	previous []persistentTarget
+	current  []persistentTarget
+	matched  []bool
+}
+
+type persistentTarget struct {
+	distance  int
+	direction Direction
+	frames    int
+}
+
+func newPersistence(config Config) *persistence {
+	if config.MinFrames <= 1 {
+		return nil
+	}
+	gate := config.PersistenceGate
+	if gate <= 0 {
+		gate = defaultPersistenceGate
+	}
+	return &persistence{gate: gate}
+}
+
+// observe records target as part of the current frame and returns the number
+// of consecutive frames it has been seen in, including this one.
+func (p *persistence) observe(target Target) int {
+	//continue the closest target of the previous frame that wasn't continued yet
+	best, bestGap := -1, p.gate+1
+	for i, previous := range p.previous {
+		if p.matched[i] || previous.direction != target.Direction {
+			continue
+		}
+		if gap := abs(previous.distance - target.Distance); gap < bestGap {
+			best, bestGap = i, gap
+		}
+	}
+	frames := 1
+	if best >= 0 {
+		p.matched[best] = true
+		frames = p.previous[best].frames + 1
+	}
+	p.current = append(p.current, persistentTarget{distance: target.Distance, direction: target.Direction, frames: frames})
+	return frames
+}
+
+// reset forgets all targets, e.g. after the port was reopened.
+func (p *persistence) reset() {
+	p.previous, p.current, p.matched = p.previous[:0], p.current[:0], p.matched[:0]
+}
+
+// endFrame makes the targets observed since the last call the previous frame.
+func (p *persistence) endFrame() {
+	p.previous, p.current = p.current, p.previous[:0]
+	p.matched = p.matched[:0]
+	for range p.previous {
+		p.matched = append(p.matched, false)
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,136 @@
+// Package presence turns targets into an occupied or vacant state for a zone,
+// e.g. for lighting control or monitoring a parking space. The zone counts as
+// occupied while a target was seen in it within a sliding window, and on and
+// off delays keep short visits and short gaps from toggling the state.
+//
+//	detector, err := presence.New(presence.Config{
+//		Zone:     presence.Zone{MaxDistance: 15},
+//		OffDelay: 30 * time.Second,
+//	})
+//	...
+//	if change, changed := detector.Update(frame); changed {
+//		setLight(change.Present)
+//	}
+package presence
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const DefaultWindow = 2 * time.Second
+
+// Zone is the area watched for presence. Zero bounds accept any distance or
+// angle.
+type Zone struct {
+	MinDistance int `json:"min_distance"` // Meters
+	MaxDistance int `json:"max_distance"` // Meters
+	MinAngle    int `json:"min_angle"`    // Degrees, both angles zero accepts any angle
+	MaxAngle    int `json:"max_angle"`    // Degrees
+}
+
+// Contains reports whether target is within the zone.
+func (z Zone) Contains(target LD2451.Target) bool {
+	if target.Distance < z.MinDistance || (z.MaxDistance > 0 && target.Distance > z.MaxDistance) {
+		return false
+	}
+	if z.MinAngle == 0 && z.MaxAngle == 0 {
+		return true
+	}
+	return target.Angle >= z.MinAngle && target.Angle <= z.MaxAngle
+}
+
+type Config struct {
+	Zone     Zone
+	Window   time.Duration // A target seen in the zone within this long counts as present (default DefaultWindow)
+	OnDelay  time.Duration // The zone only becomes occupied after targets were present for this long
+	OffDelay time.Duration // The zone only becomes vacant after no target was present for this long
+}
+
+// Change is a change between occupied and vacant.
+type Change struct {
+	Present bool          `json:"present"`
+	Since   time.Time     `json:"since"`  // When targets started or stopped being present, before the delay
+	Target  LD2451.Target `json:"target"` // Latest target seen in the zone
+	Time    time.Time     `json:"time"`
+}
+
+func (c Change) EventTime() time.Time {
+	return c.Time
+}
+
+// Detector tracks the presence state. It is not safe for concurrent use.
+type Detector struct {
+	config  Config
+	present bool
+	seen    time.Time //when a target was last seen in the zone
+	since   time.Time //when targets started being present without a gap longer than the window
+	target  LD2451.Target
+}
+
+func New(config Config) (*Detector, error) {
+	zone := config.Zone
+	if zone.MinDistance < 0 || (zone.MaxDistance > 0 && zone.MaxDistance < zone.MinDistance) {
+		return nil, fmt.Errorf("zone distance %d to %d m is empty", zone.MinDistance, zone.MaxDistance)
+	}
+	if zone.MinAngle > zone.MaxAngle {
+		return nil, fmt.Errorf("zone angle %d° to %d° is empty", zone.MinAngle, zone.MaxAngle)
+	}
+	if config.OnDelay < 0 || config.OffDelay < 0 {
+		return nil, fmt.Errorf("on delay %s and off delay %s must not be negative", config.OnDelay, config.OffDelay)
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultWindow
+	}
+	return &Detector{config: config}, nil
+}
+
+// Present reports whether the zone is occupied.
+func (d *Detector) Present() bool {
+	return d.present
+}
+
+// Update evaluates the targets of frame and returns the change when the
+// state changed.
+func (d *Detector) Update(frame LD2451.Frame) (Change, bool) {
+	now := frame.Time
+	for _, target := range frame.Targets {
+		if !d.config.Zone.Contains(target) {
+			continue
+		}
+		if !d.active(now) {
+			d.since = now
+		}
+		d.seen, d.target = now, target
+	}
+	return d.evaluate(now)
+}
+
+// Expire makes the zone vacant once the off delay passed without targets,
+// for when no frames arrive. Update expires the state itself.
+func (d *Detector) Expire(now time.Time) (Change, bool) {
+	return d.evaluate(now)
+}
+
+// active reports whether a target was seen within the window.
+func (d *Detector) active(now time.Time) bool {
+	return !d.seen.IsZero() && now.Sub(d.seen) < d.config.Window
+}
+
+func (d *Detector) evaluate(now time.Time) (Change, bool) {
+	switch {
+	case !d.present && d.active(now) && now.Sub(d.since) >= d.config.OnDelay:
+		d.present = true
+		return Change{Present: true, Since: d.since, Target: d.target, Time: now}, true
+	case d.present && !d.active(now):
+		gone := d.seen.Add(d.config.Window)
+		if now.Sub(gone) < d.config.OffDelay {
+			return Change{}, false
+		}
+		d.present = false
+		return Change{Present: false, Since: gone, Target: d.target, Time: now}, true
+	}
+	return Change{}, false
+}
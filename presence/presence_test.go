@@ -0,0 +1,104 @@
+package presence_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/presence"
+)
+
+func TestZoneContains(t *testing.T) {
+	tests := []struct {
+		name   string
+		zone   presence.Zone
+		target LD2451.Target
+		inside bool
+	}{
+		{"unbounded", presence.Zone{}, LD2451.Target{Distance: 100, Angle: -40}, true},
+		{"within the distance", presence.Zone{MinDistance: 5, MaxDistance: 15}, LD2451.Target{Distance: 15}, true},
+		{"too close", presence.Zone{MinDistance: 5, MaxDistance: 15}, LD2451.Target{Distance: 4}, false},
+		{"too far", presence.Zone{MinDistance: 5, MaxDistance: 15}, LD2451.Target{Distance: 16}, false},
+		{"no upper distance", presence.Zone{MinDistance: 5}, LD2451.Target{Distance: 90}, true},
+		{"within the angle", presence.Zone{MinAngle: -10, MaxAngle: 10}, LD2451.Target{Distance: 10, Angle: -10}, true},
+		{"beside the angle", presence.Zone{MinAngle: -10, MaxAngle: 10}, LD2451.Target{Distance: 10, Angle: 11}, false},
+	}
+	for _, test := range tests {
+		if inside := test.zone.Contains(test.target); inside != test.inside {
+			t.Errorf("%s: got %t, expected %t", test.name, inside, test.inside)
+		}
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		config presence.Config
+		valid  bool
+	}{
+		{"defaults", presence.Config{}, true},
+		{"negative distance", presence.Config{Zone: presence.Zone{MinDistance: -1}}, false},
+		{"empty distance", presence.Config{Zone: presence.Zone{MinDistance: 10, MaxDistance: 5}}, false},
+		{"empty angle", presence.Config{Zone: presence.Zone{MinAngle: 10, MaxAngle: -10}}, false},
+		{"negative delay", presence.Config{OffDelay: -time.Second}, false},
+	}
+	for _, test := range tests {
+		if _, err := presence.New(test.config); (err == nil) != test.valid {
+			t.Errorf("%s: got %v, expected valid %t", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestDetector(t *testing.T) {
+	detector, err := presence.New(presence.Config{
+		Zone:     presence.Zone{MaxDistance: 15},
+		Window:   2 * time.Second,
+		OnDelay:  time.Second,
+		OffDelay: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	inside, outside := LD2451.Target{Distance: 10}, LD2451.Target{Distance: 30}
+	steps := []struct {
+		name    string
+		at      time.Duration
+		targets []LD2451.Target
+		expire  bool          // call Expire instead of Update
+		change  bool          // the step changes the state
+		since   time.Duration // Change.Since of the change
+	}{
+		{"arriving", 0, []LD2451.Target{inside}, false, false, 0},
+		{"still within the on delay", 500 * time.Millisecond, []LD2451.Target{inside}, false, false, 0},
+		{"occupied", time.Second, []LD2451.Target{inside}, false, true, 0},
+		{"outside of the zone", 1500 * time.Millisecond, []LD2451.Target{outside}, false, false, 0},
+		{"window passed", 3 * time.Second, nil, false, false, 0},
+		{"within the off delay", 4900 * time.Millisecond, nil, true, false, 0},
+		{"vacant", 5 * time.Second, nil, true, true, 3 * time.Second},
+		{"short visit", 10 * time.Second, []LD2451.Target{inside}, false, false, 0},
+		{"short visit over", 12 * time.Second, nil, false, false, 0},
+	}
+	present := false
+	for _, step := range steps {
+		var change presence.Change
+		var changed bool
+		if step.expire {
+			change, changed = detector.Expire(start.Add(step.at))
+		} else {
+			change, changed = detector.Update(LD2451.Frame{Targets: step.targets, Time: start.Add(step.at)})
+		}
+		if changed != step.change {
+			t.Fatalf("%s: changed %t, expected %t", step.name, changed, step.change)
+		}
+		if changed {
+			present = !present
+			if change.Present != present || !change.Since.Equal(start.Add(step.since)) || !change.Time.Equal(start.Add(step.at)) || change.Target != inside {
+				t.Errorf("%s: got %+v", step.name, change)
+			}
+		}
+		if detector.Present() != present {
+			t.Errorf("%s: present %t, expected %t", step.name, detector.Present(), present)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package protobuf encodes LD2451 detections in the protocol buffers wire
+// format described by ld2451.proto, so they can be published onto protobuf
+// based pipelines. The encoders are written by hand to keep the library free
+// of the protobuf runtime, and are wire compatible with code generated from
+// the schema.
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/tracking"
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("protobuf: truncated message")
+
+// MarshalTarget encodes target as an ld2451.Target message.
+func MarshalTarget(target LD2451.Target) []byte {
+	return appendTarget(nil, target)
+}
+
+// UnmarshalTarget decodes an ld2451.Target message.
+func UnmarshalTarget(data []byte) (LD2451.Target, error) {
+	target := LD2451.Target{}
+	err := walk(data, func(field int, wire int, value uint64, bytes []byte) error {
+		switch {
+		case field == 7 && wire == wireBytes:
+			target.Sensor = string(bytes)
+		case field == 8 && wire == wireFixed64:
+			target.FineDistance = math.Float64frombits(value)
+		case field == 9 && wire == wireFixed64:
+			target.FineSpeed = math.Float64frombits(value)
+		case field == 10 && wire == wireFixed64:
+			target.RadialSpeed = math.Float64frombits(value)
+		}
+		if wire != wireVarint {
+			return nil
+		}
+		switch field {
+		case 1:
+			target.Angle = int(decodeZigZag(value))
+		case 2:
+			target.Distance = int(int32(value))
+		case 3:
+			target.Direction = LD2451.Direction(value)
+		case 4:
+			target.Speed = int(int32(value))
+		case 5:
+			target.SNR = int(int32(value))
+		case 6:
+			target.Time = fromUnixNano(int64(value))
+		case 11:
+			target.Fine = value != 0
+		}
+		return nil
+	})
+	return target, err
+}
+
+// Codec encodes targets like MarshalTarget for an LD2451.EncodedSink. The
+// messages don't delimit themselves, so wrap it with LD2451.LengthPrefixed
+// when they are written to a stream.
+var Codec LD2451.Codec = LD2451.CodecFunc(func(buf []byte, target LD2451.Target) ([]byte, error) {
+	return appendTarget(buf, target), nil
+})
+
+// MarshalFrame encodes frame as an ld2451.Frame message.
+func MarshalFrame(frame LD2451.Frame) []byte {
+	var buf []byte
+	for _, target := range frame.Targets {
+		encoded := appendTarget(nil, target)
+		buf = appendTag(buf, 1, wireBytes)
+		buf = appendVarint(buf, uint64(len(encoded)))
+		buf = append(buf, encoded...)
+	}
+	if frame.Alarm {
+		buf = appendTag(buf, 2, wireVarint)
+		buf = appendVarint(buf, 1)
+	}
+	if nanos := toUnixNano(frame.Time); nanos != 0 {
+		buf = appendTag(buf, 3, wireVarint)
+		buf = appendVarint(buf, uint64(nanos))
+	}
+	return appendString(buf, 4, frame.Sensor)
+}
+
+// UnmarshalFrame decodes an ld2451.Frame message.
+func UnmarshalFrame(data []byte) (LD2451.Frame, error) {
+	frame := LD2451.Frame{}
+	err := walk(data, func(field int, wire int, value uint64, bytes []byte) error {
+		switch {
+		case field == 1 && wire == wireBytes:
+			target, err := UnmarshalTarget(bytes)
+			if err != nil {
+				return err
+			}
+			frame.Targets = append(frame.Targets, target)
+		case field == 2 && wire == wireVarint:
+			frame.Alarm = value != 0
+		case field == 3 && wire == wireVarint:
+			frame.Time = fromUnixNano(int64(value))
+		case field == 4 && wire == wireBytes:
+			frame.Sensor = string(bytes)
+		}
+		return nil
+	})
+	return frame, err
+}
+
+// MarshalTrack encodes track as an ld2451.Track message, leaving out the
+// arrival and acceleration estimates.
+func MarshalTrack(track tracking.Track) []byte {
+	buf := appendUint(nil, 1, track.ID)
+	buf = appendUint(buf, 2, uint64(int64(track.Direction)))
+	buf = appendUint(buf, 3, uint64(toUnixNano(track.Start)))
+	buf = appendUint(buf, 4, uint64(toUnixNano(track.End)))
+	buf = appendUint(buf, 5, uint64(int64(track.Detections)))
+	buf = appendUint(buf, 6, uint64(int64(track.Distance)))
+	buf = appendUint(buf, 7, uint64(int64(track.EntryDistance)))
+	buf = appendUint(buf, 8, encodeZigZag(int64(track.Angle)))
+	buf = appendUint(buf, 9, uint64(int64(track.Speed)))
+	buf = appendUint(buf, 10, uint64(int64(track.MaxSpeed)))
+	buf = appendDouble(buf, 11, track.MeanSpeed)
+	buf = appendUint(buf, 12, uint64(int64(track.MaxSNR)))
+	buf = appendDouble(buf, 13, track.MeanSNR)
+	buf = appendUint(buf, 14, uint64(int64(track.Class)))
+	buf = appendUint(buf, 15, uint64(int64(track.Lane)))
+	buf = appendString(buf, 16, track.Band)
+	return appendString(buf, 17, track.Sensor)
+}
+
+func appendTarget(buf []byte, target LD2451.Target) []byte {
+	buf = appendUint(buf, 1, encodeZigZag(int64(target.Angle)))
+	buf = appendUint(buf, 2, uint64(int64(target.Distance)))
+	buf = appendUint(buf, 3, uint64(int64(target.Direction)))
+	buf = appendUint(buf, 4, uint64(int64(target.Speed)))
+	buf = appendUint(buf, 5, uint64(int64(target.SNR)))
+	buf = appendUint(buf, 6, uint64(toUnixNano(target.Time)))
+	buf = appendString(buf, 7, target.Sensor)
+	buf = appendDouble(buf, 8, target.FineDistance)
+	buf = appendDouble(buf, 9, target.FineSpeed)
+	buf = appendDouble(buf, 10, target.RadialSpeed)
+	if target.Fine {
+		buf = appendTag(buf, 11, wireVarint)
+		buf = appendVarint(buf, 1)
+	}
+	return buf
+}
+
+// appendUint appends a varint field unless v is zero, proto3 leaves out
+// fields holding the default value.
+func appendUint(buf []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = appendTag(buf, field, wireVarint)
+	return appendVarint(buf, v)
+}
+
+// appendDouble appends a double field unless v is zero.
+func appendDouble(buf []byte, field int, v float64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = appendTag(buf, field, wireFixed64)
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+}
+
+// appendString appends a length delimited string field unless s is empty.
+func appendString(buf []byte, field int, s string) []byte {
+	if s == "" {
+		return buf
+	}
+	buf = appendTag(buf, field, wireBytes)
+	buf = appendVarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// walk calls fn for every field in data. Varint and fixed width fields are
+// passed in value, length delimited fields in bytes.
+func walk(data []byte, fn func(field int, wire int, value uint64, bytes []byte) error) error {
+	for len(data) > 0 {
+		tag, n := readVarint(data)
+		if n == 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		field, wire := int(tag>>3), int(tag&7)
+
+		var value uint64
+		var bytes []byte
+		switch wire {
+		case wireVarint:
+			value, n = readVarint(data)
+			if n == 0 {
+				return errTruncated
+			}
+		case wireBytes:
+			length, m := readVarint(data)
+			if m == 0 || uint64(len(data)-m) < length {
+				return errTruncated
+			}
+			bytes = data[m : m+int(length)]
+			n = m + int(length)
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", wire)
+		}
+		if n > len(data) {
+			return errTruncated
+		}
+		switch wire {
+		case wireFixed64:
+			value = binary.LittleEndian.Uint64(data)
+		case wireFixed32:
+			value = uint64(binary.LittleEndian.Uint32(data))
+		}
+		data = data[n:]
+
+		if err := fn(field, wire, value, bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendTag(buf []byte, field int, wire int) []byte {
+	return appendVarint(buf, uint64(field)<<3|uint64(wire))
+}
+
+func appendVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+// readVarint returns the decoded value and the number of bytes used, or zero
+// bytes if data does not hold a complete varint.
+func readVarint(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		v |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+func encodeZigZag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func decodeZigZag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func toUnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
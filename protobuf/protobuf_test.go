@@ -0,0 +1,155 @@
+package protobuf
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/tracking"
+)
+
+func TestMarshalTargetWireFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		target LD2451.Target
+		want   []byte
+	}{
+		{"defaults", LD2451.Target{}, nil},
+		{"varints", LD2451.Target{Angle: -3, Distance: 12, Speed: 30}, []byte{0x08, 0x05, 0x10, 0x0c, 0x20, 0x1e}},
+		{"sensor", LD2451.Target{Sensor: "a"}, []byte{0x3a, 0x01, 'a'}},
+		{"fine distance", LD2451.Target{FineDistance: 1.5}, []byte{0x41, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MarshalTarget(test.target); !bytes.Equal(got, test.want) {
+				t.Fatalf("got % x, want % x", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTargetRoundTrip(t *testing.T) {
+	target := LD2451.Target{
+		Angle:        -20,
+		Distance:     45,
+		Direction:    LD2451.DirectionToward,
+		Speed:        -7,
+		SNR:          200,
+		Time:         time.Unix(1700000000, 123456789),
+		Fine:         true,
+		FineDistance: 45.25,
+		FineSpeed:    -7.5,
+		RadialSpeed:  6.75,
+		Sensor:       "north",
+	}
+	got, err := UnmarshalTarget(MarshalTarget(target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(target.Time) {
+		t.Fatalf("time came back as %s, want %s", got.Time, target.Time)
+	}
+	got.Time = target.Time
+	if got != target {
+		t.Fatalf("got %+v, want %+v", got, target)
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	frame := LD2451.Frame{
+		Targets: []LD2451.Target{{Distance: 10, Speed: 30}, {Angle: 5, FineSpeed: 12.5}},
+		Alarm:   true,
+		Time:    time.Unix(1700000000, 1),
+		Sensor:  "north",
+	}
+	got, err := UnmarshalFrame(MarshalFrame(frame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Targets) != 2 || got.Targets[0] != frame.Targets[0] || got.Targets[1] != frame.Targets[1] {
+		t.Fatalf("got targets %+v, want %+v", got.Targets, frame.Targets)
+	}
+	if !got.Alarm || !got.Time.Equal(frame.Time) || got.Sensor != frame.Sensor {
+		t.Fatalf("got %+v, want %+v", got, frame)
+	}
+}
+
+func TestMarshalTrack(t *testing.T) {
+	track := tracking.Track{
+		ID:            7,
+		Direction:     LD2451.DirectionToward,
+		Start:         time.Unix(1700000000, 0),
+		End:           time.Unix(1700000003, 500),
+		Detections:    30,
+		Distance:      5,
+		EntryDistance: 80,
+		Angle:         -12,
+		Speed:         48,
+		MaxSpeed:      52,
+		MeanSpeed:     49.5,
+		MaxSNR:        180,
+		MeanSNR:       140.25,
+		Class:         2,
+		Lane:          1,
+		Band:          "near",
+		Sensor:        "north",
+	}
+	var got tracking.Track
+	err := walk(MarshalTrack(track), func(field int, wire int, value uint64, data []byte) error {
+		switch field {
+		case 1:
+			got.ID = value
+		case 2:
+			got.Direction = LD2451.Direction(value)
+		case 3:
+			got.Start = fromUnixNano(int64(value))
+		case 4:
+			got.End = fromUnixNano(int64(value))
+		case 5:
+			got.Detections = int(value)
+		case 6:
+			got.Distance = int(value)
+		case 7:
+			got.EntryDistance = int(value)
+		case 8:
+			got.Angle = int(decodeZigZag(value))
+		case 9:
+			got.Speed = int(value)
+		case 10:
+			got.MaxSpeed = int(value)
+		case 11:
+			got.MeanSpeed = math.Float64frombits(value)
+		case 12:
+			got.MaxSNR = int(value)
+		case 13:
+			got.MeanSNR = math.Float64frombits(value)
+		case 14:
+			got.Class = tracking.Class(value)
+		case 15:
+			got.Lane = int(value)
+		case 16:
+			got.Band = string(data)
+		case 17:
+			got.Sensor = string(data)
+		default:
+			t.Errorf("unexpected field %d", field)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Start.Equal(track.Start) || !got.End.Equal(track.End) {
+		t.Fatalf("got times %s to %s, want %s to %s", got.Start, got.End, track.Start, track.End)
+	}
+	got.Start, got.End = track.Start, track.End
+	if got.ID != track.ID || got.Direction != track.Direction || got.Detections != track.Detections ||
+		got.Distance != track.Distance || got.EntryDistance != track.EntryDistance || got.Angle != track.Angle ||
+		got.Speed != track.Speed || got.MaxSpeed != track.MaxSpeed || got.MeanSpeed != track.MeanSpeed ||
+		got.MaxSNR != track.MaxSNR || got.MeanSNR != track.MeanSNR || got.Class != track.Class ||
+		got.Lane != track.Lane || got.Band != track.Band || got.Sensor != track.Sensor {
+		t.Fatalf("got %+v, want %+v", got, track)
+	}
+}
@@ -0,0 +1,124 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Command words understood by the module.
+const (
+	CmdSetDetection    uint16 = 0x0002
+	CmdSetSensitivity  uint16 = 0x0003
+	CmdReadDetection   uint16 = 0x0012
+	CmdReadSensitivity uint16 = 0x0013
+	CmdReadFirmware    uint16 = 0x00a0
+	CmdSetBaudRate     uint16 = 0x00a1
+	CmdEndConfig       uint16 = 0x00fe
+	CmdEnableConfig    uint16 = 0x00ff
+
+	// CmdReadSerial reads the serial number, answered by firmware of other
+	// HLK modules such as the LD2410S. The LD2451 manual does not document
+	// it, so older firmware rejects it or doesn't answer.
+	CmdReadSerial uint16 = 0x0011
+
+	// CmdRestart reboots the module, which comes back reporting with a baud
+	// rate set by CmdSetBaudRate in effect.
+	CmdRestart uint16 = 0x00a3
+)
+
+// CommandName describes a command word, e.g. for error messages.
+func CommandName(word uint16) string {
+	switch word {
+	case CmdSetDetection:
+		return "set detection parameters"
+	case CmdReadDetection:
+		return "read detection parameters"
+	case CmdSetSensitivity:
+		return "set sensitivity"
+	case CmdReadSensitivity:
+		return "read sensitivity"
+	case CmdReadFirmware:
+		return "read firmware version"
+	case CmdReadSerial:
+		return "read serial number"
+	case CmdSetBaudRate:
+		return "set baud rate"
+	case CmdRestart:
+		return "restart"
+	case CmdEndConfig:
+		return "end config"
+	case CmdEnableConfig:
+		return "enable config"
+	default:
+		return fmt.Sprintf("command 0x%04x", word)
+	}
+}
+
+// KnownCommand reports whether word is one of the command words understood
+// by the module.
+func KnownCommand(word uint16) bool {
+	switch word {
+	case CmdSetDetection, CmdSetSensitivity, CmdReadDetection, CmdReadSensitivity,
+		CmdReadFirmware, CmdSetBaudRate, CmdEndConfig, CmdEnableConfig, CmdReadSerial, CmdRestart:
+		return true
+	}
+	return false
+}
+
+// AckFlag is set in the command word of an acknowledgement.
+const AckFlag uint16 = 0x0100
+
+type Command struct {
+	Word  uint16 // Command word, e.g. CmdEnableConfig
+	Value []byte // Command value following the word
+}
+
+type Ack struct {
+	Word   uint16 // Word of the acknowledged command, without AckFlag
+	Status uint16 // Zero when the command succeeded
+	Data   []byte // Reply data following the status
+}
+
+// EncodeCommand builds the frame sending command word with value.
+func EncodeCommand(word uint16, value []byte) []byte {
+	payload := binary.LittleEndian.AppendUint16(nil, word)
+	return encode(commandHeader, append(payload, value...), commandFooter)
+}
+
+// EncodeAck builds the frame the module sends to acknowledge command word.
+func EncodeAck(word uint16, status uint16, data []byte) []byte {
+	payload := binary.LittleEndian.AppendUint16(nil, word|AckFlag)
+	payload = binary.LittleEndian.AppendUint16(payload, status)
+	return encode(commandHeader, append(payload, data...), commandFooter)
+}
+
+// ParseCommand decodes a KindCommand payload sent to the module.
+func ParseCommand(payload []byte) (Command, error) {
+	if len(payload) < 2 {
+		return Command{}, &ParseError{Reason: "command without command word", Payload: bytes.Clone(payload)}
+	}
+	return Command{
+		Word:  binary.LittleEndian.Uint16(payload),
+		Value: bytes.Clone(payload[2:]),
+	}, nil
+}
+
+// ParseAck decodes a KindCommand payload sent by the module.
+func ParseAck(payload []byte) (Ack, error) {
+	if len(payload) < 4 {
+		return Ack{}, &ParseError{
+			Reason:  fmt.Sprintf("acknowledgement of %d bytes is shorter than 4 bytes", len(payload)),
+			Payload: bytes.Clone(payload),
+		}
+	}
+	word := binary.LittleEndian.Uint16(payload)
+	if word&AckFlag == 0 {
+		return Ack{}, &ParseError{Reason: fmt.Sprintf("command word 0x%04x is not an acknowledgement", word), Payload: bytes.Clone(payload)}
+	}
+	return Ack{
+		Word:   word &^ AckFlag,
+		Status: binary.LittleEndian.Uint16(payload[2:]),
+		Data:   bytes.Clone(payload[4:]),
+	}, nil
+}
@@ -0,0 +1,48 @@
+package protocol
+
+import "encoding/binary"
+
+// ParseFrame decodes the targets and alarm state contained in a data frame
+// payload in the VariantV1 layout, appending the targets to targets. An empty payload is the frame
+// the module sends when nothing is in its field of view. The payload is not
+// retained, a ParseError holds a copy of it.
+func ParseFrame(payload []byte, targets []Target) (Frame, error) {
+	return VariantV1.ParseFrame(payload, targets)
+}
+
+// EncodeFrame builds the data frame the module sends when reporting targets
+// with the given alarm state, the exact inverse of ParseFrame. Without targets
+// the empty frame the module sends when nothing is in its field of view is
+// returned, which carries no alarm state.
+func EncodeFrame(targets []Target, alarm byte) []byte {
+	return encode(dataHeader, EncodeFramePayload(targets, alarm), dataFooter)
+}
+
+// EncodeFramePayload builds the payload of the data frame EncodeFrame returns,
+// as found in Packet.Payload.
+func EncodeFramePayload(targets []Target, alarm byte) []byte {
+	var payload []byte
+	if len(targets) > 0 {
+		payload = make([]byte, 0, frameHeaderSize+len(targets)*targetRecordSize)
+		payload = append(payload, byte(len(targets)), alarm)
+		for _, target := range targets {
+			payload = append(payload,
+				0,
+				byte(target.Angle+0x80),
+				byte(target.Distance),
+				byte(target.Direction),
+				byte(target.Speed),
+				byte(target.SNR),
+			)
+		}
+	}
+	return payload
+}
+
+func encode(header []byte, payload []byte, footer []byte) []byte {
+	buf := make([]byte, 0, len(header)+2+len(payload)+len(footer))
+	buf = append(buf, header...)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(payload)))
+	buf = append(buf, payload...)
+	return append(buf, footer...)
+}
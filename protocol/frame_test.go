@@ -0,0 +1,134 @@
+package protocol_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+func TestParseFrameBounds(t *testing.T) {
+	one := record(6, 0, 40, 50, 60)
+	tests := []struct {
+		name    string
+		payload []byte
+		targets int // -1 for a ParseError
+	}{
+		{"empty", nil, 0},
+		{"only the target count", []byte{1}, -1},
+		{"no targets", []byte{0, 0}, 0},
+		{"no targets with a record", slices.Concat([]byte{0, 0}, one), -1},
+		{"missing record", []byte{1, 0}, -1},
+		{"record cut short", slices.Concat([]byte{1, 0}, one[:5]), -1},
+		{"one target", slices.Concat([]byte{1, 0}, one), 1},
+		{"trailing byte", slices.Concat([]byte{1, 0}, one, []byte{0}), -1},
+		{"second record missing", slices.Concat([]byte{2, 0}, one), -1},
+		{"most targets", slices.Concat([]byte{protocol.MaxTargets, 0}, bytes.Repeat(one, protocol.MaxTargets)), protocol.MaxTargets},
+		{"count beyond the records", slices.Concat([]byte{0xff, 0}, one), -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frame, err := protocol.ParseFrame(test.payload, nil)
+			if test.targets < 0 {
+				var parseErr *protocol.ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("got %v, expected a ParseError", err)
+				}
+				if !bytes.Equal(parseErr.Payload, test.payload) {
+					t.Errorf("error holds payload % x, expected % x", parseErr.Payload, test.payload)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(frame.Targets) != test.targets {
+				t.Errorf("got %d targets, expected %d", len(frame.Targets), test.targets)
+			}
+		})
+	}
+}
+
+func TestParseFrameFieldRanges(t *testing.T) {
+	tests := []struct {
+		record []byte
+		target protocol.Target
+	}{
+		{[]byte{0, 0x00, 0, 0, 0, 0}, protocol.Target{Angle: -128}},
+		{[]byte{0, 0x80, 1, 1, 1, 1}, protocol.Target{Distance: 1, Direction: protocol.DirectionToward, Speed: 1, SNR: 1}},
+		{[]byte{0, 0xff, 0xff, 0, 0xff, 0xff}, protocol.Target{Angle: 127, Distance: 255, Speed: 255, SNR: 255}},
+	}
+	for _, test := range tests {
+		frame, err := protocol.ParseFrame(slices.Concat([]byte{1, 1}, test.record), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !frame.Alarm || len(frame.Targets) != 1 || frame.Targets[0] != test.target {
+			t.Errorf("record % x: got %+v, expected %+v", test.record, frame, test.target)
+		}
+	}
+}
+
+func TestParseErrorCopiesPayload(t *testing.T) {
+	payload := []byte{2, 0, 1}
+	_, err := protocol.ParseFrame(payload, nil)
+	var parseErr *protocol.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got %v, expected a ParseError", err)
+	}
+	payload[0] = 0x42
+	if parseErr.Payload[0] != 2 {
+		t.Error("ParseError retains the payload buffer")
+	}
+}
+
+func TestEncodeFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []protocol.Target
+		alarm   byte
+		frame   []byte
+	}{
+		{"no targets", nil, 0, []byte{0xf4, 0xf3, 0xf2, 0xf1, 0x00, 0x00, 0xf8, 0xf7, 0xf6, 0xf5}},
+		{"alarm without targets", nil, 1, []byte{0xf4, 0xf3, 0xf2, 0xf1, 0x00, 0x00, 0xf8, 0xf7, 0xf6, 0xf5}},
+		{
+			"one target",
+			[]protocol.Target{{Angle: 10, Distance: 10, Direction: protocol.DirectionToward, Speed: 20, SNR: 8}},
+			0,
+			[]byte{0xf4, 0xf3, 0xf2, 0xf1, 0x08, 0x00, 0x01, 0x00, 0x00, 0x8a, 0x0a, 0x01, 0x14, 0x08, 0xf8, 0xf7, 0xf6, 0xf5},
+		},
+		{
+			"two targets with the alarm",
+			[]protocol.Target{{Angle: -20, Distance: 45, Speed: 61, SNR: 80}, {Angle: 127, Distance: 255, Speed: 255, SNR: 255}},
+			1,
+			[]byte{
+				0xf4, 0xf3, 0xf2, 0xf1, 0x0e, 0x00, 0x02, 0x01,
+				0x00, 0x6c, 0x2d, 0x00, 0x3d, 0x50,
+				0x00, 0xff, 0xff, 0x00, 0xff, 0xff,
+				0xf8, 0xf7, 0xf6, 0xf5,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frame := protocol.EncodeFrame(test.targets, test.alarm)
+			if !bytes.Equal(frame, test.frame) {
+				t.Fatalf("got % x, expected % x", frame, test.frame)
+			}
+			//read back, it is the inverse of the reader and ParseFrame
+			packet, err := protocol.NewReader(bytes.NewReader(frame)).Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := protocol.ParseFrame(packet.Payload, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(parsed.Targets, test.targets) || parsed.Alarm != (len(test.targets) > 0 && test.alarm != 0) {
+				t.Errorf("parsed %+v", parsed)
+			}
+		})
+	}
+}
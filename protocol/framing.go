@@ -0,0 +1,29 @@
+package protocol
+
+import "bytes"
+
+// Framing describes how a module of the HLK radar family delimits its data
+// frames. Command frames are the same for the whole family and not part of
+// it.
+type Framing struct {
+	Header []byte // Bytes starting every data frame
+	Footer []byte // Bytes ending every data frame
+	Length int    // Payload length of modules sending fixed size frames without a length field, zero reads the length field
+}
+
+// LD2451Framing is the data framing of the LD2451, which the LD2410 shares.
+var LD2451Framing = Framing{Header: dataHeader, Footer: dataFooter}
+
+// CommandFraming delimits commands and acknowledgements, the same for every
+// module of the family.
+var CommandFraming = Framing{Header: commandHeader, Footer: commandFooter}
+
+// Encode builds the data frame carrying payload under the framing, the
+// inverse of what a Reader created with the framing returns.
+func (f Framing) Encode(payload []byte) []byte {
+	if f.Length > 0 {
+		buf := append(bytes.Clone(f.Header), payload...)
+		return append(buf, f.Footer...)
+	}
+	return encode(f.Header, payload, f.Footer)
+}
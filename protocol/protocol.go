@@ -0,0 +1,103 @@
+// Package protocol implements the LD2451 wire format: framing and
+// resynchronization of the byte stream, decoding of target reports, and
+// encoding of commands and their acknowledgements. It has no knowledge of
+// ports or goroutines, so it can be reused on top of any byte source.
+package protocol
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// MaxPayloadLength is the largest payload accepted in a frame, far above
+	// what the module reports in practice but small enough to never stall a
+	// reader waiting for a length made up by corrupted bytes.
+	MaxPayloadLength = 256
+
+	// MaxTargets is the most targets the module reports in a single frame.
+	// Frames claiming more can only come from corrupted or foreign data.
+	MaxTargets = 20
+
+	frameHeaderSize  = 2 //target count and alarm state at the start of every non-empty payload
+	targetRecordSize = 6 //bytes per target following the payload header
+)
+
+var (
+	dataHeader    = []byte{0xf4, 0xf3, 0xf2, 0xf1}
+	dataFooter    = []byte{0xf8, 0xf7, 0xf6, 0xf5}
+	commandHeader = []byte{0xfd, 0xfc, 0xfb, 0xfa}
+	commandFooter = []byte{0x04, 0x03, 0x02, 0x01}
+)
+
+type Target struct {
+	Angle     int       `json:"angle"`     // Angle of the target relative to the perpendicular direction of the antenna
+	Distance  int       `json:"distance"`  // Distance in meters to the target
+	Direction Direction `json:"direction"` // Direction of movement relative to the antenna
+	Speed     int       `json:"speed"`     // Speed in KM/H
+	SNR       int       `json:"snr"`       // Signal to Noise Ratio
+	Time      time.Time `json:"time"`      // When the frame containing the target was received
+
+	Fine         bool    `json:"fine,omitempty"`          // FineDistance and FineSpeed are set, by a Variant reporting either at a finer resolution
+	FineDistance float64 `json:"fine_distance,omitempty"` // Distance in meters at the finer resolution of a Variant reporting it, else in whole meters
+	FineSpeed    float64 `json:"fine_speed,omitempty"`    // Speed in KM/H at the finer resolution of a Variant reporting it, else in whole KM/H
+
+	Sensor string `json:"sensor,omitempty"` // ID of the sensor that reported the target, see LD2451.Config.SensorID
+
+	RadialSpeed float64 `json:"radial_speed,omitempty"` // Speed in KM/H along the line of sight as reported, when LD2451.Config.CosineCorrection turned Speed into the speed along the road; zero otherwise
+}
+
+// PreciseDistance returns FineDistance when the frame variant reported it and
+// Distance otherwise.
+func (t Target) PreciseDistance() float64 {
+	if t.Fine {
+		return t.FineDistance
+	}
+	return float64(t.Distance)
+}
+
+// PreciseSpeed returns FineSpeed when the frame variant reported it and Speed
+// otherwise.
+func (t Target) PreciseSpeed() float64 {
+	if t.Fine {
+		return t.FineSpeed
+	}
+	return float64(t.Speed)
+}
+
+// Frame holds everything reported by the sensor in a single data frame.
+type Frame struct {
+	Targets []Target  `json:"targets"`
+	Alarm   bool      `json:"alarm"`            // Alarm state reported alongside the targets
+	Time    time.Time `json:"time"`             // When the frame was received
+	Sensor  string    `json:"sensor,omitempty"` // ID of the sensor that reported the frame, see LD2451.Config.SensorID
+}
+
+const (
+	DirectionAway   Direction = 0
+	DirectionToward Direction = 1
+)
+
+type Direction int
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionAway:
+		return "Away"
+	case DirectionToward:
+		return "Toward"
+	default:
+		return "Unknown"
+	}
+}
+
+// ParseError is returned when a frame is correctly delimited but its payload
+// does not match the protocol layout.
+type ParseError struct {
+	Reason  string // What was wrong with the payload
+	Payload []byte // The offending payload, without header, length and footer
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("malformed frame from the LD2451: %s", e.Reason)
+}
@@ -0,0 +1,136 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+type Kind int
+
+const (
+	KindData    Kind = 0 // Target report sent by the module
+	KindCommand Kind = 1 // Command or command acknowledgement, which share their framing
+)
+
+// Packet is a complete frame read from the byte stream.
+type Packet struct {
+	Kind      Kind
+	Payload   []byte // Bytes between length, or header for fixed size frames, and footer, only valid until the next call to Next
+	Skipped   int    // Number of bytes discarded while looking for this packet
+	Oversized int    // Number of headers rejected for declaring an implausible length while looking for this packet
+}
+
+// Reader splits a byte stream into packets. Candidate frames are inspected in
+// the read buffer before being consumed, so a header that turns out to belong
+// to garbage or a truncated frame only costs a single byte and scanning
+// resumes right after it.
+type Reader struct {
+	r       *bufio.Reader
+	framing Framing
+	scratch [MaxPayloadLength]byte
+}
+
+// NewReader reads the frames of an LD2451.
+func NewReader(r io.Reader) *Reader {
+	return NewFramedReader(r, LD2451Framing)
+}
+
+// NewFramedReader reads the frames of another module of the HLK family, whose
+// data frames are delimited by framing.
+func NewFramedReader(r io.Reader, framing Framing) *Reader {
+	return &Reader{r: bufio.NewReader(r), framing: framing}
+}
+
+// Next returns the next complete packet. When an error is returned the packet
+// still reports what was skipped before the error occurred.
+func (r *Reader) Next() (Packet, error) {
+	packet := Packet{}
+	for {
+		n, kind, err := r.syncHeader()
+		packet.Skipped += n
+		if err != nil {
+			return packet, err
+		}
+		header, footer, fixed := r.framing.Header, r.framing.Footer, r.framing.Length
+		if kind == KindCommand {
+			header, footer, fixed = commandHeader, commandFooter, 0
+		}
+
+		length, offset := fixed, len(header)
+		if fixed == 0 {
+			//get length of the frame (2 bytes after the header)
+			head, err := r.r.Peek(len(header) + 2)
+			if err != nil {
+				return packet, err
+			}
+			length = int(head[len(header)+1])<<8 | int(head[len(header)])
+			offset += 2
+		}
+		if length > MaxPayloadLength {
+			//a length this large can only come from corrupted bytes, don't wait for it
+			packet.Oversized++
+			r.r.Discard(1)
+			packet.Skipped++
+			continue
+		}
+		total := offset + length + len(footer)
+
+		frame, err := r.r.Peek(total)
+		if err != nil {
+			return packet, err
+		}
+		if !bytes.Equal(frame[total-len(footer):], footer) {
+			//not a real frame, look for the next header after this one
+			r.r.Discard(1)
+			packet.Skipped++
+			continue
+		}
+
+		packet.Kind = kind
+		packet.Payload = r.scratch[:length]
+		copy(packet.Payload, frame[offset:])
+		r.r.Discard(total)
+		return packet, nil
+	}
+}
+
+// Discard drops everything currently buffered and returns the number of bytes dropped.
+func (r *Reader) Discard() int {
+	n, _ := r.r.Discard(r.r.Buffered())
+	return n
+}
+
+// syncHeader discards buffered bytes until the reader is positioned at a data
+// or command header and returns how many bytes were skipped.
+func (r *Reader) syncHeader() (int, Kind, error) {
+	skipped := 0
+	for {
+		//wait until a full header could be present, then scan everything that is buffered
+		longest := max(len(r.framing.Header), len(commandHeader))
+		_, err := r.r.Peek(min(len(r.framing.Header), len(commandHeader)))
+		if err != nil {
+			return skipped, KindData, err
+		}
+		window, _ := r.r.Peek(r.r.Buffered())
+		i, kind := bytes.Index(window, r.framing.Header), KindData
+		if j := bytes.Index(window, commandHeader); j >= 0 && (i < 0 || j < i) {
+			i, kind = j, KindCommand
+		}
+		if i >= 0 {
+			r.r.Discard(i)
+			return skipped + i, kind, nil
+		}
+		//keep the tail, it may hold the start of a header
+		n := len(window) - (longest - 1)
+		if n <= 0 {
+			//too few bytes buffered to rule out the longer header, wait for more
+			if _, err := r.r.Peek(len(window) + 1); err != nil {
+				return skipped, KindData, err
+			}
+			continue
+		}
+		r.r.Discard(n)
+		skipped += n
+	}
+}
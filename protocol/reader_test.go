@@ -0,0 +1,114 @@
+package protocol_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+func TestReaderResyncs(t *testing.T) {
+	frame := protocol.EncodeFrame([]protocol.Target{{Angle: 5, Distance: 20, Direction: protocol.DirectionToward, Speed: 30, SNR: 40}}, 0)
+	payload := frame[6 : len(frame)-4]
+	ack := protocol.EncodeAck(protocol.CmdEnableConfig, 0, []byte{0x01, 0x00})
+
+	tests := []struct {
+		name    string
+		stream  []byte
+		kind    protocol.Kind
+		payload []byte
+		skipped int
+	}{
+		{"clean frame", frame, protocol.KindData, payload, 0},
+		{"garbage before the frame", slices.Concat([]byte{0x00, 0x11, 0x22}, frame), protocol.KindData, payload, 3},
+		{"partial header before the frame", slices.Concat([]byte{0xf4, 0xf3}, frame), protocol.KindData, payload, 2},
+		{"frame cut short", slices.Concat(frame[:9], frame), protocol.KindData, payload, 9},
+		{"header without a footer", slices.Concat(frame[:6], []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c}, frame), protocol.KindData, payload, 18},
+		{"command header in garbage", slices.Concat([]byte{0xfd, 0xfc, 0xfb, 0xfa, 0x00}, frame), protocol.KindData, payload, 5},
+		{"ack after garbage", slices.Concat([]byte{0x55}, ack), protocol.KindCommand, ack[6 : len(ack)-4], 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			packet, err := protocol.NewReader(bytes.NewReader(test.stream)).Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if packet.Kind != test.kind || !bytes.Equal(packet.Payload, test.payload) || packet.Skipped != test.skipped {
+				t.Errorf("got kind %d, payload % x, %d skipped; expected kind %d, payload % x, %d skipped",
+					packet.Kind, packet.Payload, packet.Skipped, test.kind, test.payload, test.skipped)
+			}
+		})
+	}
+}
+
+func TestReaderReportsSkippedBytesAtEOF(t *testing.T) {
+	reader := protocol.NewReader(bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}))
+	packet, err := reader.Next()
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("got %v, expected EOF", err)
+	}
+	//the last three bytes could start a header and are kept
+	if packet.Skipped != 5 {
+		t.Errorf("skipped %d bytes, expected 5", packet.Skipped)
+	}
+}
+
+func TestReaderRejectsImplausibleLengths(t *testing.T) {
+	frame := protocol.EncodeFrame([]protocol.Target{{Distance: 20, Speed: 30}}, 0)
+	payload := frame[6 : len(frame)-4]
+	largest := make([]byte, protocol.MaxPayloadLength)
+	lengthHeader := func(header []byte, length int) []byte {
+		return binary.LittleEndian.AppendUint16(slices.Clone(header), uint16(length))
+	}
+	dataHeader, commandHeader := frame[:4], []byte{0xfd, 0xfc, 0xfb, 0xfa}
+
+	tests := []struct {
+		name      string
+		stream    []byte
+		payload   []byte
+		skipped   int
+		oversized int
+	}{
+		{"largest payload", protocol.LD2451Framing.Encode(largest), largest, 0, 0},
+		{"one byte too long", slices.Concat(lengthHeader(dataHeader, protocol.MaxPayloadLength+1), frame), payload, 6, 1},
+		{"made up length", slices.Concat(lengthHeader(dataHeader, 0xffff), frame), payload, 6, 1},
+		{"made up command length", slices.Concat(lengthHeader(commandHeader, 0x8000), frame), payload, 6, 1},
+		{"two made up lengths", slices.Concat(lengthHeader(dataHeader, 0xffff), lengthHeader(dataHeader, 0x1234), frame), payload, 12, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//the stream stays open, so waiting for a made up length would hang
+			r, w := io.Pipe()
+			defer w.Close()
+			go w.Write(test.stream)
+
+			type result struct {
+				packet protocol.Packet
+				err    error
+			}
+			done := make(chan result, 1)
+			go func() {
+				packet, err := protocol.NewReader(r).Next()
+				done <- result{packet, err}
+			}()
+			select {
+			case got := <-done:
+				if got.err != nil {
+					t.Fatal(got.err)
+				}
+				packet := got.packet
+				if !bytes.Equal(packet.Payload, test.payload) || packet.Skipped != test.skipped || packet.Oversized != test.oversized {
+					t.Errorf("got %d byte payload, %d skipped, %d oversized; expected %d byte payload, %d skipped, %d oversized",
+						len(packet.Payload), packet.Skipped, packet.Oversized, len(test.payload), test.skipped, test.oversized)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("reader waited for the declared length")
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func (t Target) String() string {
+	return fmt.Sprintf("%d m, %d km/h %s, SNR %d, angle %d°", t.Distance, t.Speed, strings.ToLower(t.Direction.String()), t.SNR, t.Angle)
+}
+
+func (f Frame) String() string {
+	var b strings.Builder
+	switch len(f.Targets) {
+	case 0:
+		b.WriteString("no targets")
+	case 1:
+		b.WriteString("1 target")
+	default:
+		fmt.Fprintf(&b, "%d targets", len(f.Targets))
+	}
+	if f.Alarm {
+		b.WriteString(", alarm")
+	}
+	for i, target := range f.Targets {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(target.String())
+	}
+	return b.String()
+}
+
+// MarshalText encodes known directions by name, so JSON and other text based
+// encodings carry "away" and "toward" rather than raw protocol values.
+func (d Direction) MarshalText() ([]byte, error) {
+	switch d {
+	case DirectionAway, DirectionToward:
+		return []byte(strings.ToLower(d.String())), nil
+	default:
+		return []byte(strconv.Itoa(int(d))), nil
+	}
+}
+
+func (d *Direction) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "away":
+		*d = DirectionAway
+	case "toward":
+		*d = DirectionToward
+	default:
+		v, err := strconv.Atoi(string(text))
+		if err != nil {
+			return fmt.Errorf("unknown direction %q", text)
+		}
+		*d = Direction(v)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// Variant is a revision of the data frame layout. Revisions extending the
+// target records append their fields to the six known bytes, so frames of a
+// newer firmware still decode once the size of its records is known.
+type Variant struct {
+	Name       string `json:"name"`
+	RecordSize int    `json:"record_size"` // Bytes per target record, zero infers it from the length of every frame
+
+	DistanceResolution float64 `json:"distance_resolution,omitempty"` // Meters per unit of the distance byte of firmware reporting sub-meter distances, sets Target.Fine; zero for whole meters
+	SpeedResolution    float64 `json:"speed_resolution,omitempty"`    // KM/H per unit of the speed byte of firmware reporting sub-KM/H speeds, sets Target.Fine; zero for whole KM/H
+}
+
+var (
+	// VariantV1 is the layout of the protocol version 1 firmware, the only
+	// one documented so far.
+	VariantV1 = Variant{Name: "v1", RecordSize: targetRecordSize}
+
+	// VariantExtended decodes frames of firmware reporting a newer protocol
+	// version, taking the record size from each frame and ignoring the bytes
+	// beyond the known fields.
+	VariantExtended = Variant{Name: "extended"}
+)
+
+// LookupVariant returns the layout named name, v1 or extended.
+func LookupVariant(name string) (Variant, bool) {
+	for _, v := range []Variant{VariantV1, VariantExtended} {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+// VariantFor selects the layout for the protocol version the module reports
+// when entering config mode.
+func VariantFor(protocolVersion uint16) Variant {
+	if protocolVersion <= 1 {
+		return VariantV1
+	}
+	return VariantExtended
+}
+
+func (v Variant) String() string {
+	return v.Name
+}
+
+// ParseFrame decodes a data frame payload in the layout of the variant, see
+// the ParseFrame function.
+func (v Variant) ParseFrame(payload []byte, targets []Target) (Frame, error) {
+	if len(payload) == 0 {
+		return Frame{Targets: targets}, nil
+	}
+	if len(payload) < frameHeaderSize {
+		return Frame{}, &ParseError{
+			Reason:  fmt.Sprintf("payload of %d bytes is shorter than the %d byte header", len(payload), frameHeaderSize),
+			Payload: bytes.Clone(payload),
+		}
+	}
+
+	//get the number of targets in the frame, this is the first byte after the frame length
+	numTargets := int(payload[0])
+	records := len(payload) - frameHeaderSize
+	size := v.recordSize(numTargets, records)
+	if numTargets*size != records || numTargets > 0 && size < targetRecordSize {
+		reason := fmt.Sprintf("%d targets need a %d byte payload, got %d", numTargets, frameHeaderSize+numTargets*max(size, targetRecordSize), len(payload))
+		if numTargets > 0 && records%numTargets == 0 && records/numTargets > targetRecordSize {
+			reason += fmt.Sprintf(", %d byte target records suggest a newer firmware frame layout", records/numTargets)
+		}
+		return Frame{}, &ParseError{Reason: reason, Payload: bytes.Clone(payload)}
+	}
+
+	//the byte after the target count is the alarm state
+	alarm := payload[1] != 0
+	buf := payload[frameHeaderSize:]
+
+	for i := 0; i < numTargets; i++ {
+		record := buf[i*size : i*size+targetRecordSize]
+		target := Target{
+			Angle:     int(record[1]) - 0x80,
+			Distance:  int(record[2]),
+			Direction: Direction(record[3]),
+			Speed:     int(record[4]),
+			SNR:       int(record[5]),
+		}
+		if v.DistanceResolution > 0 || v.SpeedResolution > 0 {
+			target.Fine = true
+			target.FineDistance = float64(target.Distance)
+			target.FineSpeed = float64(target.Speed)
+		}
+		if v.DistanceResolution > 0 {
+			target.FineDistance = float64(record[2]) * v.DistanceResolution
+			target.Distance = int(math.Round(target.FineDistance))
+		}
+		if v.SpeedResolution > 0 {
+			target.FineSpeed = float64(record[4]) * v.SpeedResolution
+			target.Speed = int(math.Round(target.FineSpeed))
+		}
+		targets = append(targets, target)
+	}
+	return Frame{Targets: targets, Alarm: alarm}, nil
+}
+
+// SalvageFrame decodes the complete target records of a payload whose length
+// doesn't match its target count, e.g. a frame with a record cut short or
+// trailing bytes, and returns how many of the announced targets were lost.
+// It fails when not even the payload header is intact.
+func (v Variant) SalvageFrame(payload []byte, targets []Target) (Frame, int, error) {
+	if len(payload) < frameHeaderSize {
+		return Frame{}, 0, &ParseError{
+			Reason:  fmt.Sprintf("payload of %d bytes is shorter than the %d byte header", len(payload), frameHeaderSize),
+			Payload: bytes.Clone(payload),
+		}
+	}
+	numTargets := int(payload[0])
+	records := len(payload) - frameHeaderSize
+	size := max(v.recordSize(numTargets, records), targetRecordSize)
+	complete := min(numTargets, records/size)
+	//decode the complete records as if the frame only announced them
+	trimmed := append([]byte{byte(complete), payload[1]}, payload[frameHeaderSize:frameHeaderSize+complete*size]...)
+	fixed := v
+	fixed.RecordSize = size
+	frame, err := fixed.ParseFrame(trimmed, targets)
+	if err != nil {
+		return Frame{}, 0, err
+	}
+	return frame, numTargets - complete, nil
+}
+
+// recordSize returns the bytes per target record of a payload announcing
+// numTargets targets in records bytes. Variants inferring it divide the
+// records evenly among the targets, rounded down so trailing bytes don't
+// shift them, and leave it zero without targets to infer it from.
+func (v Variant) recordSize(numTargets, records int) int {
+	if v.RecordSize != 0 || numTargets == 0 {
+		return v.RecordSize
+	}
+	return records / numTargets
+}
@@ -0,0 +1,98 @@
+package protocol_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// record returns a target record of size bytes, padded after the six known
+// ones.
+func record(size int, angle, distance, speed, snr byte) []byte {
+	return append([]byte{0x01, 0x80 + angle, distance, byte(protocol.DirectionToward), speed, snr}, make([]byte, size-6)...)
+}
+
+func TestExtendedParsesEmptyFrame(t *testing.T) {
+	for _, alarm := range []byte{0, 1} {
+		frame, err := protocol.VariantExtended.ParseFrame([]byte{0, alarm}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frame.Targets) != 0 || frame.Alarm != (alarm != 0) {
+			t.Errorf("alarm %d: got %+v", alarm, frame)
+		}
+	}
+	if _, err := protocol.VariantExtended.ParseFrame([]byte{0, 0, 0x42}, nil); err == nil {
+		t.Error("accepted trailing bytes after zero targets")
+	}
+}
+
+func TestExtendedParsesRecords(t *testing.T) {
+	payload := append([]byte{2, 0}, record(9, 3, 40, 50, 60)...)
+	payload = append(payload, record(9, 4, 41, 51, 61)...)
+	frame, err := protocol.VariantExtended.ParseFrame(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	distances := []int{frame.Targets[0].Distance, frame.Targets[1].Distance}
+	if !slices.Equal(distances, []int{40, 41}) {
+		t.Errorf("got %+v", frame.Targets)
+	}
+}
+
+func TestSalvageInfersExtendedRecordSize(t *testing.T) {
+	payload := append([]byte{2, 0}, record(9, 3, 40, 50, 60)...)
+	payload = append(payload, record(9, 4, 41, 51, 61)...)
+	payload = append(payload, 0xff)
+	if _, err := protocol.VariantExtended.ParseFrame(payload, nil); err == nil {
+		t.Fatal("parsed a frame with a trailing byte")
+	}
+	frame, lost, err := protocol.VariantExtended.SalvageFrame(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lost != 0 || len(frame.Targets) != 2 {
+		t.Fatalf("salvaged %+v, lost %d", frame.Targets, lost)
+	}
+	for i, target := range frame.Targets {
+		if target.Angle != 3+i || target.Distance != 40+i || target.Speed != 50+i || target.SNR != 60+i {
+			t.Errorf("target %d: got %+v", i, target)
+		}
+	}
+}
+
+func TestSalvageCutShortV1Frame(t *testing.T) {
+	payload := append([]byte{2, 0}, record(6, 3, 40, 50, 60)...)
+	payload = append(payload, record(6, 4, 41, 51, 61)[:4]...)
+	frame, lost, err := protocol.VariantV1.SalvageFrame(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lost != 1 || len(frame.Targets) != 1 || frame.Targets[0].Distance != 40 {
+		t.Errorf("salvaged %+v, lost %d", frame.Targets, lost)
+	}
+}
+
+func TestFineResolutionKeepsZeroSpeed(t *testing.T) {
+	variant := protocol.Variant{Name: "fine", RecordSize: 6, DistanceResolution: 0.25}
+	frame, err := variant.ParseFrame(append([]byte{1, 0}, record(6, 0, 10, 0, 60)...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := frame.Targets[0]
+	if !target.Fine || target.FineDistance != 2.5 || target.Distance != 3 {
+		t.Errorf("got %+v", target)
+	}
+	if target.PreciseSpeed() != 0 || target.PreciseDistance() != 2.5 {
+		t.Errorf("precise speed %g and distance %g, want 0 and 2.5", target.PreciseSpeed(), target.PreciseDistance())
+	}
+
+	frame, err = protocol.VariantV1.ParseFrame(append([]byte{1, 0}, record(6, 0, 10, 20, 60)...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target := frame.Targets[0]; target.Fine || target.PreciseSpeed() != 20 {
+		t.Errorf("whole unit variant: got %+v", target)
+	}
+}
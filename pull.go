@@ -0,0 +1,177 @@
+package LD2451
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// Puller reads the module on the goroutine calling Next and starts no
+// goroutine of its own, e.g. for the single threaded main loop of an embedded
+// board. Frames go through the same decoding, validation, smoothing and
+// filtering as with Open, but commands, events and the settings that need a
+// background goroutine are not available. Config.WaitForFirstFrame only
+// accepts a data frame, since the firmware version cannot be queried. A Puller
+// is not safe for concurrent use.
+type Puller struct {
+	sensor *LD2451
+	first  *Frame //frame read by waitForFirstFrame, returned by the next call to Next
+}
+
+// OpenPuller opens the serial port named in config like Open, reconnecting
+// from within Next when Config.Reconnect is set.
+func OpenPuller(config Config, options ...Option) (*Puller, error) {
+	for _, option := range options {
+		option(&config)
+	}
+	if err := config.validatePull(); err != nil {
+		return nil, wrapError("open", config.SerialPort, err)
+	}
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, wrapError("open", config.SerialPort, err)
+	}
+	if err := config.validatePort(); err != nil {
+		return nil, wrapError("open", config.SerialPort, err)
+	}
+	port, err := openPort(config)
+	if err != nil {
+		return nil, wrapError("open", config.SerialPort, err)
+	}
+	tap := newTap(config)
+	port = tap.port(port)
+	reopen := func(baud int) (transport.Port, error) {
+		port, err := transport.OpenSerial(transport.SerialConfig{Name: config.SerialPort, Baud: baud})
+		if err != nil {
+			return nil, err
+		}
+		return tap.port(port), nil
+	}
+	return newPuller(protocol.NewReader(port), port, config, reopen)
+}
+
+// NewPuller reads from an already opened port like New.
+func NewPuller(port transport.Port, config Config, options ...Option) (*Puller, error) {
+	for _, option := range options {
+		option(&config)
+	}
+	if err := config.validatePull(); err != nil {
+		port.Close()
+		return nil, wrapError("open", portName(config, port), err)
+	}
+	port = newTap(config).port(port)
+	return newPuller(protocol.NewReader(port), port, config, nil)
+}
+
+func newPuller(frames Source, port transport.Port, config Config, reopen func(baud int) (transport.Port, error)) (*Puller, error) {
+	sensor, err := newSensor(frames, port, config, reopen)
+	if err != nil {
+		return nil, err
+	}
+	sensor.pull = true
+	sensor.reportConnection(Connected, nil, 0)
+	puller := &Puller{sensor: sensor}
+	if sensor.config.WaitForFirstFrame > 0 {
+		if err := puller.waitForFirstFrame(); err != nil {
+			sensor.Close()
+			return nil, err
+		}
+	}
+	return puller, nil
+}
+
+// waitForFirstFrame reads until the first data frame was handled, failing
+// once Config.WaitForFirstFrame passed without one. Reading blocks for up to
+// the port's read timeout, so the wait can overrun by that much.
+func (p *Puller) waitForFirstFrame() error {
+	s := p.sensor
+	timeout := s.config.WaitForFirstFrame
+	deadline := s.now().Add(timeout)
+	for s.now().Before(deadline) {
+		s.pulled = nil
+		if !s.step() {
+			return s.fatal
+		}
+		if s.pulled != nil {
+			p.first = s.pulled
+			return nil
+		}
+	}
+	return s.wrap("open", fmt.Errorf("%w within %s, check the port, baud rate and wiring", ErrNoData, timeout))
+}
+
+// validatePull rejects the settings that need commands or a goroutine.
+func (config Config) validatePull() error {
+	switch {
+	case config.DetectProtocol:
+		return errors.New("pull mode cannot detect the protocol, set Config.FrameVariant instead")
+	case config.DetectionParameters != nil:
+		return errors.New("pull mode cannot apply detection parameters")
+	case config.AlarmParameters != nil:
+		return errors.New("pull mode cannot apply alarm parameters")
+	case config.RecoverConfigMode > 0:
+		return errors.New("pull mode cannot recover the config mode")
+	case config.SummaryInterval > 0:
+		return errors.New("pull mode cannot deliver summaries")
+	case config.ClearTickInterval > 0:
+		return errors.New("pull mode cannot deliver clear ticks")
+	}
+	return nil
+}
+
+// Next reads until the next data frame was handled and returns it with the
+// targets that passed the filters, without targets for frames held back by
+// Config.ReportInterval. It blocks for as long as reading the port
+// does, at most the port's read timeout unless it reconnects, after which an
+// error wrapping transport.ErrReadTimeout is returned and Next can be called
+// again. Errors that ReadTarget would return are returned instead of a frame;
+// once reading failed for good every call returns that error.
+func (p *Puller) Next() (Frame, error) {
+	s := p.sensor
+	if first := p.first; first != nil {
+		p.first = nil
+		return *first, nil
+	}
+	for {
+		select {
+		case <-s.done:
+			return Frame{}, s.fatal
+		default:
+		}
+		s.pulled = nil
+		alive := s.step()
+		select {
+		case err := <-s.errors:
+			if err != s.fatal {
+				return Frame{}, err
+			}
+		default:
+		}
+		if !alive {
+			return Frame{}, s.fatal
+		}
+		if s.pulled != nil {
+			return *s.pulled, nil
+		}
+		if s.idle {
+			return Frame{}, s.wrap("read", transport.ErrReadTimeout)
+		}
+	}
+}
+
+// Stats returns a snapshot of the reader counters.
+func (p *Puller) Stats() Stats {
+	return p.sensor.Stats()
+}
+
+// State returns the current state of the sensor.
+func (p *Puller) State() SensorState {
+	return p.sensor.State()
+}
+
+// Close closes the port, Next fails afterwards.
+func (p *Puller) Close() {
+	p.sensor.Close()
+}
@@ -0,0 +1,114 @@
+package LD2451
+
+import (
+	"context"
+	"fmt"
+)
+
+type ReportMode int
+
+const (
+	ReportAuto  ReportMode = 0 // The module reports frames continuously
+	ReportQuery ReportMode = 1 // The module is silent until Poll asks for a frame
+)
+
+func (m ReportMode) String() string {
+	switch m {
+	case ReportAuto:
+		return "Auto"
+	case ReportQuery:
+		return "Query"
+	default:
+		return "Unknown"
+	}
+}
+
+// SetReportMode switches between continuous reporting and query mode. The
+// firmware has no query mode of its own, so in query mode the module is kept
+// in standby like with Sleep and Poll wakes it for a single frame, which keeps
+// the link quiet in between.
+func (ld2451 *LD2451) SetReportMode(mode ReportMode) error {
+	var err error
+	switch mode {
+	case ReportAuto:
+		err = ld2451.Wake()
+	case ReportQuery:
+		err = ld2451.Sleep()
+	default:
+		return fmt.Errorf("unknown report mode %d", mode)
+	}
+	if err != nil {
+		return err
+	}
+	ld2451.pollMu.Lock()
+	ld2451.mode = mode
+	ld2451.pollMu.Unlock()
+	return nil
+}
+
+// Poll returns the next frame received from the module, with the targets that
+// passed the library's filters. In query mode the module is woken for that
+// frame and put back into standby afterwards. When that fails the error is
+// returned along with the polled frame.
+func (ld2451 *LD2451) Poll(ctx context.Context) (_ Frame, err error) {
+	ld2451.pollMu.Lock()
+	query := ld2451.mode == ReportQuery
+	ld2451.pollMu.Unlock()
+
+	if query {
+		if err := ld2451.Wake(); err != nil {
+			return Frame{}, err
+		}
+		defer func() {
+			if sleepErr := ld2451.Sleep(); err == nil {
+				err = sleepErr
+			}
+		}()
+	}
+
+	//register only now, so a frame still in flight before waking isn't taken for the polled one
+	ch := make(chan Frame, 1)
+	ld2451.pollMu.Lock()
+	ld2451.pollers = append(ld2451.pollers, ch)
+	ld2451.pollMu.Unlock()
+
+	select {
+	case frame := <-ch:
+		return frame, nil
+	case <-ctx.Done():
+		ld2451.removePoller(ch)
+		return Frame{}, ctx.Err()
+	case <-ld2451.done:
+		ld2451.removePoller(ch)
+		return Frame{}, ld2451.fatal
+	}
+}
+
+// polling reports whether any Poll call is waiting for a frame.
+func (ld2451 *LD2451) polling() bool {
+	ld2451.pollMu.Lock()
+	defer ld2451.pollMu.Unlock()
+	return len(ld2451.pollers) > 0
+}
+
+// notifyPollers hands frame to every waiting Poll call.
+func (ld2451 *LD2451) notifyPollers(frame Frame) {
+	ld2451.pollMu.Lock()
+	pollers := ld2451.pollers
+	ld2451.pollers = nil
+	ld2451.pollMu.Unlock()
+	for _, ch := range pollers {
+		ch <- frame
+	}
+}
+
+func (ld2451 *LD2451) removePoller(ch chan Frame) {
+	ld2451.pollMu.Lock()
+	defer ld2451.pollMu.Unlock()
+	for i, poller := range ld2451.pollers {
+		if poller == ch {
+			ld2451.pollers = append(ld2451.pollers[:i], ld2451.pollers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package LD2451_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+// keepSending sends a frame every 50 ms until the test ends, as the fake
+// sensor doesn't report by itself.
+func keepSending(t *testing.T, sensor *sensortest.Sensor) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				sensor.SendTargets(false, LD2451.Target{Distance: 20, Speed: 40})
+			}
+		}
+	}()
+}
+
+func TestPollAfterSilence(t *testing.T) {
+	sensor, radar := openSensor(t, nil)
+	if err := radar.SetReportMode(LD2451.ReportQuery); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(silence)
+	keepSending(t, sensor)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	frame, err := radar.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frame.Targets) != 1 || frame.Targets[0].Distance != 20 {
+		t.Fatalf("polled %v, want a target at 20 m", frame)
+	}
+}
+
+func TestPollReportsFailingSleep(t *testing.T) {
+	sensor, radar := openSensor(t, nil)
+	if err := radar.SetReportMode(LD2451.ReportQuery); err != nil {
+		t.Fatal(err)
+	}
+	//the module can be woken, but refuses to go back into standby
+	sensor.Handle(protocol.CmdEnableConfig, func(sensortest.Command) (uint16, []byte, bool) {
+		return 1, nil, true
+	})
+	keepSending(t, sensor)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	frame, err := radar.Poll(ctx)
+	if err == nil {
+		t.Fatal("Poll succeeded leaving the module awake")
+	}
+	if len(frame.Targets) != 1 {
+		t.Errorf("polled %v along with %v, want the frame", frame, err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package redissink appends LD2451 targets to a Redis Stream bounded by
+// MAXLEN, giving consumers a durable, replayable queue without a broker. Any
+// Redis client can be plugged in through the Commander interface.
+package redissink
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	DefaultStream  = "ld2451:targets"
+	DefaultMaxLen  = 10000
+	DefaultTimeout = 5 * time.Second
+)
+
+// Commander runs a single Redis command given as its arguments, e.g. for
+// github.com/redis/go-redis:
+//
+//	redissink.CommanderFunc(func(ctx context.Context, args ...any) error {
+//		return client.Do(ctx, args...).Err()
+//	})
+type Commander interface {
+	Do(ctx context.Context, args ...any) error
+}
+
+type CommanderFunc func(ctx context.Context, args ...any) error
+
+func (f CommanderFunc) Do(ctx context.Context, args ...any) error {
+	return f(ctx, args...)
+}
+
+type Config struct {
+	Stream      string // Stream key (default DefaultStream)
+	MaxLen      int64  // Upper bound on the stream length (default DefaultMaxLen)
+	ExactMaxLen bool   // Trim to exactly MaxLen entries instead of letting Redis trim lazily with ~
+
+	Timeout time.Duration // Bounds every command issued by Write (default DefaultTimeout)
+}
+
+// Sink implements LD2451.Sink.
+type Sink struct {
+	client Commander
+	config Config
+}
+
+func New(client Commander, config Config) *Sink {
+	if config.Stream == "" {
+		config.Stream = DefaultStream
+	}
+	if config.MaxLen <= 0 {
+		config.MaxLen = DefaultMaxLen
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return &Sink{client: client, config: config}
+}
+
+// Write XADDs target, giving up after Config.Timeout.
+func (s *Sink) Write(target LD2451.Target) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	return s.WriteContext(ctx, target)
+}
+
+// WriteContext XADDs target as an entry with one field per target attribute.
+func (s *Sink) WriteContext(ctx context.Context, target LD2451.Target) error {
+	trim := "~"
+	if s.config.ExactMaxLen {
+		trim = "="
+	}
+	direction, _ := target.Direction.MarshalText()
+	args := []any{
+		"XADD", s.config.Stream, "MAXLEN", trim, strconv.FormatInt(s.config.MaxLen, 10), "*",
+		"angle", strconv.Itoa(target.Angle),
+		"distance", strconv.Itoa(target.Distance),
+		"direction", string(direction),
+		"speed", strconv.Itoa(target.Speed),
+		"snr", strconv.Itoa(target.SNR),
+		"time", target.Time.Format(time.RFC3339Nano),
+	}
+	if target.Sensor != "" {
+		args = append(args, "sensor", target.Sensor)
+	}
+	return s.client.Do(ctx, args...)
+}
+
+// Flush does nothing, every target is added by Write.
+func (s *Sink) Flush() error { return nil }
+
+func (s *Sink) Close() error { return nil }
+
+// Run is shorthand for sensor.RunSink(ctx, s).
+func (s *Sink) Run(ctx context.Context, sensor *LD2451.LD2451) error {
+	return sensor.RunSink(ctx, s)
+}
@@ -0,0 +1,39 @@
+package redissink_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/redissink"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+func TestRunStopsWhileNoTargetsArrive(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	radar, err := LD2451.Open(sensor.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer radar.Close()
+
+	sink := redissink.New(redissink.CommanderFunc(func(context.Context, ...any) error { return nil }), redissink.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sink.Run(ctx, radar) }()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run returned %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run kept waiting for a target after ctx was canceled")
+	}
+}
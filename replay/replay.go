@@ -0,0 +1,118 @@
+// Package replay plays recorded frames back through an LD2451, either with
+// the timing they were recorded with, sped up, or as fast as they can be
+// decoded:
+//
+//	player := replay.New(replay.JSONFrames(file), replay.Config{Speed: 10})
+//	sensor, err := LD2451.NewSource(player, LD2451.Config{})
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// AsFastAsPossible plays frames back without waiting between them.
+const AsFastAsPossible = -1
+
+type Config struct {
+	Speed float64      // Playback speed relative to the recording, e.g. 2 or 10, or AsFastAsPossible (default 1)
+	Clock LD2451.Clock // Source of time frames are paced with, nil uses LD2451.SystemClock
+}
+
+// Player is an LD2451.Source handing out recorded frames at the pace they
+// were recorded with, scaled by Config.Speed. Frames are paced by their Time,
+// frames without one or going back in time are played right away.
+type Player struct {
+	next  func() (LD2451.Frame, error)
+	speed float64
+	clock LD2451.Clock
+
+	started bool
+	first   time.Time //recording time of the first frame
+	start   time.Time //clock time the first frame was played at
+	closed  chan struct{}
+	closeMu sync.Once
+}
+
+func New(next func() (LD2451.Frame, error), config Config) *Player {
+	if config.Speed == 0 {
+		config.Speed = 1
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+	return &Player{
+		next:   next,
+		speed:  config.Speed,
+		clock:  config.Clock,
+		closed: make(chan struct{}),
+	}
+}
+
+// Next waits until the next frame is due and returns it encoded as a packet.
+// It returns io.EOF once the recording ended and io.ErrClosedPipe after Close.
+func (p *Player) Next() (protocol.Packet, error) {
+	return LD2451.FrameSource(p.frame).Next()
+}
+
+func (p *Player) frame() (LD2451.Frame, error) {
+	frame, err := p.next()
+	if err != nil {
+		return frame, err
+	}
+	if p.speed < 0 || frame.Time.IsZero() {
+		return frame, p.checkClosed()
+	}
+	if !p.started {
+		p.started = true
+		p.first = frame.Time
+		p.start = p.clock.Now()
+		return frame, p.checkClosed()
+	}
+
+	offset := frame.Time.Sub(p.first)
+	if offset <= 0 {
+		return frame, p.checkClosed()
+	}
+	due := p.start.Add(time.Duration(float64(offset) / p.speed))
+	timer := p.clock.NewTimer(due.Sub(p.clock.Now()))
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return frame, nil
+	case <-p.closed:
+		return LD2451.Frame{}, io.ErrClosedPipe
+	}
+}
+
+func (p *Player) checkClosed() error {
+	select {
+	case <-p.closed:
+		return io.ErrClosedPipe
+	default:
+		return nil
+	}
+}
+
+// Close stops the playback, interrupting a pending wait for the next frame.
+func (p *Player) Close() error {
+	p.closeMu.Do(func() { close(p.closed) })
+	return nil
+}
+
+// JSONFrames reads frames encoded as one JSON object per line, the encoding
+// of LD2451.Frame, and returns io.EOF after the last one.
+func JSONFrames(r io.Reader) func() (LD2451.Frame, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	return func() (LD2451.Frame, error) {
+		var frame LD2451.Frame
+		err := decoder.Decode(&frame)
+		return frame, err
+	}
+}
@@ -0,0 +1,206 @@
+// Package report rolls detections into hourly or daily buckets and renders
+// them as CSV or JSON, e.g. so a Raspberry Pi next to the road can produce
+// the weekly neighborhood speed report by itself. Every vehicle counts once,
+// so tracks of the tracking package are the natural input:
+//
+//	r := report.New(report.Config{Period: report.Hourly})
+//	for _, track := range tracker.Expire(now) {
+//		r.AddTrack(track)
+//	}
+//	...
+//	r.WriteCSV(file)
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/tracking"
+)
+
+type Period int
+
+const (
+	Hourly Period = 0
+	Daily  Period = 1
+)
+
+type Config struct {
+	Period   Period         // Length of the buckets (default Hourly)
+	Location *time.Location // Time zone the buckets start in, e.g. at local midnight (default time.Local)
+}
+
+// DirectionStats describes the vehicles moving in one direction during a
+// bucket. Speeds are in KM/H.
+type DirectionStats struct {
+	Count     int     `json:"count"`
+	MeanSpeed float64 `json:"mean_speed"`
+	P50       int     `json:"p50"`
+	P85       int     `json:"p85"` // The standard figure of traffic calming studies
+	P95       int     `json:"p95"`
+	MaxSpeed  int     `json:"max_speed"`
+}
+
+type Bucket struct {
+	Start  time.Time      `json:"start"`
+	End    time.Time      `json:"end"`
+	Away   DirectionStats `json:"away"`
+	Toward DirectionStats `json:"toward"`
+}
+
+// Report collects speeds per bucket. It is safe for concurrent use, so
+// detections can be added while a report is written.
+type Report struct {
+	config Config
+
+	mu      sync.Mutex
+	buckets map[time.Time]*speeds
+}
+
+// speeds holds the speeds of a bucket per direction.
+type speeds struct {
+	away, toward []int
+}
+
+func New(config Config) *Report {
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	return &Report{config: config, buckets: make(map[time.Time]*speeds)}
+}
+
+// AddTrack counts a finished track with its highest speed.
+func (r *Report) AddTrack(track tracking.Track) {
+	r.Add(track.Start, track.Direction, track.MaxSpeed)
+}
+
+// Add counts a single detection at t.
+func (r *Report) Add(t time.Time, direction LD2451.Direction, speed int) {
+	start := r.bucketStart(t)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bucket := r.buckets[start]
+	if bucket == nil {
+		bucket = &speeds{}
+		r.buckets[start] = bucket
+	}
+	if direction == LD2451.DirectionToward {
+		bucket.toward = append(bucket.toward, speed)
+	} else {
+		bucket.away = append(bucket.away, speed)
+	}
+}
+
+// Reset drops everything collected so far, e.g. after a weekly report was
+// written.
+func (r *Report) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.buckets)
+}
+
+// bucketStart returns the start of the bucket t falls into. Buckets follow
+// the wall clock, so a day is a calendar day even across DST changes and the
+// hour repeated when the clocks go back gets a bucket of its own.
+func (r *Report) bucketStart(t time.Time) time.Time {
+	t = t.In(r.config.Location)
+	if r.config.Period == Daily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, r.config.Location)
+	}
+	return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+}
+
+func (r *Report) bucketEnd(start time.Time) time.Time {
+	if r.config.Period == Daily {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.Add(time.Hour)
+}
+
+// Buckets returns the buckets holding detections, oldest first.
+func (r *Report) Buckets() []Bucket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	buckets := make([]Bucket, 0, len(r.buckets))
+	for start, s := range r.buckets {
+		buckets = append(buckets, Bucket{
+			Start:  start,
+			End:    r.bucketEnd(start),
+			Away:   directionStats(s.away),
+			Toward: directionStats(s.toward),
+		})
+	}
+	slices.SortFunc(buckets, func(a, b Bucket) int { return a.Start.Compare(b.Start) })
+	return buckets
+}
+
+func directionStats(speeds []int) DirectionStats {
+	if len(speeds) == 0 {
+		return DirectionStats{}
+	}
+	sorted := slices.Clone(speeds)
+	slices.Sort(sorted)
+	total := 0
+	for _, speed := range sorted {
+		total += speed
+	}
+	return DirectionStats{
+		Count:     len(sorted),
+		MeanSpeed: float64(total) / float64(len(sorted)),
+		P50:       percentile(sorted, 50),
+		P85:       percentile(sorted, 85),
+		P95:       percentile(sorted, 95),
+		MaxSpeed:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile uses the nearest rank method on sorted speeds.
+func percentile(sorted []int, p float64) int {
+	rank := max(int(math.Ceil(p/100*float64(len(sorted)))), 1)
+	return sorted[rank-1]
+}
+
+// WriteJSON writes the buckets as a JSON array.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.Buckets())
+}
+
+// WriteCSV writes a header and one row per bucket and direction, leaving out
+// directions without vehicles.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"start", "end", "direction", "count", "mean_speed", "p50", "p85", "p95", "max_speed"})
+	for _, bucket := range r.Buckets() {
+		for _, row := range []struct {
+			direction string
+			stats     DirectionStats
+		}{{"away", bucket.Away}, {"toward", bucket.Toward}} {
+			if row.stats.Count == 0 {
+				continue
+			}
+			writer.Write([]string{
+				bucket.Start.Format(time.RFC3339),
+				bucket.End.Format(time.RFC3339),
+				row.direction,
+				strconv.Itoa(row.stats.Count),
+				fmt.Sprintf("%.1f", row.stats.MeanSpeed),
+				strconv.Itoa(row.stats.P50),
+				strconv.Itoa(row.stats.P85),
+				strconv.Itoa(row.stats.P95),
+				strconv.Itoa(row.stats.MaxSpeed),
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,155 @@
+package report_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/report"
+	"github.com/Battlekeeper/LD2451/v2/tracking"
+)
+
+func berlin(t *testing.T) *time.Location {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+	return location
+}
+
+func TestDirectionStats(t *testing.T) {
+	r := report.New(report.Config{Location: time.UTC})
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	for i, speed := range []int{120, 30, 110, 40, 100, 50, 90, 60, 80, 70} {
+		r.Add(start.Add(time.Duration(i)*time.Minute), LD2451.DirectionToward, speed)
+	}
+	r.AddTrack(tracking.Track{Start: start.Add(59 * time.Minute), Direction: LD2451.DirectionAway, Speed: 20, MaxSpeed: 45})
+
+	buckets := r.Buckets()
+	if len(buckets) != 1 {
+		t.Fatalf("got %d buckets, expected 1", len(buckets))
+	}
+	expected := report.Bucket{
+		Start:  start,
+		End:    start.Add(time.Hour),
+		Away:   report.DirectionStats{Count: 1, MeanSpeed: 45, P50: 45, P85: 45, P95: 45, MaxSpeed: 45},
+		Toward: report.DirectionStats{Count: 10, MeanSpeed: 75, P50: 70, P85: 110, P95: 120, MaxSpeed: 120},
+	}
+	if buckets[0] != expected {
+		t.Errorf("got %+v, expected %+v", buckets[0], expected)
+	}
+}
+
+func TestBucketing(t *testing.T) {
+	location := berlin(t)
+	//the clocks went back from 03:00 to 02:00 on October 27, 2024
+	tests := []struct {
+		name   string
+		period report.Period
+		times  []time.Time
+		spans  []time.Duration // length of every bucket, oldest first
+	}{
+		{
+			"hours",
+			report.Hourly,
+			[]time.Time{time.Date(2024, 5, 1, 8, 0, 0, 0, location), time.Date(2024, 5, 1, 8, 59, 59, 0, location), time.Date(2024, 5, 1, 9, 0, 0, 0, location)},
+			[]time.Duration{time.Hour, time.Hour},
+		},
+		{
+			"repeated hour",
+			report.Hourly,
+			[]time.Time{time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC)},
+			[]time.Duration{time.Hour, time.Hour},
+		},
+		{
+			"days",
+			report.Daily,
+			[]time.Time{time.Date(2024, 5, 1, 0, 0, 0, 0, location), time.Date(2024, 5, 1, 23, 59, 0, 0, location), time.Date(2024, 5, 2, 0, 0, 0, 0, location)},
+			[]time.Duration{24 * time.Hour, 24 * time.Hour},
+		},
+		{
+			"day the clocks go back",
+			report.Daily,
+			[]time.Time{time.Date(2024, 10, 27, 1, 0, 0, 0, location), time.Date(2024, 10, 27, 23, 0, 0, 0, location)},
+			[]time.Duration{25 * time.Hour},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := report.New(report.Config{Period: test.period, Location: location})
+			for _, at := range test.times {
+				r.Add(at, LD2451.DirectionToward, 50)
+			}
+			buckets := r.Buckets()
+			if len(buckets) != len(test.spans) {
+				t.Fatalf("got buckets %+v, expected %d", buckets, len(test.spans))
+			}
+			for i, bucket := range buckets {
+				if span := bucket.End.Sub(bucket.Start); span != test.spans[i] {
+					t.Errorf("bucket %d from %s spans %s, expected %s", i, bucket.Start, span, test.spans[i])
+				}
+				if i > 0 && !bucket.Start.Equal(buckets[i-1].End) {
+					t.Errorf("bucket %d starts at %s, the previous one ends at %s", i, bucket.Start, buckets[i-1].End)
+				}
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	location := berlin(t)
+	saved := report.New(report.Config{Period: report.Hourly, Location: time.UTC})
+	saved.Add(time.Date(2024, 5, 1, 21, 30, 0, 0, time.UTC), LD2451.DirectionToward, 40)
+	saved.Add(time.Date(2024, 5, 1, 22, 30, 0, 0, time.UTC), LD2451.DirectionAway, 50)
+	saved.Add(time.Date(2024, 5, 1, 22, 40, 0, 0, time.UTC), LD2451.DirectionToward, 60)
+	data, err := saved.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//restored into local days, 22:30 UTC is already May 2 in Berlin
+	restored := report.New(report.Config{Period: report.Daily, Location: location})
+	restored.Add(time.Date(2024, 4, 1, 12, 0, 0, 0, location), LD2451.DirectionToward, 90)
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	buckets := restored.Buckets()
+	if len(buckets) != 2 {
+		t.Fatalf("got buckets %+v, expected 2", buckets)
+	}
+	if !buckets[0].Start.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, location)) || buckets[0].Toward.Count != 1 || buckets[0].Away.Count != 0 {
+		t.Errorf("first bucket %+v", buckets[0])
+	}
+	if !buckets[1].Start.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, location)) || buckets[1].Toward.MaxSpeed != 60 || buckets[1].Away.MaxSpeed != 50 {
+		t.Errorf("second bucket %+v", buckets[1])
+	}
+
+	if err := restored.UnmarshalJSON([]byte("{")); err == nil {
+		t.Error("restored a broken checkpoint")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	r := report.New(report.Config{Location: time.UTC})
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	r.Add(start, LD2451.DirectionToward, 40)
+	r.Add(start.Add(time.Minute), LD2451.DirectionToward, 45)
+	r.Add(start.Add(time.Hour), LD2451.DirectionAway, 30)
+
+	var b bytes.Buffer
+	if err := r.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := "start,end,direction,count,mean_speed,p50,p85,p95,max_speed\n" +
+		"2024-05-01T08:00:00Z,2024-05-01T09:00:00Z,toward,2,42.5,40,45,45,45\n" +
+		"2024-05-01T09:00:00Z,2024-05-01T10:00:00Z,away,1,30.0,30,30,30,30\n"
+	if b.String() != expected {
+		t.Errorf("got\n%s\nexpected\n%s", b.String(), expected)
+	}
+
+	r.Reset()
+	if buckets := r.Buckets(); len(buckets) != 0 {
+		t.Errorf("Reset left %+v", buckets)
+	}
+}
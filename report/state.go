@@ -0,0 +1,51 @@
+package report
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// bucketState is the JSON form of a bucket, used to checkpoint a Report.
+type bucketState struct {
+	Start  time.Time `json:"start"`
+	Away   []int     `json:"away,omitempty"`
+	Toward []int     `json:"toward,omitempty"`
+}
+
+// MarshalJSON saves the speeds collected so far, e.g. for the checkpoint
+// package, so a restart doesn't lose the counts of the day.
+func (r *Report) MarshalJSON() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := make([]bucketState, 0, len(r.buckets))
+	for start, s := range r.buckets {
+		state = append(state, bucketState{start, s.away, s.toward})
+	}
+	slices.SortFunc(state, func(a, b bucketState) int { return a.Start.Compare(b.Start) })
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON restores the speeds saved by MarshalJSON, replacing the ones
+// collected. The buckets start in Config.Location, even if the report was
+// saved with another one.
+func (r *Report) UnmarshalJSON(data []byte) error {
+	var state []bucketState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.buckets)
+	for _, saved := range state {
+		start := r.bucketStart(saved.Start)
+		bucket := r.buckets[start]
+		if bucket == nil {
+			bucket = &speeds{}
+			r.buckets[start] = bucket
+		}
+		bucket.away = append(bucket.away, saved.Away...)
+		bucket.toward = append(bucket.toward, saved.Toward...)
+	}
+	return nil
+}
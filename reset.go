@@ -0,0 +1,128 @@
+package LD2451
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// ErrModemUnsupported is returned by SetDTR, SetRTS and ResetHardware when
+// the port cannot drive the modem lines, e.g. a TCP bridge or a serial port
+// on Windows.
+var ErrModemUnsupported = transport.ErrModemUnsupported
+
+type ModemLine int
+
+const (
+	LineDTR ModemLine = 0
+	LineRTS ModemLine = 1
+)
+
+func (l ModemLine) String() string {
+	switch l {
+	case LineDTR:
+		return "DTR"
+	case LineRTS:
+		return "RTS"
+	default:
+		return "Unknown"
+	}
+}
+
+const (
+	defaultResetPulse     = 100 * time.Millisecond
+	defaultResetBootDelay = time.Second
+)
+
+// HardwareReset describes a module whose reset or power is wired to a modem
+// line of the serial adapter, so ResetHardware can reboot it when it stopped
+// answering. The line is released when the port is opened.
+type HardwareReset struct {
+	Line      ModemLine     // Line the reset is wired to
+	Inverted  bool          // The module is held in reset while the line is deasserted instead of asserted
+	Pulse     time.Duration // How long the module is held in reset (default 100ms)
+	BootDelay time.Duration // Wait after the reset before further commands are sent (default 1s)
+}
+
+func (r HardwareReset) validate() error {
+	switch {
+	case r.Line != LineDTR && r.Line != LineRTS:
+		return fmt.Errorf("unknown modem line %d", r.Line)
+	case r.Pulse < 0:
+		return fmt.Errorf("reset pulse %s is negative", r.Pulse)
+	case r.BootDelay < 0:
+		return fmt.Errorf("reset boot delay %s is negative", r.BootDelay)
+	}
+	return nil
+}
+
+// SetDTR asserts or deasserts the DTR line of the serial adapter.
+func (ld2451 *LD2451) SetDTR(asserted bool) error {
+	return ld2451.wrap("SetDTR", ld2451.setLine(LineDTR, asserted))
+}
+
+// SetRTS asserts or deasserts the RTS line of the serial adapter.
+func (ld2451 *LD2451) SetRTS(asserted bool) error {
+	return ld2451.wrap("SetRTS", ld2451.setLine(LineRTS, asserted))
+}
+
+// ResetHardware reboots the module by pulsing the line of Config.HardwareReset,
+// e.g. from a health check when the module no longer reports or answers.
+// Queued commands wait until the module had Config.HardwareReset.BootDelay to
+// start, and a sleeping module reports again afterwards.
+func (ld2451 *LD2451) ResetHardware() error {
+	reset := ld2451.config.HardwareReset
+	if reset == nil {
+		return ld2451.wrap("ResetHardware", errors.New("no hardware reset configured"))
+	}
+	return ld2451.enqueue("ResetHardware", func() error {
+		if err := ld2451.setLine(reset.Line, !reset.Inverted); err != nil {
+			return err
+		}
+		pulse := reset.Pulse
+		if pulse == 0 {
+			pulse = defaultResetPulse
+		}
+		held := sleep(ld2451.config.Clock, pulse, ld2451.closed)
+		if err := ld2451.releaseReset(); err != nil || !held {
+			return err
+		}
+		ld2451.config.Logger.Info("module reset through modem line", "line", reset.Line)
+
+		//the module comes back reporting, whatever mode it was left in
+		if ld2451.asleep {
+			ld2451.asleep = false
+			ld2451.setState(StateReporting)
+		}
+		boot := reset.BootDelay
+		if boot == 0 {
+			boot = defaultResetBootDelay
+		}
+		sleep(ld2451.config.Clock, boot, ld2451.closed)
+		return nil
+	})
+}
+
+// releaseReset lets the module run, a freshly opened port asserts both lines.
+func (ld2451 *LD2451) releaseReset() error {
+	reset := ld2451.config.HardwareReset
+	if reset == nil {
+		return nil
+	}
+	return ld2451.setLine(reset.Line, reset.Inverted)
+}
+
+func (ld2451 *LD2451) setLine(line ModemLine, asserted bool) error {
+	ld2451.writeMu.Lock()
+	defer ld2451.writeMu.Unlock()
+	lines, ok := ld2451.port.(transport.ModemLines)
+	if !ok {
+		return ErrModemUnsupported
+	}
+	if line == LineRTS {
+		return lines.SetRTS(asserted)
+	}
+	return lines.SetDTR(asserted)
+}
@@ -0,0 +1,43 @@
+package LD2451
+
+import "unsafe"
+
+// StoreUsage describes how much of its bounds an in-memory store uses, so a
+// long unattended run can be checked for growing memory.
+type StoreUsage struct {
+	Entries    int    `json:"entries"`     // Entries currently held
+	MaxEntries int    `json:"max_entries"` // Most entries held, the oldest are evicted beyond it
+	Bytes      int    `json:"bytes"`       // Approximate memory held, including unused capacity
+	MaxBytes   int    `json:"max_bytes"`   // Approximate memory held at MaxEntries
+	Evicted    uint64 `json:"evicted"`     // Entries evicted to stay within MaxEntries
+}
+
+// Retention reports the usage of the stores behind CurrentTargets and Peaks.
+// Both are bounded by age and count, see Config.SnapshotWindow,
+// Config.SnapshotLimit and Config.PeakWindows.
+type Retention struct {
+	Snapshot StoreUsage `json:"snapshot"`
+	Peaks    StoreUsage `json:"peaks"`
+}
+
+// Retention returns the current usage of the in-memory stores.
+func (ld2451 *LD2451) Retention() Retention {
+	retention := Retention{}
+	ld2451.snapshotMu.Lock()
+	retention.Snapshot = usage(len(ld2451.snapshot), cap(ld2451.snapshot), ld2451.snapshotLimit(), int(unsafe.Sizeof(Target{})), ld2451.snapshotEvicted)
+	ld2451.snapshotMu.Unlock()
+	ld2451.statsMu.Lock()
+	retention.Peaks = usage(len(ld2451.peaks), cap(ld2451.peaks), ld2451.peakWindows(), int(unsafe.Sizeof(Peak{})), ld2451.peaksEvicted)
+	ld2451.statsMu.Unlock()
+	return retention
+}
+
+func usage(entries, capacity, maxEntries, size int, evicted uint64) StoreUsage {
+	return StoreUsage{
+		Entries:    entries,
+		MaxEntries: maxEntries,
+		Bytes:      capacity * size,
+		MaxBytes:   maxEntries * size,
+		Evicted:    evicted,
+	}
+}
@@ -0,0 +1,169 @@
+package LD2451
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Handler receives what Run reads from the sensor. Returning an error from
+// either method stops Run with that error.
+type Handler interface {
+	HandleTarget(target Target) error
+	HandleError(err error) error // Called for errors the reader recovered from, from Diagnostics and transport errors followed by a reconnect
+}
+
+// HandlerFuncs adapts plain functions to a Handler. Nil functions ignore what
+// they would have been called with.
+type HandlerFuncs struct {
+	Target func(target Target) error
+	Error  func(err error) error
+}
+
+func (h HandlerFuncs) HandleTarget(target Target) error {
+	if h.Target == nil {
+		return nil
+	}
+	return h.Target(target)
+}
+
+func (h HandlerFuncs) HandleError(err error) error {
+	if h.Error == nil {
+		return nil
+	}
+	return h.Error(err)
+}
+
+// Run opens the serial port named in config with Config.Reconnect enabled and
+// passes every target and error to handler until ctx is done, handler returns
+// an error or the port can't be reopened. The sensor is closed when Run
+// returns.
+func Run(ctx context.Context, config Config, handler Handler, options ...Option) error {
+	config.Reconnect = true
+	ld2451, err := Open(config, options...)
+	if err != nil {
+		return err
+	}
+	defer ld2451.Close()
+	return ld2451.Run(ctx, handler)
+}
+
+// AlarmHandler is implemented by a Handler or Sink that also wants the alarm
+// state changes of Alarms. Run and RunSink pass them to HandleAlarm and stop
+// with the error it returns.
+type AlarmHandler interface {
+	HandleAlarm(event AlarmEvent) error
+}
+
+// HeartbeatHandler is implemented by a Handler or Sink that also wants the
+// heartbeats of Heartbeats, delivered when Config.Heartbeats is set.
+type HeartbeatHandler interface {
+	HandleHeartbeat(heartbeat Heartbeat) error
+}
+
+// Run passes every target and error to handler until ctx is done, handler
+// returns an error or the reader stops. Targets are consumed like with
+// ReadTarget, so Run should be the only reader of the sensor. It returns
+// ctx.Err() when ctx is done and the error that stopped the reader otherwise.
+func (ld2451 *LD2451) Run(ctx context.Context, handler Handler) error {
+	return ld2451.run(ctx, handler, handler, nil, nil)
+}
+
+// RunSink writes every target to sink like Run passes them to a handler,
+// flushing sink every Config.SinkFlushInterval. A failed write or flush stops
+// RunSink with its error. sink is flushed and closed before RunSink returns.
+func (ld2451 *LD2451) RunSink(ctx context.Context, sink Sink) error {
+	interval := ld2451.config.SinkFlushInterval
+	if interval <= 0 {
+		interval = defaultSinkFlushInterval
+	}
+	ticker := ld2451.config.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	err := ld2451.run(ctx, HandlerFuncs{Target: sink.Write}, sink, ticker.C(), sink.Flush)
+	if closeErr := errors.Join(sink.Flush(), sink.Close()); closeErr != nil {
+		return errors.Join(err, closeErr)
+	}
+	return err
+}
+
+// run is the loop of Run and RunSink. Alarms and heartbeats are passed on
+// when events implements AlarmHandler or HeartbeatHandler, and flush is called
+// whenever tick fires.
+func (ld2451 *LD2451) run(ctx context.Context, handler Handler, events any, tick <-chan time.Time, flush func() error) error {
+	var alarms <-chan AlarmEvent
+	alarmHandler, ok := events.(AlarmHandler)
+	if ok {
+		alarms = ld2451.alarms
+	}
+	var beats <-chan Heartbeat
+	heartbeatHandler, ok := events.(HeartbeatHandler)
+	if ok {
+		beats = ld2451.beats
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d := <-ld2451.targets:
+			if ld2451.discarded(d) {
+				continue
+			}
+			ld2451.recordLatency(d.start)
+			err = handler.HandleTarget(d.target)
+		case event := <-alarms:
+			err = alarmHandler.HandleAlarm(event)
+		case heartbeat := <-beats:
+			err = heartbeatHandler.HandleHeartbeat(heartbeat)
+		case <-tick:
+			err = flush()
+		case diagnostic := <-ld2451.diagnostics:
+			err = handler.HandleError(diagnostic)
+		case readErr := <-ld2451.errors:
+			if ld2451.stopped() && readErr == ld2451.fatal {
+				//the error that stopped the reader is returned below instead
+				continue
+			}
+			err = handler.HandleError(readErr)
+		case <-ld2451.done:
+			//hand out what was read before the reader stopped
+			for _, target := range ld2451.ReadTargets(0) {
+				if err := handler.HandleTarget(target); err != nil {
+					return err
+				}
+			}
+			return ld2451.fatal
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Err returns the error that stopped the reader, e.g. once a subscription
+// channel was closed, and nil while it is still reading. Unlike ReadTarget it
+// doesn't take a buffered target.
+func (ld2451 *LD2451) Err() error {
+	if !ld2451.stopped() {
+		return nil
+	}
+	return ld2451.fatal
+}
+
+// stopped reports whether the read goroutine has stopped, after which
+// ld2451.fatal may be read.
+func (ld2451 *LD2451) stopped() bool {
+	select {
+	case <-ld2451.done:
+		return true
+	default:
+		return false
+	}
+}
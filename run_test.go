@@ -0,0 +1,90 @@
+package LD2451_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// recordingSink records what RunSink does with it.
+type recordingSink struct {
+	mu      sync.Mutex
+	targets []LD2451.Target
+	alarms  []LD2451.AlarmEvent
+	flushes int
+	closed  bool
+}
+
+func (s *recordingSink) Write(target LD2451.Target) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets = append(s.targets, target)
+	return nil
+}
+
+func (s *recordingSink) HandleAlarm(event LD2451.AlarmEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alarms = append(s.alarms, event)
+	return nil
+}
+
+func (s *recordingSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *recordingSink) written() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.targets)
+}
+
+func TestRunSink(t *testing.T) {
+	sensor, radar := openSensor(t, func(config *LD2451.Config) { config.SinkFlushInterval = 10 * time.Millisecond })
+	sink := &recordingSink{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- radar.RunSink(ctx, sink) }()
+
+	if err := sensor.SendTargets(true, LD2451.Target{Distance: 12, Speed: 30}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for sink.written() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunSink returned %v, want context.Canceled", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.targets) != 1 || sink.targets[0].Distance != 12 {
+		t.Fatalf("sink got targets %+v, want one at 12 m", sink.targets)
+	}
+	if len(sink.alarms) != 1 || !sink.alarms[0].Active {
+		t.Fatalf("sink got alarms %+v, want the alarm raised", sink.alarms)
+	}
+	if sink.flushes < 2 {
+		t.Fatalf("sink was flushed %d times, want periodic flushes and a final one", sink.flushes)
+	}
+	if !sink.closed {
+		t.Fatal("sink was not closed")
+	}
+}
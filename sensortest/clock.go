@@ -0,0 +1,124 @@
+package sensortest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// Clock is an LD2451.Clock that only moves when Advance is called, so tests
+// of timeouts, staleness and degradation neither sleep nor flake:
+//
+//	clock := sensortest.NewClock(time.Now())
+//	config.Clock = clock
+//	...
+//	clock.Advance(5 * time.Second)
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*clockTimer
+}
+
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) NewTimer(d time.Duration) LD2451.Timer {
+	return c.add(d, 0)
+}
+
+func (c *Clock) NewTicker(d time.Duration) LD2451.Ticker {
+	if d <= 0 {
+		panic("sensortest: non-positive interval for NewTicker")
+	}
+	return clockTicker{c.add(d, d)}
+}
+
+func (c *Clock) add(d, period time.Duration) *clockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &clockTimer{clock: c, c: make(chan time.Time, 1), due: c.now.Add(d), period: period}
+	c.timers = append(c.timers, t)
+	if d <= 0 {
+		c.fire()
+	}
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers that
+// become due on the way in order. Like with the time package a ticker whose
+// channel is full skips ticks.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for {
+		sort.Slice(c.timers, func(i, j int) bool { return c.timers[i].due.Before(c.timers[j].due) })
+		if len(c.timers) == 0 || c.timers[0].due.After(end) {
+			break
+		}
+		c.now = c.timers[0].due
+		c.fire()
+	}
+	c.now = end
+}
+
+// fire delivers all timers due at the current time. The caller must hold mu.
+func (c *Clock) fire() {
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.due.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			t.due = t.due.Add(t.period)
+			pending = append(pending, t)
+		}
+	}
+	c.timers = pending
+}
+
+type clockTimer struct {
+	clock  *Clock
+	c      chan time.Time
+	due    time.Time
+	period time.Duration //zero for timers
+}
+
+func (t *clockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop reports whether the timer was still pending, like time.Timer.Stop.
+func (t *clockTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type clockTicker struct {
+	*clockTimer
+}
+
+func (t clockTicker) Stop() {
+	t.clockTimer.Stop()
+}
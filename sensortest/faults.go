@@ -0,0 +1,117 @@
+package sensortest
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DefaultFaultDelay  = 50 * time.Millisecond
+	DefaultReplugDelay = 100 * time.Millisecond
+)
+
+// Faults are injected into the data frames sent by SendTargets, so the resync,
+// reconnect and error paths of the library can be exercised. Every fault is
+// drawn per frame with its probability from a sequence seeded by Seed, so a
+// failing run repeats exactly. SendRaw is never affected.
+type Faults struct {
+	Truncate    float64       // Probability a frame is cut short at a random byte and the rest never sent
+	BitFlip     float64       // Probability a random bit of a frame is flipped
+	Delay       float64       // Probability a frame is sent in two parts with DelayTime in between
+	DelayTime   time.Duration // Pause within a delayed frame (default DefaultFaultDelay)
+	Disconnect  float64       // Probability the sensor is unplugged partway through a frame, as by Close
+	Replug      float64       // Probability the sensor is unplugged partway through a frame and plugged in again ReplugDelay later, as by Unplug and Plug
+	ReplugDelay time.Duration // How long a replugged sensor stays unplugged (default DefaultReplugDelay)
+	Seed        uint64
+}
+
+// FaultCounts is how many faults were injected, to compare with the
+// library's Stats.
+type FaultCounts struct {
+	Frames       int  // Frames sent by SendTargets
+	Truncated    int  // Frames cut short
+	Flipped      int  // Frames with a flipped bit
+	Delayed      int  // Frames sent in two parts
+	Disconnected bool // The sensor was unplugged partway through a frame
+	Replugged    int  // Times the sensor was unplugged partway through a frame and plugged in again
+}
+
+type faultState struct {
+	faults Faults
+	rand   *rand.Rand
+	counts FaultCounts
+}
+
+// InjectFaults starts injecting faults into the frames sent from now on,
+// replacing any faults injected before. A zero Faults stops injecting.
+func (s *Sensor) InjectFaults(faults Faults) {
+	if faults.DelayTime <= 0 {
+		faults.DelayTime = DefaultFaultDelay
+	}
+	if faults.ReplugDelay <= 0 {
+		faults.ReplugDelay = DefaultReplugDelay
+	}
+	s.writeMu.Lock()
+	s.faults = &faultState{faults: faults, rand: rand.New(rand.NewPCG(faults.Seed, faults.Seed))}
+	s.writeMu.Unlock()
+}
+
+// FaultCounts returns the faults injected since the last InjectFaults.
+func (s *Sensor) FaultCounts() FaultCounts {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.faults == nil {
+		return FaultCounts{}
+	}
+	return s.faults.counts
+}
+
+// sendFrame writes frame with the injected faults applied. While a
+// replugged sensor is unplugged it fails with ErrUnplugged.
+func (s *Sensor) sendFrame(frame []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	f := s.faults
+	if f == nil || s.master == nil {
+		return s.write(frame)
+	}
+	f.counts.Frames++
+	drawn := func(probability float64) bool {
+		return probability > 0 && f.rand.Float64() < probability
+	}
+
+	if drawn(f.faults.Disconnect) {
+		f.counts.Disconnected = true
+		s.master.Write(frame[:f.rand.IntN(len(frame))])
+		s.close()
+		return nil
+	}
+	if drawn(f.faults.Replug) {
+		f.counts.Replugged++
+		s.master.Write(frame[:f.rand.IntN(len(frame))])
+		s.unplug()
+		time.AfterFunc(f.faults.ReplugDelay, func() { s.Plug() })
+		return nil
+	}
+	frame = append([]byte(nil), frame...)
+	if drawn(f.faults.BitFlip) {
+		f.counts.Flipped++
+		bit := f.rand.IntN(len(frame) * 8)
+		frame[bit/8] ^= 1 << (bit % 8)
+	}
+	if drawn(f.faults.Truncate) {
+		f.counts.Truncated++
+		frame = frame[:f.rand.IntN(len(frame))]
+	}
+	if len(frame) > 1 && drawn(f.faults.Delay) {
+		f.counts.Delayed++
+		split := 1 + f.rand.IntN(len(frame)-1)
+		if _, err := s.master.Write(frame[:split]); err != nil {
+			return err
+		}
+		time.Sleep(f.faults.DelayTime)
+		frame = frame[split:]
+	}
+	_, err := s.master.Write(frame)
+	return err
+}
@@ -0,0 +1,232 @@
+package sensortest_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+// faultFrames is how many frames the resilience tests send with faults.
+const faultFrames = 300
+
+// openFaulty opens the library on a fake sensor and drains its targets into
+// the returned channel.
+func openFaulty(t *testing.T, config LD2451.Config) (*LD2451.LD2451, <-chan LD2451.Target) {
+	t.Helper()
+	radar, err := LD2451.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { radar.Close() })
+	targets, cancel := radar.Subscribe()
+	t.Cleanup(cancel)
+	return radar, targets
+}
+
+// awaitTarget sends target until the library delivers it, because the first
+// frames after a fault can be swallowed while the reader resynchronizes or
+// the sensor is still unplugged.
+func awaitTarget(t *testing.T, sensor *sensortest.Sensor, targets <-chan LD2451.Target, target LD2451.Target) {
+	t.Helper()
+	for range 20 {
+		if err := sensor.SendTargets(false, target); err != nil && !errors.Is(err, sensortest.ErrUnplugged) {
+			t.Fatal(err)
+		}
+		timeout := time.After(100 * time.Millisecond)
+		for waiting := true; waiting; {
+			select {
+			case got := <-targets:
+				if got.Distance == target.Distance && got.Speed == target.Speed {
+					return
+				}
+			case <-timeout:
+				waiting = false
+			}
+		}
+	}
+	t.Fatalf("%v never delivered", target)
+}
+
+func TestFaultsResync(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	radar, targets := openFaulty(t, sensor.Config())
+
+	sensor.InjectFaults(sensortest.Faults{Truncate: 0.1, BitFlip: 0.1, Delay: 0.1, DelayTime: time.Millisecond, Seed: 454})
+	for i := range faultFrames {
+		target := LD2451.Target{Angle: 5, Distance: 10 + i%50, Direction: LD2451.DirectionToward, Speed: 40, SNR: 60}
+		if err := sensor.SendTargets(false, target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts := sensor.FaultCounts()
+	sensor.InjectFaults(sensortest.Faults{})
+	awaitTarget(t, sensor, targets, LD2451.Target{Angle: 1, Distance: 99, Direction: LD2451.DirectionToward, Speed: 77, SNR: 60})
+
+	if counts.Truncated == 0 || counts.Flipped == 0 || counts.Delayed == 0 {
+		t.Fatalf("faults not injected: %+v", counts)
+	}
+	stats := radar.Stats()
+	if stats.Resyncs == 0 {
+		t.Errorf("no resyncs after %d truncated and %d flipped frames", counts.Truncated, counts.Flipped)
+	}
+	if stats.ParseErrors == 0 {
+		t.Errorf("no parse errors after %d flipped frames", counts.Flipped)
+	}
+	//a faulty frame costs at most itself and the frame after it
+	damaged := uint64(counts.Truncated + counts.Flipped)
+	if stats.Frames+2*damaged < uint64(counts.Frames) {
+		t.Errorf("decoded %d of %d frames with %d damaged", stats.Frames, counts.Frames, damaged)
+	}
+	if stats.Reconnects != 0 || stats.ReadErrors != 0 {
+		t.Errorf("corrupt frames broke the connection: %+v", stats)
+	}
+}
+
+func TestFaultsDisconnectReconnects(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	//a link stands in for a /dev/serial/by-id path following the adapter
+	link := filepath.Join(t.TempDir(), "ld2451")
+	if err := os.Symlink(sensor.Path(), link); err != nil {
+		t.Fatal(err)
+	}
+	config := sensor.Config()
+	config.SerialPort = link
+	config.Reconnect = true
+	config.OpenRetryBackoff = 10 * time.Millisecond
+	radar, targets := openFaulty(t, config)
+
+	sensor.InjectFaults(sensortest.Faults{Disconnect: 1, Seed: 454})
+	sensor.SendTargets(false, LD2451.Target{Distance: 10, Speed: 30})
+	if !sensor.FaultCounts().Disconnected {
+		t.Fatal("sensor not unplugged")
+	}
+
+	replugged, err := sensortest.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replugged.Close()
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(replugged.Path(), link); err != nil {
+		t.Fatal(err)
+	}
+	awaitTarget(t, replugged, targets, LD2451.Target{Distance: 20, Speed: 40})
+
+	stats := radar.Stats()
+	if stats.ReadErrors == 0 {
+		t.Error("unplugging caused no read error")
+	}
+	if stats.Reconnects != 1 {
+		t.Errorf("reconnected %d times, expected once", stats.Reconnects)
+	}
+}
+
+func TestFaultsReplugRecovers(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	config := sensor.Config()
+	config.Reconnect = true
+	config.OpenRetryBackoff = 10 * time.Millisecond
+	radar, targets := openFaulty(t, config)
+
+	//collect the events as they come, a few reconnect attempts fill the channels
+	var mu sync.Mutex
+	var connections []LD2451.ConnectionEvent
+	var states []LD2451.SensorState
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case event := <-radar.ConnectionEvents():
+				mu.Lock()
+				connections = append(connections, event)
+				mu.Unlock()
+			case change := <-radar.StateChanges():
+				mu.Lock()
+				states = append(states, change.To)
+				mu.Unlock()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	awaitTarget(t, sensor, targets, LD2451.Target{Distance: 10, Speed: 30})
+
+	sensor.InjectFaults(sensortest.Faults{Replug: 1, ReplugDelay: 300 * time.Millisecond, Seed: 454})
+	sensor.SendTargets(false, LD2451.Target{Distance: 11, Speed: 30})
+	if replugged := sensor.FaultCounts().Replugged; replugged != 1 {
+		t.Fatalf("replugged %d times", replugged)
+	}
+	if err := sensor.SendTargets(false, LD2451.Target{Distance: 12, Speed: 30}); !errors.Is(err, sensortest.ErrUnplugged) {
+		t.Errorf("sending while unplugged: got %v", err)
+	}
+	sensor.InjectFaults(sensortest.Faults{})
+	awaitTarget(t, sensor, targets, LD2451.Target{Distance: 20, Speed: 40})
+
+	if stats := radar.Stats(); stats.ReadErrors != 1 || stats.Reconnects != 1 {
+		t.Errorf("got %d read errors and %d reconnects, expected one each", stats.ReadErrors, stats.Reconnects)
+	}
+	//the collector can lag behind the delivered target
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		mu.Lock()
+		settled := len(states) >= 4
+		mu.Unlock()
+		if settled {
+			break
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []LD2451.SensorState{LD2451.StateReporting, LD2451.StateDisconnected, LD2451.StateConnecting, LD2451.StateReporting}
+	if !slices.Equal(states, want) {
+		t.Errorf("states %v, expected %v", states, want)
+	}
+
+	//Connected on open, Disconnected, a Reconnecting per attempt, Connected
+	if len(connections) < 5 || connections[0].Status != LD2451.Connected || connections[1].Status != LD2451.Disconnected {
+		t.Fatalf("got connection events %+v", connections)
+	}
+	attempts := connections[2 : len(connections)-1]
+	last := connections[len(connections)-1]
+	if last.Status != LD2451.Connected || last.Attempt != len(attempts) {
+		t.Errorf("reconnected with %+v after %d attempts", last, len(attempts))
+	}
+	backoff := config.OpenRetryBackoff
+	for i, event := range attempts {
+		if event.Status != LD2451.Reconnecting || event.Attempt != i+1 {
+			t.Fatalf("attempt %d: got %+v", i+1, event)
+		}
+		if i == 0 {
+			continue
+		}
+		if event.Err == nil {
+			t.Errorf("attempt %d doesn't report why attempt %d failed", i+1, i)
+		}
+		//the delay doubles after every failed attempt
+		backoff *= 2
+		if gap := event.Time.Sub(attempts[i-1].Time); gap < backoff {
+			t.Errorf("attempt %d followed attempt %d after %s, expected at least %s", i+1, i, gap, backoff)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package sensortest
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2/capture"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+//go:embed testdata/fixtures
+var fixtures embed.FS
+
+// Fixtures returns the synthetic sessions bundled with the library, sorted by
+// name. They were written from the protocol documentation rather than
+// recorded, are tagged "synthetic" and cover edge cases of the frame format,
+// not the quirks of a firmware version. Recordings of a real module belong in
+// testdata/fixtures as well, as a session file naming its Firmware, with a
+// capture next to it for long recordings.
+func Fixtures() ([]*Session, error) {
+	return LoadFixtures(fixtures, "testdata/fixtures")
+}
+
+// LoadFixtures reads every .json session file in dir of fsys, sorted by name,
+// so projects can keep captures of their own next to the bundled ones.
+func LoadFixtures(fsys fs.FS, dir string) ([]*Session, error) {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	sessions := make([]*Session, 0, len(names))
+	for _, name := range names {
+		session, err := loadSession(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// RunFixtures checks every session in a subtest named after it, turning a
+// directory of captures into a regression suite:
+//
+//	func TestFixtures(t *testing.T) {
+//		sessions, err := sensortest.Fixtures()
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		sensortest.RunFixtures(t, sessions)
+//	}
+func RunFixtures(t *testing.T, sessions []*Session) {
+	for _, session := range sessions {
+		t.Run(session.Name, func(t *testing.T) {
+			if err := session.Check(); err != nil {
+				t.Errorf("%s: %v", session.Description, err)
+			}
+		})
+	}
+}
+
+// HasTag reports whether the session is tagged with tag, for selecting
+// fixtures of an edge case.
+func (s *Session) HasTag(tag string) bool {
+	return slices.Contains(s.Tags, tag)
+}
+
+// loadCapture rebuilds the frames the module sent from a capture file.
+func loadCapture(fsys fs.FS, name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r, err := capture.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	var raw bytes.Buffer
+	for {
+		record, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return raw.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Sent {
+			continue
+		}
+		framing := protocol.LD2451Framing
+		if record.Kind == protocol.KindCommand {
+			framing = protocol.CommandFraming
+		}
+		raw.Write(framing.Encode(record.Payload))
+	}
+}
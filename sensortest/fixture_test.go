@@ -0,0 +1,43 @@
+package sensortest_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+// requirePTY skips tests replaying sessions where no pseudo terminal can be
+// opened for the fake sensor.
+func requirePTY(t *testing.T) {
+	t.Helper()
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	sensor.Close()
+}
+
+func TestFixtures(t *testing.T) {
+	requirePTY(t)
+	sessions, err := sensortest.Fixtures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) == 0 {
+		t.Fatal("no fixtures bundled")
+	}
+	sensortest.RunFixtures(t, sessions)
+}
+
+func TestFixturesAreTaggedSynthetic(t *testing.T) {
+	sessions, err := sensortest.Fixtures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range sessions {
+		if session.Firmware == "" && !slices.Contains(session.Tags, "synthetic") {
+			t.Errorf("%s names no firmware it was recorded from and isn't tagged synthetic", session.Name)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package sensortest
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func openPTY() (*os.File, *os.File, string, error) {
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	master := os.NewFile(uintptr(fd), "/dev/ptmx")
+
+	for _, req := range []uint{unix.TIOCPTYGRANT, unix.TIOCPTYUNLK} {
+		if err := unix.IoctlSetInt(fd, req, 0); err != nil {
+			master.Close()
+			return nil, nil, "", err
+		}
+	}
+	name := make([]byte, 128)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(unix.TIOCPTYGNAME), uintptr(unsafe.Pointer(&name[0])))
+	if errno != 0 {
+		master.Close()
+		return nil, nil, "", errno
+	}
+	path := string(name[:bytes.IndexByte(name, 0)])
+
+	slave, err := openRawSlave(path)
+	if err != nil {
+		master.Close()
+		return nil, nil, "", err
+	}
+	return master, slave, path, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package sensortest
+
+import "os"
+
+func openPTY() (*os.File, *os.File, string, error) {
+	return nil, nil, "", ErrUnsupported
+}
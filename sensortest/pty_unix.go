@@ -0,0 +1,35 @@
+//go:build linux || darwin
+
+package sensortest
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openRawSlave opens the terminal side and switches it to raw mode, so bytes
+// written before the library configures the port are not mangled by the line
+// discipline.
+func openRawSlave(path string) (*os.File, error) {
+	slave, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	fd := int(slave.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		slave.Close()
+		return nil, err
+	}
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		slave.Close()
+		return nil, err
+	}
+	return slave, nil
+}
@@ -0,0 +1,263 @@
+// Package sensortest provides a fake LD2451 on a pseudo-terminal for
+// end-to-end tests. The library opens the terminal like a real serial port,
+// while the test scripts the sensor through the other end: it sends data
+// frames, decides how each command is acknowledged and can pull the plug,
+// plug it in again or inject faults into the frames, see InjectFaults.
+//
+//	sensor, err := sensortest.New()
+//	...
+//	defer sensor.Close()
+//	radar, err := LD2451.Open(sensor.Config())
+//	...
+//	sensor.SendTargets(false, LD2451.Target{Distance: 12, Speed: 30})
+//
+// Pseudo-terminals are supported on Linux and macOS.
+package sensortest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// ErrUnsupported is returned by New on platforms without pseudo-terminals.
+var ErrUnsupported = errors.New("sensortest: pseudo-terminals are not supported on this platform")
+
+// ErrUnplugged is returned when sending while the sensor is unplugged.
+var ErrUnplugged = errors.New("sensortest: sensor is unplugged")
+
+// Command is a command frame received from the library.
+type Command = protocol.Command
+
+// Handler decides how a command is acknowledged. Returning ok false leaves the
+// command unacknowledged, so the library times out waiting for it.
+type Handler func(command Command) (status uint16, data []byte, ok bool)
+
+type Sensor struct {
+	dir  string //temporary directory holding the link at path
+	path string //link to the terminal currently plugged in
+
+	writeMu sync.Mutex
+	master  *os.File    //guarded by writeMu, nil while unplugged
+	slave   *os.File    //kept open so the master stays usable while the library has the port closed
+	faults  *faultState //guarded by writeMu, nil without InjectFaults
+
+	mu       sync.Mutex
+	handlers map[uint16]Handler
+	commands []Command
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// New creates a pseudo-terminal pair and starts answering commands on it.
+// Without handlers every command is acknowledged with a success status, with
+// enabling config mode also reporting a protocol version and buffer size like
+// the real module.
+func New() (*Sensor, error) {
+	dir, err := os.MkdirTemp("", "sensortest")
+	if err != nil {
+		return nil, err
+	}
+	s := &Sensor{
+		dir:      dir,
+		path:     filepath.Join(dir, "ld2451"),
+		handlers: make(map[uint16]Handler),
+		done:     make(chan struct{}),
+	}
+	s.Handle(protocol.CmdEnableConfig, func(Command) (uint16, []byte, bool) {
+		return 0, []byte{0x01, 0x00, 0x40, 0x00}, true
+	})
+	if err := s.plug(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path is the device path to open in place of a serial port. It is a link to
+// the pseudo-terminal, which like a /dev/serial/by-id path follows the
+// sensor when it is plugged in again.
+func (s *Sensor) Path() string {
+	return s.path
+}
+
+// Config returns a library configuration for opening the fake sensor.
+func (s *Sensor) Config() LD2451.Config {
+	return LD2451.Config{
+		SerialPort:       s.path,
+		BaudRate:         115200,
+		TargetBufferSize: 16,
+	}
+}
+
+// Handle replaces how commands with the given word are acknowledged.
+func (s *Sensor) Handle(word uint16, handler Handler) {
+	s.mu.Lock()
+	s.handlers[word] = handler
+	s.mu.Unlock()
+}
+
+// Commands returns all commands received so far, in order.
+func (s *Sensor) Commands() []Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Command(nil), s.commands...)
+}
+
+// SendTargets sends a data frame reporting targets. Without targets an empty
+// frame is sent, like the module does when nothing is in its field of view.
+// Faults set by InjectFaults are applied to the frame.
+func (s *Sensor) SendTargets(alarm bool, targets ...LD2451.Target) error {
+	alarmByte := byte(0)
+	if alarm {
+		alarmByte = 1
+	}
+	return s.sendFrame(protocol.EncodeFrame(targets, alarmByte))
+}
+
+// SendRaw writes arbitrary bytes to the library, e.g. garbage or truncated frames.
+func (s *Sensor) SendRaw(data []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.write(data)
+}
+
+// write sends data to the library. The caller must hold writeMu.
+func (s *Sensor) write(data []byte) error {
+	if s.master == nil {
+		return ErrUnplugged
+	}
+	_, err := s.master.Write(data)
+	return err
+}
+
+// Unplug pulls the plug like Close, but the sensor can be plugged in again
+// with Plug. Reads by the library fail and Path is gone until then, so
+// opening it fails like for a USB adapter that was pulled.
+func (s *Sensor) Unplug() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.unplug()
+}
+
+// unplug closes the terminal. The caller must hold writeMu.
+func (s *Sensor) unplug() error {
+	if s.master == nil {
+		return nil
+	}
+	os.Remove(s.path)
+	err := s.master.Close()
+	s.slave.Close()
+	s.master, s.slave = nil, nil
+	return err
+}
+
+// Plug plugs an unplugged sensor in again, on a new pseudo-terminal behind
+// Path, so a library reconnecting with Config.Reconnect finds it. Commands
+// received and faults injected are kept.
+func (s *Sensor) Plug() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	select {
+	case <-s.done:
+		return errors.New("sensortest: sensor is closed")
+	default:
+	}
+	if s.master != nil {
+		return nil
+	}
+	return s.plug()
+}
+
+// plug opens a new terminal and points Path at it. The caller must hold
+// writeMu, or be New.
+func (s *Sensor) plug() error {
+	master, slave, path, err := openPTY()
+	if err != nil {
+		return err
+	}
+	//replace the link in one step, so the library never opens a stale terminal
+	next := s.path + ".next"
+	os.Remove(next)
+	if err := os.Symlink(path, next); err != nil {
+		master.Close()
+		slave.Close()
+		return err
+	}
+	if err := os.Rename(next, s.path); err != nil {
+		master.Close()
+		slave.Close()
+		return err
+	}
+	s.master, s.slave = master, slave
+	go s.serve(master)
+	return nil
+}
+
+// Close unplugs the sensor for good. Reads by the library fail from then on.
+func (s *Sensor) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.close()
+}
+
+// close is Close for callers holding writeMu.
+func (s *Sensor) close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.unplug()
+		os.RemoveAll(s.dir)
+	})
+	return err
+}
+
+// serve reads command frames from the library on master and acknowledges
+// them, until master is closed by unplugging the sensor.
+func (s *Sensor) serve(master *os.File) {
+	reader := protocol.NewReader(master)
+	for {
+		packet, err := reader.Next()
+		if err != nil {
+			return
+		}
+		if packet.Kind != protocol.KindCommand {
+			continue
+		}
+		command, err := protocol.ParseCommand(packet.Payload)
+		if err != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		handler, ok := s.handlers[command.Word]
+		s.mu.Unlock()
+
+		status, data := uint16(0), []byte(nil)
+		if ok {
+			var ack bool
+			status, data, ack = handler(command)
+			if !ack {
+				continue
+			}
+		}
+
+		s.writeMu.Lock()
+		if s.master != master {
+			//unplugged while the handler ran, the ack belongs to the old terminal
+			s.writeMu.Unlock()
+			return
+		}
+		_, err = master.Write(protocol.EncodeAck(command.Word, status, data))
+		s.writeMu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package sensortest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// quietPeriod is how long Replay waits for further targets after the last one.
+const quietPeriod = 200 * time.Millisecond
+
+// Session is an exchange with a sensor: the raw bytes it sent and the targets
+// the library is expected to decode from them. Sessions are
+// stored as JSON with the raw bytes hex encoded, whitespace allowed:
+//
+//	{
+//		"description": "two cars passing, firmware V1.07",
+//		"raw": "f4f3f2f1 0800 0100 00 8a 0a 01 14 08 f8f7f6f5",
+//		"targets": [{"angle": 10, "distance": 10, "direction": "toward", "speed": 20, "snr": 8}]
+//	}
+//
+// Target times are not compared. Instead of raw, a session can name a file
+// in the capture format next to it, whose received packets are replayed.
+// Sessions written rather than recorded from a module are tagged
+// "synthetic" and have no Firmware.
+type Session struct {
+	Name        string          `json:"-"` // File name without extension
+	Description string          `json:"description"`
+	Firmware    string          `json:"firmware,omitempty"` // Version of the module the bytes were recorded from
+	Tags        []string        `json:"tags,omitempty"`     // Edge cases the session covers, e.g. "resync" or "synthetic"
+	Raw         string          `json:"raw,omitempty"`
+	Capture     string          `json:"capture,omitempty"`
+	Targets     []LD2451.Target `json:"targets"`
+
+	captured []byte //frames rebuilt from Capture
+}
+
+// LoadSession reads a session file.
+func LoadSession(path string) (*Session, error) {
+	return loadSession(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
+
+func loadSession(fsys fs.FS, name string) (*Session, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	session := Session{Name: strings.TrimSuffix(path.Base(name), path.Ext(name))}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if session.Capture != "" {
+		if session.Raw != "" {
+			return nil, fmt.Errorf("%s: both raw bytes and a capture are given", name)
+		}
+		session.captured, err = loadCapture(fsys, path.Join(path.Dir(name), session.Capture))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if _, err := session.Bytes(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &session, nil
+}
+
+// Bytes decodes the recorded raw bytes.
+func (s *Session) Bytes() ([]byte, error) {
+	if s.captured != nil {
+		return s.captured, nil
+	}
+	return hex.DecodeString(strings.Join(strings.Fields(s.Raw), ""))
+}
+
+// Replay sends the recorded bytes to a library instance opened on a fake
+// sensor and returns the targets it decoded, with times cleared.
+func (s *Session) Replay() ([]LD2451.Target, error) {
+	raw, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	sensor, err := New()
+	if err != nil {
+		return nil, err
+	}
+	defer sensor.Close()
+
+	radar, err := LD2451.Open(sensor.Config())
+	if err != nil {
+		return nil, err
+	}
+	defer radar.Close()
+	targets, cancel := radar.Subscribe()
+	defer cancel()
+
+	if err := sensor.SendRaw(raw); err != nil {
+		return nil, err
+	}
+
+	var decoded []LD2451.Target
+	quiet := time.NewTimer(quietPeriod)
+	defer quiet.Stop()
+	for {
+		select {
+		case target, ok := <-targets:
+			if !ok {
+				return decoded, nil
+			}
+			target.Time = time.Time{}
+			decoded = append(decoded, target)
+			quiet.Reset(quietPeriod)
+		case <-quiet.C:
+			return decoded, nil
+		}
+	}
+}
+
+// Check replays the session and reports the first difference between the
+// decoded and the expected targets.
+func (s *Session) Check() error {
+	decoded, err := s.Replay()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(decoded) || i < len(s.Targets); i++ {
+		switch {
+		case i >= len(decoded):
+			return fmt.Errorf("target %d: expected %v, got nothing", i, s.Targets[i])
+		case i >= len(s.Targets):
+			return fmt.Errorf("target %d: got unexpected %v", i, decoded[i])
+		}
+		expected := s.Targets[i]
+		expected.Time = time.Time{}
+		if decoded[i] != expected {
+			return fmt.Errorf("target %d: expected %v, got %v", i, expected, decoded[i])
+		}
+	}
+	return nil
+}
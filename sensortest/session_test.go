@@ -0,0 +1,53 @@
+package sensortest_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+func TestSessionReplay(t *testing.T) {
+	requirePTY(t)
+	paths, err := filepath.Glob("testdata/fixtures/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		session, err := sensortest.LoadSession(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(session.Name, func(t *testing.T) {
+			decoded, err := session.Replay()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := slices.Clone(session.Targets)
+			for i := range expected {
+				expected[i].Time = time.Time{}
+			}
+			if !slices.Equal(decoded, expected) {
+				t.Errorf("decoded %v, expected %v", decoded, expected)
+			}
+		})
+	}
+}
+
+func TestSessionCheckReportsMismatch(t *testing.T) {
+	requirePTY(t)
+	session, err := sensortest.LoadSession("testdata/fixtures/single-target.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Targets[0].Speed++
+	if err := session.Check(); err == nil {
+		t.Error("Check accepted a target with the wrong speed")
+	}
+	session.Targets = nil
+	if err := session.Check(); err == nil {
+		t.Error("Check accepted a decoded target not in the session")
+	}
+}
@@ -0,0 +1,54 @@
+package LD2451
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// ErrNoSerialNumber is returned by SerialNumber when the firmware doesn't
+// report a serial number.
+var ErrNoSerialNumber = errors.New("firmware does not report a serial number")
+
+// SerialNumber reads the serial number of the module, e.g. to keep track of
+// the sensors of a fleet. Only some firmware reports one; the rest reject the
+// command or don't answer it, which is returned as ErrNoSerialNumber and
+// remembered, so later calls fail without the timeout.
+func (ld2451 *LD2451) SerialNumber() (string, error) {
+	var serial string
+	err := ld2451.configure("SerialNumber", func() error {
+		var err error
+		serial, err = ld2451.readSerialNumber()
+		return err
+	})
+	return serial, err
+}
+
+func (ld2451 *LD2451) readSerialNumber() (string, error) {
+	if ld2451.noSerial {
+		return "", ErrNoSerialNumber
+	}
+	data, err := ld2451.command(protocol.CmdReadSerial, nil)
+	var commandErr *CommandError
+	if errors.Is(err, ErrCommandTimeout) || errors.As(err, &commandErr) {
+		ld2451.noSerial = true
+		return "", fmt.Errorf("%w: %w", ErrNoSerialNumber, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	//the reply is the length of the number followed by its characters
+	if len(data) >= 2 {
+		if n := int(binary.LittleEndian.Uint16(data)); n <= len(data)-2 {
+			data = data[2 : 2+n]
+		}
+	}
+	serial := string(bytes.Trim(data, "\x00 "))
+	if serial == "" {
+		return "", ErrNoSerialNumber
+	}
+	return serial, nil
+}
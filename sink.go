@@ -0,0 +1,83 @@
+package LD2451
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultSinkFlushInterval = time.Second
+
+// Sink is an output targets are written to, e.g. a CSV file, an MQTT topic or
+// a database table. AddSink and RunSink only call its methods from a single
+// goroutine, so implementations need no locking.
+type Sink interface {
+	Write(target Target) error // May buffer target until Flush
+	Flush() error
+	Close() error
+}
+
+// SinkFunc adapts a function to a Sink that needs no flushing or closing.
+type SinkFunc func(target Target) error
+
+func (f SinkFunc) Write(target Target) error { return f(target) }
+func (f SinkFunc) Flush() error              { return nil }
+func (f SinkFunc) Close() error              { return nil }
+
+// AddSink writes every target delivered from now on to sink, which is flushed
+// every Config.SinkFlushInterval. Each sink is fed from its own subscription
+// and goroutine, so a slow or failing sink never holds up the reader or other
+// sinks: its failed writes are logged and counted in Stats.SinkErrors, and
+// targets it doesn't keep up with are dropped like for Subscribe.
+//
+// The returned function removes the sink, flushes and closes it and returns
+// the flush and close errors. The sink is also flushed and closed when the
+// reader stops.
+func (ld2451 *LD2451) AddSink(sink Sink) func() error {
+	targets, unsubscribe := ld2451.Subscribe()
+	done := make(chan error, 1)
+	go func() {
+		done <- ld2451.runSink(sink, targets)
+	}()
+
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() {
+			unsubscribe()
+			err = <-done
+		})
+		return err
+	}
+}
+
+func (ld2451 *LD2451) runSink(sink Sink, targets <-chan Target) error {
+	interval := ld2451.config.SinkFlushInterval
+	if interval <= 0 {
+		interval = defaultSinkFlushInterval
+	}
+	ticker := ld2451.config.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case target, ok := <-targets:
+			if !ok {
+				return errors.Join(sink.Flush(), sink.Close())
+			}
+			if err := sink.Write(target); err != nil {
+				ld2451.sinkFailed("write", err)
+			}
+		case <-ticker.C():
+			if err := sink.Flush(); err != nil {
+				ld2451.sinkFailed("flush", err)
+			}
+		}
+	}
+}
+
+func (ld2451 *LD2451) sinkFailed(op string, err error) {
+	ld2451.statsMu.Lock()
+	ld2451.stats.SinkErrors++
+	ld2451.statsMu.Unlock()
+	ld2451.config.Logger.Warn("sink failed", "op", op, "error", err)
+}
@@ -0,0 +1,113 @@
+package LD2451
+
+type SmoothingMode int
+
+const (
+	SmoothingNone          SmoothingMode = 0 // Deliver the raw speed reported by the sensor
+	SmoothingMovingAverage SmoothingMode = 1 // Average the speed over the last SmoothingWindow frames
+	SmoothingExponential   SmoothingMode = 2 // Exponentially weighted average using SmoothingFactor
+)
+
+const (
+	defaultSmoothingWindow = 5
+	defaultSmoothingFactor = 0.5
+)
+
+func (m SmoothingMode) String() string {
+	switch m {
+	case SmoothingNone:
+		return "None"
+	case SmoothingMovingAverage:
+		return "MovingAverage"
+	case SmoothingExponential:
+		return "Exponential"
+	default:
+		return "Unknown"
+	}
+}
+
+// speedSmoother keeps per-slot speed history. The sensor reports targets in a
+// stable order between frames, so the position of a target within its frame is
+// used to associate it with its history.
+type speedSmoother struct {
+	mode   SmoothingMode
+	window int
+	factor float64
+	slots  []smoothingSlot
+}
+
+type smoothingSlot struct {
+	direction Direction
+	samples   []float64 //ring of recent samples for the moving average
+	next      int       //next position to overwrite in samples
+	average   float64   //running value for the exponential average
+	valid     bool
+}
+
+func newSpeedSmoother(config Config) *speedSmoother {
+	s := &speedSmoother{
+		mode:   config.SpeedSmoothing,
+		window: config.SmoothingWindow,
+		factor: config.SmoothingFactor,
+	}
+	if s.window <= 0 {
+		s.window = defaultSmoothingWindow
+	}
+	if s.factor <= 0 || s.factor > 1 {
+		s.factor = defaultSmoothingFactor
+	}
+	return s
+}
+
+// smooth returns the smoothed speed for the target at the given index of the
+// current frame, at the resolution the module reported it in.
+func (s *speedSmoother) smooth(index int, target Target) float64 {
+	speed := target.PreciseSpeed()
+	if s.mode == SmoothingNone {
+		return speed
+	}
+	for len(s.slots) <= index {
+		s.slots = append(s.slots, smoothingSlot{})
+	}
+	slot := &s.slots[index]
+
+	//a change of direction means a different object now occupies this slot
+	if slot.valid && slot.direction != target.Direction {
+		*slot = smoothingSlot{}
+	}
+	slot.direction = target.Direction
+
+	switch s.mode {
+	case SmoothingMovingAverage:
+		if len(slot.samples) < s.window {
+			slot.samples = append(slot.samples, speed)
+		} else {
+			slot.samples[slot.next] = speed
+		}
+		slot.next = (slot.next + 1) % s.window
+		slot.valid = true
+
+		sum := 0.0
+		for _, sample := range slot.samples {
+			sum += sample
+		}
+		return sum / float64(len(slot.samples))
+	case SmoothingExponential:
+		if !slot.valid {
+			slot.average = speed
+		} else {
+			slot.average = s.factor*speed + (1-s.factor)*slot.average
+		}
+		slot.valid = true
+		return slot.average
+	default:
+		return speed
+	}
+}
+
+// trim forgets the history of slots that were not present in the current frame.
+func (s *speedSmoother) trim(numTargets int) {
+	if numTargets < len(s.slots) {
+		s.slots = s.slots[:numTargets]
+	}
+}
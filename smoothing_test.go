@@ -0,0 +1,94 @@
+package LD2451_test
+
+import (
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// readThrough runs frames through a sensor configured by config and returns
+// the targets it delivers.
+func readThrough(t *testing.T, config LD2451.Config, frames ...[]LD2451.Target) []LD2451.Target {
+	t.Helper()
+	next := 0
+	radar, err := LD2451.NewSource(LD2451.FrameSource(func() (LD2451.Frame, error) {
+		if next == len(frames) {
+			return LD2451.Frame{}, io.EOF
+		}
+		next++
+		return LD2451.Frame{Targets: frames[next-1]}, nil
+	}), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer radar.Close()
+	var targets []LD2451.Target
+	for {
+		target, err := radar.ReadTarget()
+		if err != nil {
+			return targets
+		}
+		targets = append(targets, target)
+	}
+}
+
+func TestSpeedSmoothing(t *testing.T) {
+	toward := func(speed int) []LD2451.Target {
+		return []LD2451.Target{{Distance: 20, Direction: LD2451.DirectionToward, Speed: speed, SNR: 10}}
+	}
+	away := func(speed int) []LD2451.Target {
+		return []LD2451.Target{{Distance: 20, Direction: LD2451.DirectionAway, Speed: speed, SNR: 10}}
+	}
+	tests := []struct {
+		name   string
+		config LD2451.Config
+		frames [][]LD2451.Target
+		speeds []int
+	}{
+		{"none", LD2451.Config{}, [][]LD2451.Target{toward(10), toward(20), toward(30)}, []int{10, 20, 30}},
+		{
+			"moving average",
+			LD2451.Config{SpeedSmoothing: LD2451.SmoothingMovingAverage, SmoothingWindow: 2},
+			[][]LD2451.Target{toward(10), toward(20), toward(30), toward(50)},
+			[]int{10, 15, 25, 40},
+		},
+		{
+			"exponential",
+			LD2451.Config{SpeedSmoothing: LD2451.SmoothingExponential, SmoothingFactor: 0.5},
+			[][]LD2451.Target{toward(10), toward(20), toward(30)},
+			[]int{10, 15, 23},
+		},
+		{
+			"change of direction",
+			LD2451.Config{SpeedSmoothing: LD2451.SmoothingMovingAverage, SmoothingWindow: 3},
+			[][]LD2451.Target{toward(10), toward(20), away(60), away(40)},
+			[]int{10, 15, 60, 50},
+		},
+		{
+			"target gone for a frame",
+			LD2451.Config{SpeedSmoothing: LD2451.SmoothingExponential, SmoothingFactor: 0.5},
+			[][]LD2451.Target{toward(10), toward(20), nil, toward(40)},
+			[]int{10, 15, 40},
+		},
+		{
+			"slots per position",
+			LD2451.Config{SpeedSmoothing: LD2451.SmoothingMovingAverage, SmoothingWindow: 5},
+			[][]LD2451.Target{append(toward(10), away(50)...), append(toward(20), away(70)...)},
+			[]int{10, 50, 15, 60},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targets := readThrough(t, test.config, test.frames...)
+			speeds := make([]int, len(targets))
+			for i, target := range targets {
+				speeds[i] = target.Speed
+			}
+			if !slices.Equal(speeds, test.speeds) {
+				t.Errorf("got speeds %v, expected %v", speeds, test.speeds)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package LD2451
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	defaultSnapshotWindow = time.Second
+	defaultSnapshotLimit  = 1024
+)
+
+// CurrentTargets returns the targets delivered within the last
+// Config.SnapshotWindow, oldest first, without consuming them from
+// ReadTarget or any subscription. It suits request/response consumers such
+// as HTTP handlers that need an instantaneous view.
+func (ld2451 *LD2451) CurrentTargets() []Target {
+	ld2451.snapshotMu.Lock()
+	defer ld2451.snapshotMu.Unlock()
+	ld2451.expireSnapshot(ld2451.now())
+	return slices.Clone(ld2451.snapshot)
+}
+
+func (ld2451 *LD2451) remember(target Target) {
+	ld2451.snapshotMu.Lock()
+	defer ld2451.snapshotMu.Unlock()
+	ld2451.snapshot = append(ld2451.snapshot, target)
+	ld2451.expireSnapshot(target.Time)
+	if excess := len(ld2451.snapshot) - ld2451.snapshotLimit(); excess > 0 {
+		ld2451.snapshot = slices.Delete(ld2451.snapshot, 0, excess)
+		ld2451.snapshotEvicted += uint64(excess)
+	}
+}
+
+func (ld2451 *LD2451) snapshotLimit() int {
+	if ld2451.config.SnapshotLimit <= 0 {
+		return defaultSnapshotLimit
+	}
+	return ld2451.config.SnapshotLimit
+}
+
+// expireSnapshot drops targets older than the snapshot window. The caller
+// must hold snapshotMu.
+func (ld2451 *LD2451) expireSnapshot(now time.Time) {
+	window := ld2451.config.SnapshotWindow
+	if window <= 0 {
+		window = defaultSnapshotWindow
+	}
+	i := 0
+	for i < len(ld2451.snapshot) && now.Sub(ld2451.snapshot[i].Time) > window {
+		i++
+	}
+	if i > 0 {
+		ld2451.snapshot = slices.Delete(ld2451.snapshot, 0, i)
+	}
+}
@@ -0,0 +1,61 @@
+package LD2451
+
+import "math"
+
+// The module does not document the unit of the SNR byte. Comparing it with
+// the sensitivity thresholds the module accepts suggests roughly one dB per
+// step, which is what the helpers below assume.
+const (
+	snrDBPerStep   = 1.0
+	snrFullScaleDB = 20.0 //SNR mapped to a quality score of 100
+)
+
+// Guidance thresholds for the raw SNR value.
+const (
+	SNRWeak   = 4  // Below this detections are frequently clutter or noise
+	SNRStrong = 10 // At or above this detections are solid, typically vehicles at short to medium range
+)
+
+type SNRQuality int
+
+const (
+	QualityWeak   SNRQuality = 0 // SNR below SNRWeak
+	QualityOK     SNRQuality = 1 // SNR between SNRWeak and SNRStrong
+	QualityStrong SNRQuality = 2 // SNR at or above SNRStrong
+)
+
+func (q SNRQuality) String() string {
+	switch q {
+	case QualityWeak:
+		return "Weak"
+	case QualityOK:
+		return "OK"
+	case QualityStrong:
+		return "Strong"
+	default:
+		return "Unknown"
+	}
+}
+
+// SNRDecibels converts a raw SNR value into an approximate value in dB.
+func SNRDecibels(snr int) float64 {
+	return float64(snr) * snrDBPerStep
+}
+
+// SNRScore maps a raw SNR value onto a 0-100 quality score.
+func SNRScore(snr int) int {
+	score := SNRDecibels(snr) / snrFullScaleDB * 100
+	return int(math.Round(math.Max(0, math.Min(100, score))))
+}
+
+// ClassifySNR buckets a raw SNR value using SNRWeak and SNRStrong.
+func ClassifySNR(snr int) SNRQuality {
+	switch {
+	case snr < SNRWeak:
+		return QualityWeak
+	case snr < SNRStrong:
+		return QualityOK
+	default:
+		return QualityStrong
+	}
+}
@@ -0,0 +1,66 @@
+package LD2451
+
+import (
+	"errors"
+	"io"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// ErrReadOnlySource is returned by commands sent to a sensor reading from a
+// Source that doesn't implement io.Writer.
+var ErrReadOnlySource = errors.New("source does not accept commands")
+
+// Source supplies the packets the reader decodes. A *protocol.Reader is the
+// Source of every byte stream, while replay files or tests can hand out
+// packets or frames directly through NewSource. A Source that also
+// implements io.Writer receives the commands, and io.Closer is called by
+// Close.
+type Source interface {
+	Next() (protocol.Packet, error) // Returns an error once no more packets will follow, e.g. io.EOF
+}
+
+// NewSource starts reading from source instead of a port. Like with New the
+// serial port and reconnect settings are not used.
+func NewSource(source Source, config Config, options ...Option) (*LD2451, error) {
+	for _, option := range options {
+		option(&config)
+	}
+	return start(source, sourcePort{source}, config, nil)
+}
+
+// FrameSource adapts a function returning decoded frames, e.g. read from a
+// recording, to a Source. Frames are encoded again for the reader, so they go
+// through the same filtering and smoothing as frames read from the module.
+type FrameSource func() (Frame, error)
+
+func (next FrameSource) Next() (protocol.Packet, error) {
+	frame, err := next()
+	if err != nil {
+		return protocol.Packet{}, err
+	}
+	var alarm byte
+	if frame.Alarm {
+		alarm = 1
+	}
+	return protocol.Packet{Kind: protocol.KindData, Payload: protocol.EncodeFramePayload(frame.Targets, alarm)}, nil
+}
+
+// sourcePort forwards commands and Close to a Source implementing them.
+type sourcePort struct {
+	source Source
+}
+
+func (p sourcePort) Write(data []byte) (int, error) {
+	if w, ok := p.source.(io.Writer); ok {
+		return w.Write(data)
+	}
+	return 0, ErrReadOnlySource
+}
+
+func (p sourcePort) Close() error {
+	if c, ok := p.source.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Package sse streams the targets and events of a sensor as Server-Sent
+// Events, which browsers read with a plain EventSource and curl prints as
+// they arrive:
+//
+//	events, err := sse.New(sensor, sse.Config{})
+//	...
+//	mux.Handle("/events", events)
+//
+//	curl -N 'http://localhost:8080/events?events=target,alarm'
+//
+// Every event is a message named after its kind, e.g. "target", "alarm" or
+// "state", with the JSON encoded event as its data. The events query
+// parameter selects kinds, all of them are streamed without it.
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const DefaultKeepAlive = 15 * time.Second
+
+// Kinds lists the message names in the order of the LD2451.Event types.
+var Kinds = []string{
+	"target", "alarm", "state", "connection", "parse_error", "frame_gap",
+	"target_drops", "summary", "clear_tick", "heartbeat",
+}
+
+type Config struct {
+	Kinds     []string      // Message names streamed unless a request selects others, all of Kinds when empty
+	KeepAlive time.Duration // A comment is sent after this long without messages, so proxies keep the connection open (default DefaultKeepAlive)
+	Clock     LD2451.Clock  // Source of time for KeepAlive, the one of the sensor; nil uses LD2451.SystemClock
+}
+
+type Handler struct {
+	sensor *LD2451.LD2451
+	config Config
+}
+
+// New returns an error for names in Config.Kinds that are not in Kinds.
+func New(sensor *LD2451.LD2451, config Config) (*Handler, error) {
+	for _, kind := range config.Kinds {
+		if !slices.Contains(Kinds, kind) {
+			return nil, fmt.Errorf("unknown event kind %q", kind)
+		}
+	}
+	if len(config.Kinds) == 0 {
+		config.Kinds = Kinds
+	}
+	if config.KeepAlive <= 0 {
+		config.KeepAlive = DefaultKeepAlive
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+	return &Handler{sensor: sensor, config: config}, nil
+}
+
+// ServeHTTP streams events until the client goes away or the sensor stops.
+// Events a slow client doesn't keep up with are dropped and counted in
+// Stats.DroppedEvents, like for every other Events subscriber.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kinds := h.config.Kinds
+	if selected := r.URL.Query().Get("events"); selected != "" {
+		kinds = strings.Split(selected, ",")
+		for _, kind := range kinds {
+			if !slices.Contains(Kinds, kind) {
+				http.Error(w, fmt.Sprintf("unknown event kind %q", kind), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := h.sensor.Events()
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	//restarted after every message, so it only fires while the stream is idle
+	keepAlive := h.config.Clock.NewTimer(h.config.KeepAlive)
+	defer func() { keepAlive.Stop() }()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C():
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			keepAlive = h.config.Clock.NewTimer(h.config.KeepAlive)
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			kind, data := message(event)
+			if !slices.Contains(kinds, kind) {
+				continue
+			}
+			encoded, err := json.Marshal(data)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, encoded); err != nil {
+				return
+			}
+			flusher.Flush()
+			keepAlive.Stop()
+			keepAlive = h.config.Clock.NewTimer(h.config.KeepAlive)
+		}
+	}
+}
+
+// errorEvent replaces an event's error with its message, which encoding/json
+// can't marshal on its own.
+type errorEvent struct {
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+	Sensor string    `json:"sensor,omitempty"`
+}
+
+// message returns the name and data of the message sent for event.
+func message(event LD2451.Event) (string, any) {
+	switch event := event.(type) {
+	case LD2451.TargetEvent:
+		return "target", event.Target
+	case LD2451.AlarmEvent:
+		return "alarm", event
+	case LD2451.StateChange:
+		return "state", event
+	case LD2451.ConnectionEvent:
+		data := struct {
+			Status  LD2451.ConnectionStatus `json:"status"`
+			Attempt int                     `json:"attempt,omitempty"`
+			errorEvent
+		}{event.Status, event.Attempt, errorEvent{Time: event.Time, Sensor: event.Sensor}}
+		if event.Err != nil {
+			data.Error = event.Err.Error()
+		}
+		return "connection", data
+	case LD2451.ParseErrorEvent:
+		return "parse_error", errorEvent{Error: event.Err.Error(), Time: event.Time, Sensor: event.Sensor}
+	case LD2451.FrameGap:
+		return "frame_gap", event
+	case LD2451.TargetDrops:
+		return "target_drops", event
+	case LD2451.Summary:
+		return "summary", event
+	case LD2451.ClearTick:
+		return "clear_tick", event
+	case LD2451.Heartbeat:
+		return "heartbeat", event
+	default:
+		return "", event
+	}
+}
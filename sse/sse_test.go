@@ -0,0 +1,115 @@
+package sse_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+	"github.com/Battlekeeper/LD2451/v2/sse"
+)
+
+// stream requests url and returns the lines of the response as they arrive.
+func stream(t *testing.T, url string) <-chan string {
+	t.Helper()
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got status %d, content type %q", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+	lines := make(chan string, 64)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// next returns the next line of the stream, calling tick while waiting.
+func next(t *testing.T, lines <-chan string, tick func()) string {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream ended")
+			}
+			return line
+		case <-time.After(5 * time.Millisecond):
+			tick()
+		case <-timeout:
+			t.Fatal("nothing streamed")
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sensor.Close()
+	radar, err := LD2451.Open(sensor.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer radar.Close()
+
+	clock := sensortest.NewClock(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	handler, err := sse.New(radar, sse.Config{KeepAlive: time.Minute, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close) //after the streams are closed
+	sent := LD2451.Target{Distance: 20, Direction: LD2451.DirectionToward, Speed: 40, SNR: 9}
+	send := func() { sensor.SendTargets(false, sent) }
+
+	lines := stream(t, server.URL+"?events=target")
+	for next(t, lines, send) != "event: target" {
+	}
+	var target LD2451.Target
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(next(t, lines, send), "data: ")), &target); err != nil {
+		t.Fatal(err)
+	}
+	if target.Distance != sent.Distance || target.Speed != sent.Speed {
+		t.Errorf("streamed %+v, expected %+v", target, sent)
+	}
+
+	//targets a client didn't select are not streamed, so it only gets keep-alives
+	lines = stream(t, server.URL+"?events=alarm")
+	line := next(t, lines, func() {
+		send()
+		clock.Advance(time.Minute)
+	})
+	if line != ": keep-alive" {
+		t.Errorf("got %q, expected a keep-alive comment", line)
+	}
+
+	response, err := http.Get(server.URL + "?events=target,bogus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown kind: got status %d", response.StatusCode)
+	}
+}
+
+func TestNewRejectsUnknownKinds(t *testing.T) {
+	if _, err := sse.New(nil, sse.Config{Kinds: []string{"target", "bogus"}}); err == nil {
+		t.Error("accepted an unknown kind")
+	}
+}
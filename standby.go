@@ -0,0 +1,38 @@
+package LD2451
+
+import "github.com/Battlekeeper/LD2451/v2/protocol"
+
+// Sleep stops the module from reporting targets by keeping it in config mode,
+// for duty cycled deployments. The port stays open and configuration commands
+// keep working while the module sleeps. Wake resumes reporting.
+func (ld2451 *LD2451) Sleep() error {
+	return ld2451.enqueue("Sleep", func() error {
+		if ld2451.asleep {
+			return nil
+		}
+		info, err := ld2451.command(protocol.CmdEnableConfig, []byte{0x01, 0x00})
+		if err != nil {
+			return err
+		}
+		ld2451.configInfo = info
+		ld2451.asleep = true
+		ld2451.setState(StateStandby)
+		return nil
+	})
+}
+
+// Wake makes a sleeping module report targets again.
+func (ld2451 *LD2451) Wake() error {
+	return ld2451.enqueue("Wake", func() error {
+		if !ld2451.asleep {
+			return nil
+		}
+		_, err := ld2451.command(protocol.CmdEndConfig, nil)
+		if err != nil {
+			return err
+		}
+		ld2451.asleep = false
+		ld2451.setState(StateReporting)
+		return nil
+	})
+}
@@ -0,0 +1,54 @@
+package LD2451_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// silence outlasts the read timeout of the port, so a reader taking the
+// timeout for a failure would have stopped.
+const silence = transport.DefaultReadTimeout + time.Second
+
+func TestSleepSurvivesSilence(t *testing.T) {
+	sensor, radar := openSensor(t, nil)
+	if err := radar.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(silence)
+	if state := radar.State(); state != LD2451.StateStandby {
+		t.Fatalf("state after %s asleep is %s, want %s", silence, state, LD2451.StateStandby)
+	}
+	if err := radar.Wake(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sensor.SendTargets(false, LD2451.Target{Distance: 12, Speed: 30}); err != nil {
+		t.Fatal(err)
+	}
+	target, err := radar.ReadTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Distance != 12 {
+		t.Fatalf("got target at %d m, want 12 m", target.Distance)
+	}
+	if stats := radar.Stats(); stats.ReadErrors != 0 {
+		t.Fatalf("%d read errors while asleep", stats.ReadErrors)
+	}
+}
+
+func TestReconnectingSleepDoesNotCycleThePort(t *testing.T) {
+	_, radar := openSensor(t, func(config *LD2451.Config) { config.Reconnect = true })
+	if err := radar.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(silence)
+	if stats := radar.Stats(); stats.Reconnects != 0 || stats.ReadErrors != 0 {
+		t.Fatalf("%d reconnects and %d read errors while asleep", stats.Reconnects, stats.ReadErrors)
+	}
+	if err := radar.Wake(); err != nil {
+		t.Fatal(err)
+	}
+}
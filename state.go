@@ -0,0 +1,138 @@
+package LD2451
+
+import "time"
+
+const (
+	defaultDegradedAfter = 3 * time.Second
+	stateCheckInterval   = 250 * time.Millisecond
+	stateBufferSize      = 8
+)
+
+type SensorState int
+
+const (
+	StateConnecting   SensorState = 0 // The port is open but no valid frame was received yet
+	StateConfiguring  SensorState = 1 // A configuration session is running, the module does not report targets
+	StateReporting    SensorState = 2 // Valid frames are arriving
+	StateDegraded     SensorState = 3 // No valid frame was received for Config.DegradedAfter
+	StateDisconnected SensorState = 4 // The reader stopped after a transport error, or waits for the port to come back with Config.Reconnect
+	StateClosed       SensorState = 5 // Close was called
+	StateStandby      SensorState = 6 // Sleep was called, the module does not report targets until Wake
+)
+
+func (s SensorState) String() string {
+	switch s {
+	case StateConnecting:
+		return "Connecting"
+	case StateConfiguring:
+		return "Configuring"
+	case StateReporting:
+		return "Reporting"
+	case StateDegraded:
+		return "Degraded"
+	case StateDisconnected:
+		return "Disconnected"
+	case StateClosed:
+		return "Closed"
+	case StateStandby:
+		return "Standby"
+	default:
+		return "Unknown"
+	}
+}
+
+type StateChange struct {
+	From   SensorState `json:"from"`
+	To     SensorState `json:"to"`
+	Time   time.Time   `json:"time"`
+	Sensor string      `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+// State returns the current state of the sensor.
+func (ld2451 *LD2451) State() SensorState {
+	ld2451.statsMu.Lock()
+	defer ld2451.statsMu.Unlock()
+	return ld2451.state
+}
+
+// StateChanges returns the channel state transitions are delivered on.
+// Transitions are dropped rather than stalling the library when nobody keeps
+// up with the channel.
+func (ld2451 *LD2451) StateChanges() <-chan StateChange {
+	return ld2451.stateChanges
+}
+
+func (ld2451 *LD2451) setState(state SensorState) {
+	ld2451.statsMu.Lock()
+	defer ld2451.statsMu.Unlock()
+	ld2451.transition(state)
+}
+
+// transition moves to state. The caller must hold statsMu.
+func (ld2451 *LD2451) transition(state SensorState) {
+	from := ld2451.state
+	//closed is final, disconnected can only be left by reconnecting
+	if from == state || from == StateClosed || (from == StateDisconnected && state != StateClosed && state != StateConnecting) {
+		return
+	}
+	ld2451.config.Logger.Info("state changed", "from", from, "to", state)
+	now := ld2451.now()
+	ld2451.state = state
+	ld2451.stateSince = now
+	if state != StateReporting && state != StateDegraded {
+		//no frames are expected, the next one doesn't end a gap
+		ld2451.frameExpected = time.Time{}
+	}
+	change := StateChange{From: from, To: state, Time: now, Sensor: ld2451.config.SensorID}
+	ld2451.publish(change)
+	select {
+	case ld2451.stateChanges <- change:
+	default:
+	}
+}
+
+// beginConfiguring enters StateConfiguring and returns a function restoring
+// the state the sensor was in before.
+func (ld2451 *LD2451) beginConfiguring() func() {
+	ld2451.statsMu.Lock()
+	previous := ld2451.state
+	ld2451.transition(StateConfiguring)
+	ld2451.statsMu.Unlock()
+
+	return func() {
+		ld2451.statsMu.Lock()
+		defer ld2451.statsMu.Unlock()
+		if ld2451.state == StateConfiguring {
+			ld2451.transition(previous)
+		}
+	}
+}
+
+// watchState degrades the sensor when frames stop arriving, until the reader stops.
+func (ld2451 *LD2451) watchState() {
+	degradedAfter := ld2451.config.DegradedAfter
+	if degradedAfter <= 0 {
+		degradedAfter = defaultDegradedAfter
+	}
+	ticker := ld2451.config.Clock.NewTicker(stateCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ld2451.done:
+			return
+		case <-ticker.C():
+			ld2451.statsMu.Lock()
+			if ld2451.state == StateConnecting || ld2451.state == StateReporting {
+				//frames are only expected from the moment the current state was entered
+				last := ld2451.lastFrame
+				if ld2451.stateSince.After(last) {
+					last = ld2451.stateSince
+				}
+				if ld2451.since(last) > degradedAfter {
+					ld2451.transition(StateDegraded)
+				}
+			}
+			ld2451.statsMu.Unlock()
+		}
+	}
+}
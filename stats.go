@@ -0,0 +1,203 @@
+package LD2451
+
+import (
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+type Stats struct {
+	Frames          uint64 // Number of valid data frames received
+	Targets         uint64 // Number of targets delivered
+	Resyncs         uint64 // Number of times the reader lost frame alignment and had to search for a header
+	DiscardedBytes  uint64 // Number of bytes skipped while resynchronizing
+	ParseErrors     uint64 // Number of delimited frames whose payload could not be decoded
+	OversizedFrames uint64 // Number of headers rejected because their declared length was implausible
+	ReadErrors      uint64 // Number of transport errors returned by the serial port
+	Reconnects      uint64 // Number of times the port was reopened after a transport error
+	DroppedErrors   uint64 // Number of errors dropped because the error or diagnostics channel was full
+	StaleTargets    uint64 // Number of buffered targets discarded for exceeding Config.MaxTargetAge
+	DroppedTargets  uint64 // Number of target deliveries dropped because a channel was full while subscribers exist
+	FilteredTargets uint64 // Number of targets withheld by the angle range, Config.Filters or Config.MinFrames
+	ThrottledFrames uint64 // Number of frames whose targets were held back by Config.ReportInterval
+	DroppedEvents   uint64 // Number of events dropped because an Events channel was full
+	TruncatedFrames uint64 // Number of frames cut down to Config.MaxTargets targets
+	FrameGaps       uint64 // Number of FrameGap events, see Config.FrameGapFactor
+	SinkErrors      uint64 // Number of failed writes and periodic flushes of sinks added with AddSink
+	InvalidTargets  uint64 // Number of targets dropped for values outside Config.TargetLimits
+	ClampedTargets  uint64 // Number of targets clamped into Config.TargetLimits
+	PeakTargets     uint64 // Most targets reported in a single frame, see Peaks for the peaks per window
+	FramesAtLimit   uint64 // Number of frames reporting protocol.MaxTargets targets, the most the module can report
+
+	SalvagedFrames uint64 // Number of malformed frames partially decoded with Config.ParseMode ParseLenient
+	DroppedFrames  uint64 // Number of frames dropped because a SubscribeFrames channel was full
+
+	FlushedTargets  uint64 // Number of targets discarded by Flush
+	PausedFrames    uint64 // Number of frames whose targets were dropped while paused, see Pause
+	UnknownFrames   uint64 // Number of command frames that were no acknowledgement of a known command, see Config.UnknownFrames
+	ParseRecoveries uint64 // Number of times the input was flushed after Config.ParseFailureLimit frames in a row failed to decode
+
+	TargetDropBursts uint64    // Number of TargetDrops events, each starting a burst of dropped target deliveries
+	LastTargetDrop   time.Time // When a target delivery was last dropped, zero if none was
+
+	SnapshotEvictions uint64 // Number of targets evicted from CurrentTargets for exceeding Config.SnapshotLimit
+
+	LatencySamples uint64        // Number of targets returned by ReadTarget, ReadTargets or Run, for which latency is measured
+	LatencyMean    time.Duration // Mean time from the first byte of a frame arriving to its target being returned
+	LatencyMax     time.Duration // Longest time from the first byte of a frame arriving to its target being returned
+}
+
+// Stats returns a snapshot of the reader counters.
+func (ld2451 *LD2451) Stats() Stats {
+	ld2451.statsMu.Lock()
+	stats := ld2451.stats
+	if stats.LatencySamples > 0 {
+		stats.LatencyMean = ld2451.latencyTotal / time.Duration(stats.LatencySamples)
+	}
+	ld2451.statsMu.Unlock()
+	ld2451.eventsMu.Lock()
+	stats.DroppedEvents = ld2451.droppedEvents
+	ld2451.eventsMu.Unlock()
+	ld2451.snapshotMu.Lock()
+	stats.SnapshotEvictions = ld2451.snapshotEvicted
+	ld2451.snapshotMu.Unlock()
+	return stats
+}
+
+func (ld2451 *LD2451) recordFrame() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.Frames++
+	if ld2451.stats.Frames == 1 {
+		close(ld2451.firstFrame)
+	}
+	if ld2451.summary != nil {
+		ld2451.summary.frame()
+	}
+	ld2451.lastFrame = ld2451.now()
+	ld2451.checkFrameGap(ld2451.lastFrame)
+	if ld2451.state == StateConnecting || ld2451.state == StateDegraded {
+		ld2451.transition(StateReporting)
+	}
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordTarget() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.Targets++
+	ld2451.lastTarget = ld2451.now()
+	ld2451.statsMu.Unlock()
+}
+
+// recordPacket accounts for what the frame reader skipped while looking for packet.
+func (ld2451 *LD2451) recordPacket(packet protocol.Packet) {
+	if packet.Skipped == 0 && packet.Oversized == 0 {
+		return
+	}
+	ld2451.config.Logger.Debug("resynchronized", "skipped", packet.Skipped, "oversized", packet.Oversized)
+	ld2451.statsMu.Lock()
+	if packet.Skipped > 0 {
+		ld2451.stats.Resyncs++
+		ld2451.stats.DiscardedBytes += uint64(packet.Skipped)
+	}
+	ld2451.stats.OversizedFrames += uint64(packet.Oversized)
+	ld2451.statsMu.Unlock()
+	ld2451.reportDiagnostic(&ResyncError{Skipped: packet.Skipped, Oversized: packet.Oversized})
+}
+
+func (ld2451 *LD2451) recordSalvagedFrame() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.SalvagedFrames++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordFlushedTargets(n int) {
+	ld2451.statsMu.Lock()
+	ld2451.stats.FlushedTargets += uint64(n)
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordPausedFrame() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.PausedFrames++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordUnknownFrame() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.UnknownFrames++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordParseRecovery() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.ParseRecoveries++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordParseError() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.ParseErrors++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordInvalidTarget() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.InvalidTargets++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordClampedTarget() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.ClampedTargets++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordTruncatedFrame() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.TruncatedFrames++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordReadError() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.ReadErrors++
+	ld2451.transition(StateDisconnected)
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordReconnect() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.Reconnects++
+	ld2451.transition(StateConnecting)
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordDroppedError() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.DroppedErrors++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordStaleTarget() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.StaleTargets++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordDroppedFrame() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.DroppedFrames++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordFilteredTarget() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.FilteredTargets++
+	ld2451.statsMu.Unlock()
+}
+
+func (ld2451 *LD2451) recordThrottledFrame() {
+	ld2451.statsMu.Lock()
+	ld2451.stats.ThrottledFrames++
+	ld2451.statsMu.Unlock()
+}
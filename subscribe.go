@@ -0,0 +1,164 @@
+package LD2451
+
+import (
+	"slices"
+	"time"
+)
+
+// Subscribe returns a channel receiving every target read from now on,
+// independently of ReadTarget and of other subscribers, and a function that
+// ends the subscription and closes the channel. The channel is also closed
+// when the reader stops.
+//
+// Without subscribers ReadTarget receives every target and a full buffer holds
+// up the reader. Once there are subscribers, including those of
+// SubscribeFrames and Events, no consumer may stall the others:
+// a target that doesn't fit in a full channel, including the one read by
+// ReadTarget, is dropped and counted in Stats.DroppedTargets, and a TargetDrops event
+// marks when drops start.
+func (ld2451 *LD2451) Subscribe() (<-chan Target, func()) {
+	ch := make(chan Target, subscriberBufferSize(ld2451.config))
+
+	ld2451.subsMu.Lock()
+	select {
+	case <-ld2451.done:
+		//the reader already stopped, nothing will ever be delivered
+		close(ch)
+		ld2451.subsMu.Unlock()
+		return ch, func() {}
+	default:
+	}
+	if ld2451.subs == nil {
+		ld2451.subs = make(map[chan Target]struct{})
+	}
+	ld2451.subs[ch] = struct{}{}
+	ld2451.subsMu.Unlock()
+
+	return ch, func() {
+		ld2451.subsMu.Lock()
+		defer ld2451.subsMu.Unlock()
+		if _, ok := ld2451.subs[ch]; ok {
+			delete(ld2451.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// deliver hands target to ReadTarget, all subscribers and the event bus.
+// start is when the first byte of its frame arrived.
+func (ld2451 *LD2451) deliver(target Target, start time.Time) {
+	ld2451.publish(TargetEvent{target})
+
+	if !ld2451.subscribed() {
+		//ReadTarget is the only consumer, a full buffer holds up the reader until Close
+		select {
+		case ld2451.targets <- delivery{target, start}:
+		case <-ld2451.closed:
+		}
+		return
+	}
+	ld2451.subsMu.Lock()
+	defer ld2451.subsMu.Unlock()
+
+	select {
+	case ld2451.targets <- delivery{target, start}:
+	default:
+		ld2451.recordDroppedTarget(true)
+	}
+	for ch := range ld2451.subs {
+		select {
+		case ch <- target:
+		default:
+			ld2451.recordDroppedTarget(false)
+		}
+	}
+}
+
+// subscribed reports whether anybody consumes the stream through Subscribe,
+// SubscribeFrames or Events, any of which may never call ReadTarget.
+func (ld2451 *LD2451) subscribed() bool {
+	ld2451.subsMu.Lock()
+	subscribed := len(ld2451.subs) > 0 || len(ld2451.frameSubs) > 0
+	ld2451.subsMu.Unlock()
+	if subscribed {
+		return true
+	}
+	ld2451.eventsMu.Lock()
+	defer ld2451.eventsMu.Unlock()
+	return len(ld2451.eventSubs) > 0
+}
+
+// SubscribeFrames is like Subscribe for whole frames: every frame whose
+// targets are delivered is received with Frame.Time set to when it arrived,
+// which is also the Time of its targets. Frames without targets are included,
+// so the stream shows when nothing was detected. A frame that doesn't fit in a
+// full channel is dropped and counted in Stats.DroppedFrames.
+func (ld2451 *LD2451) SubscribeFrames() (<-chan Frame, func()) {
+	ch := make(chan Frame, subscriberBufferSize(ld2451.config))
+
+	ld2451.subsMu.Lock()
+	select {
+	case <-ld2451.done:
+		close(ch)
+		ld2451.subsMu.Unlock()
+		return ch, func() {}
+	default:
+	}
+	if ld2451.frameSubs == nil {
+		ld2451.frameSubs = make(map[chan Frame]struct{})
+	}
+	ld2451.frameSubs[ch] = struct{}{}
+	ld2451.subsMu.Unlock()
+
+	return ch, func() {
+		ld2451.subsMu.Lock()
+		defer ld2451.subsMu.Unlock()
+		if _, ok := ld2451.frameSubs[ch]; ok {
+			delete(ld2451.frameSubs, ch)
+			close(ch)
+		}
+	}
+}
+
+// frameSubscribed reports whether anybody subscribed with SubscribeFrames.
+func (ld2451 *LD2451) frameSubscribed() bool {
+	ld2451.subsMu.Lock()
+	defer ld2451.subsMu.Unlock()
+	return len(ld2451.frameSubs) > 0
+}
+
+// deliverFrame hands frame to the frame subscribers. Its targets are copied,
+// every subscriber may keep the frame.
+func (ld2451 *LD2451) deliverFrame(frame Frame) {
+	ld2451.subsMu.Lock()
+	defer ld2451.subsMu.Unlock()
+	for ch := range ld2451.frameSubs {
+		delivered := frame
+		delivered.Targets = slices.Clone(frame.Targets)
+		select {
+		case ch <- delivered:
+		default:
+			ld2451.recordDroppedFrame()
+		}
+	}
+}
+
+func (ld2451 *LD2451) closeSubscribers() {
+	ld2451.subsMu.Lock()
+	defer ld2451.subsMu.Unlock()
+	for ch := range ld2451.subs {
+		close(ch)
+	}
+	ld2451.subs = nil
+	for ch := range ld2451.frameSubs {
+		close(ch)
+	}
+	ld2451.frameSubs = nil
+}
+
+func subscriberBufferSize(config Config) int {
+	if config.TargetBufferSize > 0 {
+		return config.TargetBufferSize
+	}
+	return defaultSubscriberBufferSize
+}
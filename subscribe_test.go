@@ -0,0 +1,92 @@
+package LD2451_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+)
+
+const frameCount = 100
+
+// openSensor opens the library on a fresh fake sensor, closing both when the
+// test ends.
+func openSensor(t *testing.T, configure func(*LD2451.Config)) (*sensortest.Sensor, *LD2451.LD2451) {
+	t.Helper()
+	sensor, err := sensortest.New()
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { sensor.Close() })
+	config := sensor.Config()
+	if configure != nil {
+		configure(&config)
+	}
+	radar, err := LD2451.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(radar.Close)
+	return sensor, radar
+}
+
+// sendFrames sends frameCount frames with a target each, paced so a reader
+// keeping up never drops any.
+func sendFrames(t *testing.T, sensor *sensortest.Sensor) {
+	t.Helper()
+	for i := range frameCount {
+		if err := sensor.SendTargets(false, LD2451.Target{Distance: 10 + i%50, Speed: 30}); err != nil {
+			t.Error(err)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscribeFramesOnlyDoesNotStall(t *testing.T) {
+	sensor, radar := openSensor(t, nil)
+	frames, stop := radar.SubscribeFrames()
+	defer stop()
+	go sendFrames(t, sensor)
+
+	timeout := time.After(5 * time.Second)
+	for received := 0; received < frameCount; received++ {
+		select {
+		case <-frames:
+		case <-timeout:
+			t.Fatalf("received %d of %d frames, the reader stalled", received, frameCount)
+		}
+	}
+}
+
+func TestCloseWithFullBuffer(t *testing.T) {
+	sensor, radar := openSensor(t, func(config *LD2451.Config) { config.TargetBufferSize = 2 })
+	for i := range 5 {
+		if err := sensor.SendTargets(false, LD2451.Target{Distance: 10 + i, Speed: 30}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//give the reader time to fill the buffer and block on the next target
+	time.Sleep(100 * time.Millisecond)
+	radar.Close()
+
+	//a subscription of a stopped reader is closed right away
+	deadline := time.After(2 * time.Second)
+	for {
+		targets, cancel := radar.Subscribe()
+		select {
+		case _, ok := <-targets:
+			if !ok {
+				return
+			}
+		case <-time.After(10 * time.Millisecond):
+		}
+		cancel()
+		select {
+		case <-deadline:
+			t.Fatal("reader still blocked on the full buffer after Close")
+		default:
+		}
+	}
+}
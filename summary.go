@@ -0,0 +1,96 @@
+package LD2451
+
+import (
+	"sync"
+	"time"
+)
+
+const summaryBufferSize = 4
+
+// Summary describes the targets delivered during one Config.SummaryInterval.
+type Summary struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Targets  int       `json:"targets"`   // Number of targets delivered, one object is usually reported in many frames
+	Away     int       `json:"away"`      // Targets moving away from the sensor
+	Toward   int       `json:"toward"`    // Targets moving toward the sensor
+	MaxSpeed int       `json:"max_speed"` // Highest speed in KM/H of a delivered target
+	Frames   int       `json:"frames"`    // Number of valid frames received
+
+	AwaySpeeds   SpeedStats `json:"away_speeds"`   // Speeds of the targets moving away
+	TowardSpeeds SpeedStats `json:"toward_speeds"` // Speeds of the targets moving toward
+
+	Sensor string `json:"sensor,omitempty"` // Config.SensorID of the sensor
+}
+
+func (e Summary) EventTime() time.Time { return e.End }
+
+// Summaries returns the channel summaries are delivered on every
+// Config.SummaryInterval. Summaries are dropped rather than stalling the
+// library when nobody keeps up with the channel.
+func (ld2451 *LD2451) Summaries() <-chan Summary {
+	return ld2451.summaries
+}
+
+// summarizer accumulates the summary of the running interval.
+type summarizer struct {
+	mu      sync.Mutex
+	current Summary
+	bucket  int //histogram bucket width in KM/H
+	away    speedCounts
+	toward  speedCounts
+}
+
+func (s *summarizer) frame() {
+	s.mu.Lock()
+	s.current.Frames++
+	s.mu.Unlock()
+}
+
+func (s *summarizer) target(target Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Targets++
+	if target.Direction == DirectionToward {
+		s.current.Toward++
+		s.toward.add(target.Speed)
+	} else {
+		s.current.Away++
+		s.away.add(target.Speed)
+	}
+	s.current.MaxSpeed = max(s.current.MaxSpeed, target.Speed)
+}
+
+// next ends the running interval at now and starts the next one.
+func (s *summarizer) next(now time.Time) Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary := s.current
+	summary.End = now
+	summary.AwaySpeeds = s.away.stats(s.bucket)
+	summary.TowardSpeeds = s.toward.stats(s.bucket)
+	s.current = Summary{Start: now}
+	s.away, s.toward = speedCounts{}, speedCounts{}
+	return summary
+}
+
+// summarize delivers a summary every Config.SummaryInterval until the reader stops.
+func (ld2451 *LD2451) summarize() {
+	ld2451.summary.next(ld2451.now())
+	ticker := ld2451.config.Clock.NewTicker(ld2451.config.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ld2451.done:
+			return
+		case now := <-ticker.C():
+			summary := ld2451.summary.next(now)
+			summary.Sensor = ld2451.config.SensorID
+			ld2451.publish(summary)
+			select {
+			case ld2451.summaries <- summary:
+			default:
+			}
+		}
+	}
+}
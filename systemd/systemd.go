@@ -0,0 +1,95 @@
+// Package systemd keeps the systemd service watchdog satisfied for as long as
+// an LD2451 keeps delivering frames, so a hung sensor or a wedged serial
+// driver gets the whole service restarted.
+package systemd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// ErrNoWatchdog is returned by RunWatchdog when the service was not started
+// with WatchdogSec= or the watchdog is meant for another process.
+var ErrNoWatchdog = errors.New("systemd watchdog is not enabled for this process")
+
+// HealthReporter is implemented by *LD2451.LD2451.
+type HealthReporter interface {
+	Health() LD2451.Health
+}
+
+// Notify sends a state string such as "READY=1" or "WATCHDOG=1" to the service
+// manager. It does nothing when the process was not started by systemd.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	//a leading @ refers to the abstract socket namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns the watchdog timeout configured for this process.
+func WatchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond, true
+}
+
+// RunWatchdog pings the watchdog at half the configured timeout, but only while
+// the sensor is reporting or being configured and its last valid frame is
+// younger than maxFrameAge (the watchdog timeout if zero). The pings are timed
+// by clock, the one of the sensor, nil uses LD2451.SystemClock. It returns
+// when ctx is done.
+func RunWatchdog(ctx context.Context, sensor HealthReporter, maxFrameAge time.Duration, clock LD2451.Clock) error {
+	timeout, ok := WatchdogInterval()
+	if !ok {
+		return ErrNoWatchdog
+	}
+	if maxFrameAge <= 0 {
+		maxFrameAge = timeout
+	}
+	if clock == nil {
+		clock = LD2451.SystemClock
+	}
+
+	ticker := clock.NewTicker(timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+			health := sensor.Health()
+			alive := health.State == LD2451.StateReporting || health.State == LD2451.StateConfiguring
+			if !alive || health.SinceLastFrame > maxFrameAge {
+				//let the watchdog expire
+				continue
+			}
+			if err := Notify("WATCHDOG=1"); err != nil {
+				return err
+			}
+		}
+	}
+}
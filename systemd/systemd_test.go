@@ -0,0 +1,94 @@
+package systemd_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+	"github.com/Battlekeeper/LD2451/v2/systemd"
+)
+
+type health struct {
+	mu     sync.Mutex
+	health LD2451.Health
+}
+
+func (h *health) Health() LD2451.Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.health
+}
+
+func (h *health) set(state LD2451.SensorState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.State = state
+}
+
+func TestRunWatchdog(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "2000000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	sensor := &health{health: LD2451.Health{State: LD2451.StateReporting}}
+	clock := sensortest.NewClock(time.Unix(0, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- systemd.RunWatchdog(ctx, sensor, 0, clock) }()
+
+	//pinged at half the timeout, the ticker may not exist right away
+	buf := make([]byte, 64)
+	for attempt := 0; ; attempt++ {
+		if attempt == 40 {
+			t.Fatal("no watchdog ping")
+		}
+		clock.Advance(time.Second)
+		conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		n, err := conn.Read(buf)
+		if err == nil {
+			if string(buf[:n]) != "WATCHDOG=1" {
+				t.Fatalf("got %q", buf[:n])
+			}
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("RunWatchdog returned %v", err)
+		default:
+		}
+	}
+
+	sensor.set(LD2451.StateDisconnected)
+	//a ping may have been on the way when the state changed
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	conn.Read(buf)
+	clock.Advance(5 * time.Second)
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := conn.Read(buf); err == nil {
+		t.Errorf("pinged %q for a disconnected sensor", buf[:n])
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("got %v, expected context.Canceled", err)
+	}
+}
+
+func TestRunWatchdogDisabled(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	err := systemd.RunWatchdog(context.Background(), &health{}, 0, nil)
+	if err != systemd.ErrNoWatchdog {
+		t.Errorf("got %v, expected ErrNoWatchdog", err)
+	}
+}
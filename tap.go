@@ -0,0 +1,94 @@
+package LD2451
+
+import (
+	"io"
+	"sync"
+
+	"github.com/Battlekeeper/LD2451/v2/transport"
+)
+
+// tap copies the bytes passing through a port to Config.Tap. It is shared by
+// every port opened for a sensor, so traffic stays in order across
+// reconnects.
+type tap struct {
+	mu     sync.Mutex
+	w      io.Writer
+	logger Logger
+	failed bool
+}
+
+func newTap(config Config) *tap {
+	if config.Tap == nil {
+		return nil
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	return &tap{w: config.Tap, logger: logger}
+}
+
+func (t *tap) write(data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failed || len(data) == 0 {
+		return
+	}
+	if _, err := t.w.Write(data); err != nil {
+		//a broken capture must not take the sensor down with it
+		t.failed = true
+		t.logger.Warn("tap failed, no longer copying traffic", "error", err)
+	}
+}
+
+// port wraps port so its traffic is copied, a nil tap returns port unchanged.
+func (t *tap) port(port transport.Port) transport.Port {
+	if t == nil {
+		return port
+	}
+	return &tappedPort{Port: port, tap: t, name: portName(Config{}, port)}
+}
+
+type tappedPort struct {
+	transport.Port
+	tap  *tap
+	name string
+}
+
+func (p *tappedPort) Read(b []byte) (int, error) {
+	n, err := p.Port.Read(b)
+	p.tap.write(b[:n])
+	return n, err
+}
+
+func (p *tappedPort) Write(b []byte) (int, error) {
+	n, err := p.Port.Write(b)
+	p.tap.write(b[:n])
+	return n, err
+}
+
+// Name keeps the port name visible to errors.
+func (p *tappedPort) Name() string {
+	return p.name
+}
+
+func (p *tappedPort) SetDTR(asserted bool) error {
+	if lines, ok := p.Port.(transport.ModemLines); ok {
+		return lines.SetDTR(asserted)
+	}
+	return transport.ErrModemUnsupported
+}
+
+func (p *tappedPort) SetRTS(asserted bool) error {
+	if lines, ok := p.Port.(transport.ModemLines); ok {
+		return lines.SetRTS(asserted)
+	}
+	return transport.ErrModemUnsupported
+}
+
+func (p *tappedPort) Flush() error {
+	if flusher, ok := p.Port.(transport.Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
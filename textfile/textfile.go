@@ -0,0 +1,196 @@
+// Package textfile periodically writes the counters and health of an LD2451
+// to a file in the text exposition format, for node_exporter's textfile
+// collector to pick up. No HTTP server is involved:
+//
+//	node_exporter --collector.textfile.directory=/var/lib/node_exporter
+//
+//	go textfile.New(sensor, textfile.Config{Path: "/var/lib/node_exporter/ld2451.prom"}).Run(ctx)
+package textfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const DefaultInterval = 15 * time.Second
+
+// Source is implemented by *LD2451.LD2451.
+type Source interface {
+	Stats() LD2451.Stats
+	Health() LD2451.Health
+}
+
+type Config struct {
+	Path     string            // File to write, its directory must be the collector's directory and the name must end in .prom
+	Interval time.Duration     // How often the file is rewritten (default DefaultInterval)
+	Labels   map[string]string // Labels added to every sample, e.g. to tell several sensors on one host apart
+	Clock    LD2451.Clock      // Source of time for Interval, the one of the sensor; nil uses LD2451.SystemClock
+}
+
+type Writer struct {
+	source Source
+	config Config
+	labels string
+}
+
+func New(source Source, config Config) *Writer {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+	return &Writer{source: source, config: config, labels: formatLabels(config.Labels)}
+}
+
+// Run rewrites the file every Config.Interval until ctx is done or writing fails.
+func (w *Writer) Run(ctx context.Context) error {
+	ticker := w.config.Clock.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.WriteFile(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+// WriteFile writes the current metrics to Config.Path. The file is replaced
+// atomically so the collector never reads a partial file.
+func (w *Writer) WriteFile() error {
+	var b bytes.Buffer
+	if err := w.Encode(&b); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(w.config.Path), "."+filepath.Base(w.config.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	//the collector runs as another user in most setups
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), w.config.Path)
+}
+
+// Encode writes the current metrics to out, terminated by "# EOF".
+func (w *Writer) Encode(out io.Writer) error {
+	stats := w.source.Stats()
+	health := w.source.Health()
+
+	var b bytes.Buffer
+	counters := []struct {
+		name  string
+		help  string
+		value uint64
+	}{
+		{"frames", "Valid data frames received.", stats.Frames},
+		{"targets", "Targets delivered.", stats.Targets},
+		{"resyncs", "Times the reader lost frame alignment.", stats.Resyncs},
+		{"discarded_bytes", "Bytes skipped while resynchronizing.", stats.DiscardedBytes},
+		{"parse_errors", "Delimited frames whose payload could not be decoded.", stats.ParseErrors},
+		{"oversized_frames", "Headers rejected for an implausible length.", stats.OversizedFrames},
+		{"read_errors", "Transport errors returned by the port.", stats.ReadErrors},
+		{"reconnects", "Times the port was reopened after a transport error.", stats.Reconnects},
+		{"dropped_errors", "Errors dropped because the error channel was full.", stats.DroppedErrors},
+		{"stale_targets", "Buffered targets discarded for their age.", stats.StaleTargets},
+		{"dropped_targets", "Target deliveries dropped because a channel was full.", stats.DroppedTargets},
+		{"filtered_targets", "Targets withheld by filters.", stats.FilteredTargets},
+		{"throttled_frames", "Frames held back by the report interval.", stats.ThrottledFrames},
+		{"truncated_frames", "Frames cut down to the maximum number of targets.", stats.TruncatedFrames},
+		{"dropped_events", "Events dropped because an event channel was full.", stats.DroppedEvents},
+		{"frame_gaps", "Gaps between frames longer than expected.", stats.FrameGaps},
+		{"sink_errors", "Failed sink writes and flushes.", stats.SinkErrors},
+		{"invalid_targets", "Targets dropped for out of range values.", stats.InvalidTargets},
+		{"clamped_targets", "Targets clamped into range.", stats.ClampedTargets},
+		{"frames_at_limit", "Frames reporting the most targets the module can report.", stats.FramesAtLimit},
+		{"salvaged_frames", "Malformed frames partially decoded in lenient parse mode.", stats.SalvagedFrames},
+		{"flushed_targets", "Buffered targets discarded by a flush.", stats.FlushedTargets},
+		{"paused_frames", "Frames whose targets were dropped while paused.", stats.PausedFrames},
+		{"unknown_frames", "Command frames that were no acknowledgement of a known command.", stats.UnknownFrames},
+		{"parse_recoveries", "Times the input was flushed after frames kept failing to decode.", stats.ParseRecoveries},
+		{"target_drop_bursts", "Bursts of target deliveries dropped because a consumer did not keep up.", stats.TargetDropBursts},
+		{"snapshot_evictions", "Targets evicted from the current targets for exceeding the snapshot limit.", stats.SnapshotEvictions},
+		{"dropped_frames", "Frames dropped because a frame subscription channel was full.", stats.DroppedFrames},
+	}
+	for _, c := range counters {
+		name := "ld2451_" + c.name + "_total"
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n%s%s %d\n", name, c.help, name, name, w.labels, c.value)
+	}
+
+	fmt.Fprintf(&b, "# HELP ld2451_state Current state of the sensor, 1 for the active state.\n# TYPE ld2451_state gauge\n")
+	for state := LD2451.StateConnecting; state <= LD2451.StateStandby; state++ {
+		value := 0
+		if state == health.State {
+			value = 1
+		}
+		fmt.Fprintf(&b, "ld2451_state%s %d\n", w.withLabel("state", strings.ToLower(state.String())), value)
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value time.Duration
+	}{
+		{"since_last_frame_seconds", "Time since the last valid frame, or since the port was opened.", health.SinceLastFrame},
+		{"since_last_target_seconds", "Time since the last target, or since the port was opened.", health.SinceLastTarget},
+	}
+	for _, g := range gauges {
+		name := "ld2451_" + g.name
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s%s %s\n", name, g.help, name, name, w.labels,
+			strconv.FormatFloat(g.value.Seconds(), 'f', 3, 64))
+	}
+
+	b.WriteString("# EOF\n")
+	_, err := out.Write(b.Bytes())
+	return err
+}
+
+// withLabel returns the configured labels with one more label added.
+func (w *Writer) withLabel(name, value string) string {
+	labels := make(map[string]string, len(w.config.Labels)+1)
+	for k, v := range w.config.Labels {
+		labels[k] = v
+	}
+	labels[name] = value
+	return formatLabels(labels)
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(labels[name])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
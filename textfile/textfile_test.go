@@ -0,0 +1,123 @@
+package textfile_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/sensortest"
+	"github.com/Battlekeeper/LD2451/v2/textfile"
+)
+
+// source reports fixed health and frames counted up by tick.
+type source struct {
+	mu     sync.Mutex
+	frames uint64
+	health LD2451.Health
+}
+
+func (s *source) Stats() LD2451.Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return LD2451.Stats{Frames: s.frames, Targets: 7}
+}
+
+func (s *source) Health() LD2451.Health { return s.health }
+
+func (s *source) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frames++
+}
+
+func TestEncode(t *testing.T) {
+	s := &source{frames: 12, health: LD2451.Health{State: LD2451.StateReporting, SinceLastFrame: 50 * time.Millisecond}}
+	w := textfile.New(s, textfile.Config{Labels: map[string]string{"sensor": "north", "site": `main "st"`}})
+	var b bytes.Buffer
+	if err := w.Encode(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		"# TYPE ld2451_frames_total counter",
+		`ld2451_frames_total{sensor="north",site="main \"st\""} 12`,
+		`ld2451_targets_total{sensor="north",site="main \"st\""} 7`,
+		`ld2451_state{sensor="north",site="main \"st\"",state="reporting"} 1`,
+		`ld2451_state{sensor="north",site="main \"st\"",state="degraded"} 0`,
+		`ld2451_since_last_frame_seconds{sensor="north",site="main \"st\""} 0.050`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, out)
+		}
+	}
+	if !strings.HasSuffix(out, "\n# EOF\n") {
+		t.Error("not terminated by # EOF")
+	}
+
+	b.Reset()
+	textfile.New(s, textfile.Config{}).Encode(&b)
+	if !strings.Contains(b.String(), "\nld2451_frames_total 12\n") {
+		t.Errorf("unlabeled sample missing in\n%s", b.String())
+	}
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ld2451.prom")
+	s := &source{}
+	clock := sensortest.NewClock(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	w := textfile.New(s, textfile.Config{Path: path, Interval: time.Minute, Clock: clock})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	//the file is written at once and again every interval
+	wait := func(expected string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), expected) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%q never written, file holds\n%s", expected, data)
+			}
+			clock.Advance(time.Minute)
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wait("\nld2451_frames_total 0\n")
+	s.tick()
+	wait("\nld2451_frames_total 1\n")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("file mode %v, expected readable by the collector", info.Mode())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("left %d files behind", len(entries))
+	}
+}
+
+func TestRunFailsOnAMissingDirectory(t *testing.T) {
+	w := textfile.New(&source{}, textfile.Config{Path: filepath.Join(t.TempDir(), "missing", "ld2451.prom")})
+	if err := w.Run(context.Background()); err == nil {
+		t.Error("Run kept going without a directory to write to")
+	}
+}
@@ -0,0 +1,115 @@
+package tracking
+
+import (
+	"fmt"
+	"time"
+)
+
+const DefaultAccelerationWindow = 500 * time.Millisecond
+
+type AlertKind int
+
+const (
+	HardBraking       AlertKind = 0 // Deceleration beyond Config.BrakingLimit
+	RapidAcceleration AlertKind = 1 // Acceleration beyond Config.AccelerationLimit
+)
+
+func (k AlertKind) String() string {
+	switch k {
+	case HardBraking:
+		return "HardBraking"
+	case RapidAcceleration:
+		return "RapidAcceleration"
+	default:
+		return fmt.Sprintf("AlertKind(%d)", int(k))
+	}
+}
+
+func (k AlertKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// AccelerationAlert is a track braking or accelerating harder than the
+// configured limit. A track raises an alert of each kind again only after
+// its acceleration came back within the limit.
+type AccelerationAlert struct {
+	Track        uint64    `json:"track"`
+	Kind         AlertKind `json:"kind"`
+	Acceleration float64   `json:"acceleration"` // m/s², negative when braking
+	Speed        int       `json:"speed"`        // KM/H when the limit was exceeded
+	Distance     int       `json:"distance"`
+	Time         time.Time `json:"time"`
+}
+
+func (a AccelerationAlert) EventTime() time.Time {
+	return a.Time
+}
+
+type speedSample struct {
+	time  time.Time
+	speed float64 //m/s
+}
+
+// AccelerationAlerts returns the alerts raised since the last call, in order.
+func (t *Tracker) AccelerationAlerts() []AccelerationAlert {
+	alerts := t.alerts
+	t.alerts = nil
+	return alerts
+}
+
+// accelerate estimates the acceleration of track from the speeds of the last
+// Config.AccelerationWindow and raises alerts. Speeds are reported in whole
+// KM/H, so the slope is fitted over the window rather than taken between two
+// frames, where a single step would already read like hard braking.
+func (t *Tracker) accelerate(track *Track) {
+	window := t.config.AccelerationWindow
+	track.speeds = append(track.speeds, speedSample{track.End, float64(track.Speed) / 3.6})
+	i := 0
+	for i < len(track.speeds) && track.End.Sub(track.speeds[i].time) > window {
+		i++
+	}
+	track.speeds = track.speeds[i:]
+	if len(track.speeds) < 3 || track.End.Sub(track.speeds[0].time) < window/2 {
+		return
+	}
+
+	//least squares slope of speed over time
+	origin := track.speeds[0].time
+	var sumT, sumV, sumTT, sumTV float64
+	for _, sample := range track.speeds {
+		x := sample.time.Sub(origin).Seconds()
+		sumT += x
+		sumV += sample.speed
+		sumTT += x * x
+		sumTV += x * sample.speed
+	}
+	n := float64(len(track.speeds))
+	denom := n*sumTT - sumT*sumT
+	if denom == 0 {
+		return
+	}
+	track.Acceleration = (n*sumTV - sumT*sumV) / denom
+	track.MaxAcceleration = max(track.MaxAcceleration, track.Acceleration)
+	track.MaxBraking = max(track.MaxBraking, -track.Acceleration)
+
+	if !t.confirmed(track) {
+		return
+	}
+	t.alert(track, HardBraking, t.config.BrakingLimit > 0 && -track.Acceleration > t.config.BrakingLimit, &track.braking)
+	t.alert(track, RapidAcceleration, t.config.AccelerationLimit > 0 && track.Acceleration > t.config.AccelerationLimit, &track.accelerating)
+}
+
+// alert raises an alert of kind when exceeded starts to hold for track.
+func (t *Tracker) alert(track *Track, kind AlertKind, exceeded bool, raised *bool) {
+	if exceeded && !*raised {
+		t.alerts = append(t.alerts, AccelerationAlert{
+			Track:        track.ID,
+			Kind:         kind,
+			Acceleration: track.Acceleration,
+			Speed:        track.Speed,
+			Distance:     track.Distance,
+			Time:         track.End,
+		})
+	}
+	*raised = exceeded
+}
@@ -0,0 +1,93 @@
+package tracking
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// ramp returns speeds in KM/H starting at from and changing by step every frame.
+func ramp(from, step, frames int) []int {
+	speeds := make([]int, frames)
+	for i := range speeds {
+		speeds[i] = from + i*step
+	}
+	return speeds
+}
+
+func TestAccelerationAlerts(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		speeds       []int
+		alerts       []AlertKind
+		acceleration float64 // latest estimate in m/s²
+	}{
+		{"constant speed", Config{BrakingLimit: 5, AccelerationLimit: 5}, ramp(60, 0, 10), nil, 0},
+		{"hard braking", Config{BrakingLimit: 5}, ramp(80, -4, 10), []AlertKind{HardBraking}, -4 / 3.6 * 10},
+		{"braking without a limit", Config{}, ramp(80, -4, 10), nil, -4 / 3.6 * 10},
+		{"braking within the limit", Config{BrakingLimit: 5}, ramp(80, -1, 10), nil, -1 / 3.6 * 10},
+		{"rapid acceleration", Config{AccelerationLimit: 5}, ramp(20, 4, 10), []AlertKind{RapidAcceleration}, 4 / 3.6 * 10},
+		{"acceleration checked against its own limit", Config{BrakingLimit: 5}, ramp(20, 4, 10), nil, 4 / 3.6 * 10},
+		{
+			"braking twice",
+			Config{BrakingLimit: 5},
+			append(append(ramp(80, -4, 8), ramp(52, 0, 8)...), ramp(48, -4, 8)...),
+			[]AlertKind{HardBraking, HardBraking},
+			-4 / 3.6 * 10,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.Gate = 100
+			tracker, err := NewTracker(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			targets := make([]LD2451.Target, len(test.speeds))
+			for i, speed := range test.speeds {
+				targets[i] = LD2451.Target{Distance: 80 - i, Direction: LD2451.DirectionToward, Speed: speed, SNR: 8}
+			}
+			feed(tracker, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), targets...)
+
+			alerts := tracker.AccelerationAlerts()
+			if len(alerts) != len(test.alerts) {
+				t.Fatalf("got alerts %+v, expected %v", alerts, test.alerts)
+			}
+			for i, alert := range alerts {
+				if alert.Kind != test.alerts[i] || alert.Track != 1 {
+					t.Errorf("alert %d is %+v, expected %s", i, alert, test.alerts[i])
+				}
+			}
+			active := tracker.Active()
+			if len(active) != 1 {
+				t.Fatalf("got %d tracks, expected 1", len(active))
+			}
+			if math.Abs(active[0].Acceleration-test.acceleration) > 1e-6 {
+				t.Errorf("acceleration %g m/s², expected %g", active[0].Acceleration, test.acceleration)
+			}
+		})
+	}
+}
+
+func TestAccelerationNeedsHalfAWindow(t *testing.T) {
+	tracker, err := NewTracker(Config{BrakingLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//three frames span 200 ms, less than half the default window
+	feed(tracker, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		LD2451.Target{Distance: 50, Direction: LD2451.DirectionToward, Speed: 80},
+		LD2451.Target{Distance: 49, Direction: LD2451.DirectionToward, Speed: 70},
+		LD2451.Target{Distance: 48, Direction: LD2451.DirectionToward, Speed: 60},
+	)
+	if alerts := tracker.AccelerationAlerts(); len(alerts) != 0 {
+		t.Errorf("alerts %+v raised from too few speeds", alerts)
+	}
+	if track := tracker.Active()[0]; track.Acceleration != 0 || track.MaxBraking != 0 {
+		t.Errorf("estimated %+v", track)
+	}
+}
@@ -0,0 +1,79 @@
+package tracking
+
+import (
+	"fmt"
+	"time"
+)
+
+// Band is a named distance range from Min up to but excluding Max meters,
+// e.g. {"Critical", 0, 10}, {"Near", 10, 30} and {"Far", 30, 50}.
+type Band struct {
+	Name string `json:"name"`
+	Min  int    `json:"min"`
+	Max  int    `json:"max"`
+}
+
+// BandChange is a track moving from one distance band into another. An empty
+// band name means outside of all bands.
+type BandChange struct {
+	Track uint64    `json:"track"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Time  time.Time `json:"time"`
+}
+
+func (c BandChange) EventTime() time.Time {
+	return c.Time
+}
+
+func validateBands(bands []Band) error {
+	for i, band := range bands {
+		if band.Min >= band.Max {
+			return fmt.Errorf("distance band %q from %d m to %d m is empty", band.Name, band.Min, band.Max)
+		}
+		for _, other := range bands[:i] {
+			if band.Min < other.Max && other.Min < band.Max {
+				return fmt.Errorf("distance bands %q and %q overlap", other.Name, band.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// band returns the name of the band distance falls into.
+func (t *Tracker) band(distance int) string {
+	for _, band := range t.config.Bands {
+		if distance >= band.Min && distance < band.Max {
+			return band.Name
+		}
+	}
+	return ""
+}
+
+// BandChanges returns the band changes since the last call, in order. A
+// track entering a band is reported when it starts, one leaving all bands
+// when it ends.
+func (t *Tracker) BandChanges() []BandChange {
+	changes := t.changes
+	t.changes = nil
+	return changes
+}
+
+// updateBand records a band change of track. An ended track leaves its band
+// but keeps reporting it as its last one.
+func (t *Tracker) updateBand(track *Track, ended bool) {
+	if len(t.config.Bands) == 0 || !t.confirmed(track) {
+		return
+	}
+	band := ""
+	if !ended {
+		band = t.band(track.Distance)
+	}
+	if band == track.Band {
+		return
+	}
+	t.changes = append(t.changes, BandChange{Track: track.ID, From: track.Band, To: band, Time: track.End})
+	if !ended {
+		track.Band = band
+	}
+}
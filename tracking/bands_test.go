@@ -0,0 +1,98 @@
+package tracking
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+var bands = []Band{{"Critical", 0, 10}, {"Near", 10, 30}, {"Far", 30, 50}}
+
+func TestValidateBands(t *testing.T) {
+	tests := []struct {
+		name  string
+		bands []Band
+		valid bool
+	}{
+		{"none", nil, true},
+		{"adjacent", bands, true},
+		{"with a gap", []Band{{"Near", 0, 10}, {"Far", 20, 30}}, true},
+		{"empty", []Band{{"Near", 10, 10}}, false},
+		{"inverted", []Band{{"Near", 20, 10}}, false},
+		{"overlapping", []Band{{"Near", 0, 20}, {"Far", 19, 30}}, false},
+		{"contained", []Band{{"Near", 0, 50}, {"Far", 10, 20}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTracker(Config{Bands: test.bands})
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, expected valid %t", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestBandChanges(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * 100 * time.Millisecond) }
+	tests := []struct {
+		name          string
+		distances     []int
+		minDetections int
+		changes       []BandChange
+	}{
+		{
+			"approaching through every band",
+			[]int{55, 50, 45, 30, 29, 26, 22, 18, 14, 10, 9},
+			0,
+			[]BandChange{
+				{Track: 1, From: "", To: "Far", Time: at(2)},
+				{Track: 1, From: "Far", To: "Near", Time: at(4)},
+				{Track: 1, From: "Near", To: "Critical", Time: at(10)},
+				{Track: 1, From: "Critical", To: "", Time: at(10)},
+			},
+		},
+		{
+			"starting in a band",
+			[]int{20, 18},
+			0,
+			[]BandChange{{Track: 1, From: "", To: "Near", Time: at(0)}, {Track: 1, From: "Near", To: "", Time: at(1)}},
+		},
+		{
+			"reported once confirmed",
+			[]int{35, 32, 29},
+			2,
+			[]BandChange{
+				{Track: 1, From: "", To: "Far", Time: at(1)},
+				{Track: 1, From: "Far", To: "Near", Time: at(2)},
+				{Track: 1, From: "Near", To: "", Time: at(2)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker, err := NewTracker(Config{Bands: bands, MinDetections: test.minDetections, Gate: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			targets := make([]LD2451.Target, len(test.distances))
+			for i, distance := range test.distances {
+				targets[i] = LD2451.Target{Distance: distance, Direction: LD2451.DirectionToward, Speed: 50}
+			}
+			feed(tracker, start, targets...)
+			ended := tracker.Flush()
+			changes := tracker.BandChanges()
+			if !slices.Equal(changes, test.changes) {
+				t.Errorf("got %+v, expected %+v", changes, test.changes)
+			}
+			if len(ended) != 1 || ended[0].Band != test.changes[len(test.changes)-1].From {
+				t.Errorf("ended %+v, expected the last band to be kept", ended)
+			}
+			if again := tracker.BandChanges(); len(again) != 0 {
+				t.Errorf("changes reported twice: %+v", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package tracking
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Class int
+
+const (
+	ClassUnknown Class = 0
+	ClassBicycle Class = 1
+	ClassCar     Class = 2
+	ClassTruck   Class = 3
+)
+
+const (
+	DefaultBicycleMaxSpeed  = 35
+	DefaultBicycleMaxSNR    = 6
+	DefaultTruckMinSNR      = 12
+	DefaultTruckMinDuration = 2 * time.Second
+	DefaultMinDetections    = 3
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassUnknown:
+		return "Unknown"
+	case ClassBicycle:
+		return "Bicycle"
+	case ClassCar:
+		return "Car"
+	case ClassTruck:
+		return "Truck"
+	default:
+		return "Unknown"
+	}
+}
+
+func (c Class) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(c.String())), nil
+}
+
+func (c *Class) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "unknown":
+		*c = ClassUnknown
+	case "bicycle":
+		*c = ClassBicycle
+	case "car":
+		*c = ClassCar
+	case "truck":
+		*c = ClassTruck
+	default:
+		return fmt.Errorf("unknown vehicle class %q", text)
+	}
+	return nil
+}
+
+// Classifier labels tracks with a vehicle class. Larger vehicles reflect more
+// energy and stay in the beam for longer, so the class is derived from the
+// peak SNR, the observed duration and the speed of a track. The defaults
+// suit a sensor at the roadside and should be tuned per installation.
+type Classifier struct {
+	MinDetections    int           // Tracks with fewer detections are ClassUnknown (default DefaultMinDetections)
+	BicycleMaxSpeed  int           // Slow tracks up to this speed in KM/H may be bicycles (default DefaultBicycleMaxSpeed)
+	BicycleMaxSNR    int           // ... if their peak SNR stays at or below this (default DefaultBicycleMaxSNR)
+	TruckMinSNR      int           // Tracks reaching this peak SNR may be trucks (default DefaultTruckMinSNR)
+	TruckMinDuration time.Duration // ... if they were also observed for at least this long (default DefaultTruckMinDuration)
+}
+
+func (c *Classifier) Classify(track Track) Class {
+	if track.Detections < or(c.MinDetections, DefaultMinDetections) {
+		return ClassUnknown
+	}
+	if track.MaxSpeed <= or(c.BicycleMaxSpeed, DefaultBicycleMaxSpeed) && track.MaxSNR <= or(c.BicycleMaxSNR, DefaultBicycleMaxSNR) {
+		return ClassBicycle
+	}
+	if track.MaxSNR >= or(c.TruckMinSNR, DefaultTruckMinSNR) && track.Duration() >= or(c.TruckMinDuration, DefaultTruckMinDuration) {
+		return ClassTruck
+	}
+	return ClassCar
+}
+
+// or returns value, or fallback when value is not set.
+func or[T int | time.Duration](value, fallback T) T {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,82 @@
+package tracking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestClassify(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	track := func(detections, maxSpeed, maxSNR int, duration time.Duration) Track {
+		return Track{Detections: detections, MaxSpeed: maxSpeed, MaxSNR: maxSNR, Start: start, End: start.Add(duration)}
+	}
+	tests := []struct {
+		name       string
+		classifier Classifier
+		track      Track
+		class      Class
+	}{
+		{"too few detections", Classifier{}, track(2, 50, 8, time.Second), ClassUnknown},
+		{"fewer detections required", Classifier{MinDetections: 2}, track(2, 50, 8, time.Second), ClassCar},
+		{"slow and weak", Classifier{}, track(5, 20, 4, time.Second), ClassBicycle},
+		{"at the bicycle limits", Classifier{}, track(5, DefaultBicycleMaxSpeed, DefaultBicycleMaxSNR, time.Second), ClassBicycle},
+		{"slow but strong", Classifier{}, track(5, 20, 7, time.Second), ClassCar},
+		{"weak but fast", Classifier{}, track(5, 36, 4, time.Second), ClassCar},
+		{"car", Classifier{}, track(5, 60, 9, time.Second), ClassCar},
+		{"strong and long", Classifier{}, track(5, 60, DefaultTruckMinSNR, DefaultTruckMinDuration), ClassTruck},
+		{"strong but short", Classifier{}, track(5, 60, 20, time.Second), ClassCar},
+		{"long but weak", Classifier{}, track(5, 60, 9, 5*time.Second), ClassCar},
+		{"tuned truck limits", Classifier{TruckMinSNR: 30, TruckMinDuration: time.Second}, track(5, 60, 20, time.Second), ClassCar},
+		{"tuned bicycle limits", Classifier{BicycleMaxSpeed: 25, BicycleMaxSNR: 10}, track(5, 30, 8, time.Second), ClassCar},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if class := test.classifier.Classify(test.track); class != test.class {
+				t.Errorf("got %s, expected %s", class, test.class)
+			}
+		})
+	}
+}
+
+func TestClassText(t *testing.T) {
+	for _, class := range []Class{ClassUnknown, ClassBicycle, ClassCar, ClassTruck} {
+		text, err := class.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Class
+		if err := decoded.UnmarshalText(text); err != nil || decoded != class {
+			t.Errorf("%s: decoded %q as %s, %v", class, text, decoded, err)
+		}
+	}
+	var class Class
+	if err := class.UnmarshalText([]byte("tractor")); err == nil {
+		t.Error("decoded an unknown class")
+	}
+}
+
+func TestTrackerClassifiesEndedTracks(t *testing.T) {
+	tracker, err := NewTracker(Config{Classifier: &Classifier{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	feed(tracker, start,
+		LD2451.Target{Distance: 30, Direction: LD2451.DirectionToward, Speed: 60, SNR: 9},
+		LD2451.Target{Distance: 29, Direction: LD2451.DirectionToward, Speed: 60, SNR: 9},
+		LD2451.Target{Distance: 27, Direction: LD2451.DirectionToward, Speed: 62, SNR: 10},
+	)
+	if active := tracker.Active(); len(active) != 1 || active[0].Class != ClassUnknown {
+		t.Fatalf("active tracks %+v, expected one unclassified track", active)
+	}
+	//not detected for longer than the timeout
+	ended := tracker.Expire(start.Add(200*time.Millisecond + DefaultTimeout + time.Millisecond))
+	if len(ended) != 1 {
+		t.Fatalf("%d tracks ended, expected 1", len(ended))
+	}
+	if track := ended[0]; track.Class != ClassCar || track.Detections != 3 || track.MaxSpeed != 62 || track.EntryDistance != 30 {
+		t.Errorf("ended track %+v", track)
+	}
+}
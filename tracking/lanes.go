@@ -0,0 +1,91 @@
+package tracking
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Lanes splits the field of view into lanes by angle. Lanes are numbered from
+// 1 starting at the most negative angle, lane 0 means no lane was assigned.
+type Lanes struct {
+	Count      int   // Number of lanes
+	Boundaries []int // Count-1 increasing angles separating neighbouring lanes
+}
+
+func (l Lanes) validate() error {
+	if l.Count < 1 {
+		return fmt.Errorf("lane count %d must be at least 1", l.Count)
+	}
+	if len(l.Boundaries) != l.Count-1 {
+		return fmt.Errorf("%d lanes need %d boundaries, got %d", l.Count, l.Count-1, len(l.Boundaries))
+	}
+	for i := 1; i < len(l.Boundaries); i++ {
+		if l.Boundaries[i] <= l.Boundaries[i-1] {
+			return fmt.Errorf("lane boundaries %v are not increasing", l.Boundaries)
+		}
+	}
+	return nil
+}
+
+// Lane returns the lane a detection at angle belongs to. An angle on a
+// boundary belongs to the lane with the larger angles.
+func (l Lanes) Lane(angle int) int {
+	lane, _ := slices.BinarySearch(l.Boundaries, angle+1)
+	return lane + 1
+}
+
+// LaneStats summarizes the tracks counted in one lane.
+type LaneStats struct {
+	Lane      int     `json:"lane"`
+	Count     int     `json:"count"`
+	MeanSpeed float64 `json:"mean_speed"`
+	MaxSpeed  int     `json:"max_speed"`
+}
+
+// LaneCounter accumulates per lane statistics of ended tracks. It is safe
+// for concurrent use.
+type LaneCounter struct {
+	mu    sync.Mutex
+	lanes []LaneStats
+}
+
+func NewLaneCounter(lanes Lanes) (*LaneCounter, error) {
+	if err := lanes.validate(); err != nil {
+		return nil, err
+	}
+	c := &LaneCounter{lanes: make([]LaneStats, lanes.Count)}
+	for i := range c.lanes {
+		c.lanes[i].Lane = i + 1
+	}
+	return c, nil
+}
+
+// Add counts track in its lane. Tracks without a lane are ignored.
+func (c *LaneCounter) Add(track Track) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if track.Lane < 1 || track.Lane > len(c.lanes) {
+		return
+	}
+	s := &c.lanes[track.Lane-1]
+	s.MeanSpeed = (s.MeanSpeed*float64(s.Count) + track.MeanSpeed) / float64(s.Count+1)
+	s.MaxSpeed = max(s.MaxSpeed, track.MaxSpeed)
+	s.Count++
+}
+
+// Stats returns the statistics of every lane, ordered by lane.
+func (c *LaneCounter) Stats() []LaneStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.lanes)
+}
+
+// Reset clears all counts, e.g. at the start of a new reporting period.
+func (c *LaneCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.lanes {
+		c.lanes[i] = LaneStats{Lane: i + 1}
+	}
+}
@@ -0,0 +1,108 @@
+package tracking
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestLane(t *testing.T) {
+	lanes := Lanes{Count: 3, Boundaries: []int{-10, 15}}
+	tests := []struct {
+		angle int
+		lane  int
+	}{
+		{-60, 1},
+		{-11, 1},
+		{-10, 2}, // on a boundary, the lane with the larger angles
+		{0, 2},
+		{14, 2},
+		{15, 3},
+		{60, 3},
+	}
+	for _, test := range tests {
+		if lane := lanes.Lane(test.angle); lane != test.lane {
+			t.Errorf("angle %d: got lane %d, expected %d", test.angle, lane, test.lane)
+		}
+	}
+	if lane := (Lanes{Count: 1}).Lane(30); lane != 1 {
+		t.Errorf("single lane: got lane %d", lane)
+	}
+}
+
+func TestLanesValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		lanes Lanes
+		valid bool
+	}{
+		{"one lane", Lanes{Count: 1}, true},
+		{"two lanes", Lanes{Count: 2, Boundaries: []int{0}}, true},
+		{"no lanes", Lanes{}, false},
+		{"missing boundary", Lanes{Count: 3, Boundaries: []int{0}}, false},
+		{"extra boundary", Lanes{Count: 2, Boundaries: []int{-5, 5}}, false},
+		{"decreasing boundaries", Lanes{Count: 3, Boundaries: []int{5, -5}}, false},
+		{"equal boundaries", Lanes{Count: 3, Boundaries: []int{5, 5}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTracker(Config{Lanes: &test.lanes})
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, expected valid %t", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestTrackLaneByMajority(t *testing.T) {
+	tests := []struct {
+		name   string
+		angles []int
+		lane   int
+	}{
+		{"one lane throughout", []int{-20, -25, -18}, 1},
+		{"drifting into the other lane", []int{-5, 5, 8, 10}, 2},
+		{"a stray detection", []int{-5, 5, -8, -10}, 1},
+		{"a tie keeps the first lane", []int{-5, 5}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker, err := NewTracker(Config{Lanes: &Lanes{Count: 2, Boundaries: []int{0}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			targets := make([]LD2451.Target, len(test.angles))
+			for i, angle := range test.angles {
+				targets[i] = LD2451.Target{Angle: angle, Distance: 40 - i, Direction: LD2451.DirectionToward, Speed: 50, SNR: 8}
+			}
+			feed(tracker, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), targets...)
+			ended := tracker.Flush()
+			if len(ended) != 1 || ended[0].Lane != test.lane {
+				t.Errorf("got %+v, expected one track in lane %d", ended, test.lane)
+			}
+		})
+	}
+}
+
+func TestLaneCounter(t *testing.T) {
+	counter, err := NewLaneCounter(Lanes{Count: 2, Boundaries: []int{0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Add(Track{Lane: 1, MeanSpeed: 40, MaxSpeed: 45})
+	counter.Add(Track{Lane: 1, MeanSpeed: 60, MaxSpeed: 70})
+	counter.Add(Track{Lane: 2, MeanSpeed: 30, MaxSpeed: 30})
+	counter.Add(Track{Lane: 0, MeanSpeed: 90, MaxSpeed: 90})
+	counter.Add(Track{Lane: 3, MeanSpeed: 90, MaxSpeed: 90})
+
+	expected := []LaneStats{{Lane: 1, Count: 2, MeanSpeed: 50, MaxSpeed: 70}, {Lane: 2, Count: 1, MeanSpeed: 30, MaxSpeed: 30}}
+	if stats := counter.Stats(); !slices.Equal(stats, expected) {
+		t.Errorf("got %+v, expected %+v", stats, expected)
+	}
+	counter.Reset()
+	if stats := counter.Stats(); !slices.Equal(stats, []LaneStats{{Lane: 1}, {Lane: 2}}) {
+		t.Errorf("after Reset got %+v", stats)
+	}
+}
@@ -0,0 +1,65 @@
+package tracking
+
+import "github.com/Battlekeeper/LD2451/v2"
+
+const (
+	DefaultMergeAngle = 5
+	DefaultMergeSpeed = 5
+)
+
+// merge combines the detections of one frame that are close enough to be
+// parts of one vehicle, such as the cab and trailer of a truck, into the part
+// with the highest SNR. Detections are merged when they move the same way and
+// are within the Config.MergeDistance, MergeAngle and MergeSpeed of any other
+// part, so a long vehicle may span more than MergeDistance. parts holds the
+// number of detections merged into each returned target.
+func (t *Tracker) merge(targets []LD2451.Target) (merged []LD2451.Target, parts []int) {
+	if t.config.MergeDistance <= 0 || len(targets) < 2 {
+		parts = make([]int, len(targets))
+		for i := range parts {
+			parts[i] = 1
+		}
+		return targets, parts
+	}
+
+	//label every detection with the group of the first detection it is close to
+	group := make([]int, len(targets))
+	for i := range targets {
+		group[i] = i
+		for j := range i {
+			if group[j] == group[i] || !t.sameVehicle(targets[i], targets[j]) {
+				continue
+			}
+			//join the groups, keeping the lower label
+			from, to := max(group[i], group[j]), min(group[i], group[j])
+			for k := range i + 1 {
+				if group[k] == from {
+					group[k] = to
+				}
+			}
+		}
+	}
+
+	index := make(map[int]int, len(targets)) //group to its position in merged
+	for i, target := range targets {
+		n, ok := index[group[i]]
+		if !ok {
+			index[group[i]] = len(merged)
+			merged = append(merged, target)
+			parts = append(parts, 1)
+			continue
+		}
+		parts[n]++
+		if target.SNR > merged[n].SNR {
+			merged[n] = target
+		}
+	}
+	return merged, parts
+}
+
+func (t *Tracker) sameVehicle(a, b LD2451.Target) bool {
+	return a.Direction == b.Direction &&
+		abs(a.Distance-b.Distance) <= t.config.MergeDistance &&
+		abs(a.Angle-b.Angle) <= t.config.MergeAngle &&
+		abs(a.Speed-b.Speed) <= t.config.MergeSpeed
+}
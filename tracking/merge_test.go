@@ -0,0 +1,82 @@
+package tracking
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestMerge(t *testing.T) {
+	toward := func(distance, angle, speed, snr int) LD2451.Target {
+		return LD2451.Target{Distance: distance, Angle: angle, Direction: LD2451.DirectionToward, Speed: speed, SNR: snr}
+	}
+	cab, trailer := toward(30, 10, 60, 20), toward(34, 12, 61, 12)
+	tests := []struct {
+		name     string
+		distance int
+		targets  []LD2451.Target
+		merged   []LD2451.Target
+		parts    []int
+	}{
+		{"disabled", 0, []LD2451.Target{cab, trailer}, []LD2451.Target{cab, trailer}, []int{1, 1}},
+		{"single target", 5, []LD2451.Target{cab}, []LD2451.Target{cab}, []int{1}},
+		{"truck in two parts", 5, []LD2451.Target{trailer, cab}, []LD2451.Target{cab}, []int{2}},
+		{"too far apart", 3, []LD2451.Target{cab, trailer}, []LD2451.Target{cab, trailer}, []int{1, 1}},
+		{"different angles", 5, []LD2451.Target{cab, toward(34, 20, 61, 12)}, []LD2451.Target{cab, toward(34, 20, 61, 12)}, []int{1, 1}},
+		{"different speeds", 5, []LD2451.Target{cab, toward(34, 12, 70, 12)}, []LD2451.Target{cab, toward(34, 12, 70, 12)}, []int{1, 1}},
+		{
+			"opposite directions",
+			5,
+			[]LD2451.Target{cab, {Distance: 34, Angle: 12, Direction: LD2451.DirectionAway, Speed: 61, SNR: 12}},
+			[]LD2451.Target{cab, {Distance: 34, Angle: 12, Direction: LD2451.DirectionAway, Speed: 61, SNR: 12}},
+			[]int{1, 1},
+		},
+		{
+			//each part is close to the next, the ends are not
+			"chained parts",
+			5,
+			[]LD2451.Target{toward(30, 10, 60, 8), toward(38, 10, 60, 9), toward(34, 10, 60, 15)},
+			[]LD2451.Target{toward(34, 10, 60, 15)},
+			[]int{3},
+		},
+		{
+			"two vehicles",
+			5,
+			[]LD2451.Target{cab, toward(60, -20, 40, 9), trailer, toward(62, -18, 41, 11)},
+			[]LD2451.Target{cab, toward(62, -18, 41, 11)},
+			[]int{2, 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker, err := NewTracker(Config{MergeDistance: test.distance})
+			if err != nil {
+				t.Fatal(err)
+			}
+			merged, parts := tracker.merge(test.targets)
+			if !slices.Equal(merged, test.merged) || !slices.Equal(parts, test.parts) {
+				t.Errorf("got %+v in %v parts, expected %+v in %v parts", merged, parts, test.merged, test.parts)
+			}
+		})
+	}
+}
+
+func TestMergedTrack(t *testing.T) {
+	tracker, err := NewTracker(Config{MergeDistance: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		tracker.Update(LD2451.Frame{Time: start.Add(time.Duration(i) * 100 * time.Millisecond), Targets: []LD2451.Target{
+			{Distance: 30 - i, Direction: LD2451.DirectionToward, Speed: 60, SNR: 20},
+			{Distance: 34 - i, Direction: LD2451.DirectionToward, Speed: 60, SNR: 12},
+		}})
+	}
+	active := tracker.Active()
+	if len(active) != 1 || active[0].MaxParts != 2 || active[0].Detections != 3 || active[0].Distance != 28 {
+		t.Errorf("got %+v, expected a single track of two parts", active)
+	}
+}
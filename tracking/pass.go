@@ -0,0 +1,62 @@
+package tracking
+
+import (
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// Pass is the traversal of one object through the field of view, from its
+// first detection to its disappearance, the natural unit to count and report
+// traffic in rather than single frames.
+type Pass struct {
+	Track         uint64           `json:"track"`
+	Direction     LD2451.Direction `json:"direction"`
+	Start         time.Time        `json:"start"`
+	End           time.Time        `json:"end"`
+	Duration      time.Duration    `json:"duration"`
+	EntryDistance int              `json:"entry_distance"` // Distance in meters of the first detection
+	ExitDistance  int              `json:"exit_distance"`  // Distance in meters of the last detection
+	MaxSpeed      int              `json:"max_speed"`
+	MeanSpeed     float64          `json:"mean_speed"`
+	Detections    int              `json:"detections"`
+	Class         Class            `json:"class"`
+	Lane          int              `json:"lane,omitempty"`
+	Sensor        string           `json:"sensor,omitempty"` // LD2451.Config.SensorID of the sensor that saw the object
+}
+
+func (p Pass) EventTime() time.Time {
+	return p.End
+}
+
+// Pass summarizes an ended track, as returned by Tracker.Update, Expire or
+// Flush.
+func (t Track) Pass() Pass {
+	return Pass{
+		Track:         t.ID,
+		Direction:     t.Direction,
+		Start:         t.Start,
+		End:           t.End,
+		Duration:      t.Duration(),
+		EntryDistance: t.EntryDistance,
+		ExitDistance:  t.Distance,
+		MaxSpeed:      t.MaxSpeed,
+		MeanSpeed:     t.MeanSpeed,
+		Detections:    t.Detections,
+		Class:         t.Class,
+		Lane:          t.Lane,
+		Sensor:        t.Sensor,
+	}
+}
+
+// passes summarizes every track of ended.
+func passes(ended []Track) []Pass {
+	if len(ended) == 0 {
+		return nil
+	}
+	summaries := make([]Pass, len(ended))
+	for i, track := range ended {
+		summaries[i] = track.Pass()
+	}
+	return summaries
+}
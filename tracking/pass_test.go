@@ -0,0 +1,70 @@
+package tracking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestPass(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		minDetections int
+		distances     []int
+		pass          *Pass // nil when no pass is expected
+	}{
+		{
+			"approach",
+			0,
+			[]int{40, 38, 36, 35},
+			&Pass{
+				Track: 1, Direction: LD2451.DirectionToward, Start: start, End: start.Add(300 * time.Millisecond),
+				Duration: 300 * time.Millisecond, EntryDistance: 40, ExitDistance: 35, MaxSpeed: 53, MeanSpeed: 51.5,
+				Detections: 4, Class: ClassCar, Lane: 2, Sensor: "north",
+			},
+		},
+		{"noise blip", 3, []int{40, 38}, nil},
+		{
+			"confirmed",
+			3,
+			[]int{40, 38, 36},
+			&Pass{
+				Track: 1, Direction: LD2451.DirectionToward, Start: start, End: start.Add(200 * time.Millisecond),
+				Duration: 200 * time.Millisecond, EntryDistance: 40, ExitDistance: 36, MaxSpeed: 52, MeanSpeed: 51,
+				Detections: 3, Class: ClassCar, Lane: 2, Sensor: "north",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker, err := NewTracker(Config{
+				Classifier:    &Classifier{},
+				Lanes:         &Lanes{Count: 2, Boundaries: []int{0}},
+				MinDetections: test.minDetections,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			targets := make([]LD2451.Target, len(test.distances))
+			for i, distance := range test.distances {
+				targets[i] = LD2451.Target{Angle: 5, Distance: distance, Direction: LD2451.DirectionToward, Speed: 50 + i, SNR: 8, Sensor: "north"}
+			}
+			feed(tracker, start, targets...)
+
+			//a frame long after the last detection ends the track
+			ended := tracker.Update(LD2451.Frame{Time: start.Add(time.Minute)})
+			summaries := passes(ended)
+			if test.pass == nil {
+				if len(summaries) != 0 {
+					t.Errorf("got passes %+v, expected none", summaries)
+				}
+				return
+			}
+			if len(summaries) != 1 || summaries[0] != *test.pass {
+				t.Errorf("got passes %+v, expected %+v", summaries, *test.pass)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package tracking
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// trackerState is the JSON form of a Tracker, used to checkpoint it. Band
+// changes, alerts and wrong way events not collected yet are left out.
+type trackerState struct {
+	NextID uint64       `json:"next_id"`
+	Active []trackState `json:"active"`
+}
+
+// trackState adds the bookkeeping of the tracker to a Track.
+type trackState struct {
+	Track
+	LaneVotes    []int         `json:"lane_votes,omitempty"`
+	Speeds       []sampleState `json:"speeds,omitempty"`
+	Braking      bool          `json:"braking,omitempty"`
+	Accelerating bool          `json:"accelerating,omitempty"`
+}
+
+type sampleState struct {
+	Time  time.Time `json:"time"`
+	Speed float64   `json:"speed"`
+}
+
+// MarshalJSON saves the active tracks and the next track ID, e.g. for the
+// checkpoint package, so a restart doesn't lose the vehicles passing.
+func (t *Tracker) MarshalJSON() ([]byte, error) {
+	state := trackerState{NextID: t.nextID, Active: make([]trackState, 0, len(t.active))}
+	for _, track := range t.active {
+		saved := trackState{
+			Track:        track.public(),
+			LaneVotes:    track.laneVotes,
+			Braking:      track.braking,
+			Accelerating: track.accelerating,
+		}
+		for _, sample := range track.speeds {
+			saved.Speeds = append(saved.Speeds, sampleState{sample.time, sample.speed})
+		}
+		state.Active = append(state.Active, saved)
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON restores the tracks saved by MarshalJSON, replacing the active
+// ones. Tracks that were not detected for Config.Timeout, because the
+// process was down for longer, end with the next Update or Expire.
+func (t *Tracker) UnmarshalJSON(data []byte) error {
+	var state trackerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	clear(t.active)
+	t.active = t.active[:0]
+	for _, saved := range state.Active {
+		track := saved.Track
+		if t.config.Lanes != nil && len(saved.LaneVotes) == t.config.Lanes.Count {
+			track.laneVotes = saved.LaneVotes
+		} else {
+			//the lanes changed, the track is assigned to one afresh
+			track.Lane = 0
+		}
+		track.braking, track.accelerating = saved.Braking, saved.Accelerating
+		for _, sample := range saved.Speeds {
+			track.speeds = append(track.speeds, speedSample{sample.Time, sample.Speed})
+		}
+		t.active = append(t.active, &track)
+		//IDs stay unique even if the saved next ID got lost
+		state.NextID = max(state.NextID, track.ID+1)
+	}
+	t.nextID = max(state.NextID, 1)
+	return nil
+}
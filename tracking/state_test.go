@@ -0,0 +1,109 @@
+package tracking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// feed updates tracker with a frame per target, a tenth of a second apart.
+func feed(tracker *Tracker, start time.Time, targets ...LD2451.Target) []Track {
+	var ended []Track
+	for i, target := range targets {
+		ended = append(ended, tracker.Update(LD2451.Frame{Targets: []LD2451.Target{target}, Time: start.Add(time.Duration(i) * 100 * time.Millisecond)})...)
+	}
+	return ended
+}
+
+func TestTrackerStateRoundTrip(t *testing.T) {
+	config := Config{Lanes: &Lanes{Count: 2, Boundaries: []int{0}}, AccelerationLimit: 3}
+	tracker, err := NewTracker(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	feed(tracker, start,
+		LD2451.Target{Angle: 10, Distance: 40, Direction: LD2451.DirectionToward, Speed: 50, SNR: 60},
+		LD2451.Target{Angle: 10, Distance: 39, Direction: LD2451.DirectionToward, Speed: 52, SNR: 70},
+	)
+	data, err := tracker.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewTracker(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	want, got := tracker.Active(), restored.Active()
+	if len(got) != 1 || got[0].ID != want[0].ID || got[0].Detections != 2 || got[0].Lane != 2 || !got[0].End.Equal(want[0].End) {
+		t.Fatalf("restored %+v, saved %+v", got, want)
+	}
+	if votes := restored.active[0].laneVotes; len(votes) != 2 || votes[1] != 2 {
+		t.Errorf("restored lane votes %v", votes)
+	}
+	if speeds := restored.active[0].speeds; len(speeds) != 2 {
+		t.Errorf("restored %d speeds, expected 2", len(speeds))
+	}
+
+	//the restored track continues and new tracks get fresh IDs
+	feed(restored, start.Add(200*time.Millisecond),
+		LD2451.Target{Angle: 10, Distance: 38, Direction: LD2451.DirectionToward, Speed: 52, SNR: 60},
+	)
+	restored.Update(LD2451.Frame{Targets: []LD2451.Target{
+		{Angle: 10, Distance: 37, Direction: LD2451.DirectionToward, Speed: 52},
+		{Angle: -10, Distance: 70, Direction: LD2451.DirectionAway, Speed: 30},
+	}, Time: start.Add(300 * time.Millisecond)})
+	active := restored.Active()
+	if len(active) != 2 || active[0].Detections != 4 || active[1].ID != want[0].ID+1 {
+		t.Errorf("after restoring got %+v", active)
+	}
+}
+
+func TestTrackerStateWithChangedLanes(t *testing.T) {
+	saved, err := NewTracker(Config{Lanes: &Lanes{Count: 3, Boundaries: []int{-10, 10}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	feed(saved, start, LD2451.Target{Angle: 20, Distance: 40, Direction: LD2451.DirectionToward, Speed: 50})
+	if lane := saved.Active()[0].Lane; lane != 3 {
+		t.Fatalf("saved track in lane %d, expected 3", lane)
+	}
+	data, err := saved.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		lanes *Lanes
+		lane  int
+	}{
+		{"fewer lanes", &Lanes{Count: 2, Boundaries: []int{0}}, 2},
+		{"more lanes", &Lanes{Count: 4, Boundaries: []int{-20, 0, 30}}, 3},
+		{"no lanes", nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restored, err := NewTracker(Config{Lanes: test.lanes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := restored.UnmarshalJSON(data); err != nil {
+				t.Fatal(err)
+			}
+			if lane := restored.Active()[0].Lane; lane != 0 {
+				t.Errorf("restored track kept lane %d of the old layout", lane)
+			}
+			feed(restored, start.Add(100*time.Millisecond), LD2451.Target{Angle: 20, Distance: 39, Direction: LD2451.DirectionToward, Speed: 50})
+			if lane := restored.Active()[0].Lane; lane != test.lane {
+				t.Errorf("continued track in lane %d, expected %d", lane, test.lane)
+			}
+		})
+	}
+}
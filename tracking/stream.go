@@ -0,0 +1,189 @@
+package tracking
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const DefaultStreamBuffer = 64
+
+// Update is what a single frame changed about the tracks.
+type Update struct {
+	Time        time.Time           `json:"time"`   // Time of the frame, equal to Frame.Time of the raw stream
+	Active      []Track             `json:"active"` // Tracks still going after the frame
+	Ended       []Track             `json:"ended,omitempty"`
+	BandChanges []BandChange        `json:"band_changes,omitempty"`
+	Alerts      []AccelerationAlert `json:"alerts,omitempty"`
+	Passes      []Pass              `json:"passes,omitempty"` // One for every track in Ended
+
+	WrongWays []WrongWay `json:"wrong_ways,omitempty"` // Tracks found moving against Config.WrongWay
+}
+
+func (u Update) EventTime() time.Time {
+	return u.Time
+}
+
+// Stream feeds a Tracker with the frames of a sensor and hands out both, so
+// the raw detections can be checked against the tracks built from them:
+//
+//	stream := tracking.NewStream(sensor, tracker)
+//	frames, stopFrames := stream.Frames()
+//	updates, stopUpdates := stream.Updates()
+//	go stream.Run(ctx)
+//
+// Every frame is sent to the frame subscribers before the Update it caused,
+// and both carry the same time. The tracker belongs to the stream while Run
+// is running and must not be used elsewhere, except for its state through
+// the stream's MarshalJSON and UnmarshalJSON.
+type Stream struct {
+	sensor  *LD2451.LD2451
+	tracker *Tracker
+
+	mu      sync.Mutex
+	frames  map[chan LD2451.Frame]struct{}
+	updates map[chan Update]struct{}
+	dropped uint64
+	stopped bool
+
+	last time.Time //time of the latest frame, only used by Run
+
+	trackerMu sync.Mutex //held while the tracker is used
+}
+
+func NewStream(sensor *LD2451.LD2451, tracker *Tracker) *Stream {
+	return &Stream{
+		sensor:  sensor,
+		tracker: tracker,
+		frames:  make(map[chan LD2451.Frame]struct{}),
+		updates: make(map[chan Update]struct{}),
+	}
+}
+
+// Frames returns a channel receiving the frames the tracker is fed with, and
+// a function ending the subscription. The channel is closed when Run returns.
+func (s *Stream) Frames() (<-chan LD2451.Frame, func()) {
+	return subscribe(s, s.frames)
+}
+
+// Updates returns a channel receiving an Update for every frame, and a
+// function ending the subscription. The channel is closed when Run returns.
+func (s *Stream) Updates() (<-chan Update, func()) {
+	return subscribe(s, s.updates)
+}
+
+func subscribe[T any](s *Stream, subs map[chan T]struct{}) (<-chan T, func()) {
+	ch := make(chan T, DefaultStreamBuffer)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		close(ch)
+		return ch, func() {}
+	}
+	subs[ch] = struct{}{}
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := subs[ch]; ok {
+			delete(subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Dropped returns the number of frames and updates dropped because a
+// subscriber's channel was full. Slow subscribers never hold up the others.
+func (s *Stream) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Run tracks the sensor's frames until ctx is done or the sensor is closed,
+// then ends the remaining tracks in a last Update, timed like the latest
+// frame, and closes every subscription.
+func (s *Stream) Run(ctx context.Context) error {
+	frames, cancel := s.sensor.SubscribeFrames()
+	defer cancel()
+	defer s.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case frame, ok := <-frames:
+			if !ok {
+				return nil
+			}
+			s.last = frame.Time
+			broadcast(s, s.frames, frame)
+			s.trackerMu.Lock()
+			update := s.update(frame.Time, s.tracker.Update(frame))
+			s.trackerMu.Unlock()
+			broadcast(s, s.updates, update)
+		}
+	}
+}
+
+func (s *Stream) update(t time.Time, ended []Track) Update {
+	return Update{
+		Time:        t,
+		Active:      s.tracker.Active(),
+		Ended:       ended,
+		BandChanges: s.tracker.BandChanges(),
+		Alerts:      s.tracker.AccelerationAlerts(),
+		Passes:      passes(ended),
+		WrongWays:   s.tracker.WrongWays(),
+	}
+}
+
+// MarshalJSON saves the state of the tracker, see Tracker.MarshalJSON. It is
+// safe to call while Run is running, e.g. by the checkpoint package.
+func (s *Stream) MarshalJSON() ([]byte, error) {
+	s.trackerMu.Lock()
+	defer s.trackerMu.Unlock()
+	return s.tracker.MarshalJSON()
+}
+
+// UnmarshalJSON restores the state of the tracker, normally before Run.
+func (s *Stream) UnmarshalJSON(data []byte) error {
+	s.trackerMu.Lock()
+	defer s.trackerMu.Unlock()
+	return s.tracker.UnmarshalJSON(data)
+}
+
+func broadcast[T any](s *Stream, subs map[chan T]struct{}, value T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range subs {
+		select {
+		case ch <- value:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// stop flushes the tracks left and closes the subscriptions.
+func (s *Stream) stop() {
+	s.trackerMu.Lock()
+	ended := s.tracker.Flush()
+	update := s.update(s.last, ended)
+	s.trackerMu.Unlock()
+	if len(ended) > 0 {
+		broadcast(s, s.updates, update)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	for ch := range s.frames {
+		close(ch)
+	}
+	for ch := range s.updates {
+		close(ch)
+	}
+	clear(s.frames)
+	clear(s.updates)
+}
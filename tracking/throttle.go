@@ -0,0 +1,43 @@
+package tracking
+
+import "time"
+
+// Throttle limits how often updates of the same track are passed on, e.g.
+// when forwarding Tracker.Active over a constrained link:
+//
+//	throttle := tracking.NewThrottle(200 * time.Millisecond)
+//	for _, track := range tracker.Active() {
+//		if throttle.Allow(track) {
+//			send(track)
+//		}
+//	}
+//
+// Time is taken from Track.End, so replays are limited like live data.
+type Throttle struct {
+	interval time.Duration
+	last     map[uint64]time.Time //end of the last allowed update per track
+}
+
+func NewThrottle(interval time.Duration) *Throttle {
+	return &Throttle{interval: interval, last: make(map[uint64]time.Time)}
+}
+
+// Allow reports whether an update of track may be passed on, which is the
+// case for the first update of a track and once interval passed since the
+// last allowed one.
+func (t *Throttle) Allow(track Track) bool {
+	last, ok := t.last[track.ID]
+	if ok && track.End.Sub(last) < t.interval {
+		return false
+	}
+	t.last[track.ID] = track.End
+	return true
+}
+
+// Forget drops what is known about tracks that ended, e.g. the tracks
+// returned by Tracker.Update, to keep the throttle from growing.
+func (t *Throttle) Forget(ended []Track) {
+	for _, track := range ended {
+		delete(t.last, track.ID)
+	}
+}
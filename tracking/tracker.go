@@ -0,0 +1,329 @@
+// Package tracking associates the detections of consecutive frames into
+// tracks, one per object passing the sensor, and summarizes each track once
+// the object has left the field of view.
+//
+//	tracker, err := tracking.NewTracker(tracking.Config{Classifier: &tracking.Classifier{}})
+//	...
+//	for _, track := range tracker.Update(frame) {
+//		fmt.Println(track)
+//	}
+package tracking
+
+import (
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	DefaultGate             = 5
+	DefaultTimeout          = time.Second
+	DefaultArrivalSmoothing = 0.3
+)
+
+type Config struct {
+	Gate       int           // Maximum distance in meters between a detection and the track it continues (default DefaultGate)
+	Timeout    time.Duration // A track ends when it was not detected for this long (default DefaultTimeout)
+	Classifier *Classifier   // Labels tracks when they end, nil leaves them unclassified
+	Lanes      *Lanes        // Assigns tracks to lanes by angle, nil leaves Track.Lane at 0
+	Bands      []Band        // Distance bands whose changes are reported by Tracker.BandChanges
+
+	MinDetections int // Tracks detected in fewer frames are not reported, neither by Active nor when they end, suppressing noise blips
+
+	ArrivalSmoothing float64 // Weight (0-1] of the newest closing speed in Track.ClosingSpeed (default DefaultArrivalSmoothing)
+
+	BrakingLimit       float64       // Deceleration in m/s² beyond which a track raises a HardBraking alert, zero disables them
+	AccelerationLimit  float64       // Acceleration in m/s² beyond which a track raises a RapidAcceleration alert, zero disables them
+	AccelerationWindow time.Duration // Span of speeds the acceleration is estimated from (default DefaultAccelerationWindow)
+
+	MergeDistance int // Detections of one frame moving the same way within this many meters of each other are merged into one, so a truck or bus reported in parts is tracked once; zero disables merging
+	MergeAngle    int // Largest angle difference in degrees between merged detections (default DefaultMergeAngle)
+	MergeSpeed    int // Largest speed difference in KM/H between merged detections (default DefaultMergeSpeed)
+
+	WrongWay *WrongWayDetection // Expected direction of travel, raising a WrongWay for tracks sustained against it, nil disables them
+}
+
+type Track struct {
+	ID         uint64           `json:"id"`
+	Direction  LD2451.Direction `json:"direction"`
+	Start      time.Time        `json:"start"`      // Time of the first detection
+	End        time.Time        `json:"end"`        // Time of the latest detection
+	Detections int              `json:"detections"` // Number of frames the object was detected in
+	Distance   int              `json:"distance"`   // Latest distance in meters
+	Angle      int              `json:"angle"`      // Latest angle
+	Speed      int              `json:"speed"`      // Latest speed in KM/H
+	MaxSpeed   int              `json:"max_speed"`
+	MeanSpeed  float64          `json:"mean_speed"`
+	MaxSNR     int              `json:"max_snr"`
+	MeanSNR    float64          `json:"mean_snr"`
+	Class      Class            `json:"class"` // Set when the track ends and a classifier is configured
+	Lane       int              `json:"lane"`  // Lane most detections fell into when lanes are configured
+	Band       string           `json:"band"`  // Distance band of the latest detection when bands are configured
+
+	ClosingSpeed  float64       `json:"closing_speed"`   // Smoothed speed in m/s at which an approaching track nears the sensor, zero otherwise
+	TimeToArrival time.Duration `json:"time_to_arrival"` // Estimated time until an approaching track reaches the sensor plane, zero otherwise
+
+	Acceleration    float64 `json:"acceleration"`     // Latest estimate in m/s², negative when braking
+	MaxAcceleration float64 `json:"max_acceleration"` // m/s²
+	MaxBraking      float64 `json:"max_braking"`      // Strongest deceleration in m/s², as a positive value
+
+	EntryDistance int    `json:"entry_distance"`   // Distance in meters of the first detection
+	Sensor        string `json:"sensor,omitempty"` // Sensor of the targets, see LD2451.Config.SensorID
+
+	MaxParts int `json:"max_parts,omitempty"` // Most detections of one frame merged into the track, above 1 for a vehicle reported in parts, see Config.MergeDistance
+
+	WrongWay bool `json:"wrong_way,omitempty"` // A WrongWay was raised for the track
+
+	laneVotes    []int         //detections per lane, indexed by lane-1
+	speeds       []speedSample //speeds within the acceleration window
+	braking      bool          //whether a HardBraking alert is raised
+	accelerating bool          //whether a RapidAcceleration alert is raised
+}
+
+// Duration is how long the object was observed.
+func (t Track) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+type Tracker struct {
+	config  Config
+	nextID  uint64
+	active  []*Track
+	changes []BandChange
+	alerts  []AccelerationAlert
+
+	wrongWays []WrongWay
+}
+
+// NewTracker returns an error only for invalid Config.Lanes, Config.Bands or
+// Config.WrongWay.
+func NewTracker(config Config) (*Tracker, error) {
+	if config.Lanes != nil {
+		if err := config.Lanes.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateBands(config.Bands); err != nil {
+		return nil, err
+	}
+	if w := config.WrongWay; w != nil {
+		if err := w.validate(config.Lanes); err != nil {
+			return nil, err
+		}
+		wrongWay := *w
+		if wrongWay.MinDetections <= 0 {
+			wrongWay.MinDetections = DefaultWrongWayDetections
+		}
+		if wrongWay.MinDuration <= 0 {
+			wrongWay.MinDuration = DefaultWrongWayDuration
+		}
+		if wrongWay.MinTravel <= 0 {
+			wrongWay.MinTravel = DefaultWrongWayTravel
+		}
+		config.WrongWay = &wrongWay
+	}
+	if config.Gate <= 0 {
+		config.Gate = DefaultGate
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.ArrivalSmoothing <= 0 || config.ArrivalSmoothing > 1 {
+		config.ArrivalSmoothing = DefaultArrivalSmoothing
+	}
+	if config.AccelerationWindow <= 0 {
+		config.AccelerationWindow = DefaultAccelerationWindow
+	}
+	if config.MergeAngle <= 0 {
+		config.MergeAngle = DefaultMergeAngle
+	}
+	if config.MergeSpeed <= 0 {
+		config.MergeSpeed = DefaultMergeSpeed
+	}
+	return &Tracker{config: config, nextID: 1}, nil
+}
+
+// Update continues tracks with the targets of frame, starts new tracks for
+// targets that continue none of them, and returns the tracks that ended
+// because they were not detected for Config.Timeout. Parts of one vehicle are
+// merged first, see Config.MergeDistance.
+func (t *Tracker) Update(frame LD2451.Frame) []Track {
+	ended := t.Expire(frame.Time)
+
+	targets, parts := t.merge(frame.Targets)
+	matched := make([]bool, len(t.active))
+	for n, target := range targets {
+		if target.Time.IsZero() {
+			target.Time = frame.Time
+		}
+		//continue the closest track moving the same way that wasn't continued by this frame yet
+		best, bestGap := -1, t.config.Gate+1
+		for i, track := range t.active {
+			if matched[i] || track.Direction != target.Direction {
+				continue
+			}
+			if gap := abs(track.Distance - target.Distance); gap < bestGap {
+				best, bestGap = i, gap
+			}
+		}
+		if best < 0 {
+			track := t.start(target)
+			track.MaxParts = parts[n]
+			t.active = append(t.active, track)
+			matched = append(matched, true)
+			continue
+		}
+		matched[best] = true
+		t.add(t.active[best], target)
+		t.active[best].MaxParts = max(t.active[best].MaxParts, parts[n])
+	}
+	return ended
+}
+
+// Expire ends and returns the tracks that were not detected for Config.Timeout
+// before now. Update expires tracks itself, Expire is only needed to end
+// tracks while no targets are reported.
+func (t *Tracker) Expire(now time.Time) []Track {
+	var ended []Track
+	active := t.active[:0]
+	for _, track := range t.active {
+		if now.Sub(track.End) <= t.config.Timeout {
+			active = append(active, track)
+			continue
+		}
+		if t.confirmed(track) {
+			ended = append(ended, t.finish(track))
+		}
+	}
+	clear(t.active[len(active):])
+	t.active = active
+	return ended
+}
+
+// Flush ends and returns all active tracks, e.g. before shutting down.
+func (t *Tracker) Flush() []Track {
+	ended := make([]Track, 0, len(t.active))
+	for _, track := range t.active {
+		if t.confirmed(track) {
+			ended = append(ended, t.finish(track))
+		}
+	}
+	clear(t.active)
+	t.active = t.active[:0]
+	return ended
+}
+
+// Active returns the tracks that have not ended yet.
+func (t *Tracker) Active() []Track {
+	tracks := make([]Track, 0, len(t.active))
+	for _, track := range t.active {
+		if !t.confirmed(track) {
+			continue
+		}
+		tracks = append(tracks, track.public())
+	}
+	return tracks
+}
+
+// confirmed reports whether track was detected often enough to be reported.
+func (t *Tracker) confirmed(track *Track) bool {
+	return track.Detections >= t.config.MinDetections
+}
+
+func (t *Tracker) start(target LD2451.Target) *Track {
+	track := &Track{
+		ID:            t.nextID,
+		Direction:     target.Direction,
+		Start:         target.Time,
+		EntryDistance: target.Distance,
+		Sensor:        target.Sensor,
+	}
+	t.nextID++
+	t.add(track, target)
+	return track
+}
+
+func (t *Tracker) finish(track *Track) Track {
+	t.updateBand(track, true)
+	if t.config.Classifier != nil {
+		track.Class = t.config.Classifier.Classify(*track)
+	}
+	return track.public()
+}
+
+// public returns a copy of track without the bookkeeping of the tracker.
+func (t *Track) public() Track {
+	public := *t
+	public.laneVotes, public.speeds = nil, nil
+	return public
+}
+
+func (t *Tracker) add(track *Track, target LD2451.Target) {
+	track.add(target)
+	t.arrival(track)
+	t.accelerate(track)
+	t.updateBand(track, false)
+	t.assignLane(track, target)
+	t.checkWrongWay(track)
+}
+
+// assignLane counts the lane of target for track, which is in the lane most
+// of its detections fell into.
+func (t *Tracker) assignLane(track *Track, target LD2451.Target) {
+	if t.config.Lanes == nil {
+		return
+	}
+	if track.laneVotes == nil {
+		track.laneVotes = make([]int, t.config.Lanes.Count)
+	}
+	lane := t.config.Lanes.Lane(target.Angle)
+	track.laneVotes[lane-1]++
+	if lane != track.Lane && (track.Lane == 0 || track.laneVotes[lane-1] > track.laneVotes[track.Lane-1]) {
+		track.Lane = lane
+	}
+}
+
+// arrival updates the time to arrival of track from its latest detection.
+func (t *Tracker) arrival(track *Track) {
+	if track.Direction != LD2451.DirectionToward || track.Speed <= 0 {
+		track.ClosingSpeed, track.TimeToArrival = 0, 0
+		return
+	}
+	speed := float64(track.Speed) / 3.6
+	//a fresh approach starts from the measured speed
+	if track.ClosingSpeed == 0 {
+		track.ClosingSpeed = speed
+	} else {
+		track.ClosingSpeed += t.config.ArrivalSmoothing * (speed - track.ClosingSpeed)
+	}
+	track.TimeToArrival = time.Duration(float64(track.Distance) / track.ClosingSpeed * float64(time.Second))
+}
+
+// Arrival is when an approaching track is expected to reach the sensor plane,
+// or the zero time when it is not approaching.
+func (t Track) Arrival() time.Time {
+	if t.ClosingSpeed == 0 {
+		return time.Time{}
+	}
+	return t.End.Add(t.TimeToArrival)
+}
+
+func (t *Track) add(target LD2451.Target) {
+	n := float64(t.Detections)
+	t.End = target.Time
+	t.Detections++
+	t.Distance = target.Distance
+	t.Angle = target.Angle
+	t.Speed = target.Speed
+	t.MaxSpeed = max(t.MaxSpeed, target.Speed)
+	t.MaxSNR = max(t.MaxSNR, target.SNR)
+	t.MeanSpeed = (t.MeanSpeed*n + float64(target.Speed)) / (n + 1)
+	t.MeanSNR = (t.MeanSNR*n + float64(target.SNR)) / (n + 1)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,80 @@
+package tracking
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestTimeToArrival(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	toward := func(distance, speed int) LD2451.Target {
+		return LD2451.Target{Distance: distance, Direction: LD2451.DirectionToward, Speed: speed, SNR: 8}
+	}
+	tests := []struct {
+		name      string
+		smoothing float64
+		targets   []LD2451.Target
+		closing   float64 // m/s
+	}{
+		{"first detection", 0, []LD2451.Target{toward(50, 36)}, 10},
+		{"smoothed", 0, []LD2451.Target{toward(50, 36), toward(40, 72)}, 13},
+		{"unsmoothed", 1, []LD2451.Target{toward(50, 36), toward(40, 72)}, 20},
+		{"receding", 0, []LD2451.Target{{Distance: 50, Direction: LD2451.DirectionAway, Speed: 36}}, 0},
+		{"standing", 0, []LD2451.Target{toward(50, 0)}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker, err := NewTracker(Config{ArrivalSmoothing: test.smoothing, Gate: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			feed(tracker, start, test.targets...)
+			active := tracker.Active()
+			if len(active) != 1 {
+				t.Fatalf("got %d tracks, expected 1", len(active))
+			}
+			track := active[0]
+			if math.Abs(track.ClosingSpeed-test.closing) > 1e-9 {
+				t.Errorf("closing speed %g m/s, expected %g", track.ClosingSpeed, test.closing)
+			}
+			if test.closing == 0 {
+				if track.TimeToArrival != 0 || !track.Arrival().IsZero() {
+					t.Errorf("time to arrival %s, arrival %s for a track not approaching", track.TimeToArrival, track.Arrival())
+				}
+				return
+			}
+			expected := time.Duration(float64(track.Distance) / test.closing * float64(time.Second))
+			if track.TimeToArrival != expected || !track.Arrival().Equal(track.End.Add(expected)) {
+				t.Errorf("time to arrival %s, arrival %s; expected %s", track.TimeToArrival, track.Arrival(), expected)
+			}
+		})
+	}
+}
+
+func TestTrackerGate(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		second LD2451.Target
+		tracks int
+	}{
+		{"within the gate", LD2451.Target{Distance: 45, Direction: LD2451.DirectionToward, Speed: 50}, 1},
+		{"beyond the gate", LD2451.Target{Distance: 44, Direction: LD2451.DirectionToward, Speed: 50}, 2},
+		{"other direction", LD2451.Target{Distance: 50, Direction: LD2451.DirectionAway, Speed: 50}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker, err := NewTracker(Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			feed(tracker, start, LD2451.Target{Distance: 50, Direction: LD2451.DirectionToward, Speed: 50}, test.second)
+			if active := tracker.Active(); len(active) != test.tracks {
+				t.Errorf("got %d tracks, expected %d", len(active), test.tracks)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package tracking
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+const (
+	DefaultWrongWayDetections = 5
+	DefaultWrongWayDuration   = time.Second
+	DefaultWrongWayTravel     = 5
+)
+
+// WrongWayDetection declares the expected direction of travel. A track moving
+// the other way raises a WrongWay only once it is sustained, so vehicles
+// turning or pulling out briefly against the traffic don't set it off.
+type WrongWayDetection struct {
+	Direction      LD2451.Direction   // Expected direction of travel
+	LaneDirections []LD2451.Direction // Expected direction per lane, indexed by lane-1, taking precedence over Direction for tracks assigned a lane; needs Config.Lanes
+
+	MinDetections int           // Detections a track needs before it counts as wrong way (default DefaultWrongWayDetections)
+	MinDuration   time.Duration // How long a track must have been observed (default DefaultWrongWayDuration)
+	MinTravel     int           // Meters a track must have moved since its first detection (default DefaultWrongWayTravel)
+}
+
+func (w *WrongWayDetection) validate(lanes *Lanes) error {
+	if len(w.LaneDirections) == 0 {
+		return nil
+	}
+	if lanes == nil || len(w.LaneDirections) != lanes.Count {
+		count := 0
+		if lanes != nil {
+			count = lanes.Count
+		}
+		return fmt.Errorf("%d wrong way lane directions for %d lanes", len(w.LaneDirections), count)
+	}
+	return nil
+}
+
+// WrongWay is a track moving against the expected direction of travel for
+// long enough to rule out a turning vehicle. It is raised once per track, as
+// soon as the track qualifies, rather than when the track ends, so it can
+// trigger a warning while the vehicle is still approaching.
+type WrongWay struct {
+	Track      uint64           `json:"track"`
+	Direction  LD2451.Direction `json:"direction"` // Direction the track moves in
+	Lane       int              `json:"lane,omitempty"`
+	Speed      int              `json:"speed"` // KM/H when the event was raised
+	Distance   int              `json:"distance"`
+	Detections int              `json:"detections"`
+	Start      time.Time        `json:"start"` // Time of the first detection of the track
+	Time       time.Time        `json:"time"`
+	Sensor     string           `json:"sensor,omitempty"`
+}
+
+func (w WrongWay) EventTime() time.Time {
+	return w.Time
+}
+
+// WrongWays returns the wrong way events raised since the last call, in order.
+func (t *Tracker) WrongWays() []WrongWay {
+	wrongWays := t.wrongWays
+	t.wrongWays = nil
+	return wrongWays
+}
+
+// checkWrongWay raises a WrongWay for track once it is sustained against the
+// expected direction.
+func (t *Tracker) checkWrongWay(track *Track) {
+	w := t.config.WrongWay
+	if w == nil || track.WrongWay || !t.confirmed(track) {
+		return
+	}
+	expected := w.Direction
+	if track.Lane > 0 && len(w.LaneDirections) >= track.Lane {
+		expected = w.LaneDirections[track.Lane-1]
+	}
+	if track.Direction == expected ||
+		track.Detections < w.MinDetections ||
+		track.Duration() < w.MinDuration ||
+		abs(track.Distance-track.EntryDistance) < w.MinTravel {
+		return
+	}
+	track.WrongWay = true
+	t.wrongWays = append(t.wrongWays, WrongWay{
+		Track:      track.ID,
+		Direction:  track.Direction,
+		Lane:       track.Lane,
+		Speed:      track.Speed,
+		Distance:   track.Distance,
+		Detections: track.Detections,
+		Start:      track.Start,
+		Time:       track.End,
+		Sensor:     track.Sensor,
+	})
+}
@@ -0,0 +1,88 @@
+package tracking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestWrongWay(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	away, toward := LD2451.DirectionAway, LD2451.DirectionToward
+	tests := []struct {
+		name      string
+		detection WrongWayDetection
+		direction LD2451.Direction
+		angle     int
+		step      int // meters travelled per frame
+		frames    int
+		raisedAt  int // frame raising the WrongWay, -1 for none
+	}{
+		{"sustained", WrongWayDetection{Direction: away}, toward, 0, 1, 15, 10},
+		{"expected direction", WrongWayDetection{Direction: away}, away, 0, 1, 15, -1},
+		{"too short", WrongWayDetection{Direction: away}, toward, 0, 1, 10, -1},
+		{"standing", WrongWayDetection{Direction: away}, toward, 0, 0, 15, -1},
+		{"shorter duration", WrongWayDetection{Direction: away, MinDuration: 100 * time.Millisecond}, toward, 0, 1, 15, 5},
+		{"tuned", WrongWayDetection{Direction: away, MinDetections: 2, MinDuration: 100 * time.Millisecond, MinTravel: 2}, toward, 0, 1, 15, 2},
+		{"lane expecting it", WrongWayDetection{Direction: away, LaneDirections: []LD2451.Direction{toward, away}}, toward, -10, 1, 15, -1},
+		{"lane against it", WrongWayDetection{Direction: toward, LaneDirections: []LD2451.Direction{toward, away}}, toward, 10, 1, 15, 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker, err := NewTracker(Config{WrongWay: &test.detection, Lanes: &Lanes{Count: 2, Boundaries: []int{0}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			raised := -1
+			for i := range test.frames {
+				distance := 40 + i*test.step
+				if test.direction == toward {
+					distance = 40 - i*test.step
+				}
+				target := LD2451.Target{Angle: test.angle, Distance: distance, Direction: test.direction, Speed: 50, SNR: 8}
+				tracker.Update(LD2451.Frame{Targets: []LD2451.Target{target}, Time: start.Add(time.Duration(i) * 100 * time.Millisecond)})
+				wrongWays := tracker.WrongWays()
+				if len(wrongWays) == 0 {
+					continue
+				}
+				if raised >= 0 || len(wrongWays) > 1 {
+					t.Fatalf("raised again at frame %d: %+v", i, wrongWays)
+				}
+				raised = i
+				if w := wrongWays[0]; w.Track != 1 || w.Direction != test.direction || w.Detections != i+1 || !w.Start.Equal(start) {
+					t.Errorf("raised %+v", w)
+				}
+			}
+			if raised != test.raisedAt {
+				t.Errorf("raised at frame %d, expected %d", raised, test.raisedAt)
+			}
+			if track := tracker.Flush()[0]; track.WrongWay != (test.raisedAt >= 0) {
+				t.Errorf("track marked wrong way %t", track.WrongWay)
+			}
+		})
+	}
+}
+
+func TestWrongWayValidate(t *testing.T) {
+	lanes := &Lanes{Count: 2, Boundaries: []int{0}}
+	tests := []struct {
+		name      string
+		lanes     *Lanes
+		detection WrongWayDetection
+		valid     bool
+	}{
+		{"direction only", nil, WrongWayDetection{Direction: LD2451.DirectionAway}, true},
+		{"per lane", lanes, WrongWayDetection{LaneDirections: []LD2451.Direction{LD2451.DirectionToward, LD2451.DirectionAway}}, true},
+		{"lane directions without lanes", nil, WrongWayDetection{LaneDirections: []LD2451.Direction{LD2451.DirectionAway}}, false},
+		{"a direction per lane missing", lanes, WrongWayDetection{LaneDirections: []LD2451.Direction{LD2451.DirectionAway}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTracker(Config{Lanes: test.lanes, WrongWay: &test.detection})
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, expected valid %t", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+//go:build darwin
+
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+type ioregNode struct {
+	depth int
+	props map[string]string
+}
+
+// parseIoreg extracts the USB serial adapters from the output of
+// "ioreg -r -c IOUSBHostDevice -l". Every callout device is attributed to the
+// closest enclosing node carrying a USB vendor id, so adapters behind hubs
+// are matched to their own device rather than the hub.
+func parseIoreg(out []byte) []PortInfo {
+	var (
+		stack []ioregNode
+		ports []PortInfo
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "+-o "); i >= 0 {
+			for len(stack) > 0 && stack[len(stack)-1].depth >= i {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, ioregNode{depth: i, props: make(map[string]string)})
+			continue
+		}
+		if len(stack) == 0 {
+			continue
+		}
+		key, value, ok := ioregProperty(line)
+		if !ok {
+			continue
+		}
+		stack[len(stack)-1].props[key] = value
+		if key != "IOCalloutDevice" {
+			continue
+		}
+
+		port := PortInfo{Path: value}
+		for i := len(stack) - 1; i >= 0; i-- {
+			props := stack[i].props
+			vid, ok := props["idVendor"]
+			if !ok {
+				continue
+			}
+			port.USB = true
+			port.VID = ioregNumber(vid)
+			port.PID = ioregNumber(props["idProduct"])
+			port.Description = props["USB Product Name"]
+			port.Manufacturer = props["USB Vendor Name"]
+			port.SerialNumber = props["USB Serial Number"]
+			break
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// ioregProperty parses a property line such as `|   "idVendor" = 6790`.
+func ioregProperty(line string) (string, string, bool) {
+	start := strings.IndexByte(line, '"')
+	if start < 0 {
+		return "", "", false
+	}
+	key, rest, ok := strings.Cut(line[start+1:], `" = `)
+	if !ok {
+		return "", "", false
+	}
+	return key, strings.Trim(strings.TrimSpace(rest), `"`), true
+}
+
+func ioregNumber(value string) uint16 {
+	v, _ := strconv.ParseUint(value, 0, 16)
+	return uint16(v)
+}
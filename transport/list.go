@@ -0,0 +1,25 @@
+package transport
+
+import "errors"
+
+// ErrListUnsupported is returned by ListPorts on platforms it cannot enumerate ports on.
+var ErrListUnsupported = errors.New("transport: listing serial ports is not supported on this platform")
+
+// PortInfo describes a serial device found by ListPorts. The USB fields are
+// empty for ports that are not USB adapters.
+type PortInfo struct {
+	Path         string `json:"path"`                    // Path or name to pass as SerialConfig.Name
+	StablePath   string `json:"stable_path,omitempty"`   // Linux /dev/serial/by-id path that survives re-enumeration, if any
+	Description  string `json:"description,omitempty"`   // USB product name, or the name Windows shows for the port
+	Manufacturer string `json:"manufacturer,omitempty"`  // USB vendor name
+	SerialNumber string `json:"serial_number,omitempty"` // USB serial number, absent on many cheap adapters
+	USB          bool   `json:"usb"`
+	VID          uint16 `json:"vid,omitempty"`
+	PID          uint16 `json:"pid,omitempty"`
+}
+
+// ListPorts returns the serial devices currently present, on Linux, macOS and
+// Windows.
+func ListPorts() ([]PortInfo, error) {
+	return listPorts()
+}
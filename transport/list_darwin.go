@@ -0,0 +1,32 @@
+package transport
+
+import (
+	"os/exec"
+	"path/filepath"
+)
+
+// listPorts reads the USB device tree from ioreg, since IOKit itself is only
+// reachable through cgo. Callout devices not found in it, such as Bluetooth
+// serial ports, are listed without USB details.
+func listPorts() ([]PortInfo, error) {
+	paths, err := filepath.Glob("/dev/cu.*")
+	if err != nil {
+		return nil, err
+	}
+	usb := make(map[string]PortInfo)
+	if out, err := exec.Command("ioreg", "-r", "-c", "IOUSBHostDevice", "-l").Output(); err == nil {
+		for _, port := range parseIoreg(out) {
+			usb[port.Path] = port
+		}
+	}
+
+	ports := make([]PortInfo, 0, len(paths))
+	for _, path := range paths {
+		if port, ok := usb[path]; ok {
+			ports = append(ports, port)
+			continue
+		}
+		ports = append(ports, PortInfo{Path: path})
+	}
+	return ports, nil
+}
@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func listPorts() ([]PortInfo, error) {
+	entries, err := os.ReadDir("/sys/class/tty")
+	if err != nil {
+		return nil, err
+	}
+	stable := stablePaths()
+
+	var ports []PortInfo
+	for _, entry := range entries {
+		sys := filepath.Join("/sys/class/tty", entry.Name())
+		device, err := filepath.EvalSymlinks(filepath.Join(sys, "device"))
+		if err != nil {
+			//virtual terminals and pseudo-terminals have no device
+			continue
+		}
+		driver, _ := filepath.EvalSymlinks(filepath.Join(device, "driver"))
+		if filepath.Base(driver) == "serial8250" {
+			//placeholders for legacy ports the kernel reserves whether or not they exist
+			continue
+		}
+
+		port := PortInfo{Path: "/dev/" + entry.Name(), StablePath: stable[entry.Name()]}
+		if dir := usbDevice(device); dir != "" {
+			port.USB = true
+			port.VID = readHex(filepath.Join(dir, "idVendor"))
+			port.PID = readHex(filepath.Join(dir, "idProduct"))
+			port.Description = readAttr(filepath.Join(dir, "product"))
+			port.Manufacturer = readAttr(filepath.Join(dir, "manufacturer"))
+			port.SerialNumber = readAttr(filepath.Join(dir, "serial"))
+		}
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Path < ports[j].Path })
+	return ports, nil
+}
+
+// usbDevice walks up from a tty's device to the USB device it belongs to.
+func usbDevice(dir string) string {
+	for ; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "idVendor")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// stablePaths maps tty names to their /dev/serial/by-id links.
+func stablePaths() map[string]string {
+	paths := make(map[string]string)
+	links, _ := filepath.Glob("/dev/serial/by-id/*")
+	for _, link := range links {
+		target, err := filepath.EvalSymlinks(link)
+		if err == nil {
+			paths[filepath.Base(target)] = link
+		}
+	}
+	return paths
+}
+
+func readAttr(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readHex(path string) uint16 {
+	v, _ := strconv.ParseUint(readAttr(path), 16, 16)
+	return uint16(v)
+}
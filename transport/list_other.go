@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package transport
+
+func listPorts() ([]PortInfo, error) {
+	return nil, ErrListUnsupported
+}
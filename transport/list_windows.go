@@ -0,0 +1,132 @@
+package transport
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+var usbID = regexp.MustCompile(`(?i)VID_([0-9a-f]{4})[&+]PID_([0-9a-f]{4})`)
+
+// listPorts reads the present COM ports from the SERIALCOMM device map and
+// looks their USB details up in the device enumeration tree.
+func listPorts() ([]PortInfo, error) {
+	comm, err := registry.OpenKey(registry.LOCAL_MACHINE, `HARDWARE\DEVICEMAP\SERIALCOMM`, registry.QUERY_VALUE)
+	if err != nil {
+		if err == registry.ErrNotExist {
+			//no serial ports at all
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer comm.Close()
+	names, err := comm.ReadValueNames(0)
+	if err != nil {
+		return nil, err
+	}
+
+	usb := usbPorts()
+	var ports []PortInfo
+	for _, name := range names {
+		path, _, err := comm.GetStringValue(name)
+		if err != nil {
+			continue
+		}
+		port, ok := usb[path]
+		if !ok {
+			port = PortInfo{Description: name[strings.LastIndexByte(name, '\\')+1:]}
+		}
+		port.Path = path
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Path < ports[j].Path })
+	return ports, nil
+}
+
+// usbPorts maps COM port names to the USB devices that provide them. FTDI
+// adapters are enumerated by their own bus driver.
+func usbPorts() map[string]PortInfo {
+	ports := make(map[string]PortInfo)
+	for _, bus := range []string{`SYSTEM\CurrentControlSet\Enum\USB`, `SYSTEM\CurrentControlSet\Enum\FTDIBUS`} {
+		root, err := registry.OpenKey(registry.LOCAL_MACHINE, bus, registry.ENUMERATE_SUB_KEYS)
+		if err != nil {
+			continue
+		}
+		devices, _ := root.ReadSubKeyNames(0)
+		root.Close()
+		for _, device := range devices {
+			id := usbID.FindStringSubmatch(device)
+			if id == nil {
+				continue
+			}
+			vid, _ := strconv.ParseUint(id[1], 16, 16)
+			pid, _ := strconv.ParseUint(id[2], 16, 16)
+
+			key, err := registry.OpenKey(registry.LOCAL_MACHINE, bus+`\`+device, registry.ENUMERATE_SUB_KEYS)
+			if err != nil {
+				continue
+			}
+			instances, _ := key.ReadSubKeyNames(0)
+			key.Close()
+			for _, instance := range instances {
+				port, ok := usbInstance(bus + `\` + device + `\` + instance)
+				if !ok {
+					continue
+				}
+				port.USB = true
+				port.VID, port.PID = uint16(vid), uint16(pid)
+				switch {
+				case !strings.Contains(instance, "&"):
+					//composite and serial-less devices get generated instance ids containing &
+					port.SerialNumber = instance
+				case strings.Contains(device, "+"):
+					//FTDIBUS\VID_0403+PID_6001+<serial>A
+					if parts := strings.Split(device, "+"); len(parts) == 3 {
+						port.SerialNumber = strings.TrimSuffix(parts[2], "A")
+					}
+				}
+				ports[port.Path] = port
+			}
+		}
+	}
+	return ports
+}
+
+func usbInstance(path string) (PortInfo, bool) {
+	params, err := registry.OpenKey(registry.LOCAL_MACHINE, path+`\Device Parameters`, registry.QUERY_VALUE)
+	if err != nil {
+		return PortInfo{}, false
+	}
+	name, _, err := params.GetStringValue("PortName")
+	params.Close()
+	if err != nil {
+		return PortInfo{}, false
+	}
+
+	port := PortInfo{Path: name}
+	if key, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE); err == nil {
+		port.Description = registryText(key, "FriendlyName")
+		if port.Description == "" {
+			port.Description = registryText(key, "DeviceDesc")
+		}
+		port.Manufacturer = registryText(key, "Mfg")
+		key.Close()
+	}
+	return port, true
+}
+
+// registryText reads a string value, dropping the "@driver.inf,%id%;" prefix
+// localizable values carry.
+func registryText(key registry.Key, name string) string {
+	value, _, err := key.GetStringValue(name)
+	if err != nil {
+		return ""
+	}
+	if i := strings.LastIndexByte(value, ';'); i >= 0 && strings.HasPrefix(value, "@") {
+		value = value[i+1:]
+	}
+	return value
+}
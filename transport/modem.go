@@ -0,0 +1,16 @@
+package transport
+
+import "errors"
+
+// ErrModemUnsupported is returned when a port cannot drive the DTR and RTS
+// lines, e.g. network bridges or serial ports on Windows.
+var ErrModemUnsupported = errors.New("transport: the port cannot drive DTR and RTS")
+
+// ModemLines is implemented by ports that can drive the modem control lines of
+// the adapter, so a module with its reset or power pin wired to DTR or RTS
+// can be rebooted without unplugging it. Ports returned by OpenSerial
+// implement it on Linux and macOS.
+type ModemLines interface {
+	SetDTR(asserted bool) error
+	SetRTS(asserted bool) error
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !tinygo
+
+package transport
+
+const (
+	lineDTR = iota
+	lineRTS
+)
+
+func setModemLine(name string, line int, asserted bool) error {
+	return ErrModemUnsupported
+}
@@ -0,0 +1,35 @@
+//go:build (linux || darwin) && !tinygo
+
+package transport
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	lineDTR = unix.TIOCM_DTR
+	lineRTS = unix.TIOCM_RTS
+)
+
+// setModemLine drives line through a second descriptor of the device, the
+// lines belong to the device so they change for the open port as well.
+func setModemLine(name string, line int, asserted bool) error {
+	fd, err := unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer unix.Close(fd)
+	request := uint(unix.TIOCMBIC)
+	if asserted {
+		request = unix.TIOCMBIS
+	}
+	if err := unix.IoctlSetPointerInt(fd, request, line); err != nil {
+		if err == unix.ENOTTY || err == unix.EINVAL {
+			return ErrModemUnsupported
+		}
+		return &os.PathError{Op: "ioctl", Path: name, Err: err}
+	}
+	return nil
+}
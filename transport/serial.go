@@ -0,0 +1,60 @@
+//go:build !tinygo
+
+package transport
+
+import (
+	"io"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// OpenSerial opens a local serial port with 8N1 framing.
+func OpenSerial(config SerialConfig) (Port, error) {
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = DefaultReadTimeout
+	}
+	port, err := serial.OpenPort(&serial.Config{
+		Name:        config.Name,
+		Baud:        config.Baud,
+		ReadTimeout: config.ReadTimeout,
+		Parity:      serial.ParityNone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &serialPort{Port: port, name: config.Name, timeout: config.ReadTimeout}, nil
+}
+
+// serialPort adds control of the modem lines, which the serial package
+// doesn't offer.
+type serialPort struct {
+	*serial.Port
+	name    string
+	timeout time.Duration
+}
+
+// Read returns ErrReadTimeout for a read that timed out. The serial package
+// returns nothing then, with io.EOF on Linux, which a hung up device returns
+// right away instead, so only an empty read taking about the timeout counts.
+func (p *serialPort) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := p.Port.Read(b)
+	if n == 0 && len(b) > 0 && (err == nil || err == io.EOF) && time.Since(start) >= p.timeout/2 {
+		return 0, ErrReadTimeout
+	}
+	return n, err
+}
+
+func (p *serialPort) SetDTR(asserted bool) error {
+	return setModemLine(p.name, lineDTR, asserted)
+}
+
+func (p *serialPort) SetRTS(asserted bool) error {
+	return setModemLine(p.name, lineRTS, asserted)
+}
+
+// Name returns the device path the port was opened with.
+func (p *serialPort) Name() string {
+	return p.name
+}
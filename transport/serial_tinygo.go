@@ -0,0 +1,13 @@
+//go:build tinygo
+
+package transport
+
+import "errors"
+
+// ErrSerialUnsupported is returned by OpenSerial on TinyGo, where the module
+// is reached through a machine.UART opened with OpenUART instead.
+var ErrSerialUnsupported = errors.New("serial ports are not supported on TinyGo, use OpenUART")
+
+func OpenSerial(config SerialConfig) (Port, error) {
+	return nil, ErrSerialUnsupported
+}
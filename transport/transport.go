@@ -0,0 +1,45 @@
+// Package transport provides the byte streams an LD2451 can be reached over.
+// The device layer only needs a Port, so serial adapters, serial-to-network
+// bridges, BLE UART bridges or test doubles are interchangeable.
+package transport
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// DefaultReadTimeout bounds how long a serial read waits for data.
+const DefaultReadTimeout = 2 * time.Second
+
+// ErrReadTimeout is returned by the ports of OpenSerial when no data arrived
+// within SerialConfig.ReadTimeout. The port stays usable, a module that is
+// silent, e.g. in config mode, has not failed.
+var ErrReadTimeout = errors.New("read timeout")
+
+// Port is a bidirectional byte stream to the module.
+type Port interface {
+	io.Reader
+	io.Writer
+	io.Closer
+}
+
+// Flusher is implemented by ports that can discard the bytes received but not
+// read yet, and those written but not sent yet. Ports returned by OpenSerial
+// implement it.
+type Flusher interface {
+	Flush() error
+}
+
+type SerialConfig struct {
+	Name        string        // Device path, e.g. /dev/ttyUSB0 or COM3
+	Baud        int           // Baud rate configured on the module
+	ReadTimeout time.Duration // Defaults to DefaultReadTimeout
+}
+
+// DialTCP connects to a serial-to-network bridge exposing the module's UART
+// as a raw TCP stream.
+func DialTCP(address string, timeout time.Duration) (Port, error) {
+	return net.DialTimeout("tcp", address, timeout)
+}
@@ -0,0 +1,73 @@
+//go:build tinygo
+
+package transport
+
+import (
+	"io"
+	"machine"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultUARTPollInterval is how long a UART read sleeps while no data is
+// buffered.
+const DefaultUARTPollInterval = time.Millisecond
+
+type UARTConfig struct {
+	BaudRate     uint32        // Baud rate configured on the module (default 115200)
+	TX           machine.Pin   // Pin wired to the module's RX, machine.NoPin keeps the board default
+	RX           machine.Pin   // Pin wired to the module's TX, machine.NoPin keeps the board default
+	PollInterval time.Duration // Defaults to DefaultUARTPollInterval
+}
+
+// OpenUART configures uart for the module with 8N1 framing, e.g.
+// OpenUART(machine.UART1, UARTConfig{TX: machine.GP4, RX: machine.GP5}) on a
+// RP2040.
+func OpenUART(uart *machine.UART, config UARTConfig) (Port, error) {
+	if config.BaudRate == 0 {
+		config.BaudRate = 115200
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultUARTPollInterval
+	}
+	err := uart.Configure(machine.UARTConfig{
+		BaudRate: config.BaudRate,
+		TX:       config.TX,
+		RX:       config.RX,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &uartPort{uart: uart, poll: config.PollInterval}, nil
+}
+
+type uartPort struct {
+	uart   *machine.UART
+	poll   time.Duration
+	closed atomic.Bool
+}
+
+// Read waits for buffered data since machine.UART.Read returns immediately
+// when there is none.
+func (p *uartPort) Read(b []byte) (int, error) {
+	for p.uart.Buffered() == 0 {
+		if p.closed.Load() {
+			return 0, io.EOF
+		}
+		time.Sleep(p.poll)
+	}
+	return p.uart.Read(b)
+}
+
+func (p *uartPort) Write(b []byte) (int, error) {
+	if p.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	return p.uart.Write(b)
+}
+
+// Close ends pending and future reads. The UART itself stays configured.
+func (p *uartPort) Close() error {
+	p.closed.Store(true)
+	return nil
+}
@@ -0,0 +1,77 @@
+package LD2451
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// defaultMaxAngle is the widest angle accepted by default, anything beyond
+// it would be behind the antenna. The distance and speed defaults are the
+// detection ranges of the datasheet.
+const (
+	defaultMaxAngle    = 90
+	defaultMaxDistance = 100
+	defaultMaxSpeed    = 120
+)
+
+type ValidationPolicy int
+
+const (
+	ValidateReject ValidationPolicy = 0 // Drop out of range targets and report a ParseError for each
+	ValidateClamp  ValidationPolicy = 1 // Clamp angle, distance and speed into range, targets with an unknown direction are still dropped
+	ValidateOff    ValidationPolicy = 2 // Deliver targets as decoded
+)
+
+// TargetLimits bounds the values a plausible target has. Decoded targets
+// outside them come from corrupted frames and are handled according to
+// Config.TargetValidation.
+type TargetLimits struct {
+	MaxAngle    int // Largest absolute angle in degrees (default 90)
+	MaxDistance int // Farthest distance in meters (default 100)
+	MaxSpeed    int // Highest speed in KM/H (default 120)
+}
+
+// validate checks the i-th target decoded from payload against
+// Config.TargetLimits and reports whether it may be delivered, possibly
+// clamped.
+func (ld2451 *LD2451) validate(i int, target Target, payload []byte, received time.Time) (Target, bool) {
+	policy := ld2451.config.TargetValidation
+	if policy == ValidateOff {
+		return target, true
+	}
+	limits := ld2451.config.TargetLimits
+
+	var reason string
+	switch {
+	case target.Direction != DirectionAway && target.Direction != DirectionToward:
+		reason = fmt.Sprintf("target %d has unknown direction %d", i, target.Direction)
+		policy = ValidateReject
+	case target.Angle < -limits.MaxAngle || target.Angle > limits.MaxAngle:
+		reason = fmt.Sprintf("target %d angle %d° is outside ±%d°", i, target.Angle, limits.MaxAngle)
+	case target.Distance > limits.MaxDistance:
+		reason = fmt.Sprintf("target %d distance %d m exceeds %d m", i, target.Distance, limits.MaxDistance)
+	case target.Speed > limits.MaxSpeed:
+		reason = fmt.Sprintf("target %d speed %d km/h exceeds %d km/h", i, target.Speed, limits.MaxSpeed)
+	default:
+		return target, true
+	}
+
+	if policy == ValidateClamp {
+		target.Angle = max(-limits.MaxAngle, min(target.Angle, limits.MaxAngle))
+		target.Distance = min(target.Distance, limits.MaxDistance)
+		target.FineDistance = min(target.FineDistance, float64(limits.MaxDistance))
+		target.Speed = min(target.Speed, limits.MaxSpeed)
+		target.FineSpeed = min(target.FineSpeed, float64(limits.MaxSpeed))
+		ld2451.recordClampedTarget()
+		ld2451.config.Logger.Debug("clamped out of range target", "reason", reason)
+		return target, true
+	}
+
+	err := &ParseError{Reason: reason, Payload: bytes.Clone(payload)}
+	ld2451.recordInvalidTarget()
+	ld2451.config.Logger.Warn("dropping out of range target", "error", err)
+	ld2451.publish(ParseErrorEvent{Err: err, Time: received, Sensor: ld2451.config.SensorID})
+	ld2451.reportDiagnostic(err)
+	return target, false
+}
@@ -0,0 +1,46 @@
+package LD2451_test
+
+import (
+	"testing"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+func TestTargetValidation(t *testing.T) {
+	target := func(angle, distance, speed int) LD2451.Target {
+		return LD2451.Target{Angle: angle, Distance: distance, Direction: LD2451.DirectionToward, Speed: speed, SNR: 10}
+	}
+	tests := []struct {
+		name     string
+		config   LD2451.Config
+		target   LD2451.Target
+		expected []LD2451.Target //compared by angle, distance and speed
+	}{
+		{"plausible", LD2451.Config{}, target(10, 100, 120), []LD2451.Target{target(10, 100, 120)}},
+		//the defaults are the ranges of the datasheet
+		{"too far", LD2451.Config{}, target(10, 101, 50), nil},
+		{"too fast", LD2451.Config{}, target(10, 50, 121), nil},
+		{"behind the antenna", LD2451.Config{}, target(-91, 50, 50), nil},
+		{"clamped", LD2451.Config{TargetValidation: LD2451.ValidateClamp}, target(95, 200, 250), []LD2451.Target{target(90, 100, 120)}},
+		{
+			"own limits",
+			LD2451.Config{TargetLimits: LD2451.TargetLimits{MaxAngle: 30, MaxDistance: 200, MaxSpeed: 250}},
+			target(10, 200, 250),
+			[]LD2451.Target{target(10, 200, 250)},
+		},
+		{"off", LD2451.Config{TargetValidation: LD2451.ValidateOff}, target(10, 200, 250), []LD2451.Target{target(10, 200, 250)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targets := readThrough(t, test.config, []LD2451.Target{test.target})
+			if len(targets) != len(test.expected) {
+				t.Fatalf("got %+v, expected %+v", targets, test.expected)
+			}
+			for i, got := range targets {
+				if expected := test.expected[i]; got.Angle != expected.Angle || got.Distance != expected.Distance || got.Speed != expected.Speed {
+					t.Errorf("got %+v, expected %+v", got, expected)
+				}
+			}
+		})
+	}
+}
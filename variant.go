@@ -0,0 +1,59 @@
+package LD2451
+
+import (
+	"encoding/binary"
+
+	"github.com/Battlekeeper/LD2451/v2/protocol"
+)
+
+// ProtocolInfo describes the protocol revision frames are decoded with.
+type ProtocolInfo struct {
+	Version  uint16           `json:"version"`  // Protocol version reported when entering config mode, zero until detected
+	Firmware FirmwareVersion  `json:"firmware"` // Zero until detected
+	Variant  protocol.Variant `json:"variant"`  // Frame layout targets are decoded with
+}
+
+// ProtocolVersion returns the protocol revision found by DetectProtocol.
+// Until it ran, frames are decoded in the Config.FrameVariant layout.
+func (ld2451 *LD2451) ProtocolVersion() ProtocolInfo {
+	ld2451.protocolMu.Lock()
+	defer ld2451.protocolMu.Unlock()
+	return ld2451.protocol
+}
+
+// DetectProtocol reads the protocol and firmware version from the module and
+// switches decoding to the frame layout they use, so a firmware revision
+// with longer target records is decoded instead of every frame failing to
+// parse. Config.FrameVariant keeps being used when set. Open calls it with
+// Config.DetectProtocol.
+func (ld2451 *LD2451) DetectProtocol() (ProtocolInfo, error) {
+	var info ProtocolInfo
+	err := ld2451.configure("DetectProtocol", func() error {
+		if len(ld2451.configInfo) >= 2 {
+			info.Version = binary.LittleEndian.Uint16(ld2451.configInfo)
+		}
+		var err error
+		info.Firmware, err = ld2451.readFirmwareVersion()
+		return err
+	})
+	if err != nil {
+		return ProtocolInfo{}, err
+	}
+	info.Variant = protocol.VariantFor(info.Version)
+	if ld2451.config.FrameVariant != nil {
+		info.Variant = *ld2451.config.FrameVariant
+	}
+	ld2451.config.Logger.Info("detected protocol", "version", info.Version, "firmware", info.Firmware.String(), "variant", info.Variant.Name)
+
+	ld2451.protocolMu.Lock()
+	ld2451.protocol = info
+	ld2451.protocolMu.Unlock()
+	return info, nil
+}
+
+// variant returns the frame layout frames are currently decoded with.
+func (ld2451 *LD2451) variant() protocol.Variant {
+	ld2451.protocolMu.Lock()
+	defer ld2451.protocolMu.Unlock()
+	return ld2451.protocol.Variant
+}
@@ -0,0 +1,170 @@
+// Package warning turns approaching targets into a warning level, e.g. for
+// driving a warning light for cyclists or a driveway alert. Rules raise a
+// severity when a target approaches faster than a speed within a distance.
+// Hysteresis and a hold time keep the level from flickering while a target
+// hovers around a threshold.
+//
+//	engine, err := warning.New(warning.Config{Rules: []warning.Rule{
+//		{Severity: warning.SeverityNotice, MinSpeed: 10, MaxDistance: 50},
+//		{Severity: warning.SeverityCritical, MinSpeed: 30, MaxDistance: 20},
+//	}})
+//	...
+//	if w, changed := engine.Update(frame); changed {
+//		setLight(w.Severity)
+//	}
+package warning
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+type Severity int
+
+const (
+	SeverityNone     Severity = 0
+	SeverityNotice   Severity = 1
+	SeverityWarning  Severity = 2
+	SeverityCritical Severity = 3
+)
+
+const (
+	DefaultSpeedHysteresis    = 3
+	DefaultDistanceHysteresis = 3
+	DefaultHoldTime           = time.Second
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityNone:
+		return "None"
+	case SeverityNotice:
+		return "Notice"
+	case SeverityWarning:
+		return "Warning"
+	case SeverityCritical:
+		return "Critical"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+// Rule raises Severity while a target approaches at MinSpeed or faster within
+// MaxDistance.
+type Rule struct {
+	Severity    Severity `json:"severity"`
+	MinSpeed    int      `json:"min_speed"`    // KM/H
+	MaxDistance int      `json:"max_distance"` // Meters
+}
+
+type Config struct {
+	Rules              []Rule
+	SpeedHysteresis    int           // A raised rule keeps holding until the speed drops this many KM/H below MinSpeed (default DefaultSpeedHysteresis)
+	DistanceHysteresis int           // ... or the target is this many meters beyond MaxDistance (default DefaultDistanceHysteresis)
+	HoldTime           time.Duration // The level is only lowered after no rule held it for this long (default DefaultHoldTime)
+}
+
+// Warning is a change of the warning level.
+type Warning struct {
+	Severity Severity      `json:"severity"`
+	Previous Severity      `json:"previous"`
+	Target   LD2451.Target `json:"target"` // Target that raised the level, zero when it was lowered
+	Time     time.Time     `json:"time"`
+}
+
+func (w Warning) EventTime() time.Time {
+	return w.Time
+}
+
+// Engine tracks the warning level. It is not safe for concurrent use.
+type Engine struct {
+	config   Config
+	severity Severity
+	held     time.Time //when a rule last held the current level
+}
+
+// New validates the rules, which must have a positive severity and a
+// non-negative speed and distance.
+func New(config Config) (*Engine, error) {
+	for _, rule := range config.Rules {
+		if rule.Severity <= SeverityNone {
+			return nil, fmt.Errorf("rule severity %d must be positive", rule.Severity)
+		}
+		if rule.MinSpeed < 0 || rule.MaxDistance < 0 {
+			return nil, fmt.Errorf("rule %+v has a negative threshold", rule)
+		}
+	}
+	if config.SpeedHysteresis <= 0 {
+		config.SpeedHysteresis = DefaultSpeedHysteresis
+	}
+	if config.DistanceHysteresis <= 0 {
+		config.DistanceHysteresis = DefaultDistanceHysteresis
+	}
+	if config.HoldTime <= 0 {
+		config.HoldTime = DefaultHoldTime
+	}
+	return &Engine{config: config}, nil
+}
+
+// Severity returns the current warning level.
+func (e *Engine) Severity() Severity {
+	return e.severity
+}
+
+// Update evaluates the targets of frame and returns the resulting warning
+// when the level changed.
+func (e *Engine) Update(frame LD2451.Frame) (Warning, bool) {
+	now := frame.Time
+	raise, keep := SeverityNone, SeverityNone
+	var trigger LD2451.Target
+	for _, target := range frame.Targets {
+		if target.Direction != LD2451.DirectionToward {
+			continue
+		}
+		for _, rule := range e.config.Rules {
+			if rule.Severity > raise && target.Speed >= rule.MinSpeed && target.Distance <= rule.MaxDistance {
+				raise, trigger = rule.Severity, target
+			}
+			if rule.Severity > keep &&
+				target.Speed >= rule.MinSpeed-e.config.SpeedHysteresis &&
+				target.Distance <= rule.MaxDistance+e.config.DistanceHysteresis {
+				keep = rule.Severity
+			}
+		}
+	}
+	return e.evaluate(now, raise, keep, trigger)
+}
+
+// Expire lowers the level once the hold time passed without targets, for
+// when no frames arrive. Update expires the level itself.
+func (e *Engine) Expire(now time.Time) (Warning, bool) {
+	return e.evaluate(now, SeverityNone, SeverityNone, LD2451.Target{})
+}
+
+func (e *Engine) evaluate(now time.Time, raise, keep Severity, trigger LD2451.Target) (Warning, bool) {
+	previous := e.severity
+	switch {
+	case raise > e.severity:
+		e.severity, e.held = raise, now
+	case keep >= e.severity:
+		e.held = now
+		return Warning{}, false
+	case now.Sub(e.held) >= e.config.HoldTime:
+		//step down to what the hysteresis still holds
+		e.severity, e.held = keep, now
+		trigger = LD2451.Target{}
+	default:
+		return Warning{}, false
+	}
+	if e.severity == previous {
+		return Warning{}, false
+	}
+	return Warning{Severity: e.severity, Previous: previous, Target: trigger, Time: now}, true
+}
@@ -0,0 +1,91 @@
+package warning_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+	"github.com/Battlekeeper/LD2451/v2/warning"
+)
+
+var rules = []warning.Rule{
+	{Severity: warning.SeverityNotice, MinSpeed: 10, MaxDistance: 50},
+	{Severity: warning.SeverityCritical, MinSpeed: 30, MaxDistance: 20},
+}
+
+func toward(speed, distance int) LD2451.Target {
+	return LD2451.Target{Direction: LD2451.DirectionToward, Speed: speed, Distance: distance}
+}
+
+func TestEngine(t *testing.T) {
+	engine, err := warning.New(warning.Config{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name     string
+		at       time.Duration
+		targets  []LD2451.Target
+		expire   bool             // call Expire instead of Update
+		severity warning.Severity // level after the step
+		changed  bool
+	}{
+		{"nothing approaching", 0, nil, false, warning.SeverityNone, false},
+		{"receding", 100 * time.Millisecond, []LD2451.Target{{Direction: LD2451.DirectionAway, Speed: 50, Distance: 10}}, false, warning.SeverityNone, false},
+		{"too slow", 200 * time.Millisecond, []LD2451.Target{toward(9, 40)}, false, warning.SeverityNone, false},
+		{"notice", 300 * time.Millisecond, []LD2451.Target{toward(20, 40)}, false, warning.SeverityNotice, true},
+		{"critical", 400 * time.Millisecond, []LD2451.Target{toward(20, 40), toward(40, 18)}, false, warning.SeverityCritical, true},
+		{"held by the hysteresis", 500 * time.Millisecond, []LD2451.Target{toward(28, 22)}, false, warning.SeverityCritical, false},
+		{"below the hysteresis", 600 * time.Millisecond, []LD2451.Target{toward(26, 22)}, false, warning.SeverityCritical, false},
+		{"hold time passed", 1500 * time.Millisecond, []LD2451.Target{toward(26, 22)}, false, warning.SeverityNotice, true},
+		{"target gone", 1600 * time.Millisecond, nil, false, warning.SeverityNotice, false},
+		{"expired", 2500 * time.Millisecond, nil, true, warning.SeverityNone, true},
+	}
+	previous := warning.SeverityNone
+	for _, step := range steps {
+		var w warning.Warning
+		var changed bool
+		if step.expire {
+			w, changed = engine.Expire(start.Add(step.at))
+		} else {
+			w, changed = engine.Update(LD2451.Frame{Targets: step.targets, Time: start.Add(step.at)})
+		}
+		if changed != step.changed || engine.Severity() != step.severity {
+			t.Fatalf("%s: level %s, changed %t; expected %s, %t", step.name, engine.Severity(), changed, step.severity, step.changed)
+		}
+		if !changed {
+			continue
+		}
+		if w.Severity != step.severity || w.Previous != previous || !w.Time.Equal(start.Add(step.at)) {
+			t.Errorf("%s: got %+v", step.name, w)
+		}
+		//only raising a level names the target
+		if raised := step.severity > previous; raised == (w.Target == LD2451.Target{}) {
+			t.Errorf("%s: warning target %+v", step.name, w.Target)
+		}
+		previous = step.severity
+	}
+}
+
+func TestNewValidatesRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  warning.Rule
+		valid bool
+	}{
+		{"valid", warning.Rule{Severity: warning.SeverityWarning, MinSpeed: 10, MaxDistance: 30}, true},
+		{"zero thresholds", warning.Rule{Severity: warning.SeverityNotice}, true},
+		{"no severity", warning.Rule{MinSpeed: 10, MaxDistance: 30}, false},
+		{"negative speed", warning.Rule{Severity: warning.SeverityNotice, MinSpeed: -1}, false},
+		{"negative distance", warning.Rule{Severity: warning.SeverityNotice, MaxDistance: -1}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := warning.New(warning.Config{Rules: []warning.Rule{test.rule}})
+			if (err == nil) != test.valid {
+				t.Errorf("got %v, expected valid %t", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+// Package webhook POSTs JSON notifications to HTTP endpoints when a target
+// exceeds a speed limit, the alarm changes or the sensor goes offline, so
+// services like Home Assistant, n8n or a custom backend can react without
+// code of their own.
+//
+//	notifier, err := webhook.New(sensor, webhook.Config{
+//		Endpoints:  []webhook.Endpoint{{URL: "https://example.com/hook", Secret: "s3cret"}},
+//		SpeedLimit: 50,
+//	})
+//	...
+//	go notifier.Run(ctx)
+//
+// With a Secret, requests carry the hex HMAC-SHA256 of the body keyed with it
+// in the X-LD2451-Signature header as "sha256=<hex>", which receivers should
+// compare in constant time before trusting the payload.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// EventType selects what an endpoint is notified about.
+type EventType string
+
+const (
+	EventSpeeding EventType = "speeding" // A target faster than the speed limit of its direction
+	EventAlarm    EventType = "alarm"    // The alarm was raised or cleared
+	EventOffline  EventType = "offline"  // The port to the module failed
+	EventOnline   EventType = "online"   // The port was reopened after going offline
+)
+
+const (
+	SignatureHeader = "X-LD2451-Signature"
+
+	DefaultRetries          = 3
+	DefaultRetryBackoff     = time.Second
+	DefaultTimeout          = 10 * time.Second
+	DefaultSpeedingCooldown = 10 * time.Second
+	DefaultQueueSize        = 64
+)
+
+// Endpoint is a URL notified about Events, or about every event when Events
+// is empty.
+type Endpoint struct {
+	URL    string      `json:"url"`
+	Secret string      `json:"secret,omitempty"` // Key the body is signed with, unsigned when empty
+	Events []EventType `json:"events,omitempty"`
+}
+
+type Config struct {
+	Endpoints        []Endpoint
+	SpeedLimit       int           // Targets faster than this many KM/H are reported as EventSpeeding, zero disables them
+	SpeedingCooldown time.Duration // Further speeding targets are not reported for this long, so one vehicle isn't reported every frame (default DefaultSpeedingCooldown)
+	TowardSpeedLimit int           // Speed limit for approaching targets instead of SpeedLimit, zero uses SpeedLimit
+	AwaySpeedLimit   int           // Speed limit for receding targets instead of SpeedLimit, zero uses SpeedLimit
+	Retries          int           // Attempts after a failed delivery, negative disables retries (default DefaultRetries)
+	RetryBackoff     time.Duration // Wait before the first retry, doubling with every further one (default DefaultRetryBackoff)
+	Timeout          time.Duration // Limit for a single request (default DefaultTimeout)
+	QueueSize        int           // Notifications waiting for delivery before new ones are dropped (default DefaultQueueSize)
+	Client           *http.Client  // Defaults to http.DefaultClient
+	Logger           LD2451.Logger // Receives failed deliveries, discarded when nil
+	Clock            LD2451.Clock  // Source of time for RetryBackoff, the one of the sensor; nil uses LD2451.SystemClock
+}
+
+// Payload is the JSON body posted to the endpoints. Only the field matching
+// Event is set.
+type Payload struct {
+	Event  EventType          `json:"event"`
+	Time   time.Time          `json:"time"`
+	Target *LD2451.Target     `json:"target,omitempty"` // Speeding target
+	Alarm  *LD2451.AlarmEvent `json:"alarm,omitempty"`
+	Error  string             `json:"error,omitempty"` // Why the sensor went offline
+}
+
+// StatusError is a delivery the endpoint answered with a non 2xx status.
+type StatusError struct {
+	URL    string
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook %s answered %d %s", e.URL, e.Status, http.StatusText(e.Status))
+}
+
+// retryable reports whether the endpoint may accept the same request later.
+func (e *StatusError) retryable() bool {
+	return e.Status >= 500 || e.Status == http.StatusTooManyRequests || e.Status == http.StatusRequestTimeout
+}
+
+// Trigger picks the sensor events worth a notification. It is shared with
+// the notify package, so chat messages fire on the same conditions as
+// webhooks. It is not safe for concurrent use.
+//
+// The module itself has a single trigger speed for both directions, so
+// separate limits for approaching and receding traffic are applied here.
+type Trigger struct {
+	SpeedLimit       int           // Targets faster than this many KM/H are reported as EventSpeeding, zero disables them
+	SpeedingCooldown time.Duration // Further speeding targets are not reported for this long
+	TowardSpeedLimit int           // Speed limit for approaching targets instead of SpeedLimit, zero uses SpeedLimit
+	AwaySpeedLimit   int           // Speed limit for receding targets instead of SpeedLimit, zero uses SpeedLimit
+
+	speeding time.Time //when the last speeding target was reported
+	offline  bool
+}
+
+// Match turns a sensor event into a notification.
+func (t *Trigger) Match(event LD2451.Event) (Payload, bool) {
+	switch event := event.(type) {
+	case LD2451.TargetEvent:
+		if limit := t.speedLimit(event.Direction); limit == 0 || event.Speed <= limit {
+			return Payload{}, false
+		}
+		if !t.speeding.IsZero() && event.Time.Sub(t.speeding) < t.SpeedingCooldown {
+			return Payload{}, false
+		}
+		t.speeding = event.Time
+		return Payload{Event: EventSpeeding, Time: event.Time, Target: &event.Target}, true
+	case LD2451.AlarmEvent:
+		return Payload{Event: EventAlarm, Time: event.Time, Alarm: &event}, true
+	case LD2451.ConnectionEvent:
+		switch {
+		case event.Status == LD2451.Disconnected && !t.offline:
+			t.offline = true
+			payload := Payload{Event: EventOffline, Time: event.Time}
+			if event.Err != nil {
+				payload.Error = event.Err.Error()
+			}
+			return payload, true
+		case event.Status == LD2451.Connected && t.offline:
+			t.offline = false
+			return Payload{Event: EventOnline, Time: event.Time}, true
+		}
+	}
+	return Payload{}, false
+}
+
+// speedLimit returns the limit for targets moving in direction.
+func (t *Trigger) speedLimit(direction LD2451.Direction) int {
+	switch {
+	case direction == LD2451.DirectionToward && t.TowardSpeedLimit > 0:
+		return t.TowardSpeedLimit
+	case direction == LD2451.DirectionAway && t.AwaySpeedLimit > 0:
+		return t.AwaySpeedLimit
+	}
+	return t.SpeedLimit
+}
+
+// ValidEvent reports whether event is one of the EventType constants.
+func ValidEvent(event EventType) bool {
+	switch event {
+	case EventSpeeding, EventAlarm, EventOffline, EventOnline:
+		return true
+	}
+	return false
+}
+
+type Notifier struct {
+	sensor  *LD2451.LD2451
+	config  Config
+	queue   chan Payload
+	trigger Trigger
+
+	mu      sync.Mutex
+	dropped uint64
+}
+
+// New validates the endpoints and applies the defaults.
+func New(sensor *LD2451.LD2451, config Config) (*Notifier, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("no webhook endpoints")
+	}
+	for _, endpoint := range config.Endpoints {
+		u, err := url.Parse(endpoint.URL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("webhook URL %q is not http or https", endpoint.URL)
+		}
+		for _, event := range endpoint.Events {
+			if !ValidEvent(event) {
+				return nil, fmt.Errorf("unknown webhook event %q", event)
+			}
+		}
+	}
+	switch {
+	case config.SpeedLimit < 0:
+		return nil, fmt.Errorf("speed limit %d is negative", config.SpeedLimit)
+	case config.TowardSpeedLimit < 0:
+		return nil, fmt.Errorf("toward speed limit %d is negative", config.TowardSpeedLimit)
+	case config.AwaySpeedLimit < 0:
+		return nil, fmt.Errorf("away speed limit %d is negative", config.AwaySpeedLimit)
+	case config.SpeedingCooldown < 0:
+		return nil, fmt.Errorf("speeding cooldown %s is negative", config.SpeedingCooldown)
+	}
+	if config.SpeedingCooldown == 0 {
+		config.SpeedingCooldown = DefaultSpeedingCooldown
+	}
+	if config.Retries == 0 {
+		config.Retries = DefaultRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Clock == nil {
+		config.Clock = LD2451.SystemClock
+	}
+	trigger := Trigger{
+		SpeedLimit:       config.SpeedLimit,
+		SpeedingCooldown: config.SpeedingCooldown,
+		TowardSpeedLimit: config.TowardSpeedLimit,
+		AwaySpeedLimit:   config.AwaySpeedLimit,
+	}
+	return &Notifier{
+		sensor:  sensor,
+		config:  config,
+		queue:   make(chan Payload, config.QueueSize),
+		trigger: trigger,
+	}, nil
+}
+
+// Dropped returns the number of notifications dropped because the queue was
+// full, e.g. while an endpoint was unreachable for a long time.
+func (n *Notifier) Dropped() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.dropped
+}
+
+// Run subscribes to the sensor's events and delivers notifications until ctx
+// is done or the sensor is closed. Deliveries happen in the background, so a
+// slow endpoint never holds up the subscription; notifications still queued
+// when the sensor is closed are delivered before Run returns.
+func (n *Notifier) Run(ctx context.Context) error {
+	events, cancel := n.sensor.Events()
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for payload := range n.queue {
+			n.deliver(ctx, payload)
+		}
+	}()
+	defer func() {
+		close(n.queue)
+		<-done
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if payload, ok := n.trigger.Match(event); ok {
+				n.enqueue(payload)
+			}
+		}
+	}
+}
+
+func (n *Notifier) enqueue(payload Payload) {
+	select {
+	case n.queue <- payload:
+	default:
+		n.mu.Lock()
+		n.dropped++
+		n.mu.Unlock()
+	}
+}
+
+// deliver posts payload to every endpoint selecting its event.
+func (n *Notifier) deliver(ctx context.Context, payload Payload) {
+	for _, endpoint := range n.config.Endpoints {
+		if len(endpoint.Events) > 0 && !slices.Contains(endpoint.Events, payload.Event) {
+			continue
+		}
+		if err := n.Send(ctx, endpoint, payload); err != nil && n.config.Logger != nil {
+			n.config.Logger.Warn("webhook delivery failed", "url", endpoint.URL, "event", payload.Event, "err", err)
+		}
+	}
+}
+
+// Send posts payload to endpoint, retrying network errors and 5xx, 408 and
+// 429 answers with exponential backoff. Other answers are returned as a
+// *StatusError right away.
+func (n *Notifier) Send(ctx context.Context, endpoint Endpoint, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	backoff := n.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = n.post(ctx, endpoint, body)
+		var status *StatusError
+		if err == nil || attempt >= n.config.Retries || (errors.As(err, &status) && !status.retryable()) {
+			return err
+		}
+		if !n.wait(ctx, backoff) {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// wait reports whether d passed before ctx was done.
+func (n *Notifier) wait(ctx context.Context, d time.Duration) bool {
+	timer := n.config.Clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, endpoint Endpoint, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ld2451-webhook")
+	if endpoint.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(endpoint.Secret, body))
+	}
+	resp, err := n.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	//drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: endpoint.URL, Status: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the signature header value for body, for receivers written in
+// Go to compare against with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
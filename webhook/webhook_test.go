@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Battlekeeper/LD2451/v2"
+)
+
+// recorder answers requests with the statuses in order, the last one
+// repeating, and records when each request arrived.
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	arrivals []time.Time
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.arrivals = append(r.arrivals, time.Now())
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+	status := r.statuses[min(len(r.arrivals), len(r.statuses))-1]
+	w.WriteHeader(status)
+}
+
+func newNotifier(t *testing.T, config Config) *Notifier {
+	t.Helper()
+	notifier, err := New(nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notifier
+}
+
+func TestSendSignsTheBody(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusNoContent}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	endpoint := Endpoint{URL: server.URL, Secret: "s3cret"}
+	notifier := newNotifier(t, Config{Endpoints: []Endpoint{endpoint}})
+
+	payload := Payload{Event: EventSpeeding, Time: time.Unix(1700000000, 0), Target: &LD2451.Target{Distance: 20, Speed: 72}}
+	if err := notifier.Send(context.Background(), endpoint, payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.bodies) != 1 {
+		t.Fatalf("%d requests, want 1", len(rec.bodies))
+	}
+	body, header := rec.bodies[0], rec.headers[0]
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := header.Get(SignatureHeader); got != want {
+		t.Fatalf("signature header %q, want %q over the received body", got, want)
+	}
+	if got := header.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("content type %q, want application/json", got)
+	}
+	var received Payload
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatal(err)
+	}
+	if received.Event != EventSpeeding || received.Target == nil || received.Target.Speed != 72 {
+		t.Fatalf("received %+v, want the speeding payload", received)
+	}
+}
+
+func TestSendWithoutSecretIsUnsigned(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	endpoint := Endpoint{URL: server.URL}
+	notifier := newNotifier(t, Config{Endpoints: []Endpoint{endpoint}})
+
+	if err := notifier.Send(context.Background(), endpoint, Payload{Event: EventOnline}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.headers[0].Get(SignatureHeader); got != "" {
+		t.Fatalf("unsigned endpoint got signature %q", got)
+	}
+}
+
+func TestSendRetriesWithBackoff(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+	rec := &recorder{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	endpoint := Endpoint{URL: server.URL, Secret: "s3cret"}
+	notifier := newNotifier(t, Config{Endpoints: []Endpoint{endpoint}, RetryBackoff: backoff})
+
+	if err := notifier.Send(context.Background(), endpoint, Payload{Event: EventAlarm}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.arrivals) != 3 {
+		t.Fatalf("%d attempts, want 3", len(rec.arrivals))
+	}
+	for i, want := range []time.Duration{backoff, 2 * backoff} {
+		if gap := rec.arrivals[i+1].Sub(rec.arrivals[i]); gap < want {
+			t.Fatalf("retry %d came after %s, want at least %s", i+1, gap, want)
+		}
+	}
+	for i, body := range rec.bodies {
+		if got := rec.headers[i].Get(SignatureHeader); got != Sign("s3cret", body) {
+			t.Fatalf("attempt %d carries signature %q, want the one of its body", i+1, got)
+		}
+	}
+}
+
+func TestSendGivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		retries  int
+		attempts int
+	}{
+		{"client error", http.StatusBadRequest, 3, 1},
+		{"retries exhausted", http.StatusInternalServerError, 2, 3},
+		{"retries disabled", http.StatusBadGateway, -1, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := &recorder{statuses: []int{test.status}}
+			server := httptest.NewServer(rec)
+			defer server.Close()
+			endpoint := Endpoint{URL: server.URL}
+			notifier := newNotifier(t, Config{Endpoints: []Endpoint{endpoint}, Retries: test.retries, RetryBackoff: time.Millisecond})
+
+			err := notifier.Send(context.Background(), endpoint, Payload{Event: EventOffline})
+			var status *StatusError
+			if !errors.As(err, &status) || status.Status != test.status {
+				t.Fatalf("Send returned %v, want a StatusError with %d", err, test.status)
+			}
+			if len(rec.arrivals) != test.attempts {
+				t.Fatalf("%d attempts, want %d", len(rec.arrivals), test.attempts)
+			}
+		})
+	}
+}
+
+func TestSendStopsRetryingWhenCanceled(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	endpoint := Endpoint{URL: server.URL}
+	notifier := newNotifier(t, Config{Endpoints: []Endpoint{endpoint}, RetryBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := notifier.Send(ctx, endpoint, Payload{Event: EventOffline}); err == nil {
+		t.Fatal("Send succeeded against a failing endpoint")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Send waited %s for a backoff after ctx was done", elapsed)
+	}
+	if len(rec.arrivals) != 1 {
+		t.Fatalf("%d attempts, want 1", len(rec.arrivals))
+	}
+}
+
+func TestTriggerSpeedLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		trigger   Trigger
+		direction LD2451.Direction
+		speed     int
+		speeding  bool
+	}{
+		{"disabled", Trigger{}, LD2451.DirectionToward, 200, false},
+		{"below the limit", Trigger{SpeedLimit: 50}, LD2451.DirectionToward, 50, false},
+		{"above the limit", Trigger{SpeedLimit: 50}, LD2451.DirectionAway, 51, true},
+		{"toward limit", Trigger{SpeedLimit: 50, TowardSpeedLimit: 30}, LD2451.DirectionToward, 40, true},
+		{"toward limit leaves receding targets", Trigger{SpeedLimit: 50, TowardSpeedLimit: 30}, LD2451.DirectionAway, 40, false},
+		{"away limit", Trigger{SpeedLimit: 50, AwaySpeedLimit: 70}, LD2451.DirectionAway, 60, false},
+		{"away limit leaves approaching targets", Trigger{SpeedLimit: 50, AwaySpeedLimit: 70}, LD2451.DirectionToward, 60, true},
+		{"only the toward limit", Trigger{TowardSpeedLimit: 30}, LD2451.DirectionToward, 40, true},
+		{"only the toward limit, receding", Trigger{TowardSpeedLimit: 30}, LD2451.DirectionAway, 200, false},
+		{"both limits", Trigger{TowardSpeedLimit: 30, AwaySpeedLimit: 70}, LD2451.DirectionAway, 71, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := LD2451.Target{Distance: 20, Direction: test.direction, Speed: test.speed, Time: time.Unix(1700000000, 0)}
+			payload, ok := test.trigger.Match(LD2451.TargetEvent{Target: target})
+			if ok != test.speeding {
+				t.Fatalf("matched %t, want %t", ok, test.speeding)
+			}
+			if ok && (payload.Event != EventSpeeding || payload.Target == nil || payload.Target.Speed != test.speed) {
+				t.Fatalf("got %+v, want the speeding target", payload)
+			}
+		})
+	}
+}
+
+func TestNewRejectsNegativeSpeedLimits(t *testing.T) {
+	endpoints := []Endpoint{{URL: "http://localhost/hook"}}
+	for _, config := range []Config{
+		{Endpoints: endpoints, SpeedLimit: -1},
+		{Endpoints: endpoints, TowardSpeedLimit: -1},
+		{Endpoints: endpoints, AwaySpeedLimit: -1},
+	} {
+		if _, err := New(nil, config); err == nil {
+			t.Errorf("accepted %+v", config)
+		}
+	}
+}